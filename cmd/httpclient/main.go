@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/client"
+)
+
+// headerFlags collects repeated -H flags.
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("header %q is not in name: value form", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: httpclient [flags] URL\n\nflags:\n")
+	flag.PrintDefaults()
+}
+
+// openBody returns the request body source named by data: "@file", "@-" for
+// stdin, or the literal string.
+func openBody(data string) (io.ReadCloser, error) {
+	switch {
+	case data == "@-":
+		return io.NopCloser(os.Stdin), nil
+	case strings.HasPrefix(data, "@"):
+		return os.Open(data[1:])
+	default:
+		return io.NopCloser(strings.NewReader(data)), nil
+	}
+}
+
+func tlsConfig(insecure bool, caFile string) (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	cfg.RootCAs = pool
+	return cfg, nil
+}
+
+func main() {
+	var headers headerFlags
+	method := flag.String("X", "", "request method (default GET, or POST with -d)")
+	flag.Var(&headers, "H", "request header as \"Name: value\", repeatable")
+	data := flag.String("d", "", "request body: a string, @file, or @- for stdin")
+	chunked := flag.Bool("chunked", false, "stream the body with chunked transfer coding")
+	follow := flag.Bool("L", false, "follow redirects")
+	include := flag.Bool("i", false, "print the status line and headers")
+	verbose := flag.Bool("v", false, "print the request and response heads to stderr")
+	insecure := flag.Bool("k", false, "skip TLS certificate verification")
+	caFile := flag.String("cacert", "", "PEM file with CA certificates to trust")
+	timeout := flag.Duration("timeout", 30*time.Second, "overall request timeout, 0 for none")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	m := *method
+	if m == "" {
+		m = "GET"
+		if *data != "" {
+			m = "POST"
+		}
+	}
+
+	req, err := client.NewRequest(m, flag.Arg(0), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, h := range headers {
+		name, value, _ := strings.Cut(h, ":")
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	if *data != "" {
+		body, err := openBody(*data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer body.Close()
+
+		if *chunked {
+			req.BodyReader = body
+		} else if req.Body, err = io.ReadAll(body); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	cfg, err := tlsConfig(*insecure, *caFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := &client.Client{
+		Timeout:           *timeout,
+		TLSConfig:         cfg,
+		MaxRedirects:      -1,
+		DisableKeepAlives: true,
+	}
+	if *follow {
+		c.MaxRedirects = client.DefaultMaxRedirects
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *verbose {
+		fmt.Fprintf(os.Stderr, "> %s %s HTTP/1.1\n", req.Method, req.URL.RequestURI())
+		req.Header.ForEach(func(name, value string) {
+			fmt.Fprintf(os.Stderr, "> %s: %s\n", name, value)
+		})
+		fmt.Fprintln(os.Stderr, ">")
+	}
+
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *verbose {
+		fmt.Fprintf(os.Stderr, "< %s %d %s\n", resp.Proto, resp.StatusCode, resp.Reason)
+		resp.Header.ForEach(func(name, value string) {
+			fmt.Fprintf(os.Stderr, "< %s: %s\n", name, value)
+		})
+		fmt.Fprintln(os.Stderr, "<")
+	}
+	if *include {
+		fmt.Printf("%s %d %s\r\n", resp.Proto, resp.StatusCode, resp.Reason)
+		resp.Header.ForEach(func(name, value string) {
+			fmt.Printf("%s: %s\r\n", name, value)
+		})
+		fmt.Print("\r\n")
+	}
+
+	os.Stdout.Write(resp.Body)
+
+	if *include && resp.Trailer.Len() > 0 {
+		fmt.Print("\r\n")
+		resp.Trailer.ForEach(func(name, value string) {
+			fmt.Printf("%s: %s\r\n", name, value)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/client"
+)
+
+// urlFlags collects repeated -url flags.
+type urlFlags []string
+
+func (u *urlFlags) String() string {
+	return strings.Join(*u, ", ")
+}
+
+func (u *urlFlags) Set(value string) error {
+	*u = append(*u, value)
+	return nil
+}
+
+// readURLs reads one URL per line, skipping blanks and # comments.
+func readURLs(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	urls := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			urls = append(urls, line)
+		}
+	}
+	return urls, scanner.Err()
+}
+
+// result is what one worker measured.
+type result struct {
+	latencies []time.Duration
+	statuses  map[int]int
+	errors    map[string]int
+	bytes     int64
+}
+
+func worker(ctx context.Context, c *client.Client, method string, urls []string, offset int) *result {
+	res := &result{statuses: map[int]int{}, errors: map[string]int{}}
+	for i := offset; ctx.Err() == nil; i++ {
+		req, err := client.NewRequest(method, urls[i%len(urls)], nil)
+		if err != nil {
+			res.errors[err.Error()]++
+			continue
+		}
+
+		start := time.Now()
+		resp, err := c.Do(ctx, req)
+		elapsed := time.Since(start)
+		if err != nil {
+			if ctx.Err() == nil {
+				res.errors[err.Error()]++
+			}
+			continue
+		}
+		res.latencies = append(res.latencies, elapsed)
+		res.statuses[int(resp.StatusCode)]++
+		res.bytes += int64(len(resp.Body))
+	}
+	return res
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+func report(results []*result, elapsed time.Duration, stats client.PoolStats) {
+	latencies := []time.Duration{}
+	statuses := map[int]int{}
+	errors := map[string]int{}
+	var bytes int64
+	for _, r := range results {
+		latencies = append(latencies, r.latencies...)
+		for code, n := range r.statuses {
+			statuses[code] += n
+		}
+		for msg, n := range r.errors {
+			errors[msg] += n
+		}
+		bytes += r.bytes
+	}
+	slices.Sort(latencies)
+
+	errCount := 0
+	for _, n := range errors {
+		errCount += n
+	}
+
+	fmt.Printf("requests:     %d completed, %d errors in %s\n", len(latencies), errCount, elapsed.Round(time.Millisecond))
+	fmt.Printf("throughput:   %.1f req/s, %.1f KiB/s\n",
+		float64(len(latencies))/elapsed.Seconds(), float64(bytes)/1024/elapsed.Seconds())
+	if len(latencies) > 0 {
+		fmt.Printf("latency:      p50 %s  p90 %s  p99 %s  max %s\n",
+			percentile(latencies, 0.50), percentile(latencies, 0.90),
+			percentile(latencies, 0.99), latencies[len(latencies)-1])
+	}
+	fmt.Printf("connections:  %d dialed, %d reused\n", stats.Dialed, stats.Reused)
+
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	fmt.Printf("status codes:")
+	for _, code := range codes {
+		fmt.Printf("  %d: %d", code, statuses[code])
+	}
+	fmt.Println()
+
+	for msg, n := range errors {
+		fmt.Printf("error:        %dx %s\n", n, msg)
+	}
+}
+
+func main() {
+	var urls urlFlags
+	flag.Var(&urls, "url", "target URL, repeatable; requests rotate through them")
+	urlFile := flag.String("urls", "", "file with one target URL per line")
+	concurrency := flag.Int("c", 10, "number of concurrent workers")
+	duration := flag.Duration("d", 10*time.Second, "how long to send requests")
+	method := flag.String("X", "GET", "request method")
+	keepAlive := flag.Bool("keepalive", true, "reuse connections between requests")
+	timeout := flag.Duration("timeout", 10*time.Second, "per-request timeout")
+	flag.Parse()
+
+	urls = append(urls, flag.Args()...)
+	if *urlFile != "" {
+		fromFile, err := readURLs(*urlFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		urls = append(urls, fromFile...)
+	}
+	if len(urls) == 0 || *concurrency <= 0 {
+		fmt.Fprintf(os.Stderr, "usage: httploadgen [flags] [URL...]\n\nflags:\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	c := &client.Client{
+		Timeout:           *timeout,
+		MaxRedirects:      -1,
+		DisableKeepAlives: !*keepAlive,
+		MaxIdlePerHost:    *concurrency,
+	}
+	defer c.CloseIdleConnections()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	fmt.Printf("%d workers for %s against %d url(s), keep-alive %t\n", *concurrency, *duration, len(urls), *keepAlive)
+
+	results := make([]*result, *concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = worker(ctx, c, *method, urls, i)
+		}()
+	}
+	wg.Wait()
+
+	report(results, time.Since(start), c.Stats())
+}
@@ -2,19 +2,21 @@ package main
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
-
-	"github.com/ShazimR/tcp-http-server/internal/request"
-	"github.com/ShazimR/tcp-http-server/internal/response"
-	"github.com/ShazimR/tcp-http-server/internal/router"
-	"github.com/ShazimR/tcp-http-server/internal/server"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/debug"
+	"github.com/ShazimR/tcp-http-server/pkg/metrics"
+	"github.com/ShazimR/tcp-http-server/pkg/middleware"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/ShazimR/tcp-http-server/pkg/server"
 )
 
 const port = 8080
@@ -40,42 +42,6 @@ func parseDemoBody(req *request.Request) string {
 	return fmt.Sprintf("msg: %s | ts: %d", reqBody.Message, reqBody.Timestamp)
 }
 
-func serveStatic(filename string, contentType string, req *request.Request, w *response.Writer) error {
-	h := response.GetDefaultHeaders(0)
-	h.Replace("Content-Type", contentType)
-
-	f, err := os.Open(filename)
-	if err != nil {
-		body := []byte("error loading content")
-		h.Replace("Content-Type", "text/plain")
-		h.Replace("Content-Length", strconv.Itoa(len(body)))
-		return w.WriteResponse(response.StatusInternalServerError, h, body)
-	}
-	defer f.Close()
-
-	if _, ok := req.Headers.Get("Range"); ok {
-		info, err := f.Stat()
-		if err != nil {
-			body := []byte("error loading content")
-			h.Replace("Content-Type", "text/plain")
-			h.Replace("Content-Length", strconv.Itoa(len(body)))
-			return w.WriteResponse(response.StatusInternalServerError, h, body)
-		}
-
-		filesize := int(info.Size())
-		return w.WritePartialContentResponse(f, filesize, contentType, req)
-	}
-
-	body, err := io.ReadAll(f)
-	if err != nil {
-		body = []byte("error loading file")
-		h.Replace("Content-Type", "text/plain")
-	}
-
-	h.Replace("Content-Length", strconv.Itoa(len(body)))
-	return w.WriteResponse(response.StatusOK, h, body)
-}
-
 func serveChunked(filename string, contentType string, w *response.Writer) error {
 	h := response.GetDefaultHeaders(0)
 	f, err := os.Open(filename)
@@ -86,68 +52,59 @@ func serveChunked(filename string, contentType string, w *response.Writer) error
 	}
 	defer f.Close()
 
-	status := response.StatusOK
 	h.Replace("Content-Type", contentType)
-	h.Delete("Content-Length")
-	h.Set("Transfer-Encoding", "chunked")
-
-	if err := w.WriteStatusLine(status); err != nil {
-		return err
-	}
-	if err := w.WriteHeaders(h); err != nil {
-		return err
-	}
-
-	data := [maxChunkSize]byte{}
-	for {
-		n, rErr := f.Read(data[:])
-
-		if n > 0 {
-			if err := w.WriteChunk(data[:n]); err != nil {
-				return err
-			}
-		}
-
-		if errors.Is(rErr, io.EOF) {
-			break
-		}
-		if rErr != nil {
-			return rErr
-		}
-	}
-
-	if err := w.WriteChunkEnd(false); err != nil {
-		return err
-	}
-
-	return nil
+	return w.WriteChunkedFrom(response.StatusOK, h, f, maxChunkSize, nil)
 }
 
 // Static handlers
 func serveIndex(w *response.Writer, req *request.Request) error {
-	return serveStatic("./static/index.html", "text/html", req, w)
+	return w.ServeFile(req, "./static/index.html")
 }
 
 func serveFavicon(w *response.Writer, req *request.Request) error {
-	return serveStatic("./static/favicon.ico", "image/x-icon", req, w)
+	return w.ServeFile(req, "./static/favicon.ico")
 }
 
 func serveStyles(w *response.Writer, req *request.Request) error {
-	return serveStatic("./static/styles.css", "text/css", req, w)
+	return w.ServeFile(req, "./static/styles.css")
 }
 
 func serveApp(w *response.Writer, req *request.Request) error {
-	return serveStatic("./static/app.js", "text/javascript", req, w)
+	return w.ServeFile(req, "./static/app.js")
 }
 
 func serveVideo(w *response.Writer, req *request.Request) error {
-	return serveStatic("./static/one-last-breath.mp4", "video/mp4", req, w)
+	return w.ServeFile(req, "./static/one-last-breath.mp4")
 }
 
 func serveVideoChunked(w *response.Writer, req *request.Request) error {
 	return serveChunked("./static/one-last-breath.mp4", "video/mp4", w)
 }
 
+// Streams the server time once a second until the client goes away
+func serveClock(w *response.Writer, req *request.Request) error {
+	sse, err := response.NewSSEWriter(w)
+	if err != nil {
+		return err
+	}
+
+	id, _ := strconv.Atoi(req.LastEventID())
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return nil
+		case now := <-ticker.C:
+			id++
+			if err := sse.SendEvent(strconv.Itoa(id), "tick", now.Format(time.RFC3339)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 func echo(w *response.Writer, req *request.Request) error {
 	status := response.StatusOK
 	h := response.GetDefaultHeaders(0)
@@ -206,11 +163,8 @@ func echoParams(w *response.Writer, req *request.Request) error {
 
 func login(w *response.Writer, req *request.Request) error {
 	var reqBody LoginResponse
-	if err := json.Unmarshal(req.Body, &reqBody); err != nil {
-		body := []byte("body must include 'username' and 'password' keys")
-		h := response.GetDefaultHeaders(len(body))
-		h.Replace("Content-Type", "text/plain")
-		return w.WriteResponse(response.StatusBadRequest, h, body)
+	if err := req.BindJSONWith(&reqBody, request.BindOptions{DisallowUnknownFields: true}); err != nil {
+		return err
 	}
 
 	const testUsername = "shazimr"
@@ -229,26 +183,6 @@ func login(w *response.Writer, req *request.Request) error {
 	return w.WriteResponse(response.StatusOK, h, []byte{})
 }
 
-func logger(next response.Handler) response.Handler {
-	return func(w *response.Writer, req *request.Request) error {
-		method := req.RequestLine.Method
-		path := req.RequestLine.RequestTarget
-		headerStr := ""
-		req.Headers.ForEach(func(name, value string) {
-			headerStr += fmt.Sprintf("  - %s: %s\n", name, value)
-		})
-
-		fmt.Printf("Method:      %s\n", method)
-		fmt.Printf("Path:        %s\n", path)
-		fmt.Printf("PathParams:  %s\n", req.PathParams)
-		fmt.Printf("QueryParams: %s\n", req.RequestParams)
-		fmt.Printf("Headers:\n%s", headerStr)
-		fmt.Printf("Body:\n%s\n\n", req.Body)
-
-		return next(w, req)
-	}
-}
-
 func auth(next response.Handler) response.Handler {
 	return func(w *response.Writer, req *request.Request) error {
 		if cookie, ok := req.Headers.Get("Cookie"); ok && cookie == fmt.Sprintf("Authentication=%s", testAuthKey) {
@@ -265,8 +199,13 @@ func auth(next response.Handler) response.Handler {
 
 func main() {
 	// Routers
+	m := metrics.New(metrics.Options{})
 	r := router.NewRouter()
-	r.Use(logger)
+	r.Use(
+		m.Middleware(),
+		middleware.AccessLog(middleware.AccessLogOptions{Format: middleware.LogCombined}),
+		response.Compress(response.DefaultCompressMinSize),
+	)
 	api := r.Group("/api")
 	api.Use(auth)
 	echoRouter := api.Group("/echo")
@@ -280,6 +219,8 @@ func main() {
 	r.GET("/app.js", serveApp)
 	r.GET("/video", serveVideo)
 	r.GET("/video-chunked", serveVideoChunked)
+	r.GET("/clock", serveClock)
+	r.GET("/metrics", m.Handler())
 
 	// Auth routes
 	r.POST("/login", login)
@@ -305,6 +246,19 @@ func main() {
 	userPosts.GET("/", echoParams)
 	userPosts.POST("/", echoParams)
 
+	// Profiling endpoints, only with DEBUG_ENDPOINTS=1 and behind the login cookie
+	err := debug.Register(r, debug.Options{
+		Enabled:    os.Getenv("DEBUG_ENDPOINTS") == "1",
+		Middleware: []router.Middleware{auth},
+	})
+	if err != nil {
+		log.Fatalf("error registering debug routes: %v", err)
+	}
+
+	for _, rt := range r.Routes() {
+		log.Printf("route %-7s %s", rt.Method, rt.Pattern)
+	}
+
 	// Setup and run server
 	s, err := server.Serve(port, nil, r)
 	if err != nil {
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"log"
 	"os"
@@ -13,14 +14,26 @@ import (
 	"strings"
 	"syscall"
 
-	"github.com/ShazimR/tcp-http-server/internal/headers"
-	"github.com/ShazimR/tcp-http-server/internal/request"
-	"github.com/ShazimR/tcp-http-server/internal/response"
-	"github.com/ShazimR/tcp-http-server/internal/server"
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/server"
 )
 
 const port = 8080
 
+// contentDigest hashes and counts the bytes written to it, for the trailers
+// sent after a chunked body.
+type contentDigest struct {
+	hash hash.Hash
+	n    int
+}
+
+func (d *contentDigest) Write(p []byte) (int, error) {
+	d.n += len(p)
+	return d.hash.Write(p)
+}
+
 func respond200() []byte {
 	return []byte(`<html>
 <head>
@@ -86,15 +99,7 @@ func handler(w *response.Writer, req *request.Request) error {
 		body = respond200()
 
 	} else if req.RequestLine.RequestTarget == "/favicon.ico" {
-		data, err := os.ReadFile("./static/favicon.ico")
-		if err != nil {
-			status = response.StatusNotFound
-			body = respond404()
-		} else {
-			h.Replace("Content-Type", "image/x-icon")
-			status = response.StatusOK
-			body = data
-		}
+		return w.ServeFile(req, "./static/favicon.ico")
 
 	} else if req.RequestLine.RequestTarget == "/yourproblem" {
 		status = response.StatusBadRequest
@@ -105,15 +110,7 @@ func handler(w *response.Writer, req *request.Request) error {
 		body = respond500()
 
 	} else if req.RequestLine.RequestTarget == "/video" {
-		data, err := os.ReadFile("./static/one-last-breath.mp4")
-		if err != nil {
-			status = response.StatusNotFound
-			body = respond404()
-		} else {
-			h.Replace("Content-Type", "video/mp4")
-			status = response.StatusOK
-			body = data
-		}
+		return w.ServeFile(req, "./static/one-last-breath.mp4")
 
 	} else if strings.HasPrefix(req.RequestLine.RequestTarget, "/static/") {
 		target := req.RequestLine.RequestTarget[len("/static/"):]
@@ -134,12 +131,6 @@ func handler(w *response.Writer, req *request.Request) error {
 			defer f.Close()
 			chunked = true
 
-			if err = w.WriteStatusLine(response.StatusOK); err != nil {
-				return err
-			}
-			h.Delete("Content-Length")
-			h.Set("Transfer-Encoding", "chunked")
-
 			targetParts := strings.Split(target, ".")
 			fileExt := "text/plain"
 			if len(targetParts) == 2 {
@@ -154,37 +145,14 @@ func handler(w *response.Writer, req *request.Request) error {
 			h.Set("Trailer", "X-Content-SHA256")
 			h.Set("Trailer", "X-Content-Length")
 
-			if err = w.WriteHeaders(h); err != nil {
-				return err
-			}
-
-			fullBody := []byte{}
-			data := make([]byte, 32)
-			for {
-				n, rErr := f.Read(data)
-				if n > 0 {
-					fullBody = append(fullBody, data[:n]...)
-
-					if err = w.WriteChunk(data[:n]); err != nil {
-						return err
-					}
-				}
-
-				if errors.Is(rErr, io.EOF) {
-					break
-				}
-				if rErr != nil {
-					return rErr
-				}
-			}
-			if err = w.WriteChunkEnd(true); err != nil {
-				return err
-			}
-			trailer := headers.NewHeaders()
-			sum := sha256.Sum256(fullBody)
-			trailer.Set("X-Content-SHA256", hex.EncodeToString(sum[:]))
-			trailer.Set("X-Content-Length", fmt.Sprintf("%d", len(fullBody)))
-			if err = w.WriteHeaders(trailer); err != nil {
+			digest := &contentDigest{hash: sha256.New()}
+			err = w.WriteChunkedFrom(response.StatusOK, h, io.TeeReader(f, digest), 32, func() *headers.Headers {
+				trailer := headers.NewHeaders()
+				trailer.Set("X-Content-SHA256", hex.EncodeToString(digest.hash.Sum(nil)))
+				trailer.Set("X-Content-Length", fmt.Sprintf("%d", digest.n))
+				return trailer
+			})
+			if err != nil {
 				return err
 			}
 		}
@@ -6,7 +6,7 @@ import (
 	"log"
 	"net"
 
-	"github.com/ShazimR/tcp-http-server/internal/request"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
 )
 
 func main() {
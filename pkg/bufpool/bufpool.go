@@ -0,0 +1,47 @@
+package bufpool
+
+import "sync"
+
+// classes are the buffer sizes kept for reuse. Requests round up to the next
+// class; larger buffers are allocated and dropped as usual.
+var classes = [...]int{1 << 10, 4 << 10, 16 << 10, 64 << 10}
+
+var pools [len(classes)]sync.Pool
+
+// class returns the index of the smallest class holding size, or -1.
+func class(size int) int {
+	for i, c := range classes {
+		if size <= c {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns a buffer of at least size bytes, its length set to its
+// capacity. Pass it to Put once nothing refers to its contents any more.
+// Pointers are handed around so that pooling doesn't allocate.
+func Get(size int) *[]byte {
+	i := class(size)
+	if i < 0 {
+		b := make([]byte, size)
+		return &b
+	}
+	if bp, ok := pools[i].Get().(*[]byte); ok {
+		*bp = (*bp)[:cap(*bp)]
+		return bp
+	}
+	b := make([]byte, classes[i])
+	return &b
+}
+
+// Put hands bp back for reuse. Buffers whose capacity isn't exactly a class,
+// e.g. ones grown by append, are left to the garbage collector.
+func Put(bp *[]byte) {
+	if bp == nil {
+		return
+	}
+	if i := class(cap(*bp)); i >= 0 && cap(*bp) == classes[i] {
+		pools[i].Put(bp)
+	}
+}
@@ -0,0 +1,34 @@
+package bufpool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPut(t *testing.T) {
+	// Test: Sizes round up to their class
+	for size, want := range map[int]int{0: 1 << 10, 1: 1 << 10, 1024: 1 << 10, 1025: 4 << 10, 60000: 64 << 10} {
+		bp := Get(size)
+		assert.Len(t, *bp, want, size)
+		assert.Equal(t, want, cap(*bp), size)
+		Put(bp)
+	}
+
+	// Test: Larger buffers are plain allocations
+	bp := Get(1 << 20)
+	assert.Len(t, *bp, 1<<20)
+	Put(bp)
+
+	// Test: Buffers come back at full length
+	bp = Get(100)
+	*bp = (*bp)[:3]
+	Put(bp)
+	assert.Len(t, *Get(100), 1<<10)
+
+	// Test: Odd capacities and nil are ignored
+	odd := make([]byte, 3000)
+	Put(&odd)
+	Put(nil)
+	assert.Equal(t, 4<<10, cap(*Get(3000)))
+}
@@ -0,0 +1,663 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+)
+
+var (
+	ErrUnsupportedScheme   = fmt.Errorf("unsupported url scheme")
+	ErrMalformedStatusLine = fmt.Errorf("malformed status-line")
+	ErrMalformedResponse   = fmt.Errorf("malformed response")
+	ErrTooManyRedirects    = fmt.Errorf("too many redirects")
+	ErrBodyTooLarge        = fmt.Errorf("response body too large")
+)
+
+const (
+	DefaultMaxRedirects = 10
+	DefaultUserAgent    = "tcp-http-server-client/1.0"
+	// DefaultMaxBodySize bounds how much of a response body is read into
+	// memory.
+	DefaultMaxBodySize = 64 * 1024 * 1024
+)
+
+// Request is an outgoing HTTP/1.1 request.
+type Request struct {
+	Method string
+	URL    *url.URL
+	Header *headers.Headers
+	// Body is sent with a Content-Length. BodyReader, when set instead, is
+	// streamed with chunked transfer coding, followed by Trailer if any.
+	Body       []byte
+	BodyReader io.Reader
+	Trailer    *headers.Headers
+}
+
+// NewRequest builds a request for rawURL with an optional body.
+func NewRequest(method string, rawURL string, body []byte) (*Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+
+	return &Request{
+		Method: strings.ToUpper(method),
+		URL:    u,
+		Header: headers.NewHeaders(),
+		Body:   body,
+	}, nil
+}
+
+// Response is a fully read HTTP response.
+type Response struct {
+	Proto      string // e.g. "HTTP/1.1"
+	StatusCode response.StatusCode
+	Reason     string
+	Header     *headers.Headers
+	Body       []byte
+	Trailer    *headers.Headers
+	// Request is the request that produced this response, which differs
+	// from the one passed to Do when redirects were followed.
+	Request *Request
+
+	closeDelimited bool // body ran to the end of the connection
+}
+
+// Client sends requests over TCP or TLS, keeping connections alive for reuse.
+// The zero value is ready to use; a Client must not be copied after first use.
+type Client struct {
+	// Timeout bounds each request, including connecting, redirects and
+	// reading the body. Zero means no timeout beyond the context's.
+	Timeout time.Duration
+	// TLSConfig is used for https URLs; nil uses the defaults.
+	TLSConfig *tls.Config
+	// MaxRedirects is the number of redirects followed before giving up,
+	// defaulting to DefaultMaxRedirects. Negative disables following.
+	MaxRedirects int
+	// MaxBodySize defaults to DefaultMaxBodySize.
+	MaxBodySize int64
+	// Dial opens connections; defaults to a net.Dialer.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// DisableKeepAlives sends every request on a new connection with
+	// Connection: close.
+	DisableKeepAlives bool
+	// MaxIdlePerHost bounds the idle connections kept per host, defaulting
+	// to DefaultMaxIdlePerHost. Negative keeps none.
+	MaxIdlePerHost int
+	// IdleTimeout closes connections left idle that long, defaulting to
+	// DefaultIdleTimeout.
+	IdleTimeout time.Duration
+
+	poolOnce sync.Once
+	pool     *connPool
+}
+
+func (c *Client) connPool() *connPool {
+	c.poolOnce.Do(func() {
+		c.pool = newConnPool(c.MaxIdlePerHost, c.IdleTimeout)
+	})
+	return c.pool
+}
+
+// Stats reports the state of the client's connection pool.
+func (c *Client) Stats() PoolStats {
+	return c.connPool().stats()
+}
+
+// CloseIdleConnections closes every pooled connection not in use.
+func (c *Client) CloseIdleConnections() {
+	c.connPool().closeIdle()
+}
+
+// Get is a convenience wrapper around Do.
+func (c *Client) Get(ctx context.Context, rawURL string) (*Response, error) {
+	req, err := NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(ctx, req)
+}
+
+// Do sends req and reads the response, following redirects.
+func (c *Client) Do(ctx context.Context, req *Request) (*Response, error) {
+	for _, h := range []*headers.Headers{req.Header, req.Trailer} {
+		if h == nil {
+			continue
+		}
+		if err := h.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	maxRedirects := c.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	for redirects := 0; ; redirects++ {
+		resp, err := c.roundTrip(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		next := redirectRequest(req, resp)
+		if next == nil || maxRedirects < 0 {
+			return resp, nil
+		}
+		if redirects >= maxRedirects {
+			return resp, fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, redirects)
+		}
+		req = next
+	}
+}
+
+// redirectRequest returns the request to follow resp with, or nil when resp
+// isn't a followable redirect.
+func redirectRequest(req *Request, resp *Response) *Request {
+	if !response.IsRedirect(resp.StatusCode) || req.BodyReader != nil {
+		return nil
+	}
+	location, ok := resp.Header.Get("Location")
+	if !ok {
+		return nil
+	}
+	u, err := req.URL.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+
+	next := &Request{
+		Method: req.Method,
+		URL:    u,
+		Header: req.Header.Clone(),
+		Body:   req.Body,
+	}
+
+	// 303, and 301/302 for anything but GET/HEAD as browsers do, become GET
+	switch resp.StatusCode {
+	case response.StatusSeeOther, response.StatusMovedPermanently, response.StatusFound:
+		if next.Method != "GET" && next.Method != "HEAD" {
+			next.Method = "GET"
+			next.Body = nil
+			next.Header.Del("Content-Type")
+		}
+	}
+
+	if u.Host != req.URL.Host {
+		next.Header.Del("Authorization")
+		next.Header.Del("Cookie")
+	}
+	return next
+}
+
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+func (c *Client) dial(ctx context.Context, u *url.URL) (net.Conn, error) {
+	dial := c.Dial
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+
+	conn, err := dial(ctx, "tcp", hostPort(u))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return conn, nil
+	}
+
+	cfg := &tls.Config{}
+	if c.TLSConfig != nil {
+		cfg = c.TLSConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = u.Hostname()
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func poolKey(u *url.URL) string {
+	return u.Scheme + "://" + hostPort(u)
+}
+
+// getConn takes an idle connection to req's host or dials a new one.
+func (c *Client) getConn(ctx context.Context, u *url.URL) (*persistConn, error) {
+	pool := c.connPool()
+	key := poolKey(u)
+	if !c.DisableKeepAlives {
+		if pc := pool.get(key); pc != nil {
+			return pc, nil
+		}
+	}
+
+	conn, err := c.dial(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	pool.addDialed()
+	return newPersistConn(conn, key), nil
+}
+
+// roundTrip sends a single request, on a pooled connection when one is idle.
+func (c *Client) roundTrip(ctx context.Context, req *Request) (*Response, error) {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, req.URL.Scheme)
+	}
+
+	for {
+		pc, err := c.getConn(ctx, req.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		// closing the connection unblocks reads and writes when ctx ends
+		stop := context.AfterFunc(ctx, func() { pc.conn.Close() })
+
+		resp, reusable, err := c.exchange(pc, req)
+		if !stop() {
+			reusable = false // ctx ended and closed the connection
+		}
+		if err != nil {
+			pc.conn.Close()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// the server may have closed an idle connection just as we
+			// picked it up. A request it never saw is safe to resend; one it
+			// may have acted on without answering only if it's idempotent.
+			if pc.reused && req.BodyReader == nil &&
+				(errors.Is(err, errNotSent) || errors.Is(err, errNoResponse) && idempotent(req.Method)) {
+				continue
+			}
+			return nil, err
+		}
+
+		if reusable {
+			c.connPool().put(pc)
+		} else {
+			pc.conn.Close()
+		}
+		return resp, nil
+	}
+}
+
+var (
+	// errNotSent marks a failure before any byte of the request went out.
+	errNotSent = fmt.Errorf("connection closed before request was sent")
+	// errNoResponse marks a failure after the request went out, at least in
+	// part, but before any byte of the response arrived.
+	errNoResponse = fmt.Errorf("connection closed before response")
+)
+
+// idempotent reports whether sending a request with method twice has the
+// same effect as sending it once.
+func idempotent(method string) bool {
+	switch method {
+	case "", "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// exchange writes req on pc and reads the response, reporting whether the
+// connection can carry another request.
+func (c *Client) exchange(pc *persistConn, req *Request) (*Response, bool, error) {
+	connection := ""
+	if c.DisableKeepAlives {
+		connection = "close"
+	}
+	sent := pc.written
+	err := writeRequest(pc.bw, req, connection)
+	if err == nil {
+		err = pc.bw.Flush()
+	}
+	if err != nil {
+		if pc.written == sent {
+			return nil, false, fmt.Errorf("%w: %w", errNotSent, err)
+		}
+		return nil, false, fmt.Errorf("%w: %w", errNoResponse, err)
+	}
+	if _, err := pc.br.Peek(1); err != nil {
+		return nil, false, fmt.Errorf("%w: %w", errNoResponse, err)
+	}
+
+	maxBody := c.MaxBodySize
+	if maxBody <= 0 {
+		maxBody = DefaultMaxBodySize
+	}
+	resp, err := readResponse(pc.br, req.Method, maxBody)
+	if err != nil {
+		return nil, false, err
+	}
+	resp.Request = req
+
+	reusable := !c.DisableKeepAlives &&
+		!resp.closeDelimited &&
+		resp.StatusCode != response.StatusSwitchingProtocols &&
+		keepAlive(resp.Proto, resp.Header) &&
+		!hasToken(req.Header, "Connection", "close") &&
+		pc.br.Buffered() == 0 // anything extra is a protocol error
+	return resp, reusable, nil
+}
+
+// hasToken reports whether the comma-separated header name lists token.
+func hasToken(h *headers.Headers, name string, token string) bool {
+	if h == nil {
+		return false
+	}
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keepAlive reports whether the server is keeping the connection open after
+// a response: HTTP/1.1 unless told otherwise, HTTP/1.0 only when asked.
+func keepAlive(proto string, h *headers.Headers) bool {
+	if hasToken(h, "Connection", "close") {
+		return false
+	}
+	if proto == "HTTP/1.0" {
+		return hasToken(h, "Connection", "keep-alive")
+	}
+	return true
+}
+
+// writeRequest writes req in HTTP/1.1 framing, setting the Connection header
+// when connection is not empty.
+func writeRequest(w *bufio.Writer, req *Request, connection string) error {
+	target := req.URL.RequestURI()
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+	fmt.Fprintf(w, "%s %s HTTP/1.1\r\n", method, target)
+
+	h := headers.NewHeaders()
+	if req.Header != nil {
+		h = req.Header.Clone()
+	}
+	if _, ok := h.Get("Host"); !ok {
+		h.Set("Host", req.URL.Host)
+	}
+	if _, ok := h.Get("User-Agent"); !ok {
+		h.Set("User-Agent", DefaultUserAgent)
+	}
+	if connection != "" {
+		h.Replace("Connection", connection)
+	}
+
+	chunked := req.BodyReader != nil
+	if chunked {
+		h.Del("Content-Length")
+		h.Replace("Transfer-Encoding", "chunked")
+		if req.Trailer != nil && req.Trailer.Len() > 0 {
+			names := []string{}
+			req.Trailer.ForEach(func(name, _ string) { names = append(names, name) })
+			h.Replace("Trailer", strings.Join(names, ", "))
+		}
+	} else if len(req.Body) > 0 || method == "POST" || method == "PUT" || method == "PATCH" {
+		h.Replace("Content-Length", strconv.Itoa(len(req.Body)))
+	}
+
+	h.ForEach(func(name, value string) {
+		fmt.Fprintf(w, "%s: %s\r\n", name, value)
+	})
+	w.WriteString("\r\n")
+
+	if !chunked {
+		_, err := w.Write(req.Body)
+		return err
+	}
+	return writeChunked(w, req.BodyReader, req.Trailer)
+}
+
+func writeChunked(w *bufio.Writer, r io.Reader, trailer *headers.Headers) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			w.WriteString("\r\n")
+			// let the server see each chunk as it is produced
+			if fErr := w.Flush(); fErr != nil {
+				return fErr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	w.WriteString("0\r\n")
+	if trailer != nil {
+		trailer.ForEach(func(name, value string) {
+			fmt.Fprintf(w, "%s: %s\r\n", name, value)
+		})
+	}
+	_, err := w.WriteString("\r\n")
+	return err
+}
+
+// ReadResponse reads one response to a request with the given method from
+// br, skipping interim 1xx responses. Bodies are limited to
+// DefaultMaxBodySize.
+func ReadResponse(br *bufio.Reader, method string) (*Response, error) {
+	return readResponse(br, method, DefaultMaxBodySize)
+}
+
+// readResponse reads one final response, skipping interim 1xx responses.
+func readResponse(br *bufio.Reader, method string, maxBody int64) (*Response, error) {
+	for {
+		resp, err := readHead(br)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 100 && resp.StatusCode < 200 && resp.StatusCode != response.StatusSwitchingProtocols {
+			continue
+		}
+
+		if err := readBody(br, resp, method, maxBody); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+func readLine(br *bufio.Reader) ([]byte, error) {
+	line, err := br.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, fmt.Errorf("%w: line too long", ErrMalformedResponse)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("%w: line not terminated by CRLF", ErrMalformedResponse)
+	}
+	return line, nil
+}
+
+func parseStatusLine(line []byte) (*Response, error) {
+	line = bytes.TrimRight(line, "\r\n")
+	proto, rest, ok := bytes.Cut(line, []byte(" "))
+	if !ok || !bytes.HasPrefix(proto, []byte("HTTP/1.")) {
+		return nil, ErrMalformedStatusLine
+	}
+	codeStr, reason, _ := bytes.Cut(rest, []byte(" "))
+	code, err := strconv.Atoi(string(codeStr))
+	if err != nil || len(codeStr) != 3 || code < 100 {
+		return nil, ErrMalformedStatusLine
+	}
+
+	return &Response{
+		Proto:      string(proto),
+		StatusCode: response.StatusCode(code),
+		Reason:     string(reason),
+		Header:     headers.NewHeaders(),
+		Trailer:    headers.NewHeaders(),
+	}, nil
+}
+
+func readHead(br *bufio.Reader) (*Response, error) {
+	line, err := readLine(br)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := parseStatusLine(line)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readFields(br, resp.Header); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// readFields parses header (or trailer) field lines up to the empty line.
+func readFields(br *bufio.Reader, h *headers.Headers) error {
+	for {
+		line, err := readLine(br)
+		if err != nil {
+			return err
+		}
+		_, done, err := h.Parse(line)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// bodyless reports whether a response to method with status has no body
+// regardless of its headers.
+func bodyless(method string, status response.StatusCode) bool {
+	return method == "HEAD" ||
+		(status >= 100 && status < 200) ||
+		status == response.StatusNoContent ||
+		status == response.StatusNotModified
+}
+
+func readBody(br *bufio.Reader, resp *Response, method string, maxBody int64) error {
+	if bodyless(method, resp.StatusCode) {
+		return nil
+	}
+
+	if te, ok := resp.Header.Get("Transfer-Encoding"); ok {
+		if !strings.EqualFold(strings.TrimSpace(te), "chunked") {
+			return fmt.Errorf("%w: unsupported transfer-encoding %q", ErrMalformedResponse, te)
+		}
+		return readChunked(br, resp, maxBody)
+	}
+
+	if cl, ok := resp.Header.Get("Content-Length"); ok {
+		n, err := strconv.ParseInt(strings.TrimSpace(cl), 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%w: bad content-length %q", ErrMalformedResponse, cl)
+		}
+		if n > maxBody {
+			return ErrBodyTooLarge
+		}
+		resp.Body = make([]byte, n)
+		_, err = io.ReadFull(br, resp.Body)
+		return err
+	}
+
+	// no framing: the body runs until the server closes the connection
+	resp.closeDelimited = true
+	body, err := io.ReadAll(io.LimitReader(br, maxBody+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > maxBody {
+		return ErrBodyTooLarge
+	}
+	resp.Body = body
+	return nil
+}
+
+func readChunked(br *bufio.Reader, resp *Response, maxBody int64) error {
+	body := []byte{}
+	for {
+		line, err := readLine(br)
+		if err != nil {
+			return err
+		}
+		sizeStr, _, _ := strings.Cut(strings.TrimSpace(string(line)), ";") // ignore extensions
+		size, err := strconv.ParseInt(sizeStr, 16, 64)
+		if err != nil || size < 0 {
+			return fmt.Errorf("%w: bad chunk size %q", ErrMalformedResponse, sizeStr)
+		}
+		if size == 0 {
+			break
+		}
+		if int64(len(body))+size > maxBody {
+			return ErrBodyTooLarge
+		}
+
+		start := len(body)
+		body = append(body, make([]byte, size)...)
+		if _, err := io.ReadFull(br, body[start:]); err != nil {
+			return err
+		}
+		crlf := make([]byte, 2)
+		if _, err := io.ReadFull(br, crlf); err != nil {
+			return err
+		}
+		if string(crlf) != "\r\n" {
+			return fmt.Errorf("%w: chunk not terminated by CRLF", ErrMalformedResponse)
+		}
+	}
+
+	resp.Body = body
+	return readFields(br, resp.Trailer)
+}
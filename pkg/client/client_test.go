@@ -0,0 +1,212 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer serves handler on a loopback port and returns its base URL.
+func startServer(t *testing.T, handler response.Handler) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := server.ServeListener(l, handler, nil, server.Options{})
+	t.Cleanup(func() { s.Close() })
+	return "http://" + l.Addr().String()
+}
+
+// cannedServer answers every connection with raw after reading the request
+// head, and returns its base URL.
+func cannedServer(t *testing.T, raw string) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				for {
+					line, err := br.ReadString('\n')
+					if err != nil || line == "\r\n" {
+						break
+					}
+				}
+				io.WriteString(conn, raw)
+			}()
+		}
+	}()
+	return "http://" + l.Addr().String()
+}
+
+func TestClient_Get(t *testing.T) {
+	base := startServer(t, func(w *response.Writer, req *request.Request) error {
+		body := []byte("hello " + req.RequestParams["name"])
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+	})
+
+	c := &Client{}
+	resp, err := c.Get(context.Background(), base+"/greet?name=x")
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+	assert.Equal(t, response.StatusOK, resp.StatusCode)
+	assert.Equal(t, "OK", resp.Reason)
+	assert.Equal(t, "hello x", string(resp.Body))
+	cl, _ := resp.Header.Get("Content-Length")
+	assert.Equal(t, "7", cl)
+}
+
+func TestClient_PostBody(t *testing.T) {
+	base := startServer(t, func(w *response.Writer, req *request.Request) error {
+		body := []byte(fmt.Sprintf("%s %s", req.RequestLine.Method, req.Body))
+		if req.Trailer != nil {
+			if v, ok := req.Trailer.Get("X-Checksum"); ok {
+				body = append(body, " "+v...)
+			}
+		}
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+	})
+	c := &Client{}
+
+	// Test: Fixed-length body
+	req, err := NewRequest("post", base+"/", []byte("payload"))
+	require.NoError(t, err)
+	resp, err := c.Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "POST payload", string(resp.Body))
+
+	// Test: Streamed body is sent chunked with trailers
+	req, err = NewRequest("PUT", base+"/", nil)
+	require.NoError(t, err)
+	req.BodyReader = strings.NewReader("streamed")
+	req.Trailer = req.Header.Clone()
+	req.Trailer.Set("X-Checksum", "abc")
+	resp, err = c.Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "PUT streamed abc", string(resp.Body))
+}
+
+func TestClient_ChunkedResponse(t *testing.T) {
+	base := cannedServer(t, "HTTP/1.1 100 Continue\r\n\r\n"+
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Sum\r\n\r\n"+
+		"5;ext=1\r\nhello\r\n6\r\n world\r\n0\r\nX-Sum: 42\r\n\r\n")
+
+	resp, err := (&Client{}).Get(context.Background(), base)
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello world", string(resp.Body))
+	sum, ok := resp.Trailer.Get("X-Sum")
+	assert.True(t, ok)
+	assert.Equal(t, "42", sum)
+}
+
+func TestClient_BodyFraming(t *testing.T) {
+	// Test: No framing reads until close
+	base := cannedServer(t, "HTTP/1.1 200 OK\r\nConnection: close\r\n\r\nuntil close")
+	resp, err := (&Client{}).Get(context.Background(), base)
+	require.NoError(t, err)
+	assert.Equal(t, "until close", string(resp.Body))
+
+	// Test: 204 has no body whatever the headers say
+	base = cannedServer(t, "HTTP/1.1 204 No Content\r\nContent-Length: 5\r\n\r\n")
+	resp, err = (&Client{}).Get(context.Background(), base)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Body)
+
+	// Test: Body over the limit
+	base = cannedServer(t, "HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\n")
+	_, err = (&Client{MaxBodySize: 10}).Get(context.Background(), base)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	// Test: Malformed status line
+	base = cannedServer(t, "HTTP/1.1 2x0 OK\r\n\r\n")
+	_, err = (&Client{}).Get(context.Background(), base)
+	assert.ErrorIs(t, err, ErrMalformedStatusLine)
+}
+
+func TestClient_Redirects(t *testing.T) {
+	base := startServer(t, func(w *response.Writer, req *request.Request) error {
+		switch req.RequestLine.RequestTarget {
+		case "/old":
+			return w.Redirect(response.StatusSeeOther, "/new")
+		case "/loop":
+			return w.Redirect(response.StatusFound, "/loop")
+		}
+		body := []byte(req.RequestLine.Method + " " + string(req.Body))
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+	})
+	c := &Client{}
+
+	// Test: 303 turns a POST into a GET without the body
+	req, err := NewRequest("POST", base+"/old", []byte("form"))
+	require.NoError(t, err)
+	resp, err := c.Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "GET ", string(resp.Body))
+	assert.Equal(t, "/new", resp.Request.URL.Path)
+
+	// Test: Redirect loops give up
+	c.MaxRedirects = 3
+	resp, err = c.Get(context.Background(), base+"/loop")
+	assert.ErrorIs(t, err, ErrTooManyRedirects)
+	assert.Equal(t, response.StatusFound, resp.StatusCode)
+
+	// Test: Following can be disabled
+	c.MaxRedirects = -1
+	resp, err = c.Get(context.Background(), base+"/old")
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusSeeOther, resp.StatusCode)
+}
+
+func TestClient_Timeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn) // never answer
+		}
+	}()
+
+	c := &Client{Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	_, err = c.Get(context.Background(), "http://"+l.Addr().String())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestNewRequest_Scheme(t *testing.T) {
+	_, err := NewRequest("GET", "ftp://example.com/", nil)
+	assert.ErrorIs(t, err, ErrUnsupportedScheme)
+}
+
+func TestClient_InvalidHeader(t *testing.T) {
+	req, err := NewRequest("GET", "http://127.0.0.1:1/", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Echo", "a\r\nX-Injected: 1")
+
+	// rejected before anything is dialed
+	_, err = (&Client{}).Do(context.Background(), req)
+	assert.ErrorIs(t, err, headers.ErrMalformedHeaderValue)
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxIdlePerHost = 2
+	DefaultIdleTimeout    = 90 * time.Second
+)
+
+// PoolStats is a snapshot of a client's connection pool for diagnostics.
+type PoolStats struct {
+	Dialed  uint64         // connections opened
+	Reused  uint64         // requests sent on an idle connection
+	Evicted uint64         // idle connections closed by timeout or the per-host limit
+	Idle    int            // connections currently idle
+	Hosts   map[string]int // idle connections per scheme://host:port
+}
+
+// persistConn is a connection that may carry several requests in turn.
+type persistConn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	bw      *bufio.Writer
+	key     string
+	reused  bool
+	written int64       // bytes that reached the connection
+	timer   *time.Timer // fires when the connection has idled too long
+}
+
+func newPersistConn(conn net.Conn, key string) *persistConn {
+	pc := &persistConn{
+		conn: conn,
+		br:   bufio.NewReader(conn),
+		key:  key,
+	}
+	pc.bw = bufio.NewWriter(pc)
+	return pc
+}
+
+// Write writes to the connection, counting what went out so that a failed
+// request can tell whether any of it reached the server.
+func (pc *persistConn) Write(p []byte) (int, error) {
+	n, err := pc.conn.Write(p)
+	pc.written += int64(n)
+	return n, err
+}
+
+// connPool keeps idle keep-alive connections per host, most recently used
+// last.
+type connPool struct {
+	maxIdlePerHost int
+	idleTimeout    time.Duration
+
+	mu      sync.Mutex
+	idle    map[string][]*persistConn
+	dialed  uint64
+	reused  uint64
+	evicted uint64
+}
+
+func newConnPool(maxIdlePerHost int, idleTimeout time.Duration) *connPool {
+	if maxIdlePerHost == 0 {
+		maxIdlePerHost = DefaultMaxIdlePerHost
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	return &connPool{
+		maxIdlePerHost: maxIdlePerHost,
+		idleTimeout:    idleTimeout,
+		idle:           map[string][]*persistConn{},
+	}
+}
+
+// get takes the most recently idled connection for key, if any.
+func (p *connPool) get(key string) *persistConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.idle[key]
+	if len(conns) == 0 {
+		return nil
+	}
+	pc := conns[len(conns)-1]
+	p.setIdle(key, conns[:len(conns)-1])
+	pc.timer.Stop()
+	pc.reused = true
+	p.reused++
+	return pc
+}
+
+// put returns a connection whose last response was fully read and which the
+// server is keeping open.
+func (p *connPool) put(pc *persistConn) {
+	if p.maxIdlePerHost < 0 {
+		pc.conn.Close()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := append(p.idle[pc.key], pc)
+	if len(conns) > p.maxIdlePerHost {
+		// the oldest connection is the likeliest to be closed by the server
+		conns[0].timer.Stop()
+		conns[0].conn.Close()
+		conns = conns[1:]
+		p.evicted++
+	}
+	p.setIdle(pc.key, conns)
+	pc.timer = time.AfterFunc(p.idleTimeout, func() { p.expire(pc) })
+}
+
+func (p *connPool) expire(pc *persistConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.idle[pc.key]
+	for i, c := range conns {
+		if c == pc {
+			p.setIdle(pc.key, append(conns[:i:i], conns[i+1:]...))
+			pc.conn.Close()
+			p.evicted++
+			return
+		}
+	}
+}
+
+// setIdle must be called with mu held.
+func (p *connPool) setIdle(key string, conns []*persistConn) {
+	if len(conns) == 0 {
+		delete(p.idle, key)
+		return
+	}
+	p.idle[key] = conns
+}
+
+func (p *connPool) addDialed() {
+	p.mu.Lock()
+	p.dialed++
+	p.mu.Unlock()
+}
+
+func (p *connPool) closeIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, conns := range p.idle {
+		for _, pc := range conns {
+			pc.timer.Stop()
+			pc.conn.Close()
+		}
+		delete(p.idle, key)
+	}
+}
+
+func (p *connPool) stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := PoolStats{
+		Dialed:  p.dialed,
+		Reused:  p.reused,
+		Evicted: p.evicted,
+		Hosts:   map[string]int{},
+	}
+	for key, conns := range p.idle {
+		s.Hosts[key] = len(conns)
+		s.Idle += len(conns)
+	}
+	return s
+}
@@ -0,0 +1,222 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keepAliveServer answers up to perConn GET requests on each connection with
+// extra response headers, then closes it. It returns the base URL and the
+// number of connections accepted.
+func keepAliveServer(t *testing.T, perConn int, extra string) (string, *atomic.Int32) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	accepted := &atomic.Int32{}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			n := accepted.Add(1)
+			go func() {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				for i := 0; i < perConn; i++ {
+					for {
+						line, err := br.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if line == "\r\n" {
+							break
+						}
+					}
+					body := fmt.Sprintf("conn %d", n)
+					fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n%s\r\n%s", len(body), extra, body)
+				}
+			}()
+		}
+	}()
+	return "http://" + l.Addr().String(), accepted
+}
+
+func TestPool_Reuse(t *testing.T) {
+	base, accepted := keepAliveServer(t, 100, "")
+	c := &Client{}
+	defer c.CloseIdleConnections()
+
+	for i := 0; i < 3; i++ {
+		resp, err := c.Get(context.Background(), base)
+		require.NoError(t, err)
+		assert.Equal(t, "conn 1", string(resp.Body))
+	}
+
+	assert.Equal(t, int32(1), accepted.Load())
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Dialed)
+	assert.Equal(t, uint64(2), stats.Reused)
+	assert.Equal(t, 1, stats.Idle)
+	assert.Equal(t, 1, stats.Hosts[poolKey(mustURL(t, base))])
+}
+
+func TestPool_ConnectionClose(t *testing.T) {
+	// Test: Server asking to close
+	base, accepted := keepAliveServer(t, 100, "Connection: close\r\n")
+	c := &Client{}
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(context.Background(), base)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), accepted.Load())
+	assert.Equal(t, 0, c.Stats().Idle)
+
+	// Test: Keep-alives disabled on the client
+	base, accepted = keepAliveServer(t, 100, "")
+	c = &Client{DisableKeepAlives: true}
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(context.Background(), base)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), accepted.Load())
+	assert.Equal(t, 0, c.Stats().Idle)
+}
+
+func TestPool_RetryClosedIdle(t *testing.T) {
+	// the server closes every connection after one response without saying so
+	base, accepted := keepAliveServer(t, 1, "")
+	c := &Client{}
+	defer c.CloseIdleConnections()
+
+	for i := 1; i <= 2; i++ {
+		resp, err := c.Get(context.Background(), base)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("conn %d", i), string(resp.Body))
+	}
+	assert.Equal(t, int32(2), accepted.Load())
+}
+
+func TestPool_NoRetryAfterSending(t *testing.T) {
+	// the server answers the first request on each connection, then reads
+	// the second and closes without answering, as if it crashed mid-way
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	received := &atomic.Int32{}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				for i := 0; i < 2; i++ {
+					length := 0
+					for {
+						line, err := br.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if line == "\r\n" {
+							break
+						}
+						fmt.Sscanf(line, "Content-Length: %d", &length)
+					}
+					if _, err := br.Discard(length); err != nil {
+						return
+					}
+					received.Add(1)
+					if i == 0 {
+						fmt.Fprint(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+					}
+				}
+			}()
+		}
+	}()
+	base := "http://" + l.Addr().String()
+
+	// Test: A POST the server may have acted on isn't sent again
+	c := &Client{}
+	defer c.CloseIdleConnections()
+	_, err = c.Get(context.Background(), base)
+	require.NoError(t, err)
+	req, err := NewRequest("POST", base, []byte("pay"))
+	require.NoError(t, err)
+	_, err = c.Do(context.Background(), req)
+	assert.ErrorIs(t, err, errNoResponse)
+	assert.Equal(t, int32(2), received.Load())
+
+	// Test: An idempotent request is, on a fresh connection
+	received.Store(0)
+	_, err = c.Get(context.Background(), base)
+	require.NoError(t, err)
+	req, err = NewRequest("PUT", base, []byte("v"))
+	require.NoError(t, err)
+	resp, err := c.Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(resp.Body))
+	assert.Equal(t, int32(3), received.Load())
+}
+
+func TestPool_Limits(t *testing.T) {
+	pool := newConnPool(1, 50*time.Millisecond)
+	newConn := func() *persistConn {
+		a, b := net.Pipe()
+		t.Cleanup(func() { b.Close() })
+		return newPersistConn(a, "http://x:80")
+	}
+
+	// Test: Over the per-host limit the oldest is evicted
+	first, second := newConn(), newConn()
+	pool.put(first)
+	pool.put(second)
+	stats := pool.stats()
+	assert.Equal(t, 1, stats.Idle)
+	assert.Equal(t, uint64(1), stats.Evicted)
+	assert.Same(t, second, pool.get("http://x:80"))
+
+	// Test: Idle timeout
+	pool.put(second)
+	assert.Eventually(t, func() bool { return pool.stats().Idle == 0 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(2), pool.stats().Evicted)
+	assert.Nil(t, pool.get("http://x:80"))
+}
+
+func TestKeepAlive(t *testing.T) {
+	h := mustHeaders(t, "Connection: keep-alive\r\n\r\n")
+	assert.True(t, keepAlive("HTTP/1.1", mustHeaders(t, "\r\n")))
+	assert.True(t, keepAlive("HTTP/1.0", h))
+	assert.False(t, keepAlive("HTTP/1.0", mustHeaders(t, "\r\n")))
+	assert.False(t, keepAlive("HTTP/1.1", mustHeaders(t, "Connection: Upgrade, Close\r\n\r\n")))
+}
+
+func mustURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	return u
+}
+
+func mustHeaders(t *testing.T, raw string) *headers.Headers {
+	t.Helper()
+	h := headers.NewHeaders()
+	_, done, err := h.Parse([]byte(raw))
+	require.NoError(t, err)
+	require.True(t, done)
+	return h
+}
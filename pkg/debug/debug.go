@@ -0,0 +1,181 @@
+package debug
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"html"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+const (
+	defaultCPUSeconds = 30
+	maxCPUSeconds     = 300
+)
+
+type Options struct {
+	// Enabled must be set for Register to add any routes, so the endpoints
+	// can be switched on from configuration without code changes.
+	Enabled bool
+	// Middleware guards every debug route, e.g. middleware.BasicAuth.
+	Middleware []router.Middleware
+	// BlockProfileRate and MutexProfileFraction are passed to the runtime
+	// when non-zero; without them the block and mutex profiles stay empty.
+	BlockProfileRate     int
+	MutexProfileFraction int
+}
+
+// Register mounts the runtime profiles under /debug/pprof/ and the expvar
+// variables at /debug/vars on r. It does nothing unless opts.Enabled is set.
+func Register(r *router.Router, opts Options) error {
+	if !opts.Enabled {
+		return nil
+	}
+
+	if opts.BlockProfileRate != 0 {
+		runtime.SetBlockProfileRate(opts.BlockProfileRate)
+	}
+	if opts.MutexProfileFraction != 0 {
+		runtime.SetMutexProfileFraction(opts.MutexProfileFraction)
+	}
+
+	g := r.Group("/debug")
+	g.Use(opts.Middleware...)
+
+	routes := []struct {
+		path    string
+		handler response.Handler
+	}{
+		{"/pprof", Index},
+		{"/pprof/cmdline", Cmdline},
+		{"/pprof/profile", Profile},
+		{"/pprof/:name", Lookup},
+		{"/vars", Vars},
+	}
+	for _, rt := range routes {
+		if err := g.GET(rt.path, rt.handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeText(w *response.Writer, status response.StatusCode, contentType string, body []byte) error {
+	h := response.GetDefaultHeaders(len(body))
+	h.Replace("Content-Type", contentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "no-store")
+	return w.WriteResponse(status, h, body)
+}
+
+func writeProfile(w *response.Writer, name string, body []byte) error {
+	h := response.GetDefaultHeaders(len(body))
+	h.Replace("Content-Type", "application/octet-stream")
+	h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	h.Set("Cache-Control", "no-store")
+	return w.WriteResponse(response.StatusOK, h, body)
+}
+
+// Index lists the available profiles with links to their text form.
+func Index(w *response.Writer, req *request.Request) error {
+	var buf bytes.Buffer
+	buf.WriteString("<html><head><title>/debug/pprof/</title></head><body>\n")
+	buf.WriteString("<p>/debug/pprof/</p>\n<table>\n")
+	for _, p := range pprof.Profiles() {
+		name := html.EscapeString(p.Name())
+		fmt.Fprintf(&buf, "<tr><td>%d</td><td><a href=\"/debug/pprof/%s?debug=1\">%s</a></td></tr>\n", p.Count(), name, name)
+	}
+	buf.WriteString("<tr><td></td><td><a href=\"/debug/pprof/profile\">profile</a> (CPU, ?seconds=30)</td></tr>\n")
+	buf.WriteString("</table>\n<p><a href=\"/debug/vars\">/debug/vars</a></p>\n</body></html>\n")
+
+	return writeText(w, response.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// Cmdline responds with the running program's command line, NUL separated.
+func Cmdline(w *response.Writer, req *request.Request) error {
+	return writeText(w, response.StatusOK, "text/plain; charset=utf-8", []byte(strings.Join(os.Args, "\x00")))
+}
+
+// Profile records a CPU profile for ?seconds= (default 30) and responds with
+// it in pprof's protobuf format. Only one CPU profile can run at a time.
+func Profile(w *response.Writer, req *request.Request) error {
+	seconds := defaultCPUSeconds
+	if s, ok := req.RequestParams["seconds"]; ok {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxCPUSeconds {
+			return response.NewHTTPError(response.StatusBadRequest, "invalid seconds")
+		}
+		seconds = n
+	}
+
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return response.WrapHTTPError(response.StatusConflict, "cpu profiling already in use", err)
+	}
+
+	timer := time.NewTimer(time.Duration(seconds) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-req.Context().Done():
+	}
+	pprof.StopCPUProfile()
+
+	if req.Context().Err() != nil {
+		return nil // client went away, nobody to answer
+	}
+	return writeProfile(w, "profile", buf.Bytes())
+}
+
+// Lookup responds with the named runtime profile (heap, goroutine, block,
+// mutex, allocs, threadcreate). ?debug=N selects the text form; gc=1 runs a
+// garbage collection before taking a heap profile.
+func Lookup(w *response.Writer, req *request.Request) error {
+	name := req.PathParams["name"]
+	p := pprof.Lookup(name)
+	if p == nil {
+		return response.NewHTTPError(response.StatusNotFound, "unknown profile")
+	}
+
+	debugLevel, _ := strconv.Atoi(req.RequestParams["debug"])
+	if name == "heap" && req.RequestParams["gc"] == "1" {
+		runtime.GC()
+	}
+
+	var buf bytes.Buffer
+	if err := p.WriteTo(&buf, debugLevel); err != nil {
+		return err
+	}
+
+	if debugLevel > 0 {
+		return writeText(w, response.StatusOK, "text/plain; charset=utf-8", buf.Bytes())
+	}
+	return writeProfile(w, name, buf.Bytes())
+}
+
+// Vars responds with every published expvar variable as a JSON object, in the
+// same format as the expvar package's own handler.
+func Vars(w *response.Writer, req *request.Request) error {
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if !first {
+			buf.WriteString(",\n")
+		}
+		first = false
+		fmt.Fprintf(&buf, "%q: %s", kv.Key, kv.Value)
+	})
+	buf.WriteString("\n}\n")
+
+	return writeText(w, response.StatusOK, "application/json; charset=utf-8", buf.Bytes())
+}
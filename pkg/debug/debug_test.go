@@ -0,0 +1,110 @@
+package debug
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mkReq(method, target string, query map[string]string) *request.Request {
+	if query == nil {
+		query = map[string]string{}
+	}
+	return &request.Request{
+		RequestLine: request.RequestLine{
+			Method:        method,
+			RequestTarget: target,
+		},
+		Headers:       headers.NewHeaders(),
+		PathParams:    make(map[string]string),
+		RequestParams: query,
+	}
+}
+
+func serve(t *testing.T, r *router.Router, req *request.Request) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+	if err := r.GetHandler(req)(w, req); err != nil {
+		require.NoError(t, response.DefaultErrorHandler(w, req, err))
+	}
+	require.NoError(t, w.Finish())
+	return buf.String()
+}
+
+func bodyOf(out string) string {
+	_, body, _ := strings.Cut(out, "\r\n\r\n")
+	return body
+}
+
+func TestRegister_Disabled(t *testing.T) {
+	r := router.NewRouter()
+	require.NoError(t, Register(r, Options{}))
+	assert.Empty(t, r.Routes())
+}
+
+func TestRegister_Endpoints(t *testing.T) {
+	r := router.NewRouter()
+	require.NoError(t, Register(r, Options{Enabled: true}))
+
+	// Test: Index lists the runtime profiles
+	out := serve(t, r, mkReq("GET", "/debug/pprof/", nil))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, out, `href="/debug/pprof/goroutine?debug=1"`)
+
+	// Test: Text form of a profile
+	out = serve(t, r, mkReq("GET", "/debug/pprof/goroutine", map[string]string{"debug": "1"}))
+	assert.Contains(t, out, "Content-Type: text/plain; charset=utf-8\r\n")
+	assert.Contains(t, bodyOf(out), "goroutine profile:")
+
+	// Test: Binary form is sent as an attachment
+	out = serve(t, r, mkReq("GET", "/debug/pprof/heap", nil))
+	assert.Contains(t, out, "Content-Disposition: attachment; filename=\"heap\"\r\n")
+
+	// Test: Unknown profile
+	out = serve(t, r, mkReq("GET", "/debug/pprof/nope", nil))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 404 Not Found\r\n"))
+
+	// Test: Invalid CPU profile duration
+	out = serve(t, r, mkReq("GET", "/debug/pprof/profile", map[string]string{"seconds": "abc"}))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 400 Bad Request\r\n"))
+
+	// Test: expvar variables are valid JSON
+	out = serve(t, r, mkReq("GET", "/debug/vars", nil))
+	var vars map[string]any
+	require.NoError(t, json.Unmarshal([]byte(bodyOf(out)), &vars))
+	assert.Contains(t, vars, "memstats")
+}
+
+func TestProfile_ClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := mkReq("GET", "/debug/pprof/profile", map[string]string{"seconds": "30"}).WithContext(ctx)
+
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+	require.NoError(t, Profile(w, req))
+	assert.Empty(t, buf.String())
+}
+
+func TestRegister_Middleware(t *testing.T) {
+	deny := func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			return response.NewHTTPError(response.StatusForbidden, "forbidden")
+		}
+	}
+	r := router.NewRouter()
+	require.NoError(t, Register(r, Options{Enabled: true, Middleware: []router.Middleware{deny}}))
+
+	out := serve(t, r, mkReq("GET", "/debug/vars", nil))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 403 Forbidden\r\n"))
+}
@@ -0,0 +1,191 @@
+package headers
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheControl holds the directives of a Cache-Control field (RFC 9111 §5.2),
+// covering both request and response directives. Durations are whole seconds;
+// a nil duration means the directive is absent.
+type CacheControl struct {
+	NoCache         bool
+	NoStore         bool
+	NoTransform     bool
+	MustRevalidate  bool
+	ProxyRevalidate bool
+	MustUnderstand  bool
+	Public          bool
+	Private         bool
+	Immutable       bool
+	OnlyIfCached    bool
+
+	MaxAge  *time.Duration
+	SMaxAge *time.Duration
+	// MaxStale is negative for a max-stale without a value, which accepts a
+	// response however stale.
+	MaxStale             *time.Duration
+	MinFresh             *time.Duration
+	StaleWhileRevalidate *time.Duration
+	StaleIfError         *time.Duration
+
+	// Extensions holds directives not listed above by lowercased name, with
+	// "" for directives without a value.
+	Extensions map[string]string
+}
+
+// Seconds returns a pointer to n seconds, for filling in CacheControl.
+func Seconds(n int) *time.Duration {
+	d := time.Duration(n) * time.Second
+	return &d
+}
+
+var cacheFlags = []struct {
+	name  string
+	field func(c *CacheControl) *bool
+}{
+	{"no-cache", func(c *CacheControl) *bool { return &c.NoCache }},
+	{"no-store", func(c *CacheControl) *bool { return &c.NoStore }},
+	{"no-transform", func(c *CacheControl) *bool { return &c.NoTransform }},
+	{"must-revalidate", func(c *CacheControl) *bool { return &c.MustRevalidate }},
+	{"proxy-revalidate", func(c *CacheControl) *bool { return &c.ProxyRevalidate }},
+	{"must-understand", func(c *CacheControl) *bool { return &c.MustUnderstand }},
+	{"public", func(c *CacheControl) *bool { return &c.Public }},
+	{"private", func(c *CacheControl) *bool { return &c.Private }},
+	{"immutable", func(c *CacheControl) *bool { return &c.Immutable }},
+	{"only-if-cached", func(c *CacheControl) *bool { return &c.OnlyIfCached }},
+}
+
+var cacheDurations = []struct {
+	name  string
+	field func(c *CacheControl) **time.Duration
+}{
+	{"max-age", func(c *CacheControl) **time.Duration { return &c.MaxAge }},
+	{"s-maxage", func(c *CacheControl) **time.Duration { return &c.SMaxAge }},
+	{"max-stale", func(c *CacheControl) **time.Duration { return &c.MaxStale }},
+	{"min-fresh", func(c *CacheControl) **time.Duration { return &c.MinFresh }},
+	{"stale-while-revalidate", func(c *CacheControl) **time.Duration { return &c.StaleWhileRevalidate }},
+	{"stale-if-error", func(c *CacheControl) **time.Duration { return &c.StaleIfError }},
+}
+
+// ParseCacheControl parses a Cache-Control value. Directive names are
+// case-insensitive and quoted values are unquoted. The field names no-cache
+// and private may list are dropped, leaving the directive set. A duration
+// with a value that isn't a non-negative integer is ignored, and so is a
+// repeated duration.
+func ParseCacheControl(value string) CacheControl {
+	var c CacheControl
+
+directives:
+	for _, part := range splitList(value) {
+		name, arg, hasArg := strings.Cut(strings.TrimSpace(part), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		arg = strings.Trim(strings.TrimSpace(arg), `"`)
+		if name == "" {
+			continue
+		}
+
+		for _, f := range cacheFlags {
+			if f.name == name {
+				*f.field(&c) = true
+				continue directives
+			}
+		}
+
+		for _, f := range cacheDurations {
+			if f.name != name {
+				continue
+			}
+			field := f.field(&c)
+			if *field != nil {
+				continue directives
+			}
+			if !hasArg && name == "max-stale" {
+				*field = Seconds(-1)
+				continue directives
+			}
+			if n, err := strconv.ParseUint(arg, 10, 31); err == nil {
+				*field = Seconds(int(n))
+			}
+			continue directives
+		}
+
+		if c.Extensions == nil {
+			c.Extensions = map[string]string{}
+		}
+		c.Extensions[name] = arg
+	}
+
+	return c
+}
+
+// String formats the directives as a Cache-Control value, in a fixed order
+// with extensions last, sorted by name.
+func (c CacheControl) String() string {
+	parts := []string{}
+	for _, f := range cacheFlags {
+		if *f.field(&c) {
+			parts = append(parts, f.name)
+		}
+	}
+	for _, f := range cacheDurations {
+		d := *f.field(&c)
+		switch {
+		case d == nil:
+		case *d < 0 && f.name == "max-stale":
+			parts = append(parts, f.name)
+		default:
+			parts = append(parts, f.name+"="+strconv.FormatInt(int64(max(*d, 0)/time.Second), 10))
+		}
+	}
+
+	names := make([]string, 0, len(c.Extensions))
+	for name := range c.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if arg := c.Extensions[name]; arg != "" {
+			parts = append(parts, name+"="+quoteIfNeeded(arg))
+		} else {
+			parts = append(parts, name)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// splitList splits a comma-separated field value, leaving commas inside
+// quoted strings alone.
+func splitList(value string) []string {
+	parts := []string{}
+	start, quoted := 0, false
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quoted:
+			i++
+		case value[i] == '"':
+			quoted = !quoted
+		case value[i] == ',' && !quoted:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+func quoteIfNeeded(s string) string {
+	if isToken([]byte(s)) {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// CacheControl parses the Cache-Control field, with repeated fields taken
+// together. A missing field gives no directives.
+func (h *Headers) CacheControl() CacheControl {
+	value, _ := h.Get("Cache-Control")
+	return ParseCacheControl(value)
+}
@@ -0,0 +1,60 @@
+package headers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	c := ParseCacheControl(`Public, MAX-AGE=3600, s-maxage="60", must-revalidate, no-cache="Set-Cookie, X-Id", max-stale, ext=a`)
+	assert.True(t, c.Public)
+	assert.True(t, c.MustRevalidate)
+	assert.True(t, c.NoCache)
+	assert.False(t, c.NoStore)
+	require.NotNil(t, c.MaxAge)
+	assert.Equal(t, time.Hour, *c.MaxAge)
+	require.NotNil(t, c.SMaxAge)
+	assert.Equal(t, time.Minute, *c.SMaxAge)
+	require.NotNil(t, c.MaxStale)
+	assert.Negative(t, *c.MaxStale)
+	assert.Nil(t, c.MinFresh)
+	assert.Equal(t, map[string]string{"ext": "a"}, c.Extensions)
+
+	// Test: Bad and repeated durations are ignored
+	c = ParseCacheControl("max-age=-1, s-maxage=soon, max-age=5, min-fresh=10, min-fresh=20")
+	assert.Nil(t, c.SMaxAge)
+	require.NotNil(t, c.MaxAge)
+	assert.Equal(t, 5*time.Second, *c.MaxAge)
+	assert.Equal(t, 10*time.Second, *c.MinFresh)
+
+	// Test: Repeated fields are read together
+	h := NewHeaders()
+	h.Set("Cache-Control", "no-store")
+	h.Set("Cache-Control", "max-age=0")
+	c = h.CacheControl()
+	assert.True(t, c.NoStore)
+	assert.Equal(t, time.Duration(0), *c.MaxAge)
+
+	assert.Equal(t, CacheControl{}, NewHeaders().CacheControl())
+}
+
+func TestCacheControlString(t *testing.T) {
+	assert.Equal(t, "", CacheControl{}.String())
+	assert.Equal(t, "no-store", CacheControl{NoStore: true}.String())
+
+	c := CacheControl{
+		Private:    true,
+		NoCache:    true,
+		MaxAge:     Seconds(0),
+		MaxStale:   Seconds(-1),
+		Extensions: map[string]string{"b": "x y", "a": ""},
+	}
+	assert.Equal(t, `no-cache, private, max-age=0, max-stale, a, b="x y"`, c.String())
+
+	// Test: String and ParseCacheControl round-trip
+	c = CacheControl{Public: true, Immutable: true, MaxAge: Seconds(31536000), StaleIfError: Seconds(60)}
+	assert.Equal(t, c, ParseCacheControl(c.String()))
+}
@@ -0,0 +1,50 @@
+package headers
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeFormat is the IMF-fixdate layout (RFC 7231 §7.1.1.1) HTTP dates are
+// sent in: Date, Last-Modified, Expires, cookie Expires attributes and so on.
+const TimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// obsolete date layouts recipients must still accept
+const (
+	rfc850Format  = "Monday, 02-Jan-06 15:04:05 GMT"
+	asctimeFormat = "Mon Jan _2 15:04:05 2006"
+)
+
+var ErrMalformedDate = fmt.Errorf("malformed http date")
+
+// FormatTime formats t as an IMF-fixdate in UTC.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// ParseTime parses an HTTP date in IMF-fixdate form or either of the
+// obsolete RFC 850 and asctime forms. The result is in UTC.
+func ParseTime(value string) (time.Time, error) {
+	for _, layout := range []string{TimeFormat, rfc850Format, asctimeFormat} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrMalformedDate, value)
+}
+
+// GetTime returns the named field parsed as an HTTP date. It reports false
+// when the field is missing or isn't a valid date.
+func (h *Headers) GetTime(name string) (time.Time, bool) {
+	value, ok := h.Get(name)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := ParseTime(value)
+	return t, err == nil
+}
+
+// SetTime replaces the named field with t formatted as an IMF-fixdate.
+func (h *Headers) SetTime(name string, t time.Time) {
+	h.Replace(name, FormatTime(t))
+}
@@ -0,0 +1,45 @@
+package headers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(1994, time.November, 6, 8, 49, 37, 0, time.UTC)
+
+	// Test: The three forms of RFC 7231 §7.1.1.1
+	for _, value := range []string{
+		"Sun, 06 Nov 1994 08:49:37 GMT",
+		"Sunday, 06-Nov-94 08:49:37 GMT",
+		"Sun Nov  6 08:49:37 1994",
+	} {
+		got, err := ParseTime(value)
+		require.NoError(t, err, value)
+		assert.True(t, want.Equal(got), value)
+	}
+
+	for _, value := range []string{"", "yesterday", "Sun, 06 Nov 1994 08:49:37 PST", "1994-11-06T08:49:37Z"} {
+		_, err := ParseTime(value)
+		assert.ErrorIs(t, err, ErrMalformedDate, value)
+	}
+
+	// Test: Formatting is always IMF-fixdate in GMT
+	est := time.FixedZone("EST", -5*60*60)
+	assert.Equal(t, "Sun, 06 Nov 1994 08:49:37 GMT", FormatTime(want.In(est)))
+
+	h := NewHeaders()
+	h.SetTime("Expires", want)
+	v, _ := h.Get("Expires")
+	assert.Equal(t, "Sun, 06 Nov 1994 08:49:37 GMT", v)
+	got, ok := h.GetTime("Expires")
+	assert.True(t, ok)
+	assert.True(t, want.Equal(got))
+
+	h.Replace("Expires", "0")
+	_, ok = h.GetTime("Expires")
+	assert.False(t, ok)
+}
@@ -0,0 +1,402 @@
+package headers
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+var (
+	sepCRLF = []byte("\r\n")
+	sepSP   = []byte(" ")
+)
+
+var (
+	ErrMalformedHeader      = fmt.Errorf("malformed header")
+	ErrMalformedFieldLine   = fmt.Errorf("malformed field line")
+	ErrMalformedHeaderName  = fmt.Errorf("malformed header name")
+	ErrMalformedHeaderValue = fmt.Errorf("malformed header value")
+	ErrHeaderTooLarge       = fmt.Errorf("header fields too large")
+)
+
+// isTchar reports whether ch may appear in a token (RFC 7230 §3.2.6).
+func isTchar(ch byte) bool {
+	switch {
+	case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", ch) != -1
+}
+
+func isToken(str []byte) bool {
+	if len(str) == 0 {
+		return false
+	}
+	for _, ch := range str {
+		if !isTchar(ch) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsToken reports whether s is a token (RFC 7230 §3.2.6), the syntax of
+// field names, methods and many parameter values.
+func IsToken(s string) bool {
+	return isToken([]byte(s))
+}
+
+// isFieldValue reports whether str is a valid field value: visible
+// characters, spaces and tabs, plus obs-text (RFC 7230 §3.2), so no control
+// bytes such as CR, LF or NUL.
+func isFieldValue(str []byte) bool {
+	for _, ch := range str {
+		if ch < ' ' && ch != '\t' || ch == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseHeader(fieldLine []byte) (string, string, error) {
+	if fieldLine[0] == ' ' || fieldLine[0] == '\t' {
+		// whitespace before the name, or obsolete line folding (RFC 7230 §3.2.4)
+		return "", "", ErrMalformedFieldLine
+	}
+
+	parts := bytes.SplitN(fieldLine, []byte(":"), 2)
+	if len(parts) != 2 {
+		return "", "", ErrMalformedHeader
+	}
+
+	name := parts[0]
+	value := bytes.Trim(parts[1], " \t")
+	if bytes.HasSuffix(name, sepSP) || bytes.HasSuffix(name, []byte("\t")) {
+		return "", "", ErrMalformedFieldLine
+	}
+	if !isFieldValue(value) {
+		return "", "", ErrMalformedHeaderValue
+	}
+
+	return string(name), string(value), nil
+}
+
+// canonicalName keeps a caller-provided mixed-case name (e.g. X-Content-SHA256)
+// as-is and converts all-lowercase names to MIME casing (content-type ->
+// Content-Type).
+func canonicalName(name string) string {
+	if hasUpper(name) {
+		return name
+	}
+
+	b := []byte(name)
+	upper := true
+	for i, ch := range b {
+		if upper && ch >= 'a' && ch <= 'z' {
+			b[i] = ch - ('a' - 'A')
+		}
+		upper = ch == '-'
+	}
+
+	return string(b)
+}
+
+func hasUpper(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if 'A' <= s[i] && s[i] <= 'Z' {
+			return true
+		}
+	}
+	return false
+}
+
+// lower lowercases the ASCII letters of name, returning it as-is, without
+// allocating, when it is already lowercase. Field names are tokens, so
+// nothing else needs folding.
+func lower(name string) string {
+	if !hasUpper(name) {
+		return name
+	}
+	var buf [64]byte
+	return string(appendLower(buf[:0], name))
+}
+
+func appendLower(b []byte, name string) []byte {
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		if 'A' <= ch && ch <= 'Z' {
+			ch += 'a' - 'A'
+		}
+		b = append(b, ch)
+	}
+	return b
+}
+
+// equalFold reports whether two field names match ignoring ASCII case.
+func equalFold(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		x, y := a[i], b[i]
+		if x == y {
+			continue
+		}
+		if 'A' <= x && x <= 'Z' {
+			x += 'a' - 'A'
+		}
+		if 'A' <= y && y <= 'Z' {
+			y += 'a' - 'A'
+		}
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
+
+type field struct {
+	name  string
+	value string
+}
+
+// smallHeaders is how many fields are looked up by scanning them; past it,
+// an index is built. Most messages stay below it, and for them lookups
+// neither hash nor allocate.
+const smallHeaders = 16
+
+// Headers keeps fields in insertion order so serialization is deterministic.
+// Once there are more than smallHeaders fields, index maps the lowercased
+// name to the field's position in fields.
+type Headers struct {
+	fields []field
+	index  map[string]int
+	// field lines and bytes consumed by Parse so far, for Limits
+	parsedLines int
+	parsedBytes int
+}
+
+func NewHeaders() *Headers {
+	return &Headers{
+		fields: []field{},
+	}
+}
+
+// find returns the position of the field called name, or -1.
+func (h *Headers) find(name string) int {
+	if h.index != nil {
+		var buf [64]byte
+		if i, ok := h.index[string(appendLower(buf[:0], name))]; ok {
+			return i
+		}
+		return -1
+	}
+
+	for i := range h.fields {
+		if equalFold(h.fields[i].name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (h *Headers) Get(name string) (string, bool) {
+	i := h.find(name)
+	if i < 0 {
+		return "", false
+	}
+	return h.fields[i].value, true
+}
+
+func (h *Headers) add(name string, value string) {
+	h.fields = append(h.fields, field{name: canonicalName(name), value: value})
+	switch {
+	case h.index != nil:
+		h.index[lower(name)] = len(h.fields) - 1
+	case len(h.fields) > smallHeaders:
+		h.index = make(map[string]int, 2*len(h.fields))
+		for i, f := range h.fields {
+			h.index[lower(f.name)] = i
+		}
+	}
+}
+
+func (h *Headers) Replace(name string, value string) {
+	if i := h.find(name); i >= 0 {
+		h.fields[i].value = value
+	} else {
+		h.add(name, value)
+	}
+}
+
+func (h *Headers) Set(name string, value string) {
+	if i := h.find(name); i >= 0 {
+		h.fields[i].value = h.fields[i].value + "," + value
+	} else {
+		h.add(name, value)
+	}
+}
+
+func (h *Headers) Delete(name string) {
+	h.Del(name)
+}
+
+func (h *Headers) Del(name string) {
+	i := h.find(name)
+	if i < 0 {
+		return
+	}
+
+	h.fields = append(h.fields[:i], h.fields[i+1:]...)
+	if h.index == nil {
+		return
+	}
+	delete(h.index, lower(name))
+	for k, j := range h.index {
+		if j > i {
+			h.index[k] = j - 1
+		}
+	}
+}
+
+// Values splits a combined field value back into its individual elements.
+func (h *Headers) Values(name string) []string {
+	str, ok := h.Get(name)
+	if !ok {
+		return nil
+	}
+
+	parts := strings.Split(str, ",")
+	values := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if v := strings.TrimSpace(part); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+func (h *Headers) Clone() *Headers {
+	clone := &Headers{
+		fields: make([]field, len(h.fields)),
+		index:  maps.Clone(h.index),
+	}
+	copy(clone.fields, h.fields)
+
+	return clone
+}
+
+// Reset removes every field, keeping the storage for the next set of fields.
+func (h *Headers) Reset() {
+	clear(h.fields)
+	h.fields = h.fields[:0]
+	clear(h.index)
+	h.parsedLines = 0
+	h.parsedBytes = 0
+}
+
+func (h *Headers) Len() int {
+	return len(h.fields)
+}
+
+// Validate reports the first field whose name isn't a token or whose value
+// holds control bytes such as CR or LF. Fields set from user input could
+// otherwise split the message they are written into.
+func (h *Headers) Validate() error {
+	for _, f := range h.fields {
+		if !isToken([]byte(f.name)) {
+			return fmt.Errorf("%w: %q", ErrMalformedHeaderName, f.name)
+		}
+		if !isFieldValue([]byte(f.value)) {
+			return fmt.Errorf("%w: %s: %q", ErrMalformedHeaderValue, f.name, f.value)
+		}
+	}
+
+	return nil
+}
+
+// ForEach visits every field in insertion order using its canonical (output)
+// casing.
+func (h *Headers) ForEach(cb func(name, value string)) {
+	for _, f := range h.fields {
+		cb(f.name, f.value)
+	}
+}
+
+// Limits bounds what ParseLimited accepts across all calls for one header
+// block. Zero fields are unlimited.
+type Limits struct {
+	MaxFields    int // field lines, counting repeated names separately
+	MaxFieldSize int // bytes in one field line, without its CRLF
+	MaxBytes     int // bytes in all field lines, with their CRLFs
+}
+
+func (h *Headers) Parse(data []byte) (int, bool, error) {
+	return h.ParseLimited(data, Limits{})
+}
+
+// ParseLimited is like Parse but fails with ErrHeaderTooLarge as soon as the
+// block parsed so far, or a field line still waiting for its CRLF, exceeds
+// limits.
+func (h *Headers) ParseLimited(data []byte, limits Limits) (int, bool, error) {
+	read := 0
+	done := false
+
+	for {
+		idx := bytes.Index(data[read:], sepCRLF)
+		if idx == -1 {
+			// don't wait for the end of a line that is already too long
+			if err := h.checkLimits(len(data)-read, 0, limits); err != nil {
+				return 0, false, err
+			}
+			break
+		}
+
+		// Empty Header
+		if idx == 0 {
+			done = true
+			read += len(sepCRLF)
+			break
+		}
+
+		if err := h.checkLimits(idx, 1, limits); err != nil {
+			return 0, false, err
+		}
+
+		name, value, err := parseHeader(data[read : read+idx])
+		if err != nil {
+			return 0, false, err
+		}
+
+		if !isToken([]byte(name)) {
+			return 0, false, ErrMalformedHeaderName
+		}
+
+		read += idx + len(sepCRLF)
+		h.parsedLines++
+		h.parsedBytes += idx + len(sepCRLF)
+
+		h.Set(name, value)
+	}
+
+	return read, done, nil
+}
+
+// checkLimits reports whether a field line of lineLen bytes (partial when
+// lines is 0) would take the block past limits.
+func (h *Headers) checkLimits(lineLen int, lines int, limits Limits) error {
+	if limits.MaxFieldSize > 0 && lineLen > limits.MaxFieldSize {
+		return fmt.Errorf("%w: field line over %d bytes", ErrHeaderTooLarge, limits.MaxFieldSize)
+	}
+	if limits.MaxFields > 0 && h.parsedLines+lines > limits.MaxFields {
+		return fmt.Errorf("%w: more than %d fields", ErrHeaderTooLarge, limits.MaxFields)
+	}
+	if limits.MaxBytes > 0 && h.parsedBytes+lineLen+lines*len(sepCRLF) > limits.MaxBytes {
+		return fmt.Errorf("%w: more than %d bytes", ErrHeaderTooLarge, limits.MaxBytes)
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package headers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderParse(t *testing.T) {
+	// Test: Valid headers
+	headers := NewHeaders()
+	data := []byte("Host: localhost:8080\r\nFooFoo: barbar\r\n\r\n")
+	n, done, err := headers.Parse(data)
+	require.NoError(t, err)
+	require.NotNil(t, headers)
+	hostStr1, ok := headers.Get("Host")
+	assert.True(t, ok)
+	assert.Equal(t, "localhost:8080", hostStr1)
+	hostStr2, ok := headers.Get("hOsT")
+	assert.True(t, ok)
+	assert.Equal(t, "localhost:8080", hostStr2)
+	fooStr, ok := headers.Get("FooFoo")
+	assert.True(t, ok)
+	assert.Equal(t, "barbar", fooStr)
+	missStr, ok := headers.Get("MissingKey")
+	assert.False(t, ok)
+	assert.Equal(t, "", missStr)
+	assert.Equal(t, 40, n)
+	assert.True(t, done)
+
+	// Test: Valid headers with OWS
+	headers = NewHeaders()
+	data = []byte("Host:    localhost:3000     \r\nBarBar: foofoo    \r\n\r\n")
+	n, done, err = headers.Parse(data)
+	require.NoError(t, err)
+	require.NotNil(t, headers)
+	hostStr, ok := headers.Get("Host")
+	assert.True(t, ok)
+	assert.Equal(t, "localhost:3000", hostStr)
+	barStr, ok := headers.Get("BarBar")
+	assert.True(t, ok)
+	assert.Equal(t, "foofoo", barStr)
+	missStr, ok = headers.Get("MissingKey")
+	assert.False(t, ok)
+	assert.Equal(t, "", missStr)
+	assert.Equal(t, 52, n)
+	assert.True(t, done)
+
+	// Test: Valid headers with multiple values for single headers
+	headers = NewHeaders()
+	data = []byte("Host: localhost:3000\r\nSet-Person: shazim-rahman\r\nSet-Person: bob-dingo\r\nSet-Person: john-doe\r\nSet-Person: shazim-rahman\r\n\r\n")
+	n, done, err = headers.Parse(data)
+	require.NoError(t, err)
+	require.NotNil(t, headers)
+	hostStr, ok = headers.Get("Host")
+	assert.True(t, ok)
+	assert.Equal(t, "localhost:3000", hostStr)
+	personStr, ok := headers.Get("Set-Person")
+	assert.True(t, ok)
+	assert.Equal(t, "shazim-rahman,bob-dingo,john-doe,shazim-rahman", personStr)
+	missStr, ok = headers.Get("MissingKey")
+	assert.False(t, ok)
+	assert.Equal(t, "", missStr)
+	assert.Equal(t, 123, n)
+	assert.True(t, done)
+
+	// Test: Invalid header spacing
+	headers = NewHeaders()
+	data = []byte("    Host : localhost:8080       \r\n\r\n")
+	n, done, err = headers.Parse(data)
+	require.Error(t, err)
+	assert.Equal(t, ErrMalformedFieldLine, err)
+	assert.Equal(t, 0, n)
+	assert.False(t, done)
+
+	// Test: Invalid header name (uses invalid chars)
+	headers = NewHeaders()
+	data = []byte("H©st: localhost:8080\r\n\r\n")
+	n, done, err = headers.Parse(data)
+	require.Error(t, err)
+	assert.Equal(t, ErrMalformedHeaderName, err)
+	assert.Equal(t, 0, n)
+	assert.False(t, done)
+}
+
+func TestHeaderHelpers(t *testing.T) {
+	// Test: Values splits combined fields
+	headers := NewHeaders()
+	headers.Set("Accept", "text/html")
+	headers.Set("Accept", "application/json, text/plain")
+	assert.Equal(t, []string{"text/html", "application/json", "text/plain"}, headers.Values("accept"))
+	assert.Nil(t, headers.Values("Missing"))
+
+	// Test: Del removes regardless of casing
+	headers.Set("X-Foo", "bar")
+	assert.Equal(t, 2, headers.Len())
+	headers.Del("x-FOO")
+	_, ok := headers.Get("X-Foo")
+	assert.False(t, ok)
+	assert.Equal(t, 1, headers.Len())
+
+	// Test: Clone is independent of the original
+	clone := headers.Clone()
+	clone.Replace("Accept", "*/*")
+	clone.Set("X-New", "1")
+	acceptStr, _ := headers.Get("Accept")
+	assert.Equal(t, "text/html,application/json, text/plain", acceptStr)
+	assert.Equal(t, 1, headers.Len())
+	assert.Equal(t, 2, clone.Len())
+}
+
+func TestHeaderCasing(t *testing.T) {
+	// Test: Lowercase names are emitted in canonical MIME casing
+	headers := NewHeaders()
+	headers.Set("content-type", "text/plain")
+	headers.Set("x-request-id", "abc")
+
+	// Test: Mixed-case names keep the casing they were first set with
+	headers.Set("X-Content-SHA256", "deadbeef")
+	headers.Replace("x-content-sha256", "cafebabe")
+
+	out := map[string]string{}
+	headers.ForEach(func(name, value string) {
+		out[name] = value
+	})
+	assert.Equal(t, map[string]string{
+		"Content-Type":     "text/plain",
+		"X-Request-Id":     "abc",
+		"X-Content-SHA256": "cafebabe",
+	}, out)
+}
+
+func TestHeaderOrder(t *testing.T) {
+	// Test: ForEach follows insertion order
+	headers := NewHeaders()
+	headers.Set("Content-Length", "0")
+	headers.Set("Connection", "close")
+	headers.Set("Content-Type", "text/html")
+	headers.Set("X-A", "1")
+	headers.Set("connection", "keep-alive")
+
+	var names []string
+	headers.ForEach(func(name, value string) {
+		names = append(names, name)
+	})
+	assert.Equal(t, []string{"Content-Length", "Connection", "Content-Type", "X-A"}, names)
+
+	// Test: Deleting keeps the remaining order and lookups intact
+	headers.Del("Connection")
+	headers.Set("X-B", "2")
+	names = nil
+	headers.ForEach(func(name, value string) {
+		names = append(names, name)
+	})
+	assert.Equal(t, []string{"Content-Length", "Content-Type", "X-A", "X-B"}, names)
+	xa, ok := headers.Get("x-a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", xa)
+}
+
+func TestHeaderReset(t *testing.T) {
+	headers := NewHeaders()
+	_, done, err := headers.ParseLimited([]byte("Host: x\r\nX-A: 1\r\n\r\n"), Limits{MaxFields: 2})
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	// Test: Fields and parse counters are gone, the storage stays
+	headers.Reset()
+	assert.Equal(t, 0, headers.Len())
+	_, ok := headers.Get("host")
+	assert.False(t, ok)
+	assert.Equal(t, 2, cap(headers.fields))
+
+	_, done, err = headers.ParseLimited([]byte("X-B: 2\r\nX-C: 3\r\n\r\n"), Limits{MaxFields: 2})
+	require.NoError(t, err)
+	assert.True(t, done)
+	xb, _ := headers.Get("X-B")
+	assert.Equal(t, "2", xb)
+	assert.Equal(t, 2, headers.Len())
+}
+
+func TestHeaderParse_Strict(t *testing.T) {
+	cases := []struct {
+		data string
+		err  error
+	}{
+		{"Host: a\r\n folded: value\r\n\r\n", ErrMalformedFieldLine},
+		{"Host: a\r\n\tcontinued\r\n\r\n", ErrMalformedFieldLine},
+		{"Host\t: a\r\n\r\n", ErrMalformedFieldLine},
+		{": a\r\n\r\n", ErrMalformedHeaderName},
+		{"X;Y: a\r\n\r\n", ErrMalformedHeaderName},
+		{"X\"Y\": a\r\n\r\n", ErrMalformedHeaderName},
+		{"X[Y]: a\r\n\r\n", ErrMalformedHeaderName},
+		{"X-Null: a\x00b\r\n\r\n", ErrMalformedHeaderValue},
+		{"X-Bell: \x07\r\n\r\n", ErrMalformedHeaderValue},
+		{"X-Del: a\x7f\r\n\r\n", ErrMalformedHeaderValue},
+		{"X-Cr: a\rb\r\n\r\n", ErrMalformedHeaderValue},
+	}
+	for _, tc := range cases {
+		_, _, err := NewHeaders().Parse([]byte(tc.data))
+		assert.ErrorIs(t, err, tc.err, "%q", tc.data)
+	}
+
+	// Test: Every tchar, tabs and obs-text are accepted
+	h := NewHeaders()
+	_, done, err := h.Parse([]byte("!#$%&'*+-.^_`|~09azAZ: a\tb \"q\" (c) \xe9\r\n\r\n"))
+	require.NoError(t, err)
+	assert.True(t, done)
+	v, _ := h.Get("!#$%&'*+-.^_`|~09azAZ")
+	assert.Equal(t, "a\tb \"q\" (c) \xe9", v)
+}
+
+func TestHeaderValidate(t *testing.T) {
+	h := NewHeaders()
+	h.Set("X-Ok", "a\tb")
+	require.NoError(t, h.Validate())
+
+	h.Set("X-Echo", "x\r\nSet-Cookie: session=stolen")
+	assert.ErrorIs(t, h.Validate(), ErrMalformedHeaderValue)
+
+	h = NewHeaders()
+	h.Set("X-Bad\r\nName", "v")
+	assert.ErrorIs(t, h.Validate(), ErrMalformedHeaderName)
+}
+
+func TestHeaderParseLimited(t *testing.T) {
+	limits := Limits{MaxFields: 2, MaxFieldSize: 16, MaxBytes: 30}
+
+	// Test: Within limits
+	h := NewHeaders()
+	_, done, err := h.ParseLimited([]byte("A: 1\r\nB: 2\r\n\r\n"), limits)
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	cases := []string{
+		"A: 1\r\nB: 2\r\nC: 3\r\n\r\n",              // too many fields
+		"Long: " + strings.Repeat("x", 11) + "\r\n", // one field too long
+		"Long: " + strings.Repeat("x", 11),          // even before its CRLF
+		"A: 12345678901\r\nB: 12345678901\r\n\r\n",  // block too long
+	}
+	for _, data := range cases {
+		_, _, err := NewHeaders().ParseLimited([]byte(data), limits)
+		assert.ErrorIs(t, err, ErrHeaderTooLarge, "%q", data)
+	}
+
+	// Test: Limits hold across calls
+	h = NewHeaders()
+	n, done, err := h.ParseLimited([]byte("A: 1\r\nB: 2\r\n"), limits)
+	require.NoError(t, err)
+	assert.Equal(t, 12, n)
+	assert.False(t, done)
+	_, _, err = h.ParseLimited([]byte("C: 3\r\n\r\n"), limits)
+	assert.ErrorIs(t, err, ErrHeaderTooLarge)
+}
+
+func TestHeaderIndex(t *testing.T) {
+	// Test: Lookups keep working once the fields outgrow the scan
+	headers := NewHeaders()
+	for i := range smallHeaders + 4 {
+		headers.Set(fmt.Sprintf("X-Field-%d", i), strconv.Itoa(i))
+	}
+	require.NotNil(t, headers.index)
+	v, ok := headers.Get("x-field-0")
+	assert.True(t, ok)
+	assert.Equal(t, "0", v)
+	headers.Set("X-FIELD-19", "again")
+	v, _ = headers.Get("X-Field-19")
+	assert.Equal(t, "19,again", v)
+
+	// Test: Deleting renumbers the index
+	headers.Del("X-Field-3")
+	_, ok = headers.Get("X-Field-3")
+	assert.False(t, ok)
+	v, _ = headers.Get("x-field-10")
+	assert.Equal(t, "10", v)
+	headers.Replace("X-Field-19", "replaced")
+	v, _ = headers.Get("X-Field-19")
+	assert.Equal(t, "replaced", v)
+	assert.Equal(t, smallHeaders+3, headers.Len())
+
+	// Test: Clones index independently
+	clone := headers.Clone()
+	clone.Del("X-Field-0")
+	v, _ = headers.Get("X-Field-0")
+	assert.Equal(t, "0", v)
+	v, _ = clone.Get("X-Field-1")
+	assert.Equal(t, "1", v)
+}
+
+func TestHeaderLookupAllocs(t *testing.T) {
+	small := NewHeaders()
+	small.Set("Content-Type", "text/plain")
+	small.Set("content-length", "5")
+	large := small.Clone()
+	for i := range smallHeaders {
+		large.Set(fmt.Sprintf("X-Field-%d", i), "v")
+	}
+
+	// Test: Getting and replacing don't allocate, whatever the casing
+	for _, h := range []*Headers{small, large} {
+		allocs := testing.AllocsPerRun(100, func() {
+			h.Get("Content-Type")
+			h.Get("content-length")
+			h.Get("X-Missing")
+			h.Replace("CONTENT-LENGTH", "6")
+		})
+		assert.Zero(t, allocs, h.Len())
+	}
+}
+
+func BenchmarkHeadersGet(b *testing.B) {
+	h := NewHeaders()
+	for _, name := range []string{"Host", "User-Agent", "Accept", "Accept-Encoding", "Content-Type", "Content-Length", "Connection", "Cookie"} {
+		h.Set(name, "value")
+	}
+	b.ReportAllocs()
+	for range b.N {
+		h.Get("Content-Type")
+		h.Get("cookie")
+		h.Get("X-Missing")
+	}
+}
+
+func BenchmarkHeadersParse(b *testing.B) {
+	data := []byte("Host: example.com\r\nUser-Agent: bench/1.0\r\nAccept: */*\r\nAccept-Encoding: gzip\r\nContent-Type: application/json\r\nContent-Length: 27\r\n\r\n")
+	b.ReportAllocs()
+	for range b.N {
+		h := NewHeaders()
+		if _, _, err := h.Parse(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package httptest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/client"
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/ShazimR/tcp-http-server/pkg/server"
+)
+
+// ResponseRecorder is the sink for a response.Writer under test. The
+// accessors finish the response and parse the bytes written so far.
+type ResponseRecorder struct {
+	// Writer is what the handler under test writes to.
+	Writer *response.Writer
+	buf    bytes.Buffer
+	method string
+}
+
+// NewRecorder returns a recorder whose Writer writes into it.
+func NewRecorder() *ResponseRecorder {
+	rec := &ResponseRecorder{}
+	rec.Writer = response.NewWriter(rec)
+	return rec
+}
+
+// ForMethod sets the request method the response is parsed for, which
+// matters for HEAD where no body follows the headers.
+func (rec *ResponseRecorder) ForMethod(method string) *ResponseRecorder {
+	rec.method = method
+	return rec
+}
+
+func (rec *ResponseRecorder) Write(p []byte) (int, error) {
+	return rec.buf.Write(p)
+}
+
+// Serve runs h against req with the recorder's Writer and finishes the
+// response, returning the handler's error.
+func (rec *ResponseRecorder) Serve(h response.Handler, req *request.Request) error {
+	rec.method = req.RequestLine.Method
+	err := h(rec.Writer, req)
+	if fErr := rec.Writer.Finish(); err == nil {
+		err = fErr
+	}
+	return err
+}
+
+// Raw returns everything written, unparsed.
+func (rec *ResponseRecorder) Raw() string {
+	return rec.buf.String()
+}
+
+// Result finishes the response and parses it.
+func (rec *ResponseRecorder) Result() (*client.Response, error) {
+	if err := rec.Writer.Finish(); err != nil {
+		return nil, err
+	}
+	method := rec.method
+	if method == "" {
+		method = "GET"
+	}
+	return client.ReadResponse(bufio.NewReader(bytes.NewReader(rec.buf.Bytes())), method)
+}
+
+func (rec *ResponseRecorder) mustResult() *client.Response {
+	resp, err := rec.Result()
+	if err != nil {
+		panic(fmt.Sprintf("httptest: unparsable response: %v\n%s", err, rec.Raw()))
+	}
+	return resp
+}
+
+// Status returns the response status code.
+func (rec *ResponseRecorder) Status() response.StatusCode {
+	return rec.mustResult().StatusCode
+}
+
+// Header returns the response headers.
+func (rec *ResponseRecorder) Header() *headers.Headers {
+	return rec.mustResult().Header
+}
+
+// Body returns the response body with any chunked framing removed.
+func (rec *ResponseRecorder) Body() []byte {
+	return rec.mustResult().Body
+}
+
+// Trailer returns the trailers sent after a chunked body.
+func (rec *ResponseRecorder) Trailer() *headers.Headers {
+	return rec.mustResult().Trailer
+}
+
+// NewRequest builds a request by running its wire form through the request
+// parser, so query and trailer handling match what a server would see. A Host
+// header and the Content-Length of body are added when missing. It panics on
+// a request the parser rejects.
+func NewRequest(method string, target string, body []byte, h *headers.Headers) *request.Request {
+	if h == nil {
+		h = headers.NewHeaders()
+	} else {
+		h = h.Clone()
+	}
+	if _, ok := h.Get("Host"); !ok {
+		h.Set("Host", "example.com")
+	}
+	_, chunked := h.Get("Transfer-Encoding")
+	if _, ok := h.Get("Content-Length"); !ok && !chunked && len(body) > 0 {
+		h.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", method, target)
+	h.ForEach(func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	})
+	buf.WriteString("\r\n")
+	buf.Write(body)
+
+	req, err := request.RequestFromReader(&buf)
+	if err != nil {
+		panic(fmt.Sprintf("httptest: invalid request %s %s: %v", method, target, err))
+	}
+	return req
+}
+
+// StartTestServer serves r on an ephemeral loopback port until the test ends
+// and returns its base URL, e.g. "http://127.0.0.1:41234".
+func StartTestServer(t testing.TB, r *router.Router) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("httptest: listen: %v", err)
+	}
+	s := server.ServeListener(l, nil, r, server.Options{})
+	t.Cleanup(func() { s.Close() })
+	return "http://" + l.Addr().String()
+}
@@ -0,0 +1,93 @@
+package httptest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/client"
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func echoHandler(w *response.Writer, req *request.Request) error {
+	body := []byte(req.RequestLine.Method + " " + req.RequestParams["q"] + " " + string(req.Body))
+	h := response.GetDefaultHeaders(len(body))
+	h.Set("X-Route", req.RoutePattern)
+	return w.WriteResponse(response.StatusCreated, h, body)
+}
+
+func TestRecorder(t *testing.T) {
+	// Test: Fixed-length response
+	rec := NewRecorder()
+	req := NewRequest("POST", "/items?q=1", []byte("data"), nil)
+	require.NoError(t, rec.Serve(echoHandler, req))
+	assert.Equal(t, response.StatusCreated, rec.Status())
+	assert.Equal(t, "POST 1 data", string(rec.Body()))
+	cl, _ := rec.Header().Get("Content-Length")
+	assert.Equal(t, "11", cl)
+	assert.True(t, strings.HasPrefix(rec.Raw(), "HTTP/1.1 201 Created\r\n"))
+
+	// Test: Chunked body and trailers are decoded
+	rec = NewRecorder()
+	err := rec.Serve(func(w *response.Writer, req *request.Request) error {
+		h := response.GetDefaultHeaders(0)
+		h.Set("Trailer", "X-Count")
+		return w.WriteChunkedFrom(response.StatusOK, h, strings.NewReader("abcd"), 2, func() *headers.Headers {
+			trailer := headers.NewHeaders()
+			trailer.Set("X-Count", "2")
+			return trailer
+		})
+	}, NewRequest("GET", "/", nil, nil))
+	require.NoError(t, err)
+	assert.Equal(t, "abcd", string(rec.Body()))
+	count, _ := rec.Trailer().Get("X-Count")
+	assert.Equal(t, "2", count)
+
+	// Test: Bare body written without headers is finished by the accessors
+	rec = NewRecorder()
+	require.NoError(t, rec.Writer.WriteBody([]byte("implicit")))
+	assert.Equal(t, response.StatusOK, rec.Status())
+	assert.Equal(t, "implicit", string(rec.Body()))
+}
+
+func TestNewRequest(t *testing.T) {
+	h := headers.NewHeaders()
+	h.Set("Authorization", "Bearer tok")
+	req := NewRequest("PUT", "/a/b?x=1&y=2", []byte("{}"), h)
+
+	assert.Equal(t, "PUT", req.RequestLine.Method)
+	assert.Equal(t, "/a/b", req.RequestLine.RequestTarget)
+	assert.Equal(t, map[string]string{"x": "1", "y": "2"}, req.RequestParams)
+	assert.Equal(t, "{}", string(req.Body))
+	host, _ := req.Headers.Get("Host")
+	assert.Equal(t, "example.com", host)
+	token, ok := req.BearerToken()
+	assert.True(t, ok)
+	assert.Equal(t, "tok", token)
+
+	// the caller's headers are left alone
+	_, ok = h.Get("Content-Length")
+	assert.False(t, ok)
+
+	assert.Panics(t, func() { NewRequest("GET", "/a b", nil, nil) })
+}
+
+func TestStartTestServer(t *testing.T) {
+	r := router.NewRouter()
+	require.NoError(t, r.POST("/items/:id", echoHandler))
+	base := StartTestServer(t, r)
+
+	req, err := client.NewRequest("POST", base+"/items/7?q=z", []byte("body"))
+	require.NoError(t, err)
+	resp, err := (&client.Client{}).Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "POST z body", string(resp.Body))
+	route, _ := resp.Header.Get("X-Route")
+	assert.Equal(t, "/items/:id", route)
+}
@@ -0,0 +1,285 @@
+package metrics
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/ShazimR/tcp-http-server/pkg/server"
+)
+
+// ContentType is the Prometheus text exposition format served by Handler.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// UnmatchedRoute is the route label used for requests that matched no
+// registered pattern, so unknown paths can't blow up label cardinality.
+const UnmatchedRoute = "unmatched"
+
+// OtherMethod is the method label used for request methods outside the
+// standard set, since the parser accepts any method token.
+const OtherMethod = "OTHER"
+
+var knownMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "DELETE": true,
+	"PATCH": true, "OPTIONS": true, "CONNECT": true, "TRACE": true,
+}
+
+// DefaultBuckets are the latency histogram upper bounds, in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type Options struct {
+	Namespace string    // metric name prefix, defaults to "http"
+	Buckets   []float64 // latency buckets in seconds, defaults to DefaultBuckets
+}
+
+type routeKey struct {
+	method string
+	route  string
+}
+
+type requestKey struct {
+	routeKey
+	class string // "2xx", "4xx", ...
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative; the last one is +Inf
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	i, _ := slices.BinarySearch(buckets, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// Metrics collects request count, latency, in-flight requests and bytes
+// transferred, labelled by method and route pattern.
+type Metrics struct {
+	namespace string
+	buckets   []float64
+	inFlight  atomic.Int64
+
+	mu            sync.Mutex
+	requests      map[requestKey]uint64
+	durations     map[routeKey]*histogram
+	requestBytes  map[routeKey]uint64
+	responseBytes map[routeKey]uint64
+	servers       []*server.Server
+}
+
+func New(opts Options) *Metrics {
+	if opts.Namespace == "" {
+		opts.Namespace = "http"
+	}
+	buckets := DefaultBuckets
+	if len(opts.Buckets) > 0 {
+		buckets = slices.Clone(opts.Buckets)
+		slices.Sort(buckets)
+	}
+
+	return &Metrics{
+		namespace:     opts.Namespace,
+		buckets:       buckets,
+		requests:      map[requestKey]uint64{},
+		durations:     map[routeKey]*histogram{},
+		requestBytes:  map[routeKey]uint64{},
+		responseBytes: map[routeKey]uint64{},
+	}
+}
+
+func statusClass(status response.StatusCode) string {
+	if status < 100 || status > 599 {
+		return "none" // nothing was written, e.g. a hijacked connection
+	}
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// Observe records one finished request.
+func (m *Metrics) Observe(method string, route string, status response.StatusCode, latency time.Duration, reqBytes int64, respBytes int64) {
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	if !knownMethods[method] {
+		method = OtherMethod
+	}
+	rk := routeKey{method: method, route: route}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{routeKey: rk, class: statusClass(status)}]++
+	h := m.durations[rk]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets)+1)}
+		m.durations[rk] = h
+	}
+	h.observe(m.buckets, latency.Seconds())
+	m.requestBytes[rk] += uint64(max(reqBytes, 0))
+	m.responseBytes[rk] += uint64(max(respBytes, 0))
+}
+
+// TrackServer adds the connection counters of s (see server.Stats), summed
+// over every tracked server, to the output.
+func (m *Metrics) TrackServer(s *server.Server) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.servers = append(m.servers, s)
+}
+
+// Middleware instruments every request passing through it. Register it on the
+// root router so the route pattern set during dispatch is available.
+func (m *Metrics) Middleware() router.Middleware {
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			m.inFlight.Add(1)
+			defer m.inFlight.Add(-1)
+
+			start := time.Now()
+			err := next(w, req)
+
+			status := w.Status()
+			if err != nil && !w.Written() {
+				// the error handler answers after the middleware chain returns
+				status = response.ErrorStatus(err)
+			}
+			m.Observe(req.RequestLine.Method, req.RoutePattern, status, time.Since(start), int64(len(req.Body)), w.BytesWritten())
+			return err
+		}
+	}
+}
+
+// Handler serves the collected metrics in the Prometheus text format.
+func (m *Metrics) Handler() response.Handler {
+	return func(w *response.Writer, req *request.Request) error {
+		body := []byte(m.String())
+		h := response.GetDefaultHeaders(len(body))
+		h.Replace("Content-Type", ContentType)
+		return w.WriteResponse(response.StatusOK, h, body)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names []string, values ...string) string {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, "%s=\"%s\"", name, labelEscaper.Replace(values[i]))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeFamily(sb *strings.Builder, name string, kind string, help string) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func compareRouteKeys(a, b routeKey) int {
+	if c := strings.Compare(a.route, b.route); c != 0 {
+		return c
+	}
+	return strings.Compare(a.method, b.method)
+}
+
+func sortedKeys[K comparable, V any](m map[K]V, cmp func(a, b K) int) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, cmp)
+	return keys
+}
+
+// String renders the metrics in the Prometheus text exposition format, with
+// series sorted by route, method and status class.
+func (m *Metrics) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sb strings.Builder
+	routeLabels := []string{"method", "route"}
+	prefix := m.namespace + "_"
+
+	name := prefix + "requests_total"
+	writeFamily(&sb, name, "counter", "Total number of HTTP requests handled.")
+	keys := sortedKeys(m.requests, func(a, b requestKey) int {
+		if c := compareRouteKeys(a.routeKey, b.routeKey); c != 0 {
+			return c
+		}
+		return strings.Compare(a.class, b.class)
+	})
+	for _, k := range keys {
+		labels := formatLabels([]string{"method", "route", "code"}, k.method, k.route, k.class)
+		fmt.Fprintf(&sb, "%s%s %d\n", name, labels, m.requests[k])
+	}
+
+	name = prefix + "request_duration_seconds"
+	writeFamily(&sb, name, "histogram", "HTTP request latency in seconds.")
+	for _, k := range sortedKeys(m.durations, compareRouteKeys) {
+		h := m.durations[k]
+		var cumulative uint64
+		for i, le := range m.buckets {
+			cumulative += h.counts[i]
+			labels := formatLabels([]string{"method", "route", "le"}, k.method, k.route, formatFloat(le))
+			fmt.Fprintf(&sb, "%s_bucket%s %d\n", name, labels, cumulative)
+		}
+		labels := formatLabels([]string{"method", "route", "le"}, k.method, k.route, "+Inf")
+		fmt.Fprintf(&sb, "%s_bucket%s %d\n", name, labels, h.count)
+
+		labels = formatLabels(routeLabels, k.method, k.route)
+		fmt.Fprintf(&sb, "%s_sum%s %s\n", name, labels, formatFloat(h.sum))
+		fmt.Fprintf(&sb, "%s_count%s %d\n", name, labels, h.count)
+	}
+
+	name = prefix + "requests_in_flight"
+	writeFamily(&sb, name, "gauge", "Number of HTTP requests currently being handled.")
+	fmt.Fprintf(&sb, "%s %d\n", name, m.inFlight.Load())
+
+	name = prefix + "request_size_bytes_total"
+	writeFamily(&sb, name, "counter", "Total bytes received in HTTP request bodies.")
+	for _, k := range sortedKeys(m.requestBytes, compareRouteKeys) {
+		fmt.Fprintf(&sb, "%s%s %d\n", name, formatLabels(routeLabels, k.method, k.route), m.requestBytes[k])
+	}
+
+	name = prefix + "response_size_bytes_total"
+	writeFamily(&sb, name, "counter", "Total bytes sent in HTTP response bodies.")
+	for _, k := range sortedKeys(m.responseBytes, compareRouteKeys) {
+		fmt.Fprintf(&sb, "%s%s %d\n", name, formatLabels(routeLabels, k.method, k.route), m.responseBytes[k])
+	}
+
+	if len(m.servers) > 0 {
+		var stats server.Stats
+		for _, s := range m.servers {
+			st := s.Stats()
+			stats.Accepted += st.Accepted
+			stats.AcceptErrors += st.AcceptErrors
+		}
+
+		name = prefix + "connections_accepted_total"
+		writeFamily(&sb, name, "counter", "Total number of connections accepted.")
+		fmt.Fprintf(&sb, "%s %d\n", name, stats.Accepted)
+
+		name = prefix + "accept_errors_total"
+		writeFamily(&sb, name, "counter", "Total number of failed attempts to accept a connection.")
+		fmt.Fprintf(&sb, "%s %d\n", name, stats.AcceptErrors)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/ShazimR/tcp-http-server/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mkReq(method, target string) *request.Request {
+	return &request.Request{
+		RequestLine: request.RequestLine{
+			Method:        method,
+			RequestTarget: target,
+		},
+		Headers:       headers.NewHeaders(),
+		PathParams:    make(map[string]string),
+		RequestParams: make(map[string]string),
+	}
+}
+
+func serve(t *testing.T, r *router.Router, req *request.Request) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+	if err := r.GetHandler(req)(w, req); err != nil {
+		require.NoError(t, response.DefaultErrorHandler(w, req, err))
+	}
+	require.NoError(t, w.Finish())
+	return buf.String()
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	m := New(Options{})
+	r := router.NewRouter()
+	r.Use(m.Middleware())
+	require.NoError(t, r.GET("/users/:id", func(w *response.Writer, req *request.Request) error {
+		body := []byte("hello")
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+	}))
+	require.NoError(t, r.POST("/fail", func(w *response.Writer, req *request.Request) error {
+		return response.NewHTTPError(response.StatusConflict, "taken")
+	}))
+
+	serve(t, r, mkReq("GET", "/users/1"))
+	serve(t, r, mkReq("GET", "/users/2"))
+	post := mkReq("POST", "/fail")
+	post.Body = []byte("abc")
+	serve(t, r, post)
+	serve(t, r, mkReq("GET", "/missing/path"))
+
+	out := m.String()
+
+	// Test: Requests are grouped by route pattern and status class
+	assert.Contains(t, out, "# TYPE http_requests_total counter\n")
+	assert.Contains(t, out, `http_requests_total{method="GET",route="/users/:id",code="2xx"} 2`+"\n")
+	assert.Contains(t, out, `http_requests_total{method="POST",route="/fail",code="4xx"} 1`+"\n")
+	assert.Contains(t, out, `http_requests_total{method="GET",route="unmatched",code="4xx"} 1`+"\n")
+	assert.NotContains(t, out, "/users/1")
+
+	// Test: Histogram buckets, sum and count
+	assert.Contains(t, out, "# TYPE http_request_duration_seconds histogram\n")
+	assert.Contains(t, out, `http_request_duration_seconds_bucket{method="GET",route="/users/:id",le="+Inf"} 2`+"\n")
+	assert.Contains(t, out, `http_request_duration_seconds_count{method="GET",route="/users/:id"} 2`+"\n")
+
+	// Test: Bytes and in-flight gauge
+	assert.Contains(t, out, `http_response_size_bytes_total{method="GET",route="/users/:id"} 10`+"\n")
+	assert.Contains(t, out, `http_request_size_bytes_total{method="POST",route="/fail"} 3`+"\n")
+	assert.Contains(t, out, "http_requests_in_flight 0\n")
+}
+
+func TestMetrics_Histogram(t *testing.T) {
+	m := New(Options{Namespace: "app", Buckets: []float64{1, 0.1}})
+	m.Observe("GET", "/", response.StatusOK, 50*time.Millisecond, 0, 0)
+	m.Observe("GET", "/", response.StatusOK, 500*time.Millisecond, 0, 0)
+	m.Observe("GET", "/", response.StatusOK, 2*time.Second, 0, 0)
+
+	m.Observe("BREW", "/", response.StatusOK, time.Millisecond, 0, 0)
+	m.Observe("get", "/", response.StatusOK, time.Millisecond, 0, 0)
+
+	out := m.String()
+	// Test: Unknown methods share one label
+	assert.Contains(t, out, `app_requests_total{method="OTHER",route="/",code="2xx"} 2`+"\n")
+	assert.NotContains(t, out, "BREW")
+
+	// Test: Buckets are sorted and cumulative
+	assert.Contains(t, out, `app_request_duration_seconds_bucket{method="GET",route="/",le="0.1"} 1`+"\n")
+	assert.Contains(t, out, `app_request_duration_seconds_bucket{method="GET",route="/",le="1"} 2`+"\n")
+	assert.Contains(t, out, `app_request_duration_seconds_bucket{method="GET",route="/",le="+Inf"} 3`+"\n")
+	assert.Contains(t, out, `app_request_duration_seconds_sum{method="GET",route="/"} 2.55`+"\n")
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New(Options{})
+	m.Observe("GET", "/a\"b", response.StatusOK, time.Millisecond, 0, 0)
+
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+	require.NoError(t, m.Handler()(w, mkReq("GET", "/metrics")))
+	out := buf.String()
+
+	// Test: Exposition content type and escaped label values
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, out, "Content-Type: "+ContentType+"\r\n")
+	assert.Contains(t, out, `route="/a\"b"`)
+}
+
+func TestMetrics_TrackServer(t *testing.T) {
+	m := New(Options{})
+	assert.NotContains(t, m.String(), "connections_accepted")
+
+	// Test: Connection counters are summed over tracked servers
+	for range 2 {
+		l := server.NewPipeListener()
+		s := server.New(func(w *response.Writer, req *request.Request) error {
+			return w.WriteResponse(response.StatusNoContent, response.GetDefaultHeaders(0), nil)
+		}, nil, server.Options{})
+		s.AddListener(l, nil)
+		defer s.Close()
+		m.TrackServer(s)
+
+		conn, err := l.Dial()
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n"))
+		require.NoError(t, err)
+		_, err = io.ReadAll(conn)
+		require.NoError(t, err)
+		conn.Close()
+	}
+
+	out := m.String()
+	assert.Contains(t, out, "# TYPE http_connections_accepted_total counter\nhttp_connections_accepted_total 2\n")
+	assert.Contains(t, out, "# TYPE http_accept_errors_total counter\nhttp_accept_errors_total 0\n")
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+type LogFormat uint
+
+const (
+	LogCommon   LogFormat = iota // NCSA Common Log Format
+	LogCombined                  // Common plus Referer and User-Agent
+	LogJSON                      // one JSON object per line
+)
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+type AccessLogOptions struct {
+	Format LogFormat
+	Output io.Writer // defaults to os.Stdout
+	// Logger, when set, receives each entry as structured attributes instead
+	// of Output; Format is ignored.
+	Logger *slog.Logger
+}
+
+// AccessLogEntry describes one served request.
+type AccessLogEntry struct {
+	Time      time.Time     `json:"time"`
+	ClientIP  string        `json:"client_ip"`
+	Method    string        `json:"method"`
+	Target    string        `json:"target"`
+	Proto     string        `json:"proto"`
+	Status    int           `json:"status"`
+	Bytes     int64         `json:"bytes"`
+	Latency   time.Duration `json:"latency_ns"`
+	Referer   string        `json:"referer,omitempty"`
+	UserAgent string        `json:"user_agent,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+func newAccessLogEntry(w *response.Writer, req *request.Request, start time.Time, err error) AccessLogEntry {
+	target := req.RequestLine.RequestTarget
+	if req.RawQuery != "" {
+		target += "?" + req.RawQuery
+	}
+
+	status := w.Status()
+	if err != nil && !w.Written() {
+		// the error handler answers after the middleware chain returns
+		status = response.ErrorStatus(err)
+	}
+
+	entry := AccessLogEntry{
+		Time:     start,
+		ClientIP: req.ClientIP(),
+		Method:   req.RequestLine.Method,
+		Target:   target,
+		Proto:    "HTTP/" + req.RequestLine.HttpVersion,
+		Status:   int(status),
+		Bytes:    w.BytesWritten(),
+		Latency:  time.Since(start),
+	}
+	entry.Referer, _ = req.Headers.Get("Referer")
+	entry.UserAgent, _ = req.Headers.Get("User-Agent")
+	entry.RequestID, _ = req.Headers.Get("X-Request-ID")
+	if sent := w.WrittenHeader(); entry.RequestID == "" && sent != nil {
+		entry.RequestID, _ = sent.Get("X-Request-ID")
+	}
+
+	return entry
+}
+
+func clfField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// CommonLog formats e in Common Log Format.
+func (e AccessLogEntry) CommonLog() string {
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = strconv.FormatInt(e.Bytes, 10)
+	}
+
+	return fmt.Sprintf("%s - - [%s] %q %d %s",
+		clfField(e.ClientIP), e.Time.Format(clfTimeFormat),
+		e.Method+" "+e.Target+" "+e.Proto, e.Status, bytes)
+}
+
+// CombinedLog formats e in Combined Log Format.
+func (e AccessLogEntry) CombinedLog() string {
+	return fmt.Sprintf("%s %q %q", e.CommonLog(), clfField(e.Referer), clfField(e.UserAgent))
+}
+
+func (e AccessLogEntry) attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("client_ip", e.ClientIP),
+		slog.String("method", e.Method),
+		slog.String("target", e.Target),
+		slog.String("proto", e.Proto),
+		slog.Int("status", e.Status),
+		slog.Int64("bytes", e.Bytes),
+		slog.Duration("latency", e.Latency),
+	}
+	if e.Referer != "" {
+		attrs = append(attrs, slog.String("referer", e.Referer))
+	}
+	if e.UserAgent != "" {
+		attrs = append(attrs, slog.String("user_agent", e.UserAgent))
+	}
+	if e.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", e.RequestID))
+	}
+	return attrs
+}
+
+// AccessLog logs every request after it has been handled, including status,
+// body size and latency.
+func AccessLog(opts AccessLogOptions) router.Middleware {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	var mu sync.Mutex
+
+	emit := func(req *request.Request, e AccessLogEntry) {
+		if opts.Logger != nil {
+			opts.Logger.LogAttrs(req.Context(), slog.LevelInfo, "request", e.attrs()...)
+			return
+		}
+
+		var line []byte
+		switch opts.Format {
+		case LogCombined:
+			line = []byte(e.CombinedLog())
+		case LogJSON:
+			line, _ = json.Marshal(e)
+		default:
+			line = []byte(e.CommonLog())
+		}
+		line = append(line, '\n')
+
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = out.Write(line)
+	}
+
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			start := time.Now()
+			err := next(w, req)
+			emit(req, newAccessLogEntry(w, req, start, err))
+			return err
+		}
+	}
+}
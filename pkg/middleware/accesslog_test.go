@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logReq() *request.Request {
+	req := mkReq("GET", "/items")
+	req.RawQuery = "page=2"
+	req.RequestLine.HttpVersion = "1.1"
+	req.RemoteAddr = "10.0.0.7:51234"
+	req.Headers.Set("User-Agent", "tester/1.0")
+	req.Headers.Set("X-Request-ID", "abc123")
+	return req
+}
+
+func TestAccessLog_Formats(t *testing.T) {
+	// Test: Common Log Format
+	var out bytes.Buffer
+	run(t, AccessLog(AccessLogOptions{Output: &out})(okHandler), logReq())
+	assert.Regexp(t, regexp.MustCompile(`^10\.0\.0\.7 - - \[[^\]]+\] "GET /items\?page=2 HTTP/1\.1" 200 2\n$`), out.String())
+
+	// Test: Combined adds referer and user agent
+	out.Reset()
+	run(t, AccessLog(AccessLogOptions{Format: LogCombined, Output: &out})(okHandler), logReq())
+	assert.True(t, strings.HasSuffix(out.String(), `200 2 "-" "tester/1.0"`+"\n"))
+
+	// Test: JSON carries every field
+	out.Reset()
+	run(t, AccessLog(AccessLogOptions{Format: LogJSON, Output: &out})(okHandler), logReq())
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "10.0.0.7", entry["client_ip"])
+	assert.Equal(t, "/items?page=2", entry["target"])
+	assert.Equal(t, float64(200), entry["status"])
+	assert.Equal(t, float64(2), entry["bytes"])
+	assert.Equal(t, "abc123", entry["request_id"])
+	assert.Contains(t, entry, "latency_ns")
+}
+
+func TestAccessLog_Slog(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, nil))
+	run(t, AccessLog(AccessLogOptions{Logger: logger})(okHandler), logReq())
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "request", entry["msg"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, float64(200), entry["status"])
+	assert.Equal(t, "tester/1.0", entry["user_agent"])
+}
+
+func TestAccessLog_ErrorStatus(t *testing.T) {
+	// Test: Errors answered later by the error handler are logged with their status
+	var out bytes.Buffer
+	h := AccessLog(AccessLogOptions{Output: &out})(func(w *response.Writer, req *request.Request) error {
+		return response.NewHTTPError(response.StatusConflict, "taken")
+	})
+	_ = h(response.NewWriter(&bytes.Buffer{}), logReq())
+	assert.Contains(t, out.String(), `" 409 -`)
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strconv"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+const defaultRealm = "Restricted"
+
+type BasicAuthOptions struct {
+	Realm string            // defaults to "Restricted"
+	Users map[string]string // username -> password
+	// Validate, when set, is used instead of Users.
+	Validate func(username string, password string, req *request.Request) bool
+}
+
+type BearerOptions struct {
+	Realm  string   // defaults to "Restricted"
+	Tokens []string // accepted tokens
+	// Validate, when set, is used instead of Tokens.
+	Validate func(token string, req *request.Request) bool
+}
+
+// SecureCompare reports whether a and b are equal in time that does not
+// depend on where they differ.
+func SecureCompare(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func quoteRealm(realm string) string {
+	if realm == "" {
+		realm = defaultRealm
+	}
+	return strconv.Quote(realm)
+}
+
+func unauthorized(w *response.Writer, challenge string) error {
+	body := []byte("unauthorized")
+	h := response.GetDefaultHeaders(len(body))
+	h.Replace("Content-Type", "text/plain")
+	h.Set("WWW-Authenticate", challenge)
+	return w.WriteResponse(response.StatusUnauthorized, h, body)
+}
+
+// BasicAuth only lets requests with valid Basic credentials through; others
+// get a 401 with a WWW-Authenticate challenge for the realm.
+func BasicAuth(opts BasicAuthOptions) router.Middleware {
+	challenge := fmt.Sprintf("Basic realm=%s, charset=\"UTF-8\"", quoteRealm(opts.Realm))
+
+	validate := opts.Validate
+	if validate == nil {
+		validate = func(username string, password string, _ *request.Request) bool {
+			// compare against every user so timing doesn't reveal valid names
+			ok := false
+			for u, p := range opts.Users {
+				userMatch := SecureCompare(username, u)
+				passMatch := SecureCompare(password, p)
+				if userMatch && passMatch {
+					ok = true
+				}
+			}
+			return ok
+		}
+	}
+
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			username, password, ok := req.BasicAuth()
+			if !ok || !validate(username, password, req) {
+				return unauthorized(w, challenge)
+			}
+			return next(w, req)
+		}
+	}
+}
+
+// Bearer only lets requests with a valid Bearer token through; others get a
+// 401 with a WWW-Authenticate challenge (RFC 6750).
+func Bearer(opts BearerOptions) router.Middleware {
+	realm := quoteRealm(opts.Realm)
+
+	validate := opts.Validate
+	if validate == nil {
+		validate = func(token string, _ *request.Request) bool {
+			ok := false
+			for _, t := range opts.Tokens {
+				if SecureCompare(token, t) {
+					ok = true
+				}
+			}
+			return ok
+		}
+	}
+
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			token, ok := req.BearerToken()
+			if !ok {
+				return unauthorized(w, "Bearer realm="+realm)
+			}
+			if !validate(token, req) {
+				return unauthorized(w, "Bearer realm="+realm+`, error="invalid_token"`)
+			}
+			return next(w, req)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func basic(user, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+func TestBasicAuth(t *testing.T) {
+	h := BasicAuth(BasicAuthOptions{
+		Realm: "admin",
+		Users: map[string]string{"alice": "secret"},
+	})(okHandler)
+
+	// Test: Valid credentials pass through
+	req := mkReq("GET", "/")
+	req.Headers.Set("Authorization", basic("alice", "secret"))
+	out := run(t, h, req)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+
+	// Test: Missing or wrong credentials get a challenge
+	for _, auth := range []string{"", basic("alice", "wrong"), basic("bob", "secret")} {
+		req = mkReq("GET", "/")
+		if auth != "" {
+			req.Headers.Set("Authorization", auth)
+		}
+		out = run(t, h, req)
+		assert.True(t, strings.HasPrefix(out, "HTTP/1.1 401 Unauthorized\r\n"), auth)
+		assert.Contains(t, headerBlock(out), "WWW-Authenticate: Basic realm=\"admin\", charset=\"UTF-8\"\r\n")
+	}
+
+	// Test: Custom validator
+	h = BasicAuth(BasicAuthOptions{
+		Validate: func(user, pass string, req *request.Request) bool {
+			return user == pass
+		},
+	})(okHandler)
+	req = mkReq("GET", "/")
+	req.Headers.Set("Authorization", basic("x", "x"))
+	assert.True(t, strings.HasPrefix(run(t, h, req), "HTTP/1.1 200 OK\r\n"))
+	req = mkReq("GET", "/")
+	req.Headers.Set("Authorization", basic("x", "y"))
+	out = run(t, h, req)
+	assert.Contains(t, headerBlock(out), "WWW-Authenticate: Basic realm=\"Restricted\"")
+}
+
+func TestBearer(t *testing.T) {
+	h := Bearer(BearerOptions{Tokens: []string{"t0k3n"}})(okHandler)
+
+	req := mkReq("GET", "/")
+	req.Headers.Set("Authorization", "Bearer t0k3n")
+	assert.True(t, strings.HasPrefix(run(t, h, req), "HTTP/1.1 200 OK\r\n"))
+
+	// Test: No token gets a bare challenge
+	out := run(t, h, mkReq("GET", "/"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 401 Unauthorized\r\n"))
+	assert.Contains(t, headerBlock(out), "WWW-Authenticate: Bearer realm=\"Restricted\"\r\n")
+
+	// Test: A bad token is reported as invalid
+	req = mkReq("GET", "/")
+	req.Headers.Set("Authorization", "Bearer nope")
+	out = run(t, h, req)
+	assert.Contains(t, headerBlock(out), "WWW-Authenticate: Bearer realm=\"Restricted\", error=\"invalid_token\"\r\n")
+}
+
+func TestSecureCompare(t *testing.T) {
+	assert.True(t, SecureCompare("abc", "abc"))
+	assert.False(t, SecureCompare("abc", "abd"))
+	assert.False(t, SecureCompare("abc", "ab"))
+}
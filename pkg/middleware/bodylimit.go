@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+// BodyLimit answers requests whose body is larger than limit bytes, by
+// declared Content-Length or by the bytes actually received, with 413
+// Content Too Large instead of calling the handler. Use it on the routes or
+// groups that need a limit below the server-wide one; the server reads the
+// body before routing, so only request.Limits.MaxBodySize stops reading an
+// oversized body early.
+func BodyLimit(limit int64) router.Middleware {
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			size := int64(len(req.Body))
+			if cl, ok := req.Headers.Get("Content-Length"); ok {
+				if n, err := strconv.ParseInt(strings.TrimSpace(cl), 10, 64); err == nil && n > size {
+					size = n
+				}
+			}
+
+			if size > limit {
+				msg := fmt.Sprintf("request body must not be larger than %d bytes", limit)
+				return response.WrapHTTPError(response.StatusContentTooLarge, msg, request.ErrBodyTooLarge)
+			}
+			return next(w, req)
+		}
+	}
+}
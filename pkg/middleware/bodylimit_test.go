@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyLimit(t *testing.T) {
+	h := BodyLimit(4)(okHandler)
+
+	// Test: Bodies within the limit reach the handler
+	req := mkReq("POST", "/")
+	req.Body = []byte("abcd")
+	out := run(t, h, req)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+
+	// Test: Received bytes over the limit, e.g. of a chunked body
+	req = mkReq("POST", "/")
+	req.Body = []byte("abcde")
+	err := h(response.NewWriter(&bytes.Buffer{}), req)
+	assert.ErrorIs(t, err, request.ErrBodyTooLarge)
+	assert.Equal(t, response.StatusContentTooLarge, response.ErrorStatus(err))
+
+	// Test: Declared Content-Length over the limit
+	req = mkReq("POST", "/")
+	req.Headers.Set("Content-Length", "100")
+	err = h(response.NewWriter(&bytes.Buffer{}), req)
+	assert.ErrorIs(t, err, request.ErrBodyTooLarge)
+}
+
+func TestBodyLimit_PerGroup(t *testing.T) {
+	r := router.NewRouter()
+	uploads := r.Group("/uploads")
+	uploads.Use(BodyLimit(16))
+	api := r.Group("/api")
+	api.Use(BodyLimit(4))
+	require.NoError(t, uploads.POST("/", okHandler))
+	require.NoError(t, api.POST("/", okHandler))
+
+	body := []byte("0123456789")
+	req := mkReq("POST", "/uploads")
+	req.Body = body
+	out := run(t, r.Handler(), req)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+
+	// the server answers the returned error with DefaultErrorHandler
+	req = mkReq("POST", "/api")
+	req.Body = body
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+	err := r.Handler()(w, req)
+	require.NoError(t, response.DefaultErrorHandler(w, req, err))
+	out = buf.String()
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 413 Content Too Large\r\n"), out)
+	assert.True(t, strings.HasSuffix(out, "request body must not be larger than 4 bytes"))
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+// Skip runs mw only for requests skip returns false for; the others go
+// straight to the next handler. It lets middleware applied to a whole router
+// (auth, compression, logging) leave out e.g. health checks.
+func Skip(mw router.Middleware, skip func(req *request.Request) bool) router.Middleware {
+	return func(next response.Handler) response.Handler {
+		wrapped := mw(next)
+		return func(w *response.Writer, req *request.Request) error {
+			if skip(req) {
+				return next(w, req)
+			}
+			return wrapped(w, req)
+		}
+	}
+}
+
+// Only runs mw only for requests match returns true for.
+func Only(mw router.Middleware, match func(req *request.Request) bool) router.Middleware {
+	return Skip(mw, func(req *request.Request) bool {
+		return !match(req)
+	})
+}
+
+// SkipPaths runs mw for every request except those whose path matches one of
+// globs; see OnlyPaths for the pattern syntax.
+func SkipPaths(mw router.Middleware, globs ...string) router.Middleware {
+	return Skip(mw, pathMatcher(globs))
+}
+
+// OnlyPaths runs mw only for requests whose path matches one of globs.
+// Patterns use path.Match syntax, so "*" stays within one segment
+// ("/api/*/status"); a trailing "/**" matches the path before it and
+// everything below ("/static/**"). The path is cleaned first, so
+// "/static/../admin" doesn't count as "/static/**". A malformed pattern
+// panics.
+func OnlyPaths(mw router.Middleware, globs ...string) router.Middleware {
+	return Only(mw, pathMatcher(globs))
+}
+
+func pathMatcher(globs []string) func(req *request.Request) bool {
+	for _, g := range globs {
+		if _, err := path.Match(strings.TrimSuffix(g, "/**"), ""); err != nil {
+			panic(fmt.Sprintf("middleware: malformed path pattern %q", g))
+		}
+	}
+
+	return func(req *request.Request) bool {
+		p := req.RequestLine.RequestTarget
+		if req.URL != nil {
+			p = req.URL.Path
+		}
+		p = path.Clean("/" + p)
+
+		for _, g := range globs {
+			if matchPath(g, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func matchPath(glob string, p string) bool {
+	prefix, subtree := strings.CutSuffix(glob, "/**")
+	if !subtree {
+		ok, _ := path.Match(glob, p)
+		return ok
+	}
+
+	// match the pattern against as many leading segments as it has
+	n := strings.Count(prefix, "/")
+	head := p
+	if i := nthSlash(p, n+1); i != -1 {
+		head = p[:i]
+	}
+	ok, _ := path.Match(prefix, head)
+	return ok
+}
+
+// nthSlash returns the index of the nth '/' in s (counting from 1), or -1.
+func nthSlash(s string, n int) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '/' {
+			n--
+			if n == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPath(t *testing.T) {
+	cases := []struct {
+		glob string
+		path string
+		want bool
+	}{
+		{"/healthz", "/healthz", true},
+		{"/healthz", "/healthz/x", false},
+		{"/api/*/status", "/api/v1/status", true},
+		{"/api/*", "/api/v1/status", false},
+		{"/static/**", "/static", true},
+		{"/static/**", "/static/css/site.css", true},
+		{"/static/**", "/staticfiles/a", false},
+		{"/api/*/admin/**", "/api/v2/admin/users/1", true},
+		{"/api/*/admin/**", "/api/v2/public", false},
+		{"/**", "/anything/at/all", true},
+		{"*.css", "/site.css", false},
+		{"/*.css", "/site.css", true},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, matchPath(tc.glob, tc.path), "%s ~ %s", tc.glob, tc.path)
+	}
+}
+
+func TestConditional(t *testing.T) {
+	tag := func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			w.Header().Set("X-Tagged", "1")
+			return next(w, req)
+		}
+	}
+	tagged := func(mw router.Middleware, target string) bool {
+		return strings.Contains(run(t, mw(okHandler), mkReq("GET", target)), "X-Tagged")
+	}
+
+	// Test: Skip and Only with predicates
+	isHead := func(req *request.Request) bool { return req.RequestLine.Method == "HEAD" }
+	assert.True(t, tagged(Skip(tag, isHead), "/"))
+	assert.False(t, strings.Contains(run(t, Skip(tag, isHead)(okHandler), mkReq("HEAD", "/")), "X-Tagged"))
+	assert.False(t, tagged(Only(tag, isHead), "/"))
+
+	// Test: Path globs, on the cleaned path
+	skip := SkipPaths(tag, "/healthz", "/metrics", "/static/**")
+	assert.False(t, tagged(skip, "/healthz"))
+	assert.False(t, tagged(skip, "/static/js/app.js"))
+	assert.True(t, tagged(skip, "/api/users"))
+	assert.True(t, tagged(skip, "/static/../admin"))
+
+	only := OnlyPaths(tag, "/api/**")
+	assert.True(t, tagged(only, "/api"))
+	assert.True(t, tagged(only, "/api/users/1"))
+	assert.False(t, tagged(only, "/"))
+	assert.False(t, tagged(only, "/apix"))
+
+	assert.Panics(t, func() { OnlyPaths(tag, "/[") })
+}
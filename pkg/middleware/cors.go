@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+type CORSOptions struct {
+	// AllowedOrigins lists the origins to allow; "*" allows any origin, but
+	// only for uncredentialed requests (see allowOrigin).
+	AllowedOrigins   []string
+	AllowedMethods   []string // defaults to GET, POST, PUT, DELETE, PATCH
+	AllowedHeaders   []string // empty reflects Access-Control-Request-Headers
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int // seconds, 0 omits Access-Control-Max-Age
+}
+
+var defaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "PATCH"}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin and
+// whether credentials may be allowed with it. Only explicitly listed origins
+// get credentials: a "*" match is answered with the literal wildcard, which
+// browsers never combine with credentials, rather than echoing the origin and
+// opening credentialed access to every site.
+func (o *CORSOptions) allowOrigin(origin string) (value string, credentials bool, ok bool) {
+	wildcard := false
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" {
+			wildcard = true
+			continue
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, o.AllowCredentials, true
+		}
+	}
+
+	if wildcard {
+		return "*", false, true
+	}
+	return "", false, false
+}
+
+// CORS answers preflight OPTIONS requests and adds Access-Control-* headers to
+// responses for allowed origins. Registered with Router.Use it also sees
+// preflights for paths that have routes, since the router wraps its 405 and
+// automatic OPTIONS responses in the group's middleware. Preflights for
+// unregistered paths only reach it when it wraps the whole router (e.g.
+// server.Serve(port, CORS(opts)(r.Handler()), nil)).
+func CORS(opts CORSOptions) router.Middleware {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(opts.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(opts.ExposedHeaders, ", ")
+
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			origin, ok := req.Headers.Get("Origin")
+			if !ok {
+				return next(w, req)
+			}
+
+			reqMethod, isPreflight := req.Headers.Get("Access-Control-Request-Method")
+			isPreflight = isPreflight && req.RequestLine.Method == "OPTIONS"
+
+			allowedOrigin, credentials, allowed := opts.allowOrigin(origin)
+			if isPreflight {
+				h := response.GetDefaultHeaders(0)
+				h.Del("Content-Length")
+				h.Set("Vary", "Origin")
+				if allowed && slices.Contains(methods, strings.ToUpper(reqMethod)) {
+					h.Set("Access-Control-Allow-Origin", allowedOrigin)
+					h.Set("Access-Control-Allow-Methods", allowMethods)
+					if allowHeaders != "" {
+						h.Set("Access-Control-Allow-Headers", allowHeaders)
+					} else if reqHeaders, ok := req.Headers.Get("Access-Control-Request-Headers"); ok {
+						h.Set("Access-Control-Allow-Headers", reqHeaders)
+					}
+					if credentials {
+						h.Set("Access-Control-Allow-Credentials", "true")
+					}
+					if opts.MaxAge > 0 {
+						h.Set("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
+					}
+				}
+				return w.WriteResponse(response.StatusNoContent, h, []byte{})
+			}
+
+			if allowed {
+				wh := w.Header()
+				wh.Replace("Access-Control-Allow-Origin", allowedOrigin)
+				wh.Set("Vary", "Origin")
+				if credentials {
+					wh.Replace("Access-Control-Allow-Credentials", "true")
+				}
+				if exposeHeaders != "" {
+					wh.Replace("Access-Control-Expose-Headers", exposeHeaders)
+				}
+			}
+
+			return next(w, req)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mkReq(method, target string) *request.Request {
+	return &request.Request{
+		RequestLine: request.RequestLine{
+			Method:        method,
+			RequestTarget: target,
+		},
+		Headers:       headers.NewHeaders(),
+		PathParams:    make(map[string]string),
+		RequestParams: make(map[string]string),
+	}
+}
+
+func run(t *testing.T, h response.Handler, req *request.Request) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+	require.NoError(t, h(w, req))
+	require.NoError(t, w.Finish())
+	return buf.String()
+}
+
+func headerBlock(out string) string {
+	i := strings.Index(out, "\r\n\r\n")
+	if i == -1 {
+		return out
+	}
+	return out[:i+4]
+}
+
+func okHandler(w *response.Writer, req *request.Request) error {
+	body := []byte("ok")
+	return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	mw := CORS(CORSOptions{
+		AllowedOrigins:   []string{"https://app.example"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+
+	handlerCalled := false
+	h := mw(func(w *response.Writer, req *request.Request) error {
+		handlerCalled = true
+		return nil
+	})
+
+	// Test: Allowed preflight is answered without calling the handler
+	req := mkReq("OPTIONS", "/api/items")
+	req.Headers.Set("Origin", "https://app.example")
+	req.Headers.Set("Access-Control-Request-Method", "PUT")
+	out := run(t, h, req)
+
+	assert.False(t, handlerCalled)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 204 No Content\r\n"))
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Access-Control-Allow-Origin: https://app.example\r\n")
+	assert.Contains(t, hb, "Access-Control-Allow-Methods: GET, POST, PUT, DELETE, PATCH\r\n")
+	assert.Contains(t, hb, "Access-Control-Allow-Headers: Content-Type, Authorization\r\n")
+	assert.Contains(t, hb, "Access-Control-Allow-Credentials: true\r\n")
+	assert.Contains(t, hb, "Access-Control-Max-Age: 600\r\n")
+	assert.NotContains(t, hb, "Content-Length")
+
+	// Test: Disallowed origin gets no CORS headers
+	req = mkReq("OPTIONS", "/api/items")
+	req.Headers.Set("Origin", "https://evil.example")
+	req.Headers.Set("Access-Control-Request-Method", "PUT")
+	out = run(t, h, req)
+	assert.NotContains(t, out, "Access-Control-Allow-Origin")
+}
+
+func TestCORS_ActualRequest(t *testing.T) {
+	h := CORS(CORSOptions{
+		AllowedOrigins: []string{"*"},
+		ExposedHeaders: []string{"X-Request-Id"},
+	})(okHandler)
+
+	// Test: Headers are injected into the handler's response
+	req := mkReq("GET", "/api/items")
+	req.Headers.Set("Origin", "https://app.example")
+	out := run(t, h, req)
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Access-Control-Allow-Origin: *\r\n")
+	assert.Contains(t, hb, "Access-Control-Expose-Headers: X-Request-Id\r\n")
+	assert.Contains(t, hb, "Vary: Origin\r\n")
+	assert.True(t, strings.HasSuffix(out, "ok"))
+
+	// Test: Requests without Origin are untouched
+	req = mkReq("GET", "/api/items")
+	out = run(t, h, req)
+	assert.NotContains(t, out, "Access-Control")
+}
+
+func TestCORS_WildcardCredentials(t *testing.T) {
+	h := CORS(CORSOptions{
+		AllowedOrigins:   []string{"https://app.example", "*"},
+		AllowCredentials: true,
+	})(okHandler)
+
+	// Test: Listed origins are echoed with credentials
+	req := mkReq("GET", "/api/items")
+	req.Headers.Set("Origin", "https://app.example")
+	hb := headerBlock(run(t, h, req))
+	assert.Contains(t, hb, "Access-Control-Allow-Origin: https://app.example\r\n")
+	assert.Contains(t, hb, "Access-Control-Allow-Credentials: true\r\n")
+
+	// Test: Wildcard matches never get credentials or an echoed origin
+	req = mkReq("GET", "/api/items")
+	req.Headers.Set("Origin", "https://evil.example")
+	hb = headerBlock(run(t, h, req))
+	assert.Contains(t, hb, "Access-Control-Allow-Origin: *\r\n")
+	assert.NotContains(t, hb, "Access-Control-Allow-Credentials")
+	assert.NotContains(t, hb, "evil.example")
+
+	// Test: Same for preflights
+	req = mkReq("OPTIONS", "/api/items")
+	req.Headers.Set("Origin", "https://evil.example")
+	req.Headers.Set("Access-Control-Request-Method", "GET")
+	hb = headerBlock(run(t, h, req))
+	assert.Contains(t, hb, "Access-Control-Allow-Origin: *\r\n")
+	assert.NotContains(t, hb, "Access-Control-Allow-Credentials")
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+// ETag adds a hash of the body as ETag to small dynamic responses and
+// answers GETs whose If-None-Match lists it with 304 Not Modified; see
+// response.ETagHandler. Responses are held in memory until the handler
+// returns, up to opts.MaxSize.
+func ETag(opts response.ETagOptions) router.Middleware {
+	return func(next response.Handler) response.Handler {
+		return response.ETagHandler(next, opts)
+	}
+}
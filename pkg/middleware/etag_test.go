@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETag(t *testing.T) {
+	h := ETag(response.ETagOptions{})(okHandler)
+
+	// Test: A 200 gets a body hash, the same one every time
+	out := run(t, h, mkReq("GET", "/"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\nok"))
+	_, rest, ok := strings.Cut(out, "ETag: ")
+	require.True(t, ok)
+	etag, _, _ := strings.Cut(rest, "\r\n")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+	assert.Contains(t, run(t, h, mkReq("GET", "/")), "ETag: "+etag+"\r\n")
+
+	// Test: A matching If-None-Match turns it into a bodiless 304
+	req := mkReq("GET", "/")
+	req.Headers.Set("If-None-Match", `"other", `+etag)
+	out = run(t, h, req)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 304 Not Modified\r\n"))
+	assert.Contains(t, out, "ETag: "+etag+"\r\n")
+	assert.NotContains(t, out, "Content-Length")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\n"))
+
+	req = mkReq("GET", "/")
+	req.Headers.Set("If-None-Match", `"other"`)
+	assert.True(t, strings.HasPrefix(run(t, h, req), "HTTP/1.1 200 OK\r\n"))
+
+	// Test: A bare body is framed first, then tagged
+	out = run(t, ETag(response.ETagOptions{})(func(w *response.Writer, req *request.Request) error {
+		return w.WriteBody([]byte("bare"))
+	}), mkReq("GET", "/"))
+	assert.Contains(t, out, "Content-Length: 4\r\n")
+	assert.Contains(t, out, "ETag: ")
+
+	// Test: Other methods, statuses and existing ETags pass through
+	assert.NotContains(t, run(t, h, mkReq("POST", "/")), "ETag")
+	out = run(t, h, mkReq("HEAD", "/"))
+	assert.NotContains(t, out, "ETag")
+	notFound := ETag(response.ETagOptions{})(func(w *response.Writer, req *request.Request) error {
+		return w.WriteResponse(response.StatusNotFound, response.GetDefaultHeaders(2), []byte("no"))
+	})
+	assert.NotContains(t, run(t, notFound, mkReq("GET", "/")), "ETag")
+	tagged := ETag(response.ETagOptions{})(func(w *response.Writer, req *request.Request) error {
+		h := response.GetDefaultHeaders(2)
+		h.Set("ETag", `"v1"`)
+		return w.WriteResponse(response.StatusOK, h, []byte("ok"))
+	})
+	assert.Equal(t, 1, strings.Count(run(t, tagged, mkReq("GET", "/")), "ETag"))
+
+	// Test: Chunked and flushed responses stream untouched
+	chunked := ETag(response.ETagOptions{})(func(w *response.Writer, req *request.Request) error {
+		return w.WriteChunkedFrom(response.StatusOK, response.GetDefaultHeaders(0), strings.NewReader("data"), 0, nil)
+	})
+	out = run(t, chunked, mkReq("GET", "/"))
+	assert.Contains(t, out, "4\r\ndata\r\n0\r\n\r\n")
+	assert.NotContains(t, out, "ETag")
+	flushed := ETag(response.ETagOptions{})(func(w *response.Writer, req *request.Request) error {
+		if err := w.WriteStatusLine(response.StatusOK); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := w.WriteHeaders(response.GetDefaultHeaders(2)); err != nil {
+			return err
+		}
+		return w.WriteBody([]byte("ok"))
+	})
+	out = run(t, flushed, mkReq("GET", "/"))
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\nok"))
+	assert.NotContains(t, out, "ETag")
+
+	// Test: Size cutoffs and the exclusion predicate
+	big := strings.Repeat("x", 4096)
+	bigHandler := func(w *response.Writer, req *request.Request) error {
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(big)), []byte(big))
+	}
+	out = run(t, ETag(response.ETagOptions{MaxSize: 1024})(bigHandler), mkReq("GET", "/"))
+	assert.True(t, strings.HasSuffix(out, big))
+	assert.NotContains(t, out, "ETag")
+	assert.Contains(t, run(t, ETag(response.ETagOptions{MaxSize: 8192})(bigHandler), mkReq("GET", "/")), "ETag")
+	assert.NotContains(t, run(t, ETag(response.ETagOptions{MinSize: 3})(okHandler), mkReq("GET", "/")), "ETag")
+
+	excludeHTML := ETag(response.ETagOptions{Exclude: func(req *request.Request, h *headers.Headers) bool {
+		ct, _ := h.Get("Content-Type")
+		return ct == "text/html"
+	}})
+	out = run(t, excludeHTML(okHandler), mkReq("GET", "/"))
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\nok"))
+	assert.NotContains(t, out, "ETag")
+}
+
+func TestETag_Compressed(t *testing.T) {
+	body := strings.Repeat("compress me ", 200)
+	h := response.Compress(64)(ETag(response.ETagOptions{})(func(w *response.Writer, req *request.Request) error {
+		hdr := response.GetDefaultHeaders(len(body))
+		hdr.Replace("Content-Type", "text/plain")
+		return w.WriteResponse(response.StatusOK, hdr, []byte(body))
+	}))
+
+	req := mkReq("GET", "/")
+	req.Headers.Set("Accept-Encoding", "gzip")
+	out := run(t, h, req)
+	assert.Contains(t, out, "Content-Encoding: gzip\r\n")
+	_, rest, ok := strings.Cut(out, "ETag: ")
+	require.True(t, ok)
+	etag, _, _ := strings.Cut(rest, "\r\n")
+
+	req = mkReq("GET", "/")
+	req.Headers.Set("Accept-Encoding", "gzip")
+	req.Headers.Set("If-None-Match", etag)
+	out = run(t, h, req)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 304 Not Modified\r\n"))
+	assert.NotContains(t, out, "Content-Encoding")
+}
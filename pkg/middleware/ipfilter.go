@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+var ErrInvalidNetwork = fmt.Errorf("invalid ip or cidr")
+
+type IPFilterOptions struct {
+	// Allow, when not empty, only lets clients in these networks through.
+	// Entries are CIDRs ("10.0.0.0/8", "2001:db8::/32") or single addresses.
+	Allow []string
+	// Deny turns away clients in these networks, even allowed ones.
+	Deny []string
+}
+
+// prefixSet matches an address against any number of networks in time
+// bounded by the address length: a binary trie over the address bits, with
+// IPv4 and IPv6 apart. IPv4-mapped IPv6 addresses are matched as IPv4.
+type prefixSet struct {
+	v4, v6 trieNode
+	empty  bool
+}
+
+type trieNode struct {
+	child    [2]*trieNode
+	terminal bool // a network ends here, covering everything below
+}
+
+func addrBit(b []byte, i int) int {
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+func addrBytes(addr netip.Addr) []byte {
+	if addr.Is4() {
+		b := addr.As4()
+		return b[:]
+	}
+	b := addr.As16()
+	return b[:]
+}
+
+func parsePrefixSet(entries []string) (*prefixSet, error) {
+	s := &prefixSet{empty: len(entries) == 0}
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		var p netip.Prefix
+		var err error
+		if strings.Contains(e, "/") {
+			p, err = netip.ParsePrefix(e)
+		} else {
+			var addr netip.Addr
+			addr, err = netip.ParseAddr(e)
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidNetwork, e)
+		}
+		s.add(p)
+	}
+	return s, nil
+}
+
+func (s *prefixSet) add(p netip.Prefix) {
+	addr, bits := p.Addr(), p.Bits()
+	if addr.Is4In6() && bits >= 96 {
+		addr, bits = addr.Unmap(), bits-96
+	}
+
+	node := &s.v6
+	if addr.Is4() {
+		node = &s.v4
+	}
+	b := addrBytes(addr)
+	for i := 0; i < bits && !node.terminal; i++ {
+		bit := addrBit(b, i)
+		if node.child[bit] == nil {
+			node.child[bit] = &trieNode{}
+		}
+		node = node.child[bit]
+	}
+	node.terminal = true
+	node.child = [2]*trieNode{} // covered by the shorter network
+}
+
+func (s *prefixSet) contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	node := &s.v6
+	if addr.Is4() {
+		node = &s.v4
+	}
+	b := addrBytes(addr)
+	for i := 0; node != nil; i++ {
+		if node.terminal {
+			return true
+		}
+		if i == len(b)*8 {
+			return false
+		}
+		node = node.child[addrBit(b, i)]
+	}
+	return false
+}
+
+func forbidden(w *response.Writer) error {
+	body := []byte("forbidden")
+	h := response.GetDefaultHeaders(len(body))
+	h.Replace("Content-Type", "text/plain")
+	return w.WriteResponse(response.StatusForbidden, h, body)
+}
+
+// IPFilter answers requests from clients outside Allow or inside Deny with
+// 403 Forbidden. The client is identified by request.ClientIP, so
+// forwarding headers count only from the server's TrustedProxies. Clients
+// without a parsable IP are refused. Lookups take the same time however long
+// the lists are.
+func IPFilter(opts IPFilterOptions) (router.Middleware, error) {
+	allow, err := parsePrefixSet(opts.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parsePrefixSet(opts.Deny)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			addr, err := netip.ParseAddr(req.ClientIP())
+			if err != nil || deny.contains(addr) || !allow.empty && !allow.contains(addr) {
+				return forbidden(w)
+			}
+			return next(w, req)
+		}
+	}, nil
+}
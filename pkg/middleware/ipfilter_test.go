@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixSet(t *testing.T) {
+	s, err := parsePrefixSet([]string{"10.0.0.0/8", "192.168.1.7", "2001:db8::/32", "::ffff:172.16.0.0/108", "10.1.0.0/16"})
+	require.NoError(t, err)
+
+	cases := map[string]bool{
+		"10.0.0.1":        true,
+		"10.255.255.255":  true,
+		"11.0.0.0":        false,
+		"192.168.1.7":     true,
+		"192.168.1.8":     false,
+		"::ffff:10.2.3.4": true,
+		"172.16.5.5":      true,
+		"172.32.0.1":      false,
+		"2001:db8::1":     true,
+		"2001:db9::1":     false,
+		"::1":             false,
+	}
+	for ip, want := range cases {
+		assert.Equal(t, want, s.contains(netip.MustParseAddr(ip)), ip)
+	}
+
+	// Test: Everything
+	s, err = parsePrefixSet([]string{"0.0.0.0/0"})
+	require.NoError(t, err)
+	assert.True(t, s.contains(netip.MustParseAddr("8.8.8.8")))
+	assert.False(t, s.contains(netip.MustParseAddr("2001:db8::1")))
+
+	for _, bad := range []string{"10.0.0.0/33", "example.com", ""} {
+		_, err = parsePrefixSet([]string{bad})
+		assert.ErrorIs(t, err, ErrInvalidNetwork, bad)
+	}
+}
+
+func TestIPFilter(t *testing.T) {
+	mw, err := IPFilter(IPFilterOptions{
+		Allow: []string{"10.0.0.0/8", "2001:db8::/32"},
+		Deny:  []string{"10.6.6.0/24"},
+	})
+	require.NoError(t, err)
+	h := mw(okHandler)
+
+	cases := map[string]string{
+		"10.1.2.3:5000":      "200 OK",
+		"[2001:db8::5]:5000": "200 OK",
+		"10.6.6.6:5000":      "403 Forbidden",
+		"8.8.8.8:5000":       "403 Forbidden",
+		"pipe":               "403 Forbidden",
+	}
+	for addr, status := range cases {
+		req := mkReq("GET", "/")
+		req.RemoteAddr = addr
+		out := run(t, h, req)
+		assert.True(t, strings.HasPrefix(out, "HTTP/1.1 "+status+"\r\n"), addr)
+	}
+
+	// Test: Forwarded clients count only behind trusted proxies
+	proxies, err := request.ParseTrustedProxies("8.8.8.8")
+	require.NoError(t, err)
+	req := mkReq("GET", "/")
+	req.RemoteAddr = "8.8.8.8:5000"
+	req.Headers.Set("X-Forwarded-For", "10.1.1.1")
+	req.TrustedProxies = proxies
+	assert.True(t, strings.HasPrefix(run(t, h, req), "HTTP/1.1 200 OK\r\n"))
+	req.TrustedProxies = nil
+	assert.True(t, strings.HasPrefix(run(t, h, req), "HTTP/1.1 403 Forbidden\r\n"))
+
+	// Test: Deny only
+	mw, err = IPFilter(IPFilterOptions{Deny: []string{"10.6.6.6"}})
+	require.NoError(t, err)
+	req = mkReq("GET", "/")
+	req.RemoteAddr = "8.8.8.8:5000"
+	assert.True(t, strings.HasPrefix(run(t, mw(okHandler), req), "HTTP/1.1 200 OK\r\n"))
+
+	_, err = IPFilter(IPFilterOptions{Deny: []string{"10.0.0.0/40"}})
+	assert.ErrorIs(t, err, ErrInvalidNetwork)
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+type MethodOverrideOptions struct {
+	// Methods lists the methods a POST may be turned into; defaults to PUT,
+	// PATCH and DELETE. Overrides to any other method are ignored.
+	Methods []string
+}
+
+var defaultOverrideMethods = []string{"PUT", "PATCH", "DELETE"}
+
+// overrideMethod returns the method a POST asks to be treated as, from the
+// X-HTTP-Method-Override header or else a _method form field.
+func overrideMethod(req *request.Request) string {
+	if m, ok := req.Headers.Get("X-HTTP-Method-Override"); ok {
+		return m
+	}
+
+	switch mediaType, _ := req.ContentType(); mediaType {
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(req.Body))
+		if err == nil {
+			return form.Get("_method")
+		}
+	case "multipart/form-data":
+		form, err := req.ParseMultipartForm(request.DefaultMaxMemory)
+		if err == nil && len(form.Value["_method"]) > 0 {
+			return form.Value["_method"][0]
+		}
+	}
+	return ""
+}
+
+// MethodOverride lets HTML forms and clients limited to GET and POST send
+// PUT, PATCH or DELETE requests as a POST carrying the real method in an
+// X-HTTP-Method-Override header or a _method form field. It rewrites
+// req.RequestLine.Method, so it has to run before routing: wrap the router
+// with it (server.Serve(port, MethodOverride(opts)(r.Handler()), nil))
+// rather than registering it with Router.Use.
+func MethodOverride(opts MethodOverrideOptions) router.Middleware {
+	methods := opts.Methods
+	if len(methods) == 0 {
+		methods = defaultOverrideMethods
+	}
+
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			if req.RequestLine.Method == "POST" {
+				m := strings.ToUpper(strings.TrimSpace(overrideMethod(req)))
+				if slices.Contains(methods, m) {
+					req.RequestLine.Method = m
+				}
+			}
+			return next(w, req)
+		}
+	}
+}
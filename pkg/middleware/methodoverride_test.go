@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodOverride(t *testing.T) {
+	r := router.NewRouter()
+	reply := func(status response.StatusCode) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			return w.WriteResponse(status, response.GetDefaultHeaders(0), nil)
+		}
+	}
+	require.NoError(t, r.POST("/items/:id", reply(response.StatusCreated)))
+	require.NoError(t, r.DELETE("/items/:id", reply(response.StatusNoContent)))
+	require.NoError(t, r.PATCH("/items/:id", reply(response.StatusAccepted)))
+	h := MethodOverride(MethodOverrideOptions{})(r.Handler())
+
+	post := func(header string, contentType string, body string) string {
+		req := mkReq("POST", "/items/1")
+		if header != "" {
+			req.Headers.Set("X-HTTP-Method-Override", header)
+		}
+		if contentType != "" {
+			req.Headers.Set("Content-Type", contentType)
+		}
+		req.Body = []byte(body)
+		out := run(t, h, req)
+		status, _, _ := strings.Cut(strings.TrimPrefix(out, "HTTP/1.1 "), "\r\n")
+		return status
+	}
+
+	assert.Equal(t, "201 Created", post("", "", ""))
+	assert.Equal(t, "204 No Content", post("delete", "", ""))
+	assert.Equal(t, "204 No Content", post("", "application/x-www-form-urlencoded", "name=x&_method=DELETE"))
+	assert.Equal(t, "202 Accepted", post("", "multipart/form-data; boundary=b",
+		"--b\r\nContent-Disposition: form-data; name=\"_method\"\r\n\r\nPATCH\r\n--b--\r\n"))
+
+	// Test: Only allow-listed methods, and only from POST
+	assert.Equal(t, "201 Created", post("GET", "", ""))
+	assert.Equal(t, "201 Created", post("CONNECT", "", ""))
+	req := mkReq("GET", "/items/1")
+	req.Headers.Set("X-HTTP-Method-Override", "DELETE")
+	assert.True(t, strings.HasPrefix(run(t, h, req), "HTTP/1.1 405 "))
+
+	h = MethodOverride(MethodOverrideOptions{Methods: []string{"PATCH"}})(r.Handler())
+	assert.Equal(t, "201 Created", post("DELETE", "", ""))
+	assert.Equal(t, "202 Accepted", post("PATCH", "", ""))
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+var ErrHandlerPanic = fmt.Errorf("handler panicked")
+
+type RecoveryOptions struct {
+	Logger *slog.Logger // defaults to slog.Default()
+}
+
+// Recovery converts a panicking handler into a 500 response (when nothing has
+// been written yet) and logs the panic value with its stack trace.
+func Recovery(opts RecoveryOptions) router.Middleware {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) (err error) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+
+				logger.ErrorContext(req.Context(), "panic in handler",
+					"method", req.RequestLine.Method,
+					"target", req.RequestLine.RequestTarget,
+					"remote_addr", req.RemoteAddr,
+					"panic", rec,
+					"stack", string(debug.Stack()))
+				err = fmt.Errorf("%w: %v", ErrHandlerPanic, rec)
+
+				if !w.Written() {
+					body := []byte("")
+					h := response.GetDefaultHeaders(len(body))
+					if wErr := w.WriteResponse(response.StatusInternalServerError, h, body); wErr != nil {
+						err = wErr
+					}
+				}
+			}()
+
+			return next(w, req)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestRecovery(t *testing.T) {
+	h := Recovery(RecoveryOptions{Logger: discardLogger})(func(w *response.Writer, req *request.Request) error {
+		panic("boom")
+	})
+
+	// Test: Panic before writing becomes a 500
+	var buf bytes.Buffer
+	err := h(response.NewWriter(&buf), mkReq("GET", "/"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrHandlerPanic))
+	assert.True(t, strings.HasPrefix(buf.String(), "HTTP/1.1 500 Internal Server Error\r\n"))
+
+	// Test: Panic after writing does not append a second response
+	h = Recovery(RecoveryOptions{Logger: discardLogger})(func(w *response.Writer, req *request.Request) error {
+		_ = w.WriteStatusLine(response.StatusOK)
+		panic("late boom")
+	})
+	buf.Reset()
+	err = h(response.NewWriter(&buf), mkReq("GET", "/"))
+	require.Error(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", buf.String())
+
+	// Test: Non-panicking handler is untouched
+	out := run(t, Recovery(RecoveryOptions{Logger: discardLogger})(okHandler), mkReq("GET", "/"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+}
+
+func TestRecovery_Logger(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, nil))
+	h := Recovery(RecoveryOptions{Logger: logger})(func(w *response.Writer, req *request.Request) error {
+		panic("boom")
+	})
+
+	req := mkReq("GET", "/items")
+	req.RemoteAddr = "10.0.0.7:51234"
+	_ = h(response.NewWriter(io.Discard), req)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "ERROR", entry["level"])
+	assert.Equal(t, "panic in handler", entry["msg"])
+	assert.Equal(t, "boom", entry["panic"])
+	assert.Equal(t, "/items", entry["target"])
+	assert.Equal(t, "10.0.0.7:51234", entry["remote_addr"])
+	assert.Contains(t, entry["stack"], "runtime/debug.Stack")
+}
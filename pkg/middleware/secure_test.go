@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	// Test: Defaults, without HSTS on a plain connection
+	out := headerBlock(run(t, SecureHeaders(SecureHeadersOptions{})(okHandler), mkReq("GET", "/")))
+	assert.Contains(t, out, "X-Content-Type-Options: nosniff\r\n")
+	assert.Contains(t, out, "X-Frame-Options: DENY\r\n")
+	assert.Contains(t, out, "Referrer-Policy: strict-origin-when-cross-origin\r\n")
+	assert.NotContains(t, out, "Strict-Transport-Security")
+	assert.NotContains(t, out, "Content-Security-Policy")
+
+	// Test: HSTS on TLS connections
+	req := mkReq("GET", "/")
+	req.TLS = &tls.ConnectionState{}
+	out = headerBlock(run(t, SecureHeaders(SecureHeadersOptions{})(okHandler), req))
+	assert.Contains(t, out, "Strict-Transport-Security: max-age=31536000\r\n")
+
+	opts := SecureHeadersOptions{
+		HSTSMaxAge:            600,
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           true,
+		ForceHSTS:             true,
+		FrameOptions:          "SAMEORIGIN",
+		ReferrerPolicy:        Omit,
+		ContentSecurityPolicy: "default-src 'self'",
+		CSPReportOnly:         true,
+	}
+	out = headerBlock(run(t, SecureHeaders(opts)(okHandler), mkReq("GET", "/")))
+	assert.Contains(t, out, "Strict-Transport-Security: max-age=600; includeSubDomains; preload\r\n")
+	assert.Contains(t, out, "X-Frame-Options: SAMEORIGIN\r\n")
+	assert.Contains(t, out, "Content-Security-Policy-Report-Only: default-src 'self'\r\n")
+	assert.NotContains(t, out, "Referrer-Policy")
+
+	// Test: The handler's own values win
+	h := SecureHeaders(SecureHeadersOptions{})(func(w *response.Writer, req *request.Request) error {
+		hh := response.GetDefaultHeaders(0)
+		hh.Set("X-Frame-Options", "SAMEORIGIN")
+		return w.WriteResponse(response.StatusOK, hh, nil)
+	})
+	out = headerBlock(run(t, h, mkReq("GET", "/")))
+	assert.Contains(t, out, "X-Frame-Options: SAMEORIGIN\r\n")
+	assert.NotContains(t, out, "DENY")
+}
+
+func TestSecureHeaders_RouteOverride(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(SecureHeaders(SecureHeadersOptions{ContentSecurityPolicy: "default-src 'self'"}))
+	require.NoError(t, r.GET("/app", okHandler))
+	require.NoError(t, r.GET("/embed", okHandler, SecureHeaders(SecureHeadersOptions{
+		FrameOptions:          Omit,
+		ContentSecurityPolicy: "frame-ancestors https://partner.example",
+	})))
+
+	out := headerBlock(run(t, r.Handler(), mkReq("GET", "/app")))
+	assert.Contains(t, out, "X-Frame-Options: DENY\r\n")
+	assert.Contains(t, out, "Content-Security-Policy: default-src 'self'\r\n")
+
+	out = headerBlock(run(t, r.Handler(), mkReq("GET", "/embed")))
+	assert.NotContains(t, out, "X-Frame-Options")
+	assert.Contains(t, out, "Content-Security-Policy: frame-ancestors https://partner.example\r\n")
+	assert.Contains(t, out, "X-Content-Type-Options: nosniff\r\n")
+}
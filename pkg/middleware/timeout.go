@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+// Timeout gives each handler d to produce its response. On expiry the
+// request context is canceled and, if the handler hasn't written anything
+// yet, the client gets a 503 Service Unavailable; see
+// response.TimeoutHandler. Handlers should watch req.Context() and stop when
+// it is done.
+func Timeout(d time.Duration) router.Middleware {
+	return func(next response.Handler) response.Handler {
+		return response.TimeoutHandler(next, d, "request timed out")
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	// Test: Handlers finishing in time are untouched, middleware fields included
+	h := Timeout(time.Second)(func(w *response.Writer, req *request.Request) error {
+		_, ok := req.Context().Deadline()
+		assert.True(t, ok)
+		body := []byte("done")
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+	})
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+	w.Header().Set("X-Outer", "1")
+	require.NoError(t, h(w, mkReq("GET", "/")))
+	require.NoError(t, w.Finish())
+	assert.True(t, strings.HasPrefix(buf.String(), "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, buf.String(), "X-Outer: 1\r\n")
+	assert.True(t, strings.HasSuffix(buf.String(), "done"))
+
+	// Test: A slow handler gets a 503 and its late write is dropped
+	lateErr := make(chan error, 1)
+	h = Timeout(20 * time.Millisecond)(func(w *response.Writer, req *request.Request) error {
+		<-req.Context().Done()
+		err := w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(4), []byte("late"))
+		lateErr <- err
+		return err
+	})
+	buf.Reset()
+	w = response.NewWriter(&buf)
+	require.NoError(t, h(w, mkReq("GET", "/")))
+	require.NoError(t, w.Finish())
+	assert.ErrorIs(t, <-lateErr, response.ErrHandlerTimeout)
+	assert.True(t, strings.HasPrefix(buf.String(), "HTTP/1.1 503 Service Unavailable\r\n"))
+	assert.Equal(t, 1, strings.Count(buf.String(), "HTTP/1.1 "))
+	assert.NotContains(t, buf.String(), "late")
+
+	// Test: A started response is cut short instead of getting a second status line
+	release := make(chan struct{})
+	h = Timeout(20 * time.Millisecond)(func(w *response.Writer, req *request.Request) error {
+		defer close(release)
+		if err := w.WriteStatusLine(response.StatusOK); err != nil {
+			return err
+		}
+		<-req.Context().Done()
+		return w.WriteHeaders(response.GetDefaultHeaders(0))
+	})
+	buf.Reset()
+	w = response.NewWriter(&buf)
+	err := h(w, mkReq("GET", "/"))
+	assert.True(t, errors.Is(err, response.ErrHandlerTimeout))
+	assert.True(t, w.Written())
+	<-release
+	require.NoError(t, w.Finish())
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", buf.String())
+}
+
+func TestTimeout_Panic(t *testing.T) {
+	h := Recovery(RecoveryOptions{Logger: discardLogger})(Timeout(time.Second)(func(w *response.Writer, req *request.Request) error {
+		panic("boom")
+	}))
+
+	var buf bytes.Buffer
+	err := h(response.NewWriter(&buf), mkReq("GET", "/"))
+	assert.ErrorIs(t, err, ErrHandlerPanic)
+	assert.True(t, strings.HasPrefix(buf.String(), "HTTP/1.1 500 Internal Server Error\r\n"))
+}
@@ -0,0 +1,388 @@
+package nethttp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/client"
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+)
+
+// FromHTTP runs a net/http handler on this server, e.g. promhttp or
+// net/http/pprof. The response is streamed: a body without a Content-Length
+// is sent chunked, and trailers declared in the Trailer header or set with
+// http.TrailerPrefix are written after it.
+func FromHTTP(h http.Handler) response.Handler {
+	return func(w *response.Writer, req *request.Request) error {
+		hr, err := toHTTPRequest(req)
+		if err != nil {
+			return response.WrapHTTPError(response.StatusBadRequest, "invalid request target", err)
+		}
+
+		rw := &responseWriter{w: w, method: req.RequestLine.Method, header: http.Header{}}
+		h.ServeHTTP(rw, hr)
+		if w.Hijacked() {
+			return nil
+		}
+		return rw.finish()
+	}
+}
+
+func toHTTPRequest(req *request.Request) (*http.Request, error) {
+	target := req.RequestLine.RequestTarget
+	if req.RawQuery != "" {
+		target += "?" + req.RawQuery
+	}
+	u, err := url.ParseRequestURI(target)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	host := ""
+	req.Headers.ForEach(func(name, value string) {
+		if strings.EqualFold(name, "Host") {
+			host = value // net/http keeps Host out of the header map
+			return
+		}
+		header.Add(name, value)
+	})
+
+	var trailer http.Header
+	if req.Trailer != nil {
+		trailer = http.Header{}
+		req.Trailer.ForEach(func(name, value string) { trailer.Add(name, value) })
+	}
+
+	body := req.Body
+	hr := &http.Request{
+		Method:        req.RequestLine.Method,
+		URL:           u,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		GetBody:       func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil },
+		ContentLength: int64(len(body)),
+		Trailer:       trailer,
+		Host:          host,
+		RemoteAddr:    req.RemoteAddr,
+		RequestURI:    target,
+		TLS:           req.TLS,
+	}
+	return hr.WithContext(req.Context()), nil
+}
+
+// responseWriter exposes a response.Writer as an http.ResponseWriter. The
+// head is sent on the first Write or Flush, or when the handler returns.
+type responseWriter struct {
+	w       *response.Writer
+	method  string
+	header  http.Header
+	status  int
+	sent    bool
+	chunked bool
+}
+
+var (
+	_ http.ResponseWriter = (*responseWriter)(nil)
+	_ http.Flusher        = (*responseWriter)(nil)
+	_ http.Hijacker       = (*responseWriter)(nil)
+)
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status != 0 {
+		return
+	}
+	if code >= 100 && code < 200 {
+		// interim responses go out straight away, the final one follows
+		_ = rw.w.WriteInformational(response.StatusCode(code), toHeaders(rw.header, false))
+		return
+	}
+	rw.status = code
+}
+
+// bodyAllowed reports whether the response can carry body bytes.
+func (rw *responseWriter) bodyAllowed() bool {
+	return rw.method != "HEAD" &&
+		rw.status != int(response.StatusNoContent) &&
+		rw.status != int(response.StatusNotModified)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	if !rw.sent {
+		if err := rw.sendHead(p, false); err != nil {
+			return 0, err
+		}
+	}
+	if !rw.bodyAllowed() {
+		if rw.method == "HEAD" {
+			return len(p), nil
+		}
+		return 0, http.ErrBodyNotAllowed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	var err error
+	if rw.chunked {
+		err = rw.w.WriteChunk(p)
+	} else {
+		err = rw.w.WriteBody(p)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// sendHead writes the status line and headers. first is the start of the
+// body, used to sniff a missing Content-Type; final means the handler has
+// returned and no body follows.
+func (rw *responseWriter) sendHead(first []byte, final bool) error {
+	rw.sent = true
+	h := toHeaders(rw.header, false)
+
+	if _, ok := h.Get("Content-Type"); !ok && rw.bodyAllowed() && len(first) > 0 {
+		h.Set("Content-Type", http.DetectContentType(first))
+	}
+	if _, ok := h.Get("Content-Length"); !ok && rw.bodyAllowed() {
+		if final {
+			h.Set("Content-Length", "0")
+		} else {
+			h.Del("Content-Length")
+			h.Replace("Transfer-Encoding", "chunked")
+			rw.chunked = true
+		}
+	}
+
+	if err := rw.w.WriteStatusLine(response.StatusCode(rw.status)); err != nil {
+		return err
+	}
+	return rw.w.WriteHeaders(h)
+}
+
+func (rw *responseWriter) Flush() {
+	rw.WriteHeader(http.StatusOK)
+	if !rw.sent {
+		if err := rw.sendHead(nil, false); err != nil {
+			return
+		}
+	}
+	_ = rw.w.Flush()
+}
+
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return rw.w.Hijack()
+}
+
+// finish completes the response after the handler returns.
+func (rw *responseWriter) finish() error {
+	rw.WriteHeader(http.StatusOK)
+	if !rw.sent {
+		return rw.sendHead(nil, true)
+	}
+	if !rw.chunked {
+		return nil
+	}
+
+	trailer := toHeaders(rw.header, true)
+	if trailer.Len() == 0 {
+		return rw.w.WriteChunkEnd(false)
+	}
+	if err := rw.w.WriteChunkEnd(true); err != nil {
+		return err
+	}
+	return rw.w.WriteHeaders(trailer)
+}
+
+// toHeaders converts h in sorted key order. With trailers set it returns the
+// trailer fields instead: those announced in the Trailer header and those
+// keyed with http.TrailerPrefix.
+func toHeaders(h http.Header, trailers bool) *headers.Headers {
+	out := headers.NewHeaders()
+	announced := map[string]bool{}
+	for _, v := range h.Values("Trailer") {
+		for _, name := range strings.Split(v, ",") {
+			announced[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+
+	for _, k := range sortedKeys(h) {
+		name, prefixed := strings.CutPrefix(k, http.TrailerPrefix)
+		if trailers != (prefixed || announced[k]) {
+			continue
+		}
+		for _, v := range h[k] {
+			out.Set(name, v)
+		}
+	}
+	return out
+}
+
+// ToHTTP runs a handler written for this server under net/http. The response
+// is buffered and replayed onto the http.ResponseWriter once the handler
+// returns, so streaming handlers only flush at the end. Hijacking works when
+// the http.ResponseWriter supports it.
+func ToHTTP(h response.Handler) http.Handler {
+	return http.HandlerFunc(func(hw http.ResponseWriter, hr *http.Request) {
+		req, err := fromHTTPRequest(hr)
+		if err != nil {
+			http.Error(hw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var buf bytes.Buffer
+		w := response.NewWriter(&buf)
+		if hj, ok := hw.(http.Hijacker); ok {
+			w.SetHijacker(hj.Hijack)
+		}
+
+		err = h(w, req)
+		if w.Hijacked() {
+			return
+		}
+		if err != nil {
+			err = response.DefaultErrorHandler(w, req, err)
+		}
+		if fErr := w.Finish(); err == nil {
+			err = fErr
+		}
+		if err != nil && !w.Written() {
+			http.Error(hw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		resp, err := client.ReadResponse(bufio.NewReader(&buf), hr.Method)
+		if err != nil {
+			http.Error(hw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeHTTPResponse(hw, resp)
+	})
+}
+
+func writeHTTPResponse(hw http.ResponseWriter, resp *client.Response) {
+	out := hw.Header()
+	resp.Header.ForEach(func(name, value string) {
+		switch strings.ToLower(name) {
+		case "transfer-encoding", "connection":
+			return // framing is net/http's business
+		case "content-length":
+			if resp.Trailer.Len() > 0 {
+				return
+			}
+		}
+		out.Add(name, value)
+	})
+
+	hw.WriteHeader(int(resp.StatusCode))
+	_, _ = hw.Write(resp.Body)
+
+	resp.Trailer.ForEach(func(name, value string) {
+		out.Add(http.TrailerPrefix+name, value)
+	})
+}
+
+func fromHTTPRequest(hr *http.Request) (*request.Request, error) {
+	body := []byte{}
+	if hr.Body != nil {
+		var err error
+		if body, err = io.ReadAll(hr.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	h := headers.NewHeaders()
+	if hr.Host != "" {
+		h.Set("Host", hr.Host)
+	}
+	for _, k := range sortedKeys(hr.Header) {
+		for _, v := range hr.Header[k] {
+			h.Set(k, v)
+		}
+	}
+	if _, ok := h.Get("Content-Length"); !ok && len(body) > 0 {
+		h.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	var trailer *headers.Headers
+	if len(hr.Trailer) > 0 {
+		trailer = headers.NewHeaders()
+		for _, k := range sortedKeys(hr.Trailer) {
+			for _, v := range hr.Trailer[k] {
+				trailer.Set(k, v)
+			}
+		}
+	}
+
+	params, err := queryParams(hr.URL.RawQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	u := *hr.URL
+	u.Fragment, u.RawFragment = "", ""
+
+	req := &request.Request{
+		RequestLine: request.RequestLine{
+			Method:        hr.Method,
+			RequestTarget: hr.URL.EscapedPath(),
+			HttpVersion:   fmt.Sprintf("%d.%d", hr.ProtoMajor, hr.ProtoMinor),
+		},
+		URL:           &u,
+		Headers:       h,
+		Body:          body,
+		Trailer:       trailer,
+		RequestParams: params,
+		RawQuery:      hr.URL.RawQuery,
+		PathParams:    map[string]string{},
+		Locals:        request.NewLocals(),
+		RemoteAddr:    hr.RemoteAddr,
+		TLS:           hr.TLS,
+	}
+	return req.WithContext(hr.Context()), nil
+}
+
+func sortedKeys(h http.Header) []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// queryParams splits a raw query the way the request parser does, leaving
+// values undecoded.
+func queryParams(rawQuery string) (map[string]string, error) {
+	params := map[string]string{}
+	if rawQuery == "" {
+		return params, nil
+	}
+	for _, query := range strings.Split(rawQuery, "&") {
+		k, v, _ := strings.Cut(query, "=")
+		if k == "" {
+			return nil, request.ErrMalformedRequestLine
+		}
+		params[k] = v
+	}
+	return params, nil
+}
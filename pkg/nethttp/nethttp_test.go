@@ -0,0 +1,139 @@
+package nethttp
+
+import (
+	"io"
+	"net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/httptest"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromHTTP_Request(t *testing.T) {
+	var got *http.Request
+	var body string
+	h := FromHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Set("Content-Length", "2")
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "ok")
+	}))
+
+	hdrs := headers.NewHeaders()
+	hdrs.Set("Host", "svc.local")
+	hdrs.Set("X-Token", "abc")
+	req := httptest.NewRequest("POST", "/items?a=1&b=two", []byte("payload"), hdrs)
+	req.RemoteAddr = "10.0.0.1:5000"
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, rec.Serve(h, req))
+
+	assert.Equal(t, "POST", got.Method)
+	assert.Equal(t, "/items", got.URL.Path)
+	assert.Equal(t, "two", got.URL.Query().Get("b"))
+	assert.Equal(t, "/items?a=1&b=two", got.RequestURI)
+	assert.Equal(t, "svc.local", got.Host)
+	assert.Equal(t, "abc", got.Header.Get("X-Token"))
+	assert.Empty(t, got.Header.Get("Host"))
+	assert.Equal(t, "10.0.0.1:5000", got.RemoteAddr)
+	assert.Equal(t, "payload", body)
+	assert.Equal(t, int64(7), got.ContentLength)
+
+	assert.Equal(t, response.StatusAccepted, rec.Status())
+	assert.Equal(t, "ok", string(rec.Body()))
+}
+
+func TestFromHTTP_Response(t *testing.T) {
+	// Test: Streamed body without a length is chunked, with trailers
+	h := FromHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Digest")
+		io.WriteString(w, "<html>")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "</html>")
+		w.Header().Set("X-Digest", "d1")
+		w.Header().Set(http.TrailerPrefix+"X-Late", "l1")
+	}))
+	rec := httptest.NewRecorder()
+	require.NoError(t, rec.Serve(h, httptest.NewRequest("GET", "/", nil, nil)))
+	assert.Equal(t, response.StatusOK, rec.Status())
+	assert.Equal(t, "<html></html>", string(rec.Body()))
+	te, _ := rec.Header().Get("Transfer-Encoding")
+	assert.Equal(t, "chunked", te)
+	ct, _ := rec.Header().Get("Content-Type")
+	assert.Equal(t, "text/html; charset=utf-8", ct)
+	digest, _ := rec.Trailer().Get("X-Digest")
+	assert.Equal(t, "d1", digest)
+	late, _ := rec.Trailer().Get("X-Late")
+	assert.Equal(t, "l1", late)
+
+	// Test: No body at all gets a zero length
+	h = FromHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec = httptest.NewRecorder()
+	require.NoError(t, rec.Serve(h, httptest.NewRequest("GET", "/", nil, nil)))
+	assert.Equal(t, response.StatusOK, rec.Status())
+	cl, _ := rec.Header().Get("Content-Length")
+	assert.Equal(t, "0", cl)
+
+	// Test: 204 refuses a body
+	var writeErr error
+	h = FromHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+		_, writeErr = io.WriteString(w, "nope")
+	}))
+	rec = httptest.NewRecorder()
+	require.NoError(t, rec.Serve(h, httptest.NewRequest("GET", "/", nil, nil)))
+	assert.Equal(t, response.StatusNoContent, rec.Status())
+	assert.ErrorIs(t, writeErr, http.ErrBodyNotAllowed)
+	assert.NotContains(t, rec.Raw(), "nope")
+}
+
+func TestToHTTP(t *testing.T) {
+	h := ToHTTP(func(w *response.Writer, req *request.Request) error {
+		if req.RequestLine.RequestTarget == "/fail" {
+			return response.NewHTTPError(response.StatusForbidden, "no")
+		}
+		host, _ := req.Headers.Get("Host")
+		body := []byte(strings.Join([]string{req.RequestLine.Method, host, req.RequestParams["q"], string(req.Body)}, " "))
+		hdrs := response.GetDefaultHeaders(len(body))
+		hdrs.Set("X-Custom", "yes")
+		return w.WriteResponse(response.StatusOK, hdrs, body)
+	})
+
+	// Test: Request and response are converted
+	rec := stdhttptest.NewRecorder()
+	h.ServeHTTP(rec, stdhttptest.NewRequest("PUT", "http://api.local/x?q=1", strings.NewReader("data")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "PUT api.local 1 data", rec.Body.String())
+	assert.Equal(t, "yes", rec.Header().Get("X-Custom"))
+	assert.Empty(t, rec.Header().Get("Connection"))
+
+	// Test: Handler errors go through the default error handler
+	rec = stdhttptest.NewRecorder()
+	h.ServeHTTP(rec, stdhttptest.NewRequest("GET", "/fail", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Test: Chunked responses are de-chunked and trailers carried over
+	h = ToHTTP(func(w *response.Writer, req *request.Request) error {
+		hdrs := response.GetDefaultHeaders(0)
+		hdrs.Set("Trailer", "X-Sum")
+		return w.WriteChunkedFrom(response.StatusOK, hdrs, strings.NewReader("abcdef"), 2, func() *headers.Headers {
+			tr := headers.NewHeaders()
+			tr.Set("X-Sum", "6")
+			return tr
+		})
+	})
+	rec = stdhttptest.NewRecorder()
+	h.ServeHTTP(rec, stdhttptest.NewRequest("GET", "/", nil))
+	res := rec.Result()
+	b, _ := io.ReadAll(res.Body)
+	assert.Equal(t, "abcdef", string(b))
+	assert.Equal(t, "6", res.Trailer.Get("X-Sum"))
+}
@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+const DefaultDialTimeout = 10 * time.Second
+
+type ConnectOptions struct {
+	// AllowedHosts lists the hosts tunnels may be opened to, either exactly
+	// or as "*.example.com" for any subdomain. Empty allows any host that
+	// resolves to public addresses only, so that clients can't reach the
+	// proxy's own loopback, private or link-local networks; list such hosts
+	// (e.g. "10.0.0.5") to allow them. The checked address is the one dialed,
+	// so the name can't resolve differently in between.
+	AllowedHosts []string
+	// AllowedPorts lists the ports tunnels may be opened to, defaulting to
+	// 443 only so the proxy can't be pointed at e.g. mail servers.
+	AllowedPorts []int
+	// Authorize, when set, must accept a request before its tunnel is
+	// opened; rejected clients get 407 with a Basic challenge for Realm.
+	Authorize func(req *request.Request) bool
+	Realm     string // defaults to "proxy"
+	// Dial opens the upstream connection, defaulting to a net.Dialer bounded
+	// by DialTimeout. Without AllowedHosts it is given the resolved address
+	// rather than the requested name.
+	Dial        func(ctx context.Context, network, addr string) (net.Conn, error)
+	DialTimeout time.Duration
+	Logger      *slog.Logger // defaults to slog.Default()
+}
+
+func (opts *ConnectOptions) hostAllowed(host string) bool {
+	if len(opts.AllowedHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, allowed := range opts.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// errNotPublic refuses tunnels to hosts resolving to loopback, private or
+// other non-public addresses.
+var errNotPublic = fmt.Errorf("tunnel target is not a public address")
+
+// publicAddr resolves host, failing with errNotPublic if any of its addresses
+// isn't a public unicast one, and returns the first.
+func publicAddr(ctx context.Context, host string) (netip.Addr, error) {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+			return netip.Addr{}, fmt.Errorf("%w: %s is %s", errNotPublic, host, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return netip.Addr{}, fmt.Errorf("no addresses for %s", host)
+	}
+	return addrs[0].Unmap(), nil
+}
+
+func (opts *ConnectOptions) portAllowed(port int) bool {
+	for _, p := range opts.AllowedPorts {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// Connect turns the server into a forward proxy for CONNECT requests with an
+// authority-form target ("host:port"). Once the allow-lists and Authorize
+// accept it, a TCP connection to the target is opened, 200 is sent, and bytes
+// are copied both ways until both sides are done. Other methods go to the
+// next handler; wrap the router with it since CONNECT targets aren't paths:
+//
+//	server.Serve(port, proxy.Connect(opts)(r.Handler()), nil)
+func Connect(opts ConnectOptions) router.Middleware {
+	if len(opts.AllowedPorts) == 0 {
+		opts.AllowedPorts = []int{443}
+	}
+	if opts.Realm == "" {
+		opts.Realm = "proxy"
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = DefaultDialTimeout
+	}
+	if opts.Dial == nil {
+		d := &net.Dialer{}
+		opts.Dial = d.DialContext
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			if req.RequestLine.Method != "CONNECT" {
+				return next(w, req)
+			}
+			return opts.tunnel(w, req)
+		}
+	}
+}
+
+func (opts *ConnectOptions) tunnel(w *response.Writer, req *request.Request) error {
+	target := req.RequestLine.RequestTarget
+	host, portStr, err := net.SplitHostPort(target)
+	port, pErr := strconv.Atoi(portStr)
+	if err != nil || pErr != nil || host == "" {
+		return response.NewHTTPError(response.StatusBadRequest, "CONNECT needs a host:port target")
+	}
+
+	if opts.Authorize != nil && !opts.Authorize(req) {
+		body := []byte("proxy authentication required")
+		h := response.GetDefaultHeaders(len(body))
+		h.Set("Proxy-Authenticate", fmt.Sprintf("Basic realm=%q", opts.Realm))
+		return w.WriteResponse(response.StatusProxyAuthRequired, h, body)
+	}
+	if !opts.hostAllowed(host) || !opts.portAllowed(port) {
+		return response.NewHTTPError(response.StatusForbidden, "tunnel target not allowed")
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), opts.DialTimeout)
+	defer cancel()
+	dialAddr := target
+	if len(opts.AllowedHosts) == 0 {
+		addr, err := publicAddr(ctx, host)
+		if errors.Is(err, errNotPublic) {
+			return response.WrapHTTPError(response.StatusForbidden, "tunnel target not allowed", err)
+		}
+		if err != nil {
+			return response.WrapHTTPError(response.StatusBadGateway, "upstream unreachable", err)
+		}
+		dialAddr = net.JoinHostPort(addr.String(), portStr)
+	}
+	upstream, err := opts.Dial(ctx, "tcp", dialAddr)
+	cancel()
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return response.WrapHTTPError(response.StatusGatewayTimeout, "upstream timed out", err)
+		}
+		return response.WrapHTTPError(response.StatusBadGateway, "upstream unreachable", err)
+	}
+	defer upstream.Close()
+
+	conn, rw, err := w.Hijack()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rw.WriteString("HTTP/1.1 200 Connection Established\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		return err
+	}
+
+	logger := opts.Logger.With("target", target, "remote_addr", req.RemoteAddr)
+	logger.Debug("tunnel opened")
+	up, down := splice(upstream, conn, rw.Reader)
+	logger.Debug("tunnel closed", "bytes_up", up, "bytes_down", down)
+	return nil
+}
+
+// closeWriter is implemented by connections that support half-close, like
+// *net.TCPConn and *tls.Conn.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+func closeWrite(c net.Conn) {
+	if cw, ok := c.(closeWriter); ok {
+		_ = cw.CloseWrite()
+		return
+	}
+	_ = c.Close()
+}
+
+// splice copies client (read through clientReader, which holds any bytes
+// already buffered) to upstream and back until both directions reach EOF,
+// half-closing each side as its source finishes. An error in either
+// direction tears down both connections.
+func splice(upstream net.Conn, client net.Conn, clientReader io.Reader) (up int64, down int64) {
+	pipe := func(dst net.Conn, src io.Reader, n *int64) {
+		var err error
+		*n, err = io.Copy(dst, src)
+		if err != nil {
+			upstream.Close()
+			client.Close()
+			return
+		}
+		closeWrite(dst)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		pipe(upstream, clientReader, &up)
+	}()
+	go func() {
+		defer wg.Done()
+		pipe(client, upstream, &down)
+	}()
+	wg.Wait()
+	return up, down
+}
@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoUpstream echoes everything back until the client half-closes.
+func echoUpstream(t *testing.T) (string, int) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l.Addr().String(), l.Addr().(*net.TCPAddr).Port
+}
+
+func startProxy(t *testing.T, opts ConnectOptions) string {
+	t.Helper()
+	fallback := func(w *response.Writer, req *request.Request) error {
+		body := []byte("not a tunnel")
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := server.ServeListener(l, Connect(opts)(fallback), nil, server.Options{})
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String()
+}
+
+func sendConnect(t *testing.T, proxyAddr string, target string, extra string) (net.Conn, *bufio.Reader, string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", proxyAddr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n%s\r\n", target, target, extra)
+	br := bufio.NewReader(conn)
+	status, err := br.ReadString('\n')
+	require.NoError(t, err)
+	return conn, br, status
+}
+
+func TestConnect_Tunnel(t *testing.T) {
+	upstream, port := echoUpstream(t)
+	proxyAddr := startProxy(t, ConnectOptions{AllowedHosts: []string{"127.0.0.1"}, AllowedPorts: []int{port}})
+
+	conn, br, status := sendConnect(t, proxyAddr, upstream, "")
+	assert.Equal(t, "HTTP/1.1 200 Connection Established\r\n", status)
+	blank, err := br.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "\r\n", blank)
+
+	_, err = io.WriteString(conn, "ping through the tunnel")
+	require.NoError(t, err)
+	require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+
+	// the upstream's EOF makes its way back once our half-close reached it
+	echoed, err := io.ReadAll(br)
+	require.NoError(t, err)
+	assert.Equal(t, "ping through the tunnel", string(echoed))
+}
+
+func TestConnect_Refused(t *testing.T) {
+	upstream, port := echoUpstream(t)
+	proxyAddr := startProxy(t, ConnectOptions{
+		AllowedHosts: []string{"127.0.0.1", "*.example.com"},
+		AllowedPorts: []int{port},
+		Authorize: func(req *request.Request) bool {
+			user, pass, ok := req.ProxyBasicAuth()
+			return ok && user == "u" && pass == "p"
+		},
+	})
+	auth := "Proxy-Authorization: Basic dTpw\r\n"
+
+	// Test: Missing credentials get a challenge
+	_, br, status := sendConnect(t, proxyAddr, upstream, "")
+	assert.Equal(t, "HTTP/1.1 407 Proxy Authentication Required\r\n", status)
+	var head strings.Builder
+	for {
+		line, err := br.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\r\n" {
+			break
+		}
+		head.WriteString(line)
+	}
+	assert.Contains(t, head.String(), "Proxy-Authenticate: Basic realm=\"proxy\"\r\n")
+
+	// Test: Port outside the allow-list
+	_, _, status = sendConnect(t, proxyAddr, "127.0.0.1:"+strconv.Itoa(port+1), auth)
+	assert.Equal(t, "HTTP/1.1 403 Forbidden\r\n", status)
+
+	// Test: Host outside the allow-list
+	_, _, status = sendConnect(t, proxyAddr, "localhost:"+strconv.Itoa(port), auth)
+	assert.Equal(t, "HTTP/1.1 403 Forbidden\r\n", status)
+
+	// Test: Not an authority-form target
+	_, _, status = sendConnect(t, proxyAddr, "/path", auth)
+	assert.Equal(t, "HTTP/1.1 400 Bad Request\r\n", status)
+
+	// Test: Other methods pass through
+	conn, err := net.Dial("tcp", proxyAddr)
+	require.NoError(t, err)
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n")
+	out, _ := io.ReadAll(conn)
+	assert.True(t, strings.HasSuffix(string(out), "not a tunnel"))
+}
+
+func TestConnect_UpstreamDown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	proxyAddr := startProxy(t, ConnectOptions{AllowedHosts: []string{"127.0.0.1"}, AllowedPorts: []int{port}})
+	_, _, status := sendConnect(t, proxyAddr, addr, "")
+	assert.Equal(t, "HTTP/1.1 502 Bad Gateway\r\n", status)
+}
+
+func TestConnect_NonPublicTargets(t *testing.T) {
+	_, port := echoUpstream(t)
+	proxyAddr := startProxy(t, ConnectOptions{AllowedPorts: []int{port}})
+
+	// Test: Without AllowedHosts, the proxy's own networks are off limits
+	for _, host := range []string{"127.0.0.1", "localhost", "[::1]", "10.0.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0"} {
+		_, _, status := sendConnect(t, proxyAddr, host+":"+strconv.Itoa(port), "")
+		assert.Equal(t, "HTTP/1.1 403 Forbidden\r\n", status, host)
+	}
+
+	// Test: Public addresses are dialed as resolved
+	addr, err := publicAddr(context.Background(), "8.8.8.8")
+	require.NoError(t, err)
+	assert.Equal(t, "8.8.8.8", addr.String())
+	_, err = publicAddr(context.Background(), "::ffff:127.0.0.1")
+	assert.ErrorIs(t, err, errNotPublic)
+}
+
+func TestConnectOptions_HostAllowed(t *testing.T) {
+	opts := ConnectOptions{AllowedHosts: []string{"api.example.com", "*.internal.example"}}
+	assert.True(t, opts.hostAllowed("API.example.com"))
+	assert.True(t, opts.hostAllowed("db.internal.example"))
+	assert.False(t, opts.hostAllowed("internal.example"))
+	assert.False(t, opts.hostAllowed("example.com"))
+	assert.True(t, (&ConnectOptions{}).hostAllowed("anything"))
+}
@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/client"
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+)
+
+type ReverseProxyOptions struct {
+	// Client sends requests to the upstreams, defaulting to one that keeps
+	// connections alive. Redirects are passed back rather than followed
+	// unless the client is set up otherwise.
+	Client *client.Client
+	Logger *slog.Logger // defaults to slog.Default()
+}
+
+// hopHeaders are fields that describe a single connection and so aren't
+// forwarded, along with any the Connection field names.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// forwardHeaders returns a copy of h without hop-by-hop fields or framing,
+// which the receiving side sets for its own connection.
+func forwardHeaders(h *headers.Headers) *headers.Headers {
+	out := h.Clone()
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				out.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		out.Del(name)
+	}
+	out.Del("Content-Length")
+	return out
+}
+
+// ReverseProxy returns a handler forwarding each request to an upstream of
+// pool and relaying its response. Every forwarded request is reported to the
+// pool with Release, so upstreams that can't be reached or fail mid-request
+// are ejected as PoolOptions.MaxFails says; error statuses from an upstream
+// that answered don't count. A request whose upstream refused the connection
+// never reached it and is tried on the next one. Clients get 503 when no
+// upstream is available, 502 when the last one tried failed and 504 when it
+// timed out.
+//
+//	pool, _ := proxy.NewPool(upstreams, proxy.PoolOptions{})
+//	r.NotFound(proxy.ReverseProxy(pool, proxy.ReverseProxyOptions{}))
+func ReverseProxy(pool *Pool, opts ReverseProxyOptions) response.Handler {
+	if opts.Client == nil {
+		opts.Client = &client.Client{MaxRedirects: -1}
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	return func(w *response.Writer, req *request.Request) error {
+		target := req.RequestLine.RequestTarget
+		if req.URL != nil {
+			target = req.URL.RequestURI()
+		} else if req.RawQuery != "" {
+			target += "?" + req.RawQuery
+		}
+
+		h := forwardHeaders(req.Headers)
+		if ip, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+			if prior, ok := h.Get("X-Forwarded-For"); ok {
+				ip = prior + ", " + ip
+			}
+			h.Replace("X-Forwarded-For", ip)
+		}
+
+		var lastErr error
+		for range len(pool.upstreams) {
+			u, err := pool.Acquire()
+			if err != nil {
+				break
+			}
+			resp, err := forward(req.Context(), opts.Client, u, req, target, h)
+			pool.Release(u, err)
+			if err == nil {
+				out := forwardHeaders(resp.Header)
+				if n, ok := resp.Header.Get("Content-Length"); ok && req.RequestLine.Method == "HEAD" {
+					out.Replace("Content-Length", n) // the length a GET would get
+				} else if resp.StatusCode != response.StatusNoContent && resp.StatusCode != response.StatusNotModified {
+					out.Replace("Content-Length", strconv.Itoa(len(resp.Body)))
+				}
+				return w.WriteResponse(resp.StatusCode, out, resp.Body)
+			}
+
+			lastErr = err
+			opts.Logger.Warn("upstream request failed", "upstream", u.Addr, "error", err)
+			if !refused(err) || req.Context().Err() != nil {
+				break
+			}
+		}
+
+		switch {
+		case lastErr == nil:
+			return response.WrapHTTPError(response.StatusServiceUnavailable, "no upstream available", ErrNoHealthyUpstreams)
+		case errors.Is(lastErr, context.DeadlineExceeded):
+			return response.WrapHTTPError(response.StatusGatewayTimeout, "upstream timed out", lastErr)
+		default:
+			return response.WrapHTTPError(response.StatusBadGateway, "upstream unreachable", lastErr)
+		}
+	}
+}
+
+// forward sends req to u and reads its response.
+func forward(ctx context.Context, c *client.Client, u *Upstream, req *request.Request, target string, h *headers.Headers) (*client.Response, error) {
+	up, err := url.Parse("http://" + u.Addr + target)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(ctx, &client.Request{
+		Method: req.RequestLine.Method,
+		URL:    up,
+		Header: h,
+		Body:   req.Body,
+	})
+}
+
+// refused reports whether err means no connection to the upstream could be
+// opened, so the request was never sent.
+func refused(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/client"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backend starts a server describing each request it gets.
+func backend(t *testing.T) string {
+	t.Helper()
+	handler := func(w *response.Writer, req *request.Request) error {
+		_, hop := req.Headers.Get("X-Hop")
+		xff, _ := req.Headers.Get("X-Forwarded-For")
+		body := []byte(fmt.Sprintf("%s %s?%s body=%s hop=%t xff=%s",
+			req.RequestLine.Method, req.RequestLine.RequestTarget, req.RawQuery, req.Body, hop, xff))
+		h := response.GetDefaultHeaders(len(body))
+		h.Set("X-Backend", "up")
+		return w.WriteResponse(response.StatusOK, h, body)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := server.ServeListener(l, handler, nil, server.Options{})
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String()
+}
+
+// downAddr returns an address nothing listens on.
+func downAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func startReverseProxy(t *testing.T, pool *Pool) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := server.ServeListener(l, ReverseProxy(pool, ReverseProxyOptions{Logger: quiet}), nil, server.Options{})
+	t.Cleanup(func() { s.Close() })
+	return "http://" + l.Addr().String()
+}
+
+func TestReverseProxy(t *testing.T) {
+	up, down := backend(t), downAddr(t)
+	pool, err := NewPool([]UpstreamConfig{{Addr: down}, {Addr: up}},
+		PoolOptions{MaxFails: 1, EjectDuration: time.Minute, Logger: quiet})
+	require.NoError(t, err)
+	base := startReverseProxy(t, pool)
+	c := &client.Client{}
+	defer c.CloseIdleConnections()
+
+	// Test: The down backend is ejected, and the request it refused is
+	// served by the other
+	for range 4 {
+		resp, err := c.Get(context.Background(), base+"/items?page=2")
+		require.NoError(t, err)
+		assert.Equal(t, response.StatusOK, resp.StatusCode)
+		assert.Equal(t, "GET /items?page=2 body= hop=false xff=127.0.0.1", string(resp.Body))
+		v, _ := resp.Header.Get("X-Backend")
+		assert.Equal(t, "up", v)
+	}
+	status := pool.Status()
+	assert.False(t, status[0].Healthy)
+	assert.True(t, status[1].Healthy)
+	assert.Equal(t, 0, status[1].Active)
+
+	// Test: Bodies are forwarded, hop-by-hop fields aren't
+	req, err := client.NewRequest("POST", base+"/items", []byte("new"))
+	require.NoError(t, err)
+	req.Header.Set("Connection", "X-Hop")
+	req.Header.Set("X-Hop", "1")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	resp, err := c.Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "POST /items? body=new hop=false xff=10.0.0.1, 127.0.0.1", string(resp.Body))
+
+	// Test: HEAD keeps the length a GET would get
+	req, err = client.NewRequest("HEAD", base+"/items", nil)
+	require.NoError(t, err)
+	resp, err = c.Do(context.Background(), req)
+	require.NoError(t, err)
+	v, _ := resp.Header.Get("Content-Length")
+	assert.Equal(t, fmt.Sprint(len("HEAD /items? body= hop=false xff=127.0.0.1")), v)
+	assert.Empty(t, resp.Body)
+}
+
+func TestReverseProxy_AllDown(t *testing.T) {
+	pool, err := NewPool([]UpstreamConfig{{Addr: downAddr(t)}},
+		PoolOptions{MaxFails: 1, EjectDuration: time.Minute, Logger: quiet})
+	require.NoError(t, err)
+	base := startReverseProxy(t, pool)
+	c := &client.Client{}
+	defer c.CloseIdleConnections()
+
+	// Test: 502 while the upstream is tried, then 503 once it's ejected
+	resp, err := c.Get(context.Background(), base)
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusBadGateway, resp.StatusCode)
+	resp, err = c.Get(context.Background(), base)
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusServiceUnavailable, resp.StatusCode)
+}
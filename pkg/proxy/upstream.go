@@ -0,0 +1,317 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoUpstreams        = fmt.Errorf("no upstreams configured")
+	ErrNoHealthyUpstreams = fmt.Errorf("no healthy upstream available")
+	ErrInvalidWeight      = fmt.Errorf("upstream weight must be positive")
+)
+
+// Strategy selects which upstream serves the next request.
+type Strategy uint
+
+const (
+	RoundRobin       Strategy = iota
+	LeastConnections          // fewest in-flight requests relative to weight
+	Weighted                  // smooth weighted round robin
+)
+
+const (
+	DefaultMaxFails      = 3
+	DefaultEjectDuration = 30 * time.Second
+	DefaultProbeTimeout  = 2 * time.Second
+)
+
+// ProbeFunc checks whether the upstream at addr can take traffic.
+type ProbeFunc func(ctx context.Context, addr string) error
+
+// TCPProbe considers an upstream healthy when a TCP connection to it can be
+// opened.
+func TCPProbe(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+type UpstreamConfig struct {
+	Addr   string // host:port
+	Weight int    // relative share for Weighted and LeastConnections, defaults to 1
+}
+
+type PoolOptions struct {
+	Strategy Strategy
+	// MaxFails consecutive failed requests eject an upstream for
+	// EjectDuration (passive health checking).
+	MaxFails      int
+	EjectDuration time.Duration
+	// HealthInterval enables active probing with Probe (default TCPProbe),
+	// each attempt bounded by ProbeTimeout. Upstreams failing a probe are
+	// taken out of rotation until a probe succeeds again.
+	HealthInterval time.Duration
+	ProbeTimeout   time.Duration
+	Probe          ProbeFunc
+	Logger         *slog.Logger // defaults to slog.Default()
+}
+
+// Upstream is one backend in a Pool.
+type Upstream struct {
+	Addr   string
+	Weight int
+
+	// guarded by Pool.mu
+	active        int
+	fails         int
+	ejectedUntil  time.Time
+	probeFailed   bool
+	currentWeight int
+}
+
+func (u *Upstream) available(now time.Time) bool {
+	return !u.probeFailed && !now.Before(u.ejectedUntil)
+}
+
+// UpstreamStatus is a point-in-time view of an upstream for diagnostics.
+type UpstreamStatus struct {
+	Addr    string
+	Weight  int
+	Healthy bool
+	Active  int
+	Fails   int
+}
+
+// Pool balances requests over a set of upstreams and keeps track of their
+// health.
+type Pool struct {
+	opts      PoolOptions
+	upstreams []*Upstream
+
+	mu   sync.Mutex
+	next int
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewPool(upstreams []UpstreamConfig, opts PoolOptions) (*Pool, error) {
+	if len(upstreams) == 0 {
+		return nil, ErrNoUpstreams
+	}
+	if opts.MaxFails <= 0 {
+		opts.MaxFails = DefaultMaxFails
+	}
+	if opts.EjectDuration <= 0 {
+		opts.EjectDuration = DefaultEjectDuration
+	}
+	if opts.ProbeTimeout <= 0 {
+		opts.ProbeTimeout = DefaultProbeTimeout
+	}
+	if opts.Probe == nil {
+		opts.Probe = TCPProbe
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	p := &Pool{opts: opts}
+	for _, cfg := range upstreams {
+		weight := cfg.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidWeight, cfg.Addr)
+		}
+		p.upstreams = append(p.upstreams, &Upstream{Addr: cfg.Addr, Weight: weight})
+	}
+
+	if opts.HealthInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancel = cancel
+		p.done = make(chan struct{})
+		go p.healthLoop(ctx)
+	}
+
+	return p, nil
+}
+
+// Close stops active health checking.
+func (p *Pool) Close() {
+	if p.cancel != nil {
+		p.cancel()
+		<-p.done
+	}
+}
+
+// Acquire picks an upstream for a request according to the pool's strategy.
+// Every successful Acquire must be paired with a Release.
+func (p *Pool) Acquire() (*Upstream, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var picked *Upstream
+	switch p.opts.Strategy {
+	case LeastConnections:
+		picked = p.leastConnections(now)
+	case Weighted:
+		picked = p.weighted(now)
+	default:
+		picked = p.roundRobin(now)
+	}
+
+	if picked == nil {
+		return nil, ErrNoHealthyUpstreams
+	}
+	picked.active++
+	return picked, nil
+}
+
+// Release reports the outcome of a request sent to u. A non-nil err counts
+// towards ejecting it; a success clears its failure count.
+func (p *Pool) Release(u *Upstream, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u.active--
+	if err == nil {
+		u.fails = 0
+		return
+	}
+
+	u.fails++
+	if u.fails >= p.opts.MaxFails {
+		u.fails = 0
+		u.ejectedUntil = time.Now().Add(p.opts.EjectDuration)
+		p.opts.Logger.Warn("upstream ejected", "upstream", u.Addr, "error", err, "for", p.opts.EjectDuration)
+	}
+}
+
+func (p *Pool) roundRobin(now time.Time) *Upstream {
+	for range p.upstreams {
+		u := p.upstreams[p.next]
+		p.next = (p.next + 1) % len(p.upstreams)
+		if u.available(now) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (p *Pool) leastConnections(now time.Time) *Upstream {
+	var best *Upstream
+	for _, u := range p.upstreams {
+		if !u.available(now) {
+			continue
+		}
+		// compare active/weight without dividing
+		if best == nil || u.active*best.Weight < best.active*u.Weight {
+			best = u
+		}
+	}
+	return best
+}
+
+// weighted implements nginx's smooth weighted round robin, which spreads the
+// heavier upstreams' turns out instead of sending them in bursts.
+func (p *Pool) weighted(now time.Time) *Upstream {
+	var best *Upstream
+	total := 0
+	for _, u := range p.upstreams {
+		if !u.available(now) {
+			continue
+		}
+		u.currentWeight += u.Weight
+		total += u.Weight
+		if best == nil || u.currentWeight > best.currentWeight {
+			best = u
+		}
+	}
+
+	if best != nil {
+		best.currentWeight -= total
+	}
+	return best
+}
+
+func (p *Pool) healthLoop(ctx context.Context) {
+	defer close(p.done)
+	ticker := time.NewTicker(p.opts.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		p.probeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) probeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, u := range p.upstreams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, p.opts.ProbeTimeout)
+			err := p.opts.Probe(probeCtx, u.Addr)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			p.setProbeResult(u, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Pool) setProbeResult(u *Upstream, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	failed := err != nil
+	if failed == u.probeFailed {
+		return
+	}
+	u.probeFailed = failed
+	if failed {
+		p.opts.Logger.Warn("upstream failed health check", "upstream", u.Addr, "error", err)
+		return
+	}
+
+	// a passing probe also lifts a passive ejection
+	u.ejectedUntil = time.Time{}
+	u.fails = 0
+	p.opts.Logger.Info("upstream recovered", "upstream", u.Addr)
+}
+
+// Status reports the current state of every upstream.
+func (p *Pool) Status() []UpstreamStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	status := make([]UpstreamStatus, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		status = append(status, UpstreamStatus{
+			Addr:    u.Addr,
+			Weight:  u.Weight,
+			Healthy: u.available(now),
+			Active:  u.active,
+			Fails:   u.fails,
+		})
+	}
+	return status
+}
@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var quiet = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func pick(t *testing.T, p *Pool, n int) []string {
+	t.Helper()
+	addrs := []string{}
+	for range n {
+		u, err := p.Acquire()
+		require.NoError(t, err)
+		addrs = append(addrs, u.Addr)
+		p.Release(u, nil)
+	}
+	return addrs
+}
+
+func TestPool_RoundRobin(t *testing.T) {
+	p, err := NewPool([]UpstreamConfig{{Addr: "a"}, {Addr: "b"}, {Addr: "c"}}, PoolOptions{Logger: quiet})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "a"}, pick(t, p, 4))
+
+	_, err = NewPool(nil, PoolOptions{})
+	assert.ErrorIs(t, err, ErrNoUpstreams)
+	_, err = NewPool([]UpstreamConfig{{Addr: "a", Weight: -1}}, PoolOptions{})
+	assert.ErrorIs(t, err, ErrInvalidWeight)
+}
+
+func TestPool_Weighted(t *testing.T) {
+	p, err := NewPool([]UpstreamConfig{{Addr: "a", Weight: 5}, {Addr: "b", Weight: 1}, {Addr: "c", Weight: 1}},
+		PoolOptions{Strategy: Weighted, Logger: quiet})
+	require.NoError(t, err)
+
+	// Test: Smooth distribution matching nginx
+	assert.Equal(t, []string{"a", "a", "b", "a", "c", "a", "a"}, pick(t, p, 7))
+}
+
+func TestPool_LeastConnections(t *testing.T) {
+	p, err := NewPool([]UpstreamConfig{{Addr: "a"}, {Addr: "b", Weight: 2}}, PoolOptions{Strategy: LeastConnections, Logger: quiet})
+	require.NoError(t, err)
+
+	first, err := p.Acquire()
+	require.NoError(t, err)
+	assert.Equal(t, "a", first.Addr)
+
+	// Test: b can take two for each of a's
+	for range 2 {
+		u, err := p.Acquire()
+		require.NoError(t, err)
+		assert.Equal(t, "b", u.Addr)
+	}
+
+	p.Release(first, nil)
+	u, err := p.Acquire()
+	require.NoError(t, err)
+	assert.Equal(t, "a", u.Addr)
+}
+
+func TestPool_PassiveEjection(t *testing.T) {
+	p, err := NewPool([]UpstreamConfig{{Addr: "a"}, {Addr: "b"}},
+		PoolOptions{MaxFails: 2, EjectDuration: 50 * time.Millisecond, Logger: quiet})
+	require.NoError(t, err)
+
+	// Test: Consecutive failures eject, a success in between resets
+	u, _ := p.Acquire()
+	p.Release(u, errors.New("boom"))
+	u, _ = p.Acquire()
+	p.Release(u, nil)
+	assert.Equal(t, []string{"a", "b"}, pick(t, p, 2))
+
+	for range 2 {
+		a, _ := p.Acquire()
+		require.Equal(t, "a", a.Addr)
+		p.Release(a, errors.New("boom"))
+		b, _ := p.Acquire()
+		p.Release(b, nil)
+	}
+	assert.Equal(t, []string{"b", "b", "b"}, pick(t, p, 3))
+	assert.False(t, p.Status()[0].Healthy)
+
+	// Test: Ejected upstreams come back after the eject duration
+	time.Sleep(60 * time.Millisecond)
+	assert.ElementsMatch(t, []string{"a", "b"}, pick(t, p, 2))
+
+	// Test: Nothing available
+	p, err = NewPool([]UpstreamConfig{{Addr: "a"}}, PoolOptions{MaxFails: 1, Logger: quiet})
+	require.NoError(t, err)
+	u, _ = p.Acquire()
+	p.Release(u, errors.New("boom"))
+	_, err = p.Acquire()
+	assert.ErrorIs(t, err, ErrNoHealthyUpstreams)
+}
+
+func TestPool_ActiveHealthChecks(t *testing.T) {
+	var bDown atomic.Bool
+	bDown.Store(true)
+	probe := func(ctx context.Context, addr string) error {
+		if addr == "b" && bDown.Load() {
+			return errors.New("down")
+		}
+		return nil
+	}
+
+	p, err := NewPool([]UpstreamConfig{{Addr: "a"}, {Addr: "b"}},
+		PoolOptions{HealthInterval: 10 * time.Millisecond, Probe: probe, Logger: quiet})
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.Eventually(t, func() bool { return !p.Status()[1].Healthy }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"a", "a"}, pick(t, p, 2))
+
+	// Test: A passing probe puts it back in rotation
+	bDown.Store(false)
+	require.Eventually(t, func() bool { return p.Status()[1].Healthy }, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []string{"a", "b"}, pick(t, p, 2))
+}
+
+func TestTCPProbe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	assert.NoError(t, TCPProbe(context.Background(), addr))
+
+	l.Close()
+	assert.Error(t, TCPProbe(context.Background(), addr))
+}
@@ -0,0 +1,61 @@
+package request
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// authorization splits the header name (Authorization or
+// Proxy-Authorization) into its scheme (matched case-insensitively) and
+// credentials.
+func (r *Request) authorization(name string, scheme string) (string, bool) {
+	auth, ok := r.Headers.Get(name)
+	if !ok {
+		return "", false
+	}
+
+	got, credentials, found := strings.Cut(strings.TrimSpace(auth), " ")
+	if !found || !strings.EqualFold(got, scheme) {
+		return "", false
+	}
+	return strings.TrimSpace(credentials), true
+}
+
+// BasicAuth returns the username and password from a Basic Authorization
+// header.
+func (r *Request) BasicAuth() (username string, password string, ok bool) {
+	return r.basicAuth("Authorization")
+}
+
+// ProxyBasicAuth returns the username and password from a Basic
+// Proxy-Authorization header, sent by clients of a forward proxy.
+func (r *Request) ProxyBasicAuth() (username string, password string, ok bool) {
+	return r.basicAuth("Proxy-Authorization")
+}
+
+func (r *Request) basicAuth(name string) (username string, password string, ok bool) {
+	credentials, ok := r.authorization(name, "Basic")
+	if !ok {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return "", "", false
+	}
+
+	username, password, ok = strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", false
+	}
+	return username, password, true
+}
+
+// BearerToken returns the token from a Bearer Authorization header.
+func (r *Request) BearerToken() (string, bool) {
+	token, ok := r.authorization("Authorization", "Bearer")
+	if !ok || token == "" {
+		return "", false
+	}
+	return token, true
+}
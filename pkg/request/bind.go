@@ -0,0 +1,135 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultMaxJSONSize is the largest body BindJSON decodes.
+const DefaultMaxJSONSize = 1 << 20
+
+var (
+	ErrNotJSON      = fmt.Errorf("content-type is not json")
+	ErrBodyTooLarge = fmt.Errorf("request body too large")
+	ErrInvalidJSON  = fmt.Errorf("invalid json body")
+)
+
+// BindError is returned by BindJSON. Err is ErrNotJSON, ErrBodyTooLarge or
+// ErrInvalidJSON, which response.DefaultErrorHandler answers with 415, 413
+// and 400; Message is safe to show the client.
+type BindError struct {
+	Err     error
+	Message string
+	Field   string // JSON field at fault, when known
+	cause   error
+}
+
+func (e *BindError) Error() string {
+	return e.Message
+}
+
+func (e *BindError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.cause}
+}
+
+type BindOptions struct {
+	MaxBodySize int64 // defaults to DefaultMaxJSONSize
+	// DisallowUnknownFields rejects objects with fields v has no place for.
+	DisallowUnknownFields bool
+}
+
+// BindJSON decodes a JSON request body into v with the default options.
+func (r *Request) BindJSON(v any) error {
+	return r.BindJSONWith(v, BindOptions{})
+}
+
+// BindJSONWith decodes a JSON request body into v. The Content-Type must be
+// application/json or a +json type, and the body a single JSON value no
+// larger than MaxBodySize. Failures are returned as a *BindError.
+func (r *Request) BindJSONWith(v any, opts BindOptions) error {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxJSONSize
+	}
+
+	mediaType, _ := r.ContentType()
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return &BindError{Err: ErrNotJSON, Message: "content-type must be application/json"}
+	}
+	if int64(len(r.Body)) > opts.MaxBodySize {
+		return &BindError{
+			Err:     ErrBodyTooLarge,
+			Message: fmt.Sprintf("request body must not be larger than %d bytes", opts.MaxBodySize),
+		}
+	}
+	if len(bytes.TrimSpace(r.Body)) == 0 {
+		return &BindError{Err: ErrInvalidJSON, Message: "request body must not be empty"}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(r.Body))
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return jsonBindError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return &BindError{Err: ErrInvalidJSON, Message: "request body must contain a single JSON value"}
+	}
+	return nil
+}
+
+// jsonBindError turns a decoding error into a message that points at the
+// problem without leaking Go type names.
+func jsonBindError(err error) *BindError {
+	bindErr := &BindError{Err: ErrInvalidJSON, cause: err}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		bindErr.Message = fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		bindErr.Message = "malformed JSON: unexpected end of body"
+
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			bindErr.Field = typeErr.Field
+			bindErr.Message = fmt.Sprintf("field %q must be %s", typeErr.Field, jsonKind(typeErr.Type.Kind().String()))
+		} else {
+			bindErr.Message = fmt.Sprintf("body must be %s", jsonKind(typeErr.Type.Kind().String()))
+		}
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		bindErr.Field = strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		bindErr.Message = fmt.Sprintf("unknown field %q", bindErr.Field)
+
+	default:
+		bindErr.Message = "invalid JSON body"
+	}
+	return bindErr
+}
+
+// jsonKind names the JSON type a Go kind is decoded from.
+func jsonKind(kind string) string {
+	switch {
+	case kind == "string":
+		return "a string"
+	case kind == "bool":
+		return "a boolean"
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "slice" || kind == "array":
+		return "an array"
+	case kind == "map" || kind == "struct":
+		return "an object"
+	}
+	return "a valid value"
+}
@@ -0,0 +1,69 @@
+package request
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindTarget struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func jsonRequest(contentType string, body string) *Request {
+	r := newRequest()
+	if contentType != "" {
+		r.Headers.Set("Content-Type", contentType)
+	}
+	r.Body = []byte(body)
+	return r
+}
+
+func TestBindJSON(t *testing.T) {
+	var v bindTarget
+	r := jsonRequest("application/json; charset=utf-8", `{"name":"a","count":2,"extra":true}`)
+	require.NoError(t, r.BindJSON(&v))
+	assert.Equal(t, bindTarget{Name: "a", Count: 2}, v)
+
+	r = jsonRequest("application/problem+json", `{"name":"b"}`)
+	require.NoError(t, r.BindJSON(&v))
+	assert.Equal(t, "b", v.Name)
+}
+
+func TestBindJSON_Errors(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        BindOptions
+		err         error
+		message     string
+		field       string
+	}{
+		{"no content-type", "", `{}`, BindOptions{}, ErrNotJSON, "content-type must be application/json", ""},
+		{"wrong content-type", "text/plain", `{}`, BindOptions{}, ErrNotJSON, "content-type must be application/json", ""},
+		{"too large", "application/json", `{"name":"` + strings.Repeat("x", 20) + `"}`, BindOptions{MaxBodySize: 16}, ErrBodyTooLarge, "request body must not be larger than 16 bytes", ""},
+		{"empty", "application/json", "  ", BindOptions{}, ErrInvalidJSON, "request body must not be empty", ""},
+		{"syntax", "application/json", `{"name":}`, BindOptions{}, ErrInvalidJSON, "malformed JSON at offset 9", ""},
+		{"truncated", "application/json", `{"name":"a"`, BindOptions{}, ErrInvalidJSON, "malformed JSON: unexpected end of body", ""},
+		{"wrong type", "application/json", `{"count":"two"}`, BindOptions{}, ErrInvalidJSON, `field "count" must be a number`, "count"},
+		{"not an object", "application/json", `[1]`, BindOptions{}, ErrInvalidJSON, "body must be an object", ""},
+		{"unknown field", "application/json", `{"nmae":"a"}`, BindOptions{DisallowUnknownFields: true}, ErrInvalidJSON, `unknown field "nmae"`, "nmae"},
+		{"two values", "application/json", `{} {}`, BindOptions{}, ErrInvalidJSON, "request body must contain a single JSON value", ""},
+	}
+
+	for _, tc := range cases {
+		var v bindTarget
+		err := jsonRequest(tc.contentType, tc.body).BindJSONWith(&v, tc.opts)
+		require.Error(t, err, tc.name)
+		assert.ErrorIs(t, err, tc.err, tc.name)
+
+		var bindErr *BindError
+		require.ErrorAs(t, err, &bindErr, tc.name)
+		assert.Equal(t, tc.message, bindErr.Message, tc.name)
+		assert.Equal(t, tc.field, bindErr.Field, tc.name)
+	}
+}
@@ -0,0 +1,134 @@
+package request
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+var ErrInvalidProxy = fmt.Errorf("invalid trusted proxy")
+
+// TrustedProxies is the set of networks whose forwarding headers
+// (Forwarded, X-Forwarded-For, X-Real-IP) are believed by ClientIP.
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// ParseTrustedProxies builds a TrustedProxies from CIDRs ("10.0.0.0/8") or
+// single addresses ("192.168.1.10").
+func ParseTrustedProxies(entries ...string) (*TrustedProxies, error) {
+	tp := &TrustedProxies{}
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if strings.Contains(e, "/") {
+			p, err := netip.ParsePrefix(e)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidProxy, e)
+			}
+			tp.prefixes = append(tp.prefixes, p.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidProxy, e)
+		}
+		addr = addr.Unmap()
+		tp.prefixes = append(tp.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return tp, nil
+}
+
+// Contains reports whether ip belongs to a trusted network.
+func (tp *TrustedProxies) Contains(ip string) bool {
+	if tp == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, p := range tp.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOnly strips the port (and IPv6 brackets) from an address.
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.Trim(addr, "[]")
+}
+
+// forwardedFor returns the for= values of a Forwarded header (RFC 7239),
+// client first.
+func forwardedFor(value string) []string {
+	hops := []string{}
+	for _, elem := range strings.Split(value, ",") {
+		for _, pair := range strings.Split(elem, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || !strings.EqualFold(k, "for") {
+				continue
+			}
+			hops = append(hops, hostOnly(strings.Trim(v, `"`)))
+		}
+	}
+	return hops
+}
+
+// forwardingChain returns the client addresses reported by forwarding
+// headers, client first. Forwarded wins over X-Forwarded-For, which wins over
+// X-Real-IP.
+func (r *Request) forwardingChain() []string {
+	if values := r.Headers.Values("Forwarded"); len(values) > 0 {
+		return forwardedFor(strings.Join(values, ","))
+	}
+
+	if values := r.Headers.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := []string{}
+		for _, hop := range strings.Split(strings.Join(values, ","), ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hostOnly(hop))
+			}
+		}
+		return hops
+	}
+
+	if ip, ok := r.Headers.Get("X-Real-IP"); ok {
+		return []string{hostOnly(strings.TrimSpace(ip))}
+	}
+	return nil
+}
+
+// ClientIP returns the IP of the client that made the request. Forwarding
+// headers are only honored when the peer is in TrustedProxies, and then only
+// as far back as the hops are trusted: the chain is walked from the nearest
+// proxy and the first untrusted address is the client.
+func (r *Request) ClientIP() string {
+	ip := hostOnly(r.RemoteAddr)
+	if !r.TrustedProxies.Contains(ip) || r.Headers == nil {
+		return ip
+	}
+
+	chain := r.forwardingChain()
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop := chain[i]
+		if _, err := netip.ParseAddr(hop); err != nil {
+			// obfuscated or garbage hop, nothing before it can be trusted
+			return ip
+		}
+		ip = hop
+		if !r.TrustedProxies.Contains(hop) {
+			return ip
+		}
+	}
+	return ip
+}
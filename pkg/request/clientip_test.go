@@ -0,0 +1,60 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	tp, err := ParseTrustedProxies("10.0.0.0/8", "192.168.1.10", "fd00::/8")
+	require.NoError(t, err)
+	assert.True(t, tp.Contains("10.1.2.3"))
+	assert.True(t, tp.Contains("192.168.1.10"))
+	assert.True(t, tp.Contains("::ffff:10.0.0.1"))
+	assert.True(t, tp.Contains("fd00::1"))
+	assert.False(t, tp.Contains("192.168.1.11"))
+	assert.False(t, tp.Contains("not-an-ip"))
+
+	_, err = ParseTrustedProxies("10.0.0.0/33")
+	assert.ErrorIs(t, err, ErrInvalidProxy)
+	_, err = ParseTrustedProxies("proxy.local")
+	assert.ErrorIs(t, err, ErrInvalidProxy)
+}
+
+func TestClientIP(t *testing.T) {
+	tp, err := ParseTrustedProxies("10.0.0.0/8")
+	require.NoError(t, err)
+	mk := func(remote string, kv ...string) *Request {
+		r := &Request{Headers: headers.NewHeaders(), RemoteAddr: remote, TrustedProxies: tp}
+		for i := 0; i < len(kv); i += 2 {
+			r.Headers.Set(kv[i], kv[i+1])
+		}
+		return r
+	}
+
+	// Test: Untrusted peers are taken at their word, headers ignored
+	assert.Equal(t, "203.0.113.9", mk("203.0.113.9:4000", "X-Forwarded-For", "1.2.3.4").ClientIP())
+
+	// Test: Trusted peer, walk back over trusted hops
+	assert.Equal(t, "198.51.100.7", mk("10.0.0.1:4000", "X-Forwarded-For", "6.6.6.6, 198.51.100.7, 10.0.0.2").ClientIP())
+
+	// Test: Forwarded wins over X-Forwarded-For
+	r := mk("10.0.0.1:4000",
+		"Forwarded", `for="[2001:db8::1]:4711";proto=https, for=10.0.0.3`,
+		"X-Forwarded-For", "1.2.3.4")
+	assert.Equal(t, "2001:db8::1", r.ClientIP())
+
+	// Test: X-Real-IP as a last resort
+	assert.Equal(t, "198.51.100.8", mk("10.0.0.1:4000", "X-Real-IP", "198.51.100.8").ClientIP())
+
+	// Test: Garbage hops stop the walk at the last trusted address
+	assert.Equal(t, "10.0.0.2", mk("10.0.0.1:4000", "X-Forwarded-For", "unknown, 10.0.0.2").ClientIP())
+
+	// Test: No trusted proxies configured
+	r = mk("10.0.0.1:4000", "X-Forwarded-For", "1.2.3.4")
+	r.TrustedProxies = nil
+	assert.Equal(t, "10.0.0.1", r.ClientIP())
+}
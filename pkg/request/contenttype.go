@@ -0,0 +1,19 @@
+package request
+
+import "mime"
+
+// ContentType returns the lower-cased media type of the request body and
+// its parameters (charset, boundary, ...), keyed by lower-cased name. Both
+// are empty when the Content-Type header is missing or malformed.
+func (r *Request) ContentType() (string, map[string]string) {
+	ct, ok := r.Headers.Get("Content-Type")
+	if !ok {
+		return "", map[string]string{}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return "", map[string]string{}
+	}
+	return mediaType, params
+}
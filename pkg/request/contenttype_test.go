@@ -0,0 +1,24 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentType(t *testing.T) {
+	mediaType, params := withHeader("Content-Type", `Text/HTML; Charset="UTF-8"`).ContentType()
+	assert.Equal(t, "text/html", mediaType)
+	assert.Equal(t, map[string]string{"charset": "UTF-8"}, params)
+
+	mediaType, params = withHeader("Content-Type", "multipart/form-data; boundary=----abc").ContentType()
+	assert.Equal(t, "multipart/form-data", mediaType)
+	assert.Equal(t, "----abc", params["boundary"])
+
+	// Test: Missing or malformed headers
+	for _, r := range []*Request{newRequest(), withHeader("Content-Type", "text/html; charset")} {
+		mediaType, params = r.ContentType()
+		assert.Equal(t, "", mediaType)
+		assert.Empty(t, params)
+	}
+}
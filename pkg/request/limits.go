@@ -0,0 +1,72 @@
+package request
+
+import (
+	"fmt"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+)
+
+const (
+	DefaultMaxRequestLine  = 8 << 10
+	DefaultMaxHeaderBytes  = 64 << 10
+	DefaultMaxHeaderFields = 100
+	DefaultMaxFieldSize    = 8 << 10
+	DefaultMaxChunkSize    = 64 << 20
+)
+
+var ErrRequestLineTooLong = fmt.Errorf("request line too long")
+
+// Limits bounds what the parser accepts, so a client can't make it buffer
+// without end. Zero fields use the defaults above. Exceeding a header limit
+// fails with headers.ErrHeaderTooLarge (431) and an overlong request line
+// with ErrRequestLineTooLong (414). Trailers are held to the same header
+// limits.
+type Limits struct {
+	MaxRequestLine  int // bytes, without the CRLF
+	MaxHeaderBytes  int // bytes in all header field lines
+	MaxHeaderFields int
+	MaxFieldSize    int // bytes in one field line
+	// MaxChunkSize bounds the size one chunk of a chunked body may declare;
+	// larger chunks fail with ErrChunkTooLarge (413).
+	MaxChunkSize int64
+	// MaxBodySize bounds the body, checked against Content-Length as soon as
+	// the headers are in and against the bytes read for chunked bodies, so
+	// reading stops early with ErrBodyTooLarge (413). Zero is unlimited.
+	MaxBodySize int64
+	// StrictTrailers fails requests with trailer fields that weren't
+	// declared in the Trailer header or may not appear in a trailer with
+	// ErrInvalidTrailer (400). By default those fields are dropped.
+	StrictTrailers bool
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxRequestLine <= 0 {
+		l.MaxRequestLine = DefaultMaxRequestLine
+	}
+	if l.MaxHeaderBytes <= 0 {
+		l.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	if l.MaxHeaderFields <= 0 {
+		l.MaxHeaderFields = DefaultMaxHeaderFields
+	}
+	if l.MaxFieldSize <= 0 {
+		l.MaxFieldSize = DefaultMaxFieldSize
+	}
+	if l.MaxChunkSize <= 0 {
+		l.MaxChunkSize = DefaultMaxChunkSize
+	}
+	return l
+}
+
+func (l Limits) headerLimits() headers.Limits {
+	return headers.Limits{
+		MaxFields:    l.MaxHeaderFields,
+		MaxFieldSize: l.MaxFieldSize,
+		MaxBytes:     l.MaxHeaderBytes,
+	}
+}
+
+// maxLine is the longest line the parser may have to buffer whole.
+func (l Limits) maxLine() int {
+	return max(l.MaxRequestLine, l.MaxFieldSize) + len(sepCRLF)
+}
@@ -0,0 +1,58 @@
+package request
+
+import "sync"
+
+// Locals is a concurrency-safe per-request value store used by middleware to
+// hand data (e.g. the authenticated user) to downstream handlers.
+type Locals struct {
+	mu     sync.RWMutex
+	values map[string]any
+}
+
+func NewLocals() *Locals {
+	return &Locals{
+		values: map[string]any{},
+	}
+}
+
+func (l *Locals) Set(key string, value any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.values[key] = value
+}
+
+func (l *Locals) Get(key string) (any, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	v, ok := l.values[key]
+	return v, ok
+}
+
+func (l *Locals) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.values, key)
+}
+
+// Reset removes every value.
+func (l *Locals) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	clear(l.values)
+}
+
+// Local returns the value stored under key if it exists and has type T.
+func Local[T any](r *Request, key string) (T, bool) {
+	var zero T
+	if r.Locals == nil {
+		return zero, false
+	}
+
+	v, ok := r.Locals.Get(key)
+	if !ok {
+		return zero, false
+	}
+
+	t, ok := v.(T)
+	return t, ok
+}
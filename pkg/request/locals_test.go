@@ -0,0 +1,68 @@
+package request
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	Name string
+}
+
+func TestLocals(t *testing.T) {
+	reader := &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: localhost:8080\r\n\r\n",
+		numBytesPerRead: 8,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r.Locals)
+
+	// Test: Typed retrieval
+	r.Locals.Set("user", &testUser{Name: "shazim"})
+	u, ok := Local[*testUser](r, "user")
+	assert.True(t, ok)
+	assert.Equal(t, "shazim", u.Name)
+
+	// Test: Wrong type or missing key
+	_, ok = Local[string](r, "user")
+	assert.False(t, ok)
+	_, ok = Local[*testUser](r, "missing")
+	assert.False(t, ok)
+
+	// Test: Shared with context copies
+	r2 := r.WithContext(r.Context())
+	r2.Locals.Set("role", "admin")
+	role, ok := Local[string](r, "role")
+	assert.True(t, ok)
+	assert.Equal(t, "admin", role)
+
+	// Test: Delete
+	r.Locals.Delete("role")
+	_, ok = r.Locals.Get("role")
+	assert.False(t, ok)
+
+	// Test: Nil store on a hand-built request
+	_, ok = Local[string](&Request{}, "role")
+	assert.False(t, ok)
+}
+
+func TestLocalsConcurrent(t *testing.T) {
+	l := NewLocals()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l.Set("k", i)
+			_, _ = l.Get("k")
+		}(i)
+	}
+	wg.Wait()
+
+	_, ok := l.Get("k")
+	assert.True(t, ok)
+}
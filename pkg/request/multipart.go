@@ -0,0 +1,272 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+)
+
+// DefaultMaxMemory is how many bytes of file content FormFile keeps in memory
+// before spilling further files to temporary files.
+const DefaultMaxMemory = 32 << 20
+
+var (
+	ErrNotMultipart       = fmt.Errorf("request is not multipart/form-data")
+	ErrMalformedMultipart = fmt.Errorf("malformed multipart body")
+	ErrMissingFile        = fmt.Errorf("no such file in form")
+)
+
+// MultipartReader iterates over the parts of a multipart/form-data body
+// (RFC 7578) one at a time.
+type MultipartReader struct {
+	body  []byte
+	delim []byte // "\r\n--boundary"
+	pos   int
+	done  bool
+}
+
+// Part is one part of a multipart body. Its content is read through Read.
+type Part struct {
+	Header   *headers.Headers
+	FormName string // name parameter of Content-Disposition
+	FileName string // base name of the filename parameter, "" for fields
+	*bytes.Reader
+}
+
+// MultipartReader returns a reader over the parts of a multipart/form-data
+// request body.
+func (r *Request) MultipartReader() (*MultipartReader, error) {
+	mediaType, params := r.ContentType()
+	if mediaType != "multipart/form-data" {
+		return nil, ErrNotMultipart
+	}
+	boundary := params["boundary"]
+	if boundary == "" || len(boundary) > 70 {
+		return nil, fmt.Errorf("%w: bad boundary %q", ErrMalformedMultipart, boundary)
+	}
+
+	return newMultipartReader(r.Body, boundary)
+}
+
+func newMultipartReader(body []byte, boundary string) (*MultipartReader, error) {
+	mr := &MultipartReader{body: body, delim: []byte("\r\n--" + boundary)}
+
+	// the first delimiter may come without its CRLF, right at the start
+	dashBoundary := mr.delim[len(sepCRLF):]
+	if bytes.HasPrefix(body, dashBoundary) {
+		mr.pos = len(dashBoundary)
+	} else if i := bytes.Index(body, mr.delim); i != -1 {
+		mr.pos = i + len(mr.delim) // skip the preamble
+	} else {
+		return nil, fmt.Errorf("%w: no opening boundary", ErrMalformedMultipart)
+	}
+	return mr, nil
+}
+
+// NextPart returns the next part, or io.EOF after the last one.
+func (mr *MultipartReader) NextPart() (*Part, error) {
+	if mr.done {
+		return nil, io.EOF
+	}
+
+	rest := mr.body[mr.pos:]
+	if bytes.HasPrefix(rest, []byte("--")) {
+		mr.done = true // close delimiter; the epilogue is ignored
+		return nil, io.EOF
+	}
+	rest = bytes.TrimLeft(rest, " \t") // transport padding
+	if !bytes.HasPrefix(rest, sepCRLF) {
+		return nil, fmt.Errorf("%w: boundary not followed by CRLF", ErrMalformedMultipart)
+	}
+	rest = rest[len(sepCRLF):]
+
+	h := headers.NewHeaders()
+	n, done, err := h.Parse(rest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMultipart, err)
+	}
+	if !done {
+		return nil, fmt.Errorf("%w: unterminated part headers", ErrMalformedMultipart)
+	}
+	rest = rest[n:]
+
+	end := mr.indexDelimiter(rest)
+	if end == -1 {
+		return nil, fmt.Errorf("%w: missing closing boundary", ErrMalformedMultipart)
+	}
+	mr.pos = len(mr.body) - len(rest) + end + len(mr.delim)
+
+	part := &Part{Header: h, Reader: bytes.NewReader(rest[:end])}
+	if cd, ok := h.Get("Content-Disposition"); ok {
+		if _, params, err := mime.ParseMediaType(cd); err == nil {
+			part.FormName = params["name"]
+			if name := params["filename"]; name != "" {
+				part.FileName = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+			}
+		}
+	}
+	return part, nil
+}
+
+// indexDelimiter finds the next delimiter in b, skipping lookalikes in the
+// content where the boundary is followed by anything but "--" or (padded)
+// CRLF.
+func (mr *MultipartReader) indexDelimiter(b []byte) int {
+	offset := 0
+	for {
+		i := bytes.Index(b[offset:], mr.delim)
+		if i == -1 {
+			return -1
+		}
+		i += offset
+
+		after := b[i+len(mr.delim):]
+		if bytes.HasPrefix(after, []byte("--")) || bytes.HasPrefix(bytes.TrimLeft(after, " \t"), sepCRLF) {
+			return i
+		}
+		offset = i + 1
+	}
+}
+
+// MultipartForm is a parsed multipart/form-data body.
+type MultipartForm struct {
+	Value map[string][]string
+	File  map[string][]*FileHeader
+}
+
+// FileHeader describes a file part of a multipart form. Its content is held
+// in memory or, past the memory limit, in a temporary file.
+type FileHeader struct {
+	Filename string
+	Header   *headers.Headers
+	Size     int64
+	content  []byte
+	tmpfile  string
+}
+
+// Open returns the file's content.
+func (fh *FileHeader) Open() (io.ReadCloser, error) {
+	if fh.tmpfile != "" {
+		return os.Open(fh.tmpfile)
+	}
+	return io.NopCloser(bytes.NewReader(fh.content)), nil
+}
+
+// RemoveAll deletes the form's temporary files.
+func (f *MultipartForm) RemoveAll() error {
+	var errs []error
+	for _, fhs := range f.File {
+		for _, fh := range fhs {
+			if fh.tmpfile == "" {
+				continue
+			}
+			if err := os.Remove(fh.tmpfile); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseMultipartForm parses a multipart/form-data body into fields and
+// files, keeping up to maxMemory bytes of file content in memory and writing
+// files past that to temporary files. The form is parsed once; later calls
+// return it again. Temporary files are removed by RemoveTempFiles, which the
+// server calls after the handler returns.
+func (r *Request) ParseMultipartForm(maxMemory int64) (*MultipartForm, error) {
+	if r.multipartForm != nil {
+		return r.multipartForm, nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	form := &MultipartForm{Value: map[string][]string{}, File: map[string][]*FileHeader{}}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			form.RemoveAll()
+			return nil, err
+		}
+		if part.FormName == "" {
+			continue
+		}
+
+		if part.FileName == "" {
+			data, _ := io.ReadAll(part)
+			form.Value[part.FormName] = append(form.Value[part.FormName], string(data))
+			continue
+		}
+
+		fh := &FileHeader{Filename: part.FileName, Header: part.Header, Size: part.Size()}
+		if fh.Size <= maxMemory {
+			fh.content, _ = io.ReadAll(part)
+			maxMemory -= fh.Size
+		} else if err := spill(fh, part); err != nil {
+			form.RemoveAll()
+			return nil, err
+		}
+		form.File[part.FormName] = append(form.File[part.FormName], fh)
+	}
+
+	r.multipartForm = form
+	return form, nil
+}
+
+func spill(fh *FileHeader, src io.Reader) error {
+	f, err := os.CreateTemp("", "multipart-")
+	if err != nil {
+		return err
+	}
+	fh.tmpfile = f.Name()
+
+	_, err = io.Copy(f, src)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		os.Remove(fh.tmpfile)
+		fh.tmpfile = ""
+	}
+	return err
+}
+
+// FormFile returns the first file uploaded as name in a multipart/form-data
+// body, parsing the form with DefaultMaxMemory if needed. The caller closes
+// the returned reader.
+func (r *Request) FormFile(name string) (io.ReadCloser, *FileHeader, error) {
+	form, err := r.ParseMultipartForm(DefaultMaxMemory)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fhs := form.File[name]
+	if len(fhs) == 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrMissingFile, name)
+	}
+	f, err := fhs[0].Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, fhs[0], nil
+}
+
+// RemoveTempFiles deletes any temporary files created by ParseMultipartForm.
+func (r *Request) RemoveTempFiles() error {
+	if r.multipartForm == nil {
+		return nil
+	}
+	return r.multipartForm.RemoveAll()
+}
@@ -0,0 +1,127 @@
+package request
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const multipartBody = "preamble\r\n" +
+	"--XyZ\r\n" +
+	"Content-Disposition: form-data; name=\"title\"\r\n" +
+	"\r\n" +
+	"hello\r\nworld\r\n" +
+	"--XyZ \r\n" +
+	"Content-Disposition: form-data; name=\"upload\"; filename=\"C:\\\\docs\\\\notes.txt\"\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"line one\r\n--XyZnot a boundary\r\n" +
+	"--XyZ\r\n" +
+	"Content-Disposition: form-data; name=\"upload\"; filename=\"big.bin\"\r\n" +
+	"\r\n" +
+	"0123456789\r\n" +
+	"--XyZ--\r\n" +
+	"epilogue"
+
+func multipartRequest(contentType string, body string) *Request {
+	r := newRequest()
+	r.Headers.Set("Content-Type", contentType)
+	r.Body = []byte(body)
+	return r
+}
+
+func TestMultipartReader(t *testing.T) {
+	r := multipartRequest("multipart/form-data; boundary=XyZ", multipartBody)
+	mr, err := r.MultipartReader()
+	require.NoError(t, err)
+
+	part, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "title", part.FormName)
+	assert.Equal(t, "", part.FileName)
+	data, _ := io.ReadAll(part)
+	assert.Equal(t, "hello\r\nworld", string(data))
+
+	// Test: Bytes that only look like a boundary are content
+	part, err = mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "notes.txt", part.FileName)
+	ct, _ := part.Header.Get("Content-Type")
+	assert.Equal(t, "text/plain", ct)
+	data, _ = io.ReadAll(part)
+	assert.Equal(t, "line one\r\n--XyZnot a boundary", string(data))
+
+	_, err = mr.NextPart()
+	require.NoError(t, err)
+	_, err = mr.NextPart()
+	assert.Equal(t, io.EOF, err)
+	_, err = mr.NextPart()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestMultipartReader_Errors(t *testing.T) {
+	for _, ct := range []string{"", "application/json", "multipart/mixed; boundary=a"} {
+		r := multipartRequest(ct, "")
+		_, err := r.MultipartReader()
+		assert.ErrorIs(t, err, ErrNotMultipart, ct)
+	}
+
+	r := multipartRequest("multipart/form-data", "")
+	_, err := r.MultipartReader()
+	assert.ErrorIs(t, err, ErrMalformedMultipart)
+
+	for _, body := range []string{
+		"no boundary here",
+		"--b\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\nunterminated",
+		"--b\r\nContent-Disposition: form-data; name=\"a\"\r\nunterminated headers",
+		"--bjunk\r\n\r\nx\r\n--b--",
+	} {
+		r := multipartRequest("multipart/form-data; boundary=b", body)
+		mr, err := r.MultipartReader()
+		if err == nil {
+			_, err = mr.NextPart()
+		}
+		assert.ErrorIs(t, err, ErrMalformedMultipart, body)
+	}
+}
+
+func TestParseMultipartForm(t *testing.T) {
+	r := multipartRequest("multipart/form-data; boundary=XyZ", multipartBody)
+
+	// Test: The second file exceeds the memory budget and spills to disk
+	form, err := r.ParseMultipartForm(30)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello\r\nworld"}, form.Value["title"])
+	require.Len(t, form.File["upload"], 2)
+	inMemory, spilled := form.File["upload"][0], form.File["upload"][1]
+	assert.Empty(t, inMemory.tmpfile)
+	require.NotEmpty(t, spilled.tmpfile)
+	assert.Equal(t, int64(10), spilled.Size)
+
+	f, err := spilled.Open()
+	require.NoError(t, err)
+	data, _ := io.ReadAll(f)
+	f.Close()
+	assert.Equal(t, "0123456789", string(data))
+
+	// Test: FormFile reuses the parsed form
+	f, fh, err := r.FormFile("upload")
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, "notes.txt", fh.Filename)
+	assert.Equal(t, int64(len("line one\r\n--XyZnot a boundary")), fh.Size)
+	data, _ = io.ReadAll(f)
+	assert.True(t, strings.HasPrefix(string(data), "line one"))
+
+	_, _, err = r.FormFile("missing")
+	assert.ErrorIs(t, err, ErrMissingFile)
+
+	// Test: Temporary files are removed
+	require.NoError(t, r.RemoveTempFiles())
+	_, err = os.Stat(spilled.tmpfile)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,164 @@
+package request
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Preference is one entry of a q-valued header such as Accept or
+// Accept-Encoding, e.g. "text/html;q=0.8".
+type Preference struct {
+	Value  string
+	Q      float64
+	Params map[string]string // parameters other than q
+}
+
+// ParsePreferences parses a comma-separated, q-valued list into its entries
+// sorted by descending q, keeping the listed order among equals. Entries with
+// an unparsable q are dropped.
+func ParsePreferences(value string) []Preference {
+	prefs := []Preference{}
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Split(part, ";")
+		v := strings.TrimSpace(fields[0])
+		if v == "" {
+			continue
+		}
+
+		pref := Preference{Value: v, Q: 1, Params: map[string]string{}}
+		valid := true
+		for _, param := range fields[1:] {
+			k, pv, _ := strings.Cut(param, "=")
+			k = strings.ToLower(strings.TrimSpace(k))
+			pv = strings.Trim(strings.TrimSpace(pv), `"`)
+			if k != "q" {
+				pref.Params[k] = pv
+				continue
+			}
+
+			q, err := strconv.ParseFloat(pv, 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+				break
+			}
+			pref.Q = q
+		}
+		if valid {
+			prefs = append(prefs, pref)
+		}
+	}
+
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].Q > prefs[j].Q })
+	return prefs
+}
+
+// matchFunc reports whether a preference covers an offer and how specifically,
+// so that e.g. text/html beats text/* beats */*.
+type matchFunc func(pref string, offer string) (specificity int, ok bool)
+
+// quality returns the q of the most specific preference covering offer, or
+// -1 when none does.
+func quality(prefs []Preference, offer string, match matchFunc) float64 {
+	q, best := -1.0, -1
+	for _, pref := range prefs {
+		if spec, ok := match(pref.Value, offer); ok && spec > best {
+			q, best = pref.Q, spec
+		}
+	}
+	return q
+}
+
+// negotiate returns the offer the client prefers most, favoring earlier
+// offers on ties, or "" when none is acceptable. Without the header every
+// offer is acceptable and the first is returned.
+func (r *Request) negotiate(header string, offers []string, match matchFunc, fallback float64) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	value, ok := r.Headers.Get(header)
+	if !ok {
+		return offers[0]
+	}
+
+	prefs := ParsePreferences(value)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q := quality(prefs, offer, match)
+		if q < 0 {
+			q = fallback
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+func matchMediaType(pref string, offer string) (int, bool) {
+	prefType, prefSub, _ := strings.Cut(strings.ToLower(pref), "/")
+	offerType, offerSub, _ := strings.Cut(strings.ToLower(offer), "/")
+	switch {
+	case prefType == "*" && prefSub == "*":
+		return 0, true
+	case prefType == offerType && prefSub == "*":
+		return 1, true
+	case prefType == offerType && prefSub == offerSub:
+		return 2, true
+	}
+	return 0, false
+}
+
+func matchCoding(pref string, offer string) (int, bool) {
+	switch {
+	case pref == "*":
+		return 0, true
+	case strings.EqualFold(pref, offer):
+		return 1, true
+	}
+	return 0, false
+}
+
+// matchLanguage applies RFC 4647 basic filtering: "en" covers "en" and
+// "en-US", with longer ranges being more specific.
+func matchLanguage(pref string, offer string) (int, bool) {
+	pref, offer = strings.ToLower(pref), strings.ToLower(offer)
+	switch {
+	case pref == "*":
+		return 0, true
+	case pref == offer || strings.HasPrefix(offer, pref+"-"):
+		return len(pref), true
+	}
+	return 0, false
+}
+
+// Negotiate returns the media type among offers (e.g. "application/json",
+// "text/html") that best matches the Accept header, or "" when the client
+// accepts none of them.
+func (r *Request) Negotiate(offers ...string) string {
+	return r.negotiate("Accept", offers, matchMediaType, 0)
+}
+
+// NegotiateEncoding returns the content-coding among offers (e.g. "gzip",
+// "deflate") that best matches Accept-Encoding, or "" when none is
+// acceptable.
+func (r *Request) NegotiateEncoding(offers ...string) string {
+	return r.negotiate("Accept-Encoding", offers, matchCoding, 0)
+}
+
+// NegotiateLanguage returns the language tag among offers (e.g. "en-US",
+// "fr") that best matches Accept-Language, or "" when none is acceptable.
+func (r *Request) NegotiateLanguage(offers ...string) string {
+	return r.negotiate("Accept-Language", offers, matchLanguage, 0)
+}
+
+// AcceptsEncoding reports whether the client accepts responses in coding.
+// "identity" is acceptable unless explicitly refused, and a request without
+// Accept-Encoding accepts any coding.
+func (r *Request) AcceptsEncoding(coding string) bool {
+	fallback := 0.0
+	if strings.EqualFold(coding, "identity") {
+		fallback = 1
+	}
+	return r.negotiate("Accept-Encoding", []string{coding}, matchCoding, fallback) != ""
+}
@@ -0,0 +1,70 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withHeader(name string, value string) *Request {
+	r := newRequest()
+	r.Headers.Set(name, value)
+	return r
+}
+
+func TestParsePreferences(t *testing.T) {
+	prefs := ParsePreferences(`text/html;level=1, application/json;q=0.9, text/*;q=0.9, */*;q=0.1, bad;q=x, ,`)
+	values := []string{}
+	for _, p := range prefs {
+		values = append(values, p.Value)
+	}
+	assert.Equal(t, []string{"text/html", "application/json", "text/*", "*/*"}, values)
+	assert.Equal(t, "1", prefs[0].Params["level"])
+	assert.Equal(t, 0.9, prefs[1].Q)
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		accept   string
+		offers   []string
+		expected string
+	}{
+		{"application/json", []string{"text/html", "application/json"}, "application/json"},
+		{"text/html;q=0.5, application/json;q=0.8", []string{"text/html", "application/json"}, "application/json"},
+		{"text/*, application/json;q=0.5", []string{"application/json", "text/plain"}, "text/plain"},
+		{"*/*", []string{"text/html", "application/json"}, "text/html"},
+		// the more specific range wins over the wildcard
+		{"*/*, text/html;q=0", []string{"text/html", "application/json"}, "application/json"},
+		{"image/png", []string{"text/html"}, ""},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, withHeader("Accept", tc.accept).Negotiate(tc.offers...), tc.accept)
+	}
+
+	// Test: Without Accept the server's first choice wins
+	assert.Equal(t, "text/html", newRequest().Negotiate("text/html", "application/json"))
+	assert.Equal(t, "", newRequest().Negotiate())
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	r := withHeader("Accept-Language", "fr-CH, fr;q=0.9, en;q=0.8, *;q=0.1")
+	assert.Equal(t, "fr", r.NegotiateLanguage("en-US", "fr"))
+	assert.Equal(t, "en-US", r.NegotiateLanguage("en-US", "de"))
+	assert.Equal(t, "de", r.NegotiateLanguage("de"))
+	assert.Equal(t, "", withHeader("Accept-Language", "en").NegotiateLanguage("de", "english"))
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	r := withHeader("Accept-Encoding", "gzip;q=0.5, br")
+	assert.True(t, r.AcceptsEncoding("gzip"))
+	assert.True(t, r.AcceptsEncoding("BR"))
+	assert.False(t, r.AcceptsEncoding("deflate"))
+	assert.True(t, r.AcceptsEncoding("identity"))
+	assert.Equal(t, "br", r.NegotiateEncoding("gzip", "br"))
+
+	r = withHeader("Accept-Encoding", "*;q=0")
+	assert.False(t, r.AcceptsEncoding("identity"))
+	assert.False(t, r.AcceptsEncoding("gzip"))
+
+	assert.True(t, newRequest().AcceptsEncoding("gzip"))
+}
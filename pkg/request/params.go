@@ -0,0 +1,378 @@
+package request
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingParam = fmt.Errorf("missing parameter")
+	ErrInvalidParam = fmt.Errorf("invalid parameter")
+	ErrBindTarget   = fmt.Errorf("invalid bind target")
+)
+
+// ParamError is returned by the typed param accessors and Bind. Err is
+// ErrMissingParam or ErrInvalidParam, both of which
+// response.DefaultErrorHandler answers with 400; Message is safe to show the
+// client.
+type ParamError struct {
+	Err     error
+	Message string
+	Source  string // "path", "query" or "header"
+	Name    string
+	Value   string
+	cause   error
+}
+
+func (e *ParamError) Error() string {
+	return e.Message
+}
+
+func (e *ParamError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.cause}
+}
+
+func missingParam(source string, name string) *ParamError {
+	return &ParamError{
+		Err:     ErrMissingParam,
+		Message: fmt.Sprintf("%s parameter %q is required", source, name),
+		Source:  source,
+		Name:    name,
+	}
+}
+
+func invalidParam(source string, name string, value string, want string, cause error) *ParamError {
+	return &ParamError{
+		Err:     ErrInvalidParam,
+		Message: fmt.Sprintf("%s parameter %q must be %s", source, name, want),
+		Source:  source,
+		Name:    name,
+		Value:   value,
+		cause:   cause,
+	}
+}
+
+// pathParam returns the path param name, which must be present.
+func (r *Request) pathParam(name string) (string, error) {
+	value, ok := r.PathParams[name]
+	if !ok {
+		return "", missingParam("path", name)
+	}
+	return value, nil
+}
+
+// queryParam returns the decoded query parameter name and whether it is
+// present with a value.
+func (r *Request) queryParam(name string) (string, bool) {
+	value, ok := r.RequestParams[name]
+	if !ok || value == "" {
+		return "", false
+	}
+	if decoded, err := url.QueryUnescape(value); err == nil {
+		value = decoded
+	}
+	return value, true
+}
+
+func parseInt(source string, name string, value string, bitSize int) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, bitSize)
+	if err != nil {
+		return 0, invalidParam(source, name, value, "an integer", err)
+	}
+	return n, nil
+}
+
+// ParamInt returns the path param name as an int.
+func (r *Request) ParamInt(name string) (int, error) {
+	value, err := r.pathParam(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := parseInt("path", name, value, strconv.IntSize)
+	return int(n), err
+}
+
+// ParamInt64 returns the path param name as an int64.
+func (r *Request) ParamInt64(name string) (int64, error) {
+	value, err := r.pathParam(name)
+	if err != nil {
+		return 0, err
+	}
+	return parseInt("path", name, value, 64)
+}
+
+// ParamUUID returns the path param name, which must be a UUID in its
+// hyphenated 36-character form, in lowercase.
+func (r *Request) ParamUUID(name string) (string, error) {
+	value, err := r.pathParam(name)
+	if err != nil {
+		return "", err
+	}
+	if !isUUID(value) {
+		return "", invalidParam("path", name, value, "a UUID", nil)
+	}
+	return strings.ToLower(value), nil
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if ch != '-' {
+				return false
+			}
+		case ch >= '0' && ch <= '9', ch >= 'a' && ch <= 'f', ch >= 'A' && ch <= 'F':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// QueryInt returns the query parameter name as an int, or def if it is
+// missing or empty.
+func (r *Request) QueryInt(name string, def int) (int, error) {
+	value, ok := r.queryParam(name)
+	if !ok {
+		return def, nil
+	}
+	n, err := parseInt("query", name, value, strconv.IntSize)
+	if err != nil {
+		return def, err
+	}
+	return int(n), nil
+}
+
+// QueryBool returns the query parameter name as a bool, or def if it is
+// missing. Besides what strconv.ParseBool accepts, "yes", "no", "on" and
+// "off" are understood, and a parameter without a value ("?verbose") is
+// true.
+func (r *Request) QueryBool(name string, def bool) (bool, error) {
+	if value, ok := r.RequestParams[name]; ok && value == "" {
+		return true, nil
+	}
+	value, ok := r.queryParam(name)
+	if !ok {
+		return def, nil
+	}
+
+	b, err := parseBool(value)
+	if err != nil {
+		return def, invalidParam("query", name, value, "a boolean", err)
+	}
+	return b, nil
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "on":
+		return true, nil
+	case "no", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// Bind fills the fields of the struct v points to from path params, query
+// parameters and request headers, as named by their path, query and header
+// tags:
+//
+//	type listParams struct {
+//		UserID int64    `path:"id"`
+//		Page   int      `query:"page"`
+//		Tags   []string `query:"tags"`
+//		Token  string   `header:"X-Token,required"`
+//	}
+//
+// Values are converted to the field's type: strings, bools, integers,
+// floats, time.Duration, encoding.TextUnmarshaler implementations, pointers
+// to these (left nil when the value is absent) and slices of them, filled
+// from comma-separated values. An absent value, which includes an empty
+// query parameter, leaves the field as it was unless the tag says required.
+// Fields of embedded structs are bound too. Values that don't convert are
+// reported as a *ParamError.
+func (r *Request) Bind(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T is not a pointer to a struct", ErrBindTarget, v)
+	}
+	return r.bindStruct(rv.Elem())
+}
+
+var bindSources = []string{"path", "query", "header"}
+
+func (r *Request) bindStruct(sv reflect.Value) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		field, fv := st.Field(i), sv.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := r.bindStruct(fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		for _, source := range bindSources {
+			tag, ok := field.Tag.Lookup(source)
+			if !ok {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			value, ok := r.bindValue(source, name)
+			if !ok {
+				if opts == "required" {
+					return missingParam(source, name)
+				}
+				continue
+			}
+
+			err := setField(fv, value)
+			if errors.Is(err, ErrBindTarget) {
+				return fmt.Errorf("%w: field %s: %w", ErrBindTarget, field.Name, err)
+			}
+			if err != nil {
+				return invalidParam(source, name, value, describeType(fv.Type()), err)
+			}
+		}
+	}
+	return nil
+}
+
+// bindValue returns the value named name in source and whether it is there.
+func (r *Request) bindValue(source string, name string) (string, bool) {
+	switch source {
+	case "path":
+		value, ok := r.PathParams[name]
+		return value, ok
+	case "query":
+		return r.queryParam(name)
+	case "header":
+		if r.Headers == nil {
+			return "", false
+		}
+		return r.Headers.Get(name)
+	}
+	return "", false
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setField converts value to fv's type and stores it.
+func setField(fv reflect.Value, value string) error {
+	if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch kind := fv.Kind(); {
+	case kind == reflect.Pointer:
+		elem := reflect.New(fv.Type().Elem())
+		if err := setField(elem.Elem(), value); err != nil {
+			return err
+		}
+		fv.Set(elem)
+
+	case kind == reflect.Slice:
+		parts := strings.Split(value, ",")
+		s := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setField(s.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+
+	case kind == reflect.String:
+		fv.SetString(value)
+
+	case kind == reflect.Bool:
+		b, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+
+	case fv.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+
+	case fv.CanInt():
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+
+	case fv.CanUint():
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+
+	case fv.CanFloat():
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+
+	default:
+		return fmt.Errorf("%w: unsupported type %s", ErrBindTarget, fv.Type())
+	}
+	return nil
+}
+
+// describeType names what a value for a field of type t has to look like,
+// for ParamError messages.
+func describeType(t reflect.Type) string {
+	if t.Kind() == reflect.Slice {
+		return "a comma-separated list of " + typeNoun(t.Elem()) + "s"
+	}
+	noun := typeNoun(t)
+	if noun == "integer" {
+		return "an " + noun
+	}
+	return "a " + noun
+}
+
+func typeNoun(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		return "duration"
+	case reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()):
+		return "valid value"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "valid value"
+}
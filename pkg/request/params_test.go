@@ -0,0 +1,191 @@
+package request
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathParams(t *testing.T) {
+	r := newRequest()
+	r.PathParams = map[string]string{
+		"id":   "42",
+		"big":  "9007199254740993",
+		"bad":  "4x",
+		"uuid": "123E4567-E89B-12D3-A456-426614174000",
+	}
+
+	id, err := r.ParamInt("id")
+	require.NoError(t, err)
+	assert.Equal(t, 42, id)
+
+	big, err := r.ParamInt64("big")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9007199254740993), big)
+
+	_, err = r.ParamInt("bad")
+	assert.ErrorIs(t, err, ErrInvalidParam)
+	var paramErr *ParamError
+	require.ErrorAs(t, err, &paramErr)
+	assert.Equal(t, "path", paramErr.Source)
+	assert.Equal(t, "bad", paramErr.Name)
+	assert.Equal(t, "4x", paramErr.Value)
+	assert.Equal(t, `path parameter "bad" must be an integer`, err.Error())
+
+	_, err = r.ParamInt("missing")
+	assert.ErrorIs(t, err, ErrMissingParam)
+	assert.Equal(t, `path parameter "missing" is required`, err.Error())
+
+	u, err := r.ParamUUID("uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", u)
+	_, err = r.ParamUUID("id")
+	assert.ErrorIs(t, err, ErrInvalidParam)
+	_, err = r.ParamUUID("missing")
+	assert.ErrorIs(t, err, ErrMissingParam)
+}
+
+func TestQueryParams(t *testing.T) {
+	r := newRequest()
+	r.RequestLine.RequestTarget = "/items?page=3&limit=&neg=%2D5&bad=x&verbose&debug=off&flag=maybe"
+	require.NoError(t, parseRequestParameters(r))
+
+	// Test: Present values are parsed, missing or empty ones give the default
+	page, err := r.QueryInt("page", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, page)
+	limit, err := r.QueryInt("limit", 20)
+	require.NoError(t, err)
+	assert.Equal(t, 20, limit)
+	offset, err := r.QueryInt("offset", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, offset)
+	neg, err := r.QueryInt("neg", 0)
+	require.NoError(t, err)
+	assert.Equal(t, -5, neg)
+
+	n, err := r.QueryInt("bad", 7)
+	assert.ErrorIs(t, err, ErrInvalidParam)
+	assert.Equal(t, 7, n)
+	assert.Equal(t, `query parameter "bad" must be an integer`, err.Error())
+
+	// Test: Booleans, including bare flags
+	for name, want := range map[string]bool{"verbose": true, "debug": false, "absent": true} {
+		b, err := r.QueryBool(name, true)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, b, name)
+	}
+	_, err = r.QueryBool("flag", false)
+	assert.ErrorIs(t, err, ErrInvalidParam)
+
+	// Test: Requests without a query read as empty
+	empty := newRequest()
+	n, err = empty.QueryInt("page", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+type paging struct {
+	Page  int  `query:"page"`
+	Limit *int `query:"limit"`
+}
+
+type level int
+
+func (l *level) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return ErrInvalidParam
+	}
+	return nil
+}
+
+type listParams struct {
+	paging
+	UserID  int64         `path:"id"`
+	Tags    []string      `query:"tags"`
+	IDs     []uint        `query:"ids"`
+	Ratio   float64       `query:"ratio"`
+	Verbose bool          `query:"verbose"`
+	Wait    time.Duration `query:"wait"`
+	Level   level         `query:"level"`
+	Token   string        `header:"X-Token,required"`
+	Agent   *string       `header:"User-Agent"`
+	ignored string
+}
+
+func TestBind(t *testing.T) {
+	newParamsRequest := func(query string) *Request {
+		r := newRequest()
+		r.RequestLine.RequestTarget = "/users/7/items?" + query
+		require.NoError(t, parseRequestParameters(r))
+		r.PathParams = map[string]string{"id": "7"}
+		r.Headers.Set("X-Token", "secret")
+		return r
+	}
+
+	// Test: Values from every source are converted to the field types
+	r := newParamsRequest("page=2&tags=a,%20b&ids=1,2,3&ratio=0.5&verbose=yes&wait=1m30s&level=high")
+	p := listParams{paging: paging{Page: 1}}
+	require.NoError(t, r.Bind(&p))
+	assert.Equal(t, int64(7), p.UserID)
+	assert.Equal(t, 2, p.Page)
+	assert.Nil(t, p.Limit)
+	assert.Equal(t, []string{"a", "b"}, p.Tags)
+	assert.Equal(t, []uint{1, 2, 3}, p.IDs)
+	assert.Equal(t, 0.5, p.Ratio)
+	assert.True(t, p.Verbose)
+	assert.Equal(t, 90*time.Second, p.Wait)
+	assert.Equal(t, level(2), p.Level)
+	assert.Equal(t, "secret", p.Token)
+	assert.Nil(t, p.Agent)
+
+	// Test: Absent and empty values keep what was there
+	r = newParamsRequest("limit=5&page=")
+	p = listParams{paging: paging{Page: 1}}
+	require.NoError(t, r.Bind(&p))
+	assert.Equal(t, 1, p.Page)
+	require.NotNil(t, p.Limit)
+	assert.Equal(t, 5, *p.Limit)
+
+	// Test: Conversion failures and missing required values are ParamErrors
+	cases := []struct {
+		query   string
+		message string
+	}{
+		{"page=two", `query parameter "page" must be an integer`},
+		{"ids=1,-2", `query parameter "ids" must be a comma-separated list of non-negative integers`},
+		{"wait=soon", `query parameter "wait" must be a duration`},
+		{"level=max", `query parameter "level" must be a valid value`},
+		{"verbose=perhaps", `query parameter "verbose" must be a boolean`},
+	}
+	for _, tc := range cases {
+		err := newParamsRequest(tc.query).Bind(&listParams{})
+		assert.ErrorIs(t, err, ErrInvalidParam, tc.query)
+		assert.EqualError(t, err, tc.message)
+	}
+
+	r = newParamsRequest("")
+	r.Headers.Del("X-Token")
+	err := r.Bind(&listParams{})
+	assert.ErrorIs(t, err, ErrMissingParam)
+	var paramErr *ParamError
+	require.ErrorAs(t, err, &paramErr)
+	assert.Equal(t, "header", paramErr.Source)
+
+	// Test: Bad targets are programming errors, not ParamErrors
+	assert.ErrorIs(t, r.Bind(listParams{}), ErrBindTarget)
+	assert.ErrorIs(t, r.Bind((*listParams)(nil)), ErrBindTarget)
+	var unsupported struct {
+		M map[string]string `path:"id"`
+	}
+	err = newParamsRequest("").Bind(&unsupported)
+	assert.ErrorIs(t, err, ErrBindTarget)
+	assert.NotErrorAs(t, err, &paramErr)
+}
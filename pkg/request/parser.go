@@ -0,0 +1,100 @@
+package request
+
+// Parser parses one request incrementally from bytes the caller reads
+// itself, for servers that manage their own buffers and for tests and fuzzers
+// that want to drive the state machine directly.
+//
+//	p := request.NewParser()
+//	for {
+//		n, done, err := p.Feed(buf[:bufLen])
+//		// drop buf[:n], read more unless done or p.WaitingForContinue()
+//	}
+type Parser struct {
+	req *Request
+}
+
+func NewParser() *Parser {
+	return NewParserLimits(Limits{})
+}
+
+// NewParserLimits returns a Parser that holds the request head to limits.
+func NewParserLimits(limits Limits) *Parser {
+	req := newRequest()
+	req.limits = limits.withDefaults()
+	return &Parser{req: req}
+}
+
+// Feed parses as much of data as it can and reports how many bytes were
+// consumed; the rest must be fed again together with more data. done is true
+// once the whole request, including its body, has been parsed, after which
+// Feed consumes nothing. Any error is final.
+//
+// A request expecting 100 Continue stops before its body with
+// WaitingForContinue reporting true until Continue is called.
+func (p *Parser) Feed(data []byte) (consumed int, done bool, err error) {
+	r := p.req
+	switch r.state {
+	case stateDone:
+		return 0, true, nil
+	case stateError:
+		return 0, false, ErrReqInErrState
+	}
+
+	n, err := r.parse(data)
+	if err != nil {
+		return 0, false, err
+	}
+	if r.state != stateDone {
+		return n, false, nil
+	}
+
+	if err := r.finish(); err != nil {
+		r.state = stateError
+		return 0, false, err
+	}
+	return n, true, nil
+}
+
+// WaitingForContinue reports whether the headers of a request with "Expect:
+// 100-continue" have been parsed and the client may be waiting for an
+// interim response before it sends the body.
+func (p *Parser) WaitingForContinue() bool {
+	return p.req.state == stateExpectContinue
+}
+
+// Continue lets Feed go on to parse the body of a request that was
+// WaitingForContinue.
+func (p *Parser) Continue() {
+	if p.req.state == stateExpectContinue {
+		p.req.state = p.req.getBodyState()
+	}
+}
+
+// HeadersDone reports whether the request line and headers have been parsed,
+// so Request can be inspected before the body arrives.
+func (p *Parser) HeadersDone() bool {
+	switch p.req.state {
+	case stateInit, stateHeaders, stateError:
+		return false
+	}
+	return true
+}
+
+// Done reports whether the whole request has been parsed.
+func (p *Parser) Done() bool {
+	return p.req.state == stateDone
+}
+
+// Reset readies the parser for the next request on the same connection,
+// parsing it into the same Request after a Request.Reset instead of
+// allocating another. The previous request must no longer be in use.
+func (p *Parser) Reset() {
+	p.req.Reset()
+}
+
+// Request returns the request being parsed. Its fields fill in as parsing
+// progresses; URL, Host from an absolute-form target and RequestParams are
+// only set once Feed reports done.
+func (p *Parser) Request() *Request {
+	return p.req
+}
@@ -0,0 +1,138 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_Feed(t *testing.T) {
+	data := "POST /submit?x=1 HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\n\r\nhello"
+
+	// Test: One byte at a time, refeeding what wasn't consumed
+	p := NewParser()
+	pending := []byte{}
+	for i := 0; i < len(data); i++ {
+		pending = append(pending, data[i])
+		n, done, err := p.Feed(pending)
+		require.NoError(t, err)
+		pending = pending[n:]
+		assert.Equal(t, i == len(data)-1, done, i)
+	}
+	assert.Empty(t, pending)
+	r := p.Request()
+	assert.Equal(t, "/submit", r.RequestLine.RequestTarget)
+	assert.Equal(t, "1", r.RequestParams["x"])
+	assert.Equal(t, "hello", string(r.Body))
+
+	// Test: Bytes of a pipelined request are left unconsumed
+	p = NewParser()
+	next := "GET /next HTTP/1.1\r\nHost: x\r\n\r\n"
+	n, done, err := p.Feed([]byte(data + next))
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, len(data), n)
+
+	n, done, err = p.Feed([]byte(next))
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, 0, n)
+}
+
+func TestParser_HeadersDone(t *testing.T) {
+	p := NewParser()
+	n, done, err := p.Feed([]byte("POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 4\r\n\r\nab"))
+	require.NoError(t, err)
+	assert.False(t, done)
+	assert.True(t, p.HeadersDone())
+	assert.False(t, p.Done())
+	v, _ := p.Request().Headers.Get("Content-Length")
+	assert.Equal(t, "4", v)
+
+	_, done, err = p.Feed([]byte("cd"))
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, "abcd", string(p.Request().Body))
+	assert.Equal(t, 49, n)
+}
+
+func TestParser_Continue(t *testing.T) {
+	p := NewParser()
+	head := "PUT / HTTP/1.1\r\nHost: x\r\nExpect: 100-continue\r\nContent-Length: 2\r\n\r\n"
+	n, done, err := p.Feed([]byte(head))
+	require.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, len(head), n)
+	assert.True(t, p.WaitingForContinue())
+
+	// Test: The body isn't parsed until Continue
+	n, _, err = p.Feed([]byte("ok"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	p.Continue()
+	assert.False(t, p.WaitingForContinue())
+	n, done, err = p.Feed([]byte("ok"))
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, 2, n)
+}
+
+func TestParser_Error(t *testing.T) {
+	p := NewParser()
+	_, _, err := p.Feed([]byte("GET / HTTP/2.0\r\n"))
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	// Test: Errors are final
+	_, _, err = p.Feed(nil)
+	assert.ErrorIs(t, err, ErrReqInErrState)
+
+	// Test: Errors from the target surface once the request is complete
+	p = NewParser()
+	_, done, err := p.Feed([]byte("GET /a%zz HTTP/1.1\r\nHost: x\r\n\r\n"))
+	assert.ErrorIs(t, err, ErrMalformedRequestLine)
+	assert.False(t, done)
+}
+
+func TestParser_Reset(t *testing.T) {
+	p := NewParserLimits(Limits{MaxBodySize: 16})
+	_, done, err := p.Feed([]byte("POST /a?x=1 HTTP/1.1\r\nHost: x\r\nX-First: 1\r\nContent-Length: 5\r\n\r\nhello"))
+	require.NoError(t, err)
+	require.True(t, done)
+	r := p.Request()
+	r.PathParams = map[string]string{"id": "7"}
+	r.Locals.Set("user", "bob")
+	h, body := r.Headers, r.Body
+
+	// Test: The next request is parsed into the same storage, emptied
+	p.Reset()
+	assert.Same(t, r, p.Request())
+	assert.False(t, p.Done())
+	_, done, err = p.Feed([]byte("POST /b HTTP/1.1\r\nHost: y\r\nContent-Length: 3\r\n\r\nabc"))
+	require.NoError(t, err)
+	require.True(t, done)
+	assert.Same(t, h, r.Headers)
+	assert.Equal(t, &body[:1][0], &r.Body[:1][0], "body array reused")
+	assert.Equal(t, "abc", string(r.Body))
+	assert.Equal(t, "/b", r.URL.Path)
+	_, ok := r.Headers.Get("X-First")
+	assert.False(t, ok)
+	assert.Empty(t, r.RequestParams)
+	assert.Empty(t, r.PathParams)
+	_, ok = r.Locals.Get("user")
+	assert.False(t, ok)
+
+	// Test: Limits are kept
+	p.Reset()
+	_, _, err = p.Feed([]byte("POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 17\r\n\r\n"))
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	// Test: A zero Request can be reset and parsed into
+	var zero Request
+	zero.Reset()
+	zp := &Parser{req: &zero}
+	_, done, err = zp.Feed([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+	assert.True(t, done)
+}
@@ -0,0 +1,94 @@
+package request
+
+import (
+	"io"
+
+	"github.com/ShazimR/tcp-http-server/pkg/bufpool"
+)
+
+// Reader reads the requests a client sends one after another on a kept-alive
+// connection. Each is parsed into the same Request, reset in between (see
+// Request.Reset), and bytes read past the end of one request start the next.
+type Reader struct {
+	src    io.Reader
+	p      *Parser
+	bp     *[]byte
+	bufLen int
+	used   bool
+}
+
+func NewReader(src io.Reader, limits Limits) *Reader {
+	return &Reader{src: src, p: NewParserLimits(limits)}
+}
+
+// Next reads the next request, calling onContinue before reading the body of
+// one that expects 100 Continue, as RequestFromReaderContinue does. The
+// request returned by the previous call must no longer be in use. Its
+// Buffered bytes stay valid until the next call or Release.
+func (rd *Reader) Next(onContinue ContinueFunc) (*Request, error) {
+	if rd.used {
+		rd.p.Reset()
+	}
+	rd.used = true
+	if rd.bp == nil {
+		rd.bp = bufpool.Get(1024)
+	}
+
+	p := rd.p
+	maxBuf := p.req.limits.maxLine()
+	buf := *rd.bp
+	for {
+		// what is buffered may already hold the request, e.g. one the
+		// client pipelined, so parse before reading more
+		readN, done, err := p.Feed(buf[:rd.bufLen])
+		if err != nil {
+			return nil, err
+		}
+		copy(buf, buf[readN:rd.bufLen])
+		rd.bufLen -= readN
+		if done {
+			break
+		}
+
+		if p.WaitingForContinue() {
+			// the client may be waiting for us, so don't block on a read
+			if onContinue != nil {
+				if err := onContinue(p.Request()); err != nil {
+					return nil, err
+				}
+			}
+			p.Continue()
+			continue
+		}
+
+		if rd.bufLen == len(buf) {
+			// a line longer than the buffer; the parser fails any line
+			// longer than maxBuf before the buffer needs to grow past it
+			if len(buf) >= maxBuf {
+				return nil, ErrMalformedRequestLine
+			}
+			grown := bufpool.Get(min(2*len(buf), maxBuf))
+			copy(*grown, buf)
+			bufpool.Put(rd.bp)
+			rd.bp, buf = grown, *grown
+		}
+
+		n, err := rd.src.Read(buf[rd.bufLen:])
+		if err != nil {
+			return nil, err
+		}
+		rd.bufLen += n
+	}
+
+	request := p.Request()
+	request.buffered = buf[:rd.bufLen]
+	return request, nil
+}
+
+// Release hands the read buffer back for reuse once the connection is done
+// with. Neither the Reader nor what Buffered returned may be used afterwards.
+func (rd *Reader) Release() {
+	bufpool.Put(rd.bp)
+	rd.bp = nil
+	rd.bufLen = 0
+}
@@ -0,0 +1,67 @@
+package request
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	stream := "POST /a HTTP/1.1\r\nHost: x\r\nX-Only: a\r\nContent-Length: 3\r\n\r\none" +
+		"GET /b?q=1 HTTP/1.1\r\nHost: y\r\n\r\n" +
+		"PUT /c HTTP/1.1\r\nHost: z\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nhi\r\n0\r\n\r\n"
+
+	// Test: Requests read one after another into the same Request, whatever
+	// the read sizes
+	for _, perRead := range []int{1, 7, len(stream)} {
+		rd := NewReader(&chunkReader{data: stream, numBytesPerRead: perRead}, Limits{})
+
+		r, err := rd.Next(nil)
+		require.NoError(t, err, perRead)
+		first := r
+		assert.Equal(t, "/a", r.RequestLine.RequestTarget)
+		assert.Equal(t, "one", string(r.Body))
+		v, _ := r.Headers.Get("X-Only")
+		assert.Equal(t, "a", v)
+
+		r, err = rd.Next(nil)
+		require.NoError(t, err, perRead)
+		assert.Same(t, first, r)
+		assert.Equal(t, "/b", r.RequestLine.RequestTarget)
+		q, _ := r.QueryInt("q", 0)
+		assert.Equal(t, 1, q)
+		v, _ = r.Headers.Get("Host")
+		assert.Equal(t, "y", v)
+		_, ok := r.Headers.Get("X-Only")
+		assert.False(t, ok)
+		assert.Empty(t, r.Body)
+
+		r, err = rd.Next(nil)
+		require.NoError(t, err, perRead)
+		assert.Equal(t, "hi", string(r.Body))
+		assert.Empty(t, r.Buffered())
+
+		_, err = rd.Next(nil)
+		assert.ErrorIs(t, err, io.EOF, perRead)
+		rd.Release()
+	}
+
+	// Test: Pipelined bytes show up as Buffered
+	rd := NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: x\r\n\r\nGET /next"), Limits{})
+	r, err := rd.Next(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "GET /next", string(r.Buffered()))
+	rd.Release()
+
+	// Test: Expect: 100-continue is answered before the body is read
+	called := 0
+	rd = NewReader(strings.NewReader("POST / HTTP/1.1\r\nHost: x\r\nExpect: 100-continue\r\nContent-Length: 2\r\n\r\nok"), Limits{})
+	r, err = rd.Next(func(*Request) error { called++; return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 1, called)
+	assert.Equal(t, "ok", string(r.Body))
+	rd.Release()
+}
@@ -0,0 +1,781 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+)
+
+var (
+	sepCRLF = []byte("\r\n")
+	sepSP   = []byte(" ")
+)
+
+type RequestLine struct {
+	HttpVersion   string
+	RequestTarget string
+	Method        string
+}
+
+type Request struct {
+	RequestLine RequestLine
+	// URL is the parsed request target with the fragment dropped. Path is
+	// decoded; an authority-form CONNECT target only sets Host and an
+	// asterisk-form target only sets Path to "*".
+	URL *url.URL
+	// Host is the host the request is for, taken from the Host header or an
+	// absolute-form target, with any port split out into Port. IPv6
+	// addresses are unbracketed.
+	Host          string
+	Port          string
+	Headers       *headers.Headers
+	Body          []byte
+	Trailer       *headers.Headers
+	RequestParams map[string]string // query parameters, nil or empty without a query
+	RawQuery      string
+	PathParams    map[string]string // set by the router, nil or empty until a param matches
+	Locals        *Locals
+	RemoteAddr    string               // client address as host:port, set by the server
+	LocalAddr     string               // server address the connection was accepted on
+	TLS           *tls.ConnectionState // nil for plain connections, set by the server
+	RoutePattern  string               // registered pattern that matched, set by the router
+	// TrustedProxies lists the peers whose forwarding headers ClientIP
+	// believes, set by the server
+	TrustedProxies *TrustedProxies
+	buffered       []byte // read past the end of the request
+	multipartForm  *MultipartForm
+	state          parserState
+	limits         Limits
+	contentLength  int
+	chunked        bool
+	chunkLength    int
+	chunks         int
+	ctx            context.Context
+}
+
+var (
+	ErrMalformedRequestLine = fmt.Errorf("malformed request-line")
+	ErrUnsupportedVersion   = fmt.Errorf("unsupported http version")
+	ErrReqInErrState        = fmt.Errorf("request in error state")
+	ErrMalformedChunkedBody = fmt.Errorf("malformed chunked body")
+	ErrTooManyChunks        = fmt.Errorf("too many chunks")
+	ErrChunkTooLarge        = fmt.Errorf("chunk too large")
+	ErrExpectationFailed    = fmt.Errorf("unsupported expectation")
+	ErrMissingHost          = fmt.Errorf("missing host header")
+	ErrInvalidHost          = fmt.Errorf("invalid host header")
+	ErrInvalidContentLength = fmt.Errorf("invalid content-length")
+	ErrConflictingFraming   = fmt.Errorf("conflicting message framing")
+	ErrInvalidTrailer       = fmt.Errorf("invalid trailer field")
+	// ErrUnsupportedTransferEncoding is returned for transfer codings other
+	// than chunked, which servers answer with 501 Not Implemented.
+	ErrUnsupportedTransferEncoding = fmt.Errorf("unsupported transfer-encoding")
+)
+
+// ContinueFunc is called when a request carrying "Expect: 100-continue" has
+// been parsed up to its body. It typically sends the interim 100 Continue
+// response; returning an error aborts parsing before the body is read.
+type ContinueFunc func(r *Request) error
+
+const (
+	// MaxChunkExtensionSize bounds the extensions ("5;name=value") a chunk
+	// size line may carry. They're parsed and ignored.
+	MaxChunkExtensionSize = 256
+	// MaxChunks bounds the number of chunks in one chunked body, so a client
+	// can't keep a request open with an endless stream of tiny chunks.
+	MaxChunks = 1 << 16
+
+	// maxChunkLine is the longest chunk size line worth waiting on: 16 hex
+	// digits, padding and an extension.
+	maxChunkLine = 16 + 8 + 1 + MaxChunkExtensionSize
+
+	// maxBodyPrealloc bounds how much of a declared Content-Length is
+	// allocated up front, so a client can't reserve memory it never sends.
+	maxBodyPrealloc = 64 * 1024
+)
+
+type parserState int
+
+const (
+	stateInit parserState = iota
+	stateHeaders
+	stateBody
+	stateChunkLength
+	stateChunkData
+	stateChunkEnd
+	stateTrailer
+	stateExpectContinue
+	stateDone
+	stateError
+)
+
+func newRequest() *Request {
+	return &Request{
+		state:       stateInit,
+		limits:      Limits{}.withDefaults(),
+		chunkLength: 0,
+		Headers:     headers.NewHeaders(),
+		Body:        []byte{},
+		Trailer:     headers.NewHeaders(),
+		Locals:      NewLocals(),
+	}
+}
+
+// Reset readies r to be parsed into again, e.g. for the next request on a
+// connection, keeping its limits. Headers, trailers, locals, body and
+// parameter maps are emptied rather than reallocated, so the storage they
+// grew is reused; nothing may refer to them any more, since the next request
+// is written over them. Temporary multipart files are removed.
+func (r *Request) Reset() {
+	_ = r.RemoveTempFiles()
+
+	h, trailer, locals := r.Headers, r.Trailer, r.Locals
+	if h == nil {
+		h = headers.NewHeaders()
+	}
+	if trailer == nil {
+		trailer = headers.NewHeaders()
+	}
+	if locals == nil {
+		locals = NewLocals()
+	}
+	h.Reset()
+	trailer.Reset()
+	locals.Reset()
+	clear(r.RequestParams)
+	clear(r.PathParams)
+
+	body := r.Body[:0]
+	if body == nil {
+		body = []byte{}
+	}
+
+	*r = Request{
+		Headers:       h,
+		Body:          body,
+		Trailer:       trailer,
+		RequestParams: r.RequestParams,
+		PathParams:    r.PathParams,
+		Locals:        locals,
+		state:         stateInit,
+		limits:        r.limits.withDefaults(),
+	}
+}
+
+// Context returns the request's context. For requests served by the server it
+// is canceled when the client disconnects or the server shuts down.
+func (r *Request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// WithContext returns a shallow copy of r with its context changed to ctx.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	r2 := new(Request)
+	*r2 = *r
+	r2.ctx = ctx
+	return r2
+}
+
+// LastEventID returns the Last-Event-ID header a reconnecting Server-Sent
+// Events client sends, or "" when there is none.
+func (r *Request) LastEventID() string {
+	id, _ := r.Headers.Get("Last-Event-ID")
+	return id
+}
+
+func (r *Request) getBodyState() parserState {
+	state := stateDone
+
+	if r.chunked {
+		state = stateChunkLength
+	} else if r.contentLength > 0 {
+		state = stateBody
+	}
+
+	return state
+}
+
+// checkFraming applies the message body length rules of RFC 7230 §3.3.3
+// strictly, since a front-end proxy that reads the framing differently could
+// otherwise smuggle a second request inside the body. Transfer-Encoding must
+// be exactly chunked and can't be combined with Content-Length or sent by an
+// HTTP/1.0 client, and repeated Content-Length values must agree.
+func (r *Request) checkFraming() error {
+	te, hasTE := r.Headers.Get("Transfer-Encoding")
+	cl, hasCL := r.Headers.Get("Content-Length")
+
+	if hasTE {
+		if hasCL {
+			return fmt.Errorf("%w: both transfer-encoding and content-length", ErrConflictingFraming)
+		}
+		if r.IsHTTP10() {
+			return fmt.Errorf("%w: transfer-encoding from an http/1.0 client", ErrConflictingFraming)
+		}
+
+		codings := strings.Split(te, ",")
+		for i, coding := range codings {
+			coding = strings.TrimSpace(coding)
+			if !strings.EqualFold(coding, "chunked") {
+				return fmt.Errorf("%w: %q", ErrUnsupportedTransferEncoding, coding)
+			}
+			if i != len(codings)-1 {
+				return fmt.Errorf("%w: chunked applied more than once", ErrConflictingFraming)
+			}
+		}
+		r.chunked = true
+		return nil
+	}
+
+	if hasCL {
+		// repeated fields are combined with commas
+		values := strings.Split(cl, ",")
+		for _, v := range values {
+			if strings.TrimSpace(v) != strings.TrimSpace(values[0]) {
+				return fmt.Errorf("%w: conflicting values %q", ErrConflictingFraming, cl)
+			}
+		}
+
+		v := strings.TrimSpace(values[0])
+		length, err := strconv.ParseUint(v, 10, 63)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidContentLength, cl)
+		}
+		r.contentLength = int(length)
+		if r.limits.MaxBodySize > 0 && int64(length) > r.limits.MaxBodySize {
+			return fmt.Errorf("%w: %d bytes allowed", ErrBodyTooLarge, r.limits.MaxBodySize)
+		}
+	}
+
+	return nil
+}
+
+// Buffered returns the bytes read from the connection after the end of the
+// request, e.g. the start of another protocol's data right after an Upgrade
+// request.
+func (r *Request) Buffered() []byte {
+	return r.buffered
+}
+
+// IsHTTP10 reports whether the request came from an HTTP/1.0 client.
+func (r *Request) IsHTTP10() bool {
+	return r.RequestLine.HttpVersion == "1.0"
+}
+
+// KeepAlive reports whether the client asked for the connection to stay open
+// after the response: the default for HTTP/1.1 unless it sent Connection:
+// close, and only with Connection: keep-alive for HTTP/1.0.
+func (r *Request) KeepAlive() bool {
+	hasToken := func(token string) bool {
+		for _, v := range r.Headers.Values("Connection") {
+			for _, t := range strings.Split(v, ",") {
+				if strings.EqualFold(strings.TrimSpace(t), token) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if hasToken("close") {
+		return false
+	}
+	if r.IsHTTP10() {
+		return hasToken("keep-alive")
+	}
+	return true
+}
+
+// expectsContinue reports whether the client waits for 100 Continue. Expect
+// is ignored from HTTP/1.0 clients, which can't receive interim responses.
+func (r *Request) expectsContinue() bool {
+	expect, ok := r.Headers.Get("Expect")
+	return ok && strings.EqualFold(expect, "100-continue") && !r.IsHTTP10()
+}
+
+// checkExpect rejects Expect values other than 100-continue, the only
+// expectation defined for HTTP/1.1.
+func (r *Request) checkExpect() error {
+	if r.IsHTTP10() {
+		return nil
+	}
+	if _, ok := r.Headers.Get("Expect"); ok && !r.expectsContinue() {
+		return ErrExpectationFailed
+	}
+	return nil
+}
+
+// forbiddenTrailers are fields a sender must not put in a trailer (RFC 7230
+// §4.1.2): framing, routing, request modifiers, authentication and payload
+// processing fields, which are only acted on in the header section.
+var forbiddenTrailers = map[string]bool{
+	"transfer-encoding":   true,
+	"content-length":      true,
+	"trailer":             true,
+	"host":                true,
+	"cache-control":       true,
+	"expect":              true,
+	"max-forwards":        true,
+	"pragma":              true,
+	"range":               true,
+	"te":                  true,
+	"if-match":            true,
+	"if-none-match":       true,
+	"if-modified-since":   true,
+	"if-unmodified-since": true,
+	"if-range":            true,
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"content-encoding":    true,
+	"content-type":        true,
+	"content-range":       true,
+}
+
+// checkTrailer drops received trailer fields that weren't declared in the
+// Trailer header or are forbidden in trailers, or rejects them with
+// ErrInvalidTrailer when the limits ask for StrictTrailers.
+func (r *Request) checkTrailer() error {
+	declared := map[string]bool{}
+	for _, v := range r.Headers.Values("Trailer") {
+		for _, name := range strings.Split(v, ",") {
+			declared[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+
+	var invalid []string
+	r.Trailer.ForEach(func(name, _ string) {
+		key := strings.ToLower(name)
+		if !declared[key] || forbiddenTrailers[key] {
+			invalid = append(invalid, name)
+		}
+	})
+
+	for _, name := range invalid {
+		if r.limits.StrictTrailers {
+			if forbiddenTrailers[strings.ToLower(name)] {
+				return fmt.Errorf("%w: %s not allowed in a trailer", ErrInvalidTrailer, name)
+			}
+			return fmt.Errorf("%w: %s not declared in Trailer", ErrInvalidTrailer, name)
+		}
+		r.Trailer.Del(name)
+	}
+	return nil
+}
+
+// checkHost requires a Host header from HTTP/1.1 clients and rejects
+// malformed or repeated differing values, then fills in Host and Port.
+func (r *Request) checkHost() error {
+	value, ok := r.Headers.Get("Host")
+	if !ok {
+		if r.IsHTTP10() {
+			return nil
+		}
+		return ErrMissingHost
+	}
+
+	// repeated fields are combined with commas; copies of one value are fine
+	values := strings.Split(value, ",")
+	value = strings.TrimSpace(values[0])
+	for _, v := range values[1:] {
+		if !strings.EqualFold(strings.TrimSpace(v), value) {
+			return fmt.Errorf("%w: conflicting values", ErrInvalidHost)
+		}
+	}
+
+	host, port, err := splitHostPort(value)
+	if err != nil {
+		return err
+	}
+	r.Host, r.Port = host, port
+	return nil
+}
+
+// splitHostPort splits a Host value (uri-host [ ":" port ]) into its parts.
+func splitHostPort(value string) (string, string, error) {
+	if strings.ContainsAny(value, " \t/\\?#@") {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidHost, value)
+	}
+
+	host, port := value, ""
+	if i := strings.LastIndexByte(value, ':'); i != -1 && !strings.HasSuffix(value, "]") {
+		var err error
+		if host, port, err = net.SplitHostPort(value); err != nil {
+			return "", "", fmt.Errorf("%w: %q", ErrInvalidHost, value)
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); port != "" && err != nil {
+			return "", "", fmt.Errorf("%w: bad port %q", ErrInvalidHost, port)
+		}
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return host, port, nil
+}
+
+func (r *Request) parse(data []byte) (int, error) {
+	read := 0
+
+outer:
+	for {
+		currentData := data[read:]
+		if len(currentData) == 0 {
+			break outer
+		}
+
+		switch r.state {
+		case stateError:
+			return 0, ErrReqInErrState
+
+		case stateInit:
+			rl, n, err := parseRequestLine(currentData, r.limits.MaxRequestLine)
+			if err != nil {
+				r.state = stateError
+				return 0, err
+			}
+
+			if n == 0 {
+				break outer
+			}
+
+			r.RequestLine = *rl
+			read += n
+			r.state = stateHeaders
+
+		case stateHeaders:
+			n, done, err := r.Headers.ParseLimited(currentData, r.limits.headerLimits())
+			if err != nil {
+				r.state = stateError
+				return 0, err
+			}
+
+			if n == 0 {
+				break outer
+			}
+
+			read += n
+
+			if done {
+				if err := r.checkHost(); err != nil {
+					r.state = stateError
+					return 0, err
+				}
+				if err := r.checkFraming(); err != nil {
+					r.state = stateError
+					return 0, err
+				}
+				if err := r.checkExpect(); err != nil {
+					r.state = stateError
+					return 0, err
+				}
+
+				r.state = r.getBodyState()
+				if r.state != stateDone && r.expectsContinue() {
+					r.state = stateExpectContinue
+				}
+			}
+
+		case stateBody:
+			length := r.contentLength
+			if cap(r.Body) == 0 {
+				r.Body = make([]byte, 0, min(length, maxBodyPrealloc))
+			}
+
+			remaining := min(length-len(r.Body), len(currentData))
+			r.Body = append(r.Body, currentData[:remaining]...)
+			read += remaining
+
+			if len(r.Body) == length {
+				r.state = stateDone
+			}
+
+		case stateChunkLength:
+			n, l, err := parseChunkLength(currentData, r.limits.MaxChunkSize)
+			if err != nil {
+				r.state = stateError
+				return 0, err
+			}
+			if n == 0 {
+				break outer
+			}
+
+			read += n
+			if limit := r.limits.MaxBodySize; limit > 0 && int64(len(r.Body))+int64(l) > limit {
+				r.state = stateError
+				return 0, fmt.Errorf("%w: %d bytes allowed", ErrBodyTooLarge, limit)
+			}
+			if r.chunks++; r.chunks > MaxChunks {
+				r.state = stateError
+				return 0, ErrTooManyChunks
+			}
+			if l == 0 {
+				// the trailer section ends with a CRLF even when empty
+				r.state = stateTrailer
+			} else {
+				r.state = stateChunkData
+				r.chunkLength = l
+			}
+
+		case stateChunkData:
+			n := parseChunkData(currentData, r)
+			read += n
+			if r.chunkLength == 0 {
+				r.state = stateChunkEnd
+			}
+
+		case stateChunkEnd:
+			n, err := parseChunkEnd(currentData)
+			if err != nil {
+				r.state = stateError
+				return 0, err
+			}
+			if n == 0 {
+				break outer
+			}
+
+			read += n
+			r.state = stateChunkLength
+
+		case stateTrailer:
+			n, done, err := r.Trailer.ParseLimited(currentData, r.limits.headerLimits())
+			if err != nil {
+				r.state = stateError
+				return 0, err
+			}
+
+			if n == 0 {
+				break outer
+			}
+
+			read += n
+
+			if done {
+				if err := r.checkTrailer(); err != nil {
+					r.state = stateError
+					return 0, err
+				}
+				r.state = stateDone
+			}
+
+		case stateExpectContinue, stateDone:
+			break outer
+
+		default:
+			panic("entered default parser state")
+		}
+	}
+
+	return read, nil
+}
+
+func parseRequestLine(b []byte, maxLen int) (*RequestLine, int, error) {
+	idx := bytes.Index(b, sepCRLF)
+	if maxLen > 0 && (idx > maxLen || idx == -1 && len(b) > maxLen) {
+		return nil, 0, ErrRequestLineTooLong
+	}
+	if idx == -1 {
+		return nil, 0, nil // not enough data yet
+	}
+
+	startLine := b[:idx]
+	read := idx + len(sepCRLF)
+
+	parts := bytes.Split(startLine, sepSP)
+	if len(parts) != 3 {
+		return nil, 0, ErrMalformedRequestLine
+	}
+
+	httpParts := bytes.Split(parts[2], []byte("/"))
+	if len(httpParts) != 2 || string(httpParts[0]) != "HTTP" {
+		return nil, 0, ErrMalformedRequestLine
+	}
+	if string(httpParts[1]) != "1.1" && string(httpParts[1]) != "1.0" {
+		return nil, 0, ErrUnsupportedVersion
+	}
+
+	if !headers.IsToken(string(parts[0])) {
+		return nil, 0, ErrMalformedRequestLine
+	}
+
+	requestLine := &RequestLine{
+		Method:        string(parts[0]),
+		RequestTarget: string(parts[1]),
+		HttpVersion:   string(httpParts[1]),
+	}
+
+	return requestLine, read, nil
+}
+
+func parseChunkLength(b []byte, maxSize int64) (readSize int, chunkLength int, e error) {
+	idx := bytes.Index(b, sepCRLF)
+	if idx == -1 {
+		if len(b) > maxChunkLine {
+			return 0, -1, fmt.Errorf("%w: chunk size line too long", ErrMalformedChunkedBody)
+		}
+		return 0, -1, nil // not enough data yet
+	}
+
+	lenHexStr, ext, hasExt := bytes.Cut(b[:idx], []byte(";"))
+	if hasExt && len(ext) > MaxChunkExtensionSize {
+		return 0, -1, fmt.Errorf("%w: chunk extension too long", ErrMalformedChunkedBody)
+	}
+
+	if hasExt {
+		lenHexStr = bytes.TrimRight(lenHexStr, " \t") // BWS before ";"
+	}
+
+	if len(lenHexStr) == 0 || bytes.IndexFunc(lenHexStr, func(c rune) bool { return !isHexDigit(c) }) != -1 {
+		return 0, -1, ErrMalformedChunkedBody
+	}
+
+	read := idx + len(sepCRLF)
+	length, err := strconv.ParseUint(string(lenHexStr), 16, 63)
+	if err != nil || length > uint64(maxSize) {
+		return 0, -1, fmt.Errorf("%w: %d bytes allowed", ErrChunkTooLarge, maxSize)
+	}
+
+	return read, int(length), nil
+}
+
+func isHexDigit(c rune) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// parseChunkData appends up to the rest of the current chunk from b to the
+// body. Exactly the declared size is taken, whatever bytes it contains, so
+// binary chunks holding CRLF are framed the way their size says.
+func parseChunkData(b []byte, r *Request) int {
+	n := min(r.chunkLength, len(b))
+	r.Body = append(r.Body, b[:n]...)
+	r.chunkLength -= n
+	return n
+}
+
+// parseChunkEnd requires the CRLF that must follow a chunk's data.
+func parseChunkEnd(b []byte) (int, error) {
+	if len(b) < len(sepCRLF) {
+		if len(b) == 1 && b[0] != '\r' {
+			return 0, ErrMalformedChunkedBody
+		}
+		return 0, nil // not enough data yet
+	}
+	if !bytes.HasPrefix(b, sepCRLF) {
+		return 0, ErrMalformedChunkedBody // chunk longer than its declared size
+	}
+	return len(sepCRLF), nil
+}
+
+// parseURL parses the request target into r.URL, accepting the origin-form
+// ("/path?query"), absolute-form ("http://host/path"), authority-form
+// ("host:port", CONNECT only) and asterisk-form ("*").
+func parseURL(r *Request) error {
+	target, _, _ := strings.Cut(r.RequestLine.RequestTarget, "#")
+	r.RequestLine.RequestTarget = target
+
+	switch {
+	case r.RequestLine.Method == "CONNECT" && !strings.HasPrefix(target, "/"):
+		r.URL = &url.URL{Host: target}
+		return nil
+	case target == "*":
+		r.URL = &url.URL{Path: "*"}
+		return nil
+	}
+
+	u, err := url.ParseRequestURI(target)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedRequestLine, err)
+	}
+	if u.Path == "" {
+		u.Path = "/" // "http://host" asks for the root
+	}
+	if u.Host != "" {
+		// an absolute-form target's authority wins over the Host header
+		host, port, err := splitHostPort(u.Host)
+		if err != nil {
+			return err
+		}
+		r.Host, r.Port = host, port
+	}
+	r.URL = u
+	return nil
+}
+
+func parseRequestParameters(r *Request) error {
+	target := r.RequestLine.RequestTarget
+
+	if i := strings.IndexByte(target, '?'); i != -1 && i < len(target)-1 {
+		queryStr := target[i+1:]
+		queries := strings.Split(queryStr, "&")
+		if r.RequestParams == nil {
+			r.RequestParams = make(map[string]string, len(queries))
+		}
+
+		for _, query := range queries {
+			if k, v, hasEq := strings.Cut(query, "="); hasEq {
+				r.RequestParams[k] = v
+
+			} else if k != "" {
+				r.RequestParams[k] = ""
+
+			} else {
+				return ErrMalformedRequestLine
+			}
+		}
+
+		r.RequestLine.RequestTarget = target[:i]
+		r.RawQuery = queryStr
+	}
+
+	return nil
+}
+
+// finish fills in the fields derived from the request line once the whole
+// request has been parsed.
+func (r *Request) finish() error {
+	if err := parseURL(r); err != nil {
+		return err
+	}
+	return parseRequestParameters(r)
+}
+
+func RequestFromReader(reader io.Reader) (*Request, error) {
+	return RequestFromReaderContinue(reader, nil)
+}
+
+// RequestFromReaderContinue is like RequestFromReader but calls onContinue
+// before reading the body of a request that expects 100 Continue. A nil
+// onContinue simply goes on reading the body.
+func RequestFromReaderContinue(reader io.Reader, onContinue ContinueFunc) (*Request, error) {
+	return RequestFromReaderLimits(reader, onContinue, Limits{})
+}
+
+// RequestFromReaderLimits is like RequestFromReaderContinue but holds the
+// request head to limits instead of the defaults.
+func RequestFromReaderLimits(reader io.Reader, onContinue ContinueFunc, limits Limits) (*Request, error) {
+	rd := NewReader(reader, limits)
+	defer rd.Release()
+
+	request, err := rd.Next(onContinue)
+	if err != nil {
+		return nil, err
+	}
+	// the reader's buffer goes back to the pool
+	if len(request.buffered) > 0 {
+		request.buffered = bytes.Clone(request.buffered)
+	} else {
+		request.buffered = nil
+	}
+
+	return request, nil
+}
@@ -0,0 +1,827 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chunkReader struct {
+	data            string
+	numBytesPerRead int
+	pos             int
+}
+
+// Simulates reading a variable number of bytes per chunk from a network
+func (cr *chunkReader) Read(p []byte) (n int, err error) {
+	if cr.pos >= len(cr.data) {
+		return 0, io.EOF
+	}
+	endIndex := min(cr.pos+cr.numBytesPerRead, len(cr.data))
+	n = copy(p, cr.data[cr.pos:endIndex])
+	cr.pos += n
+	if n > cr.numBytesPerRead {
+		n = cr.numBytesPerRead
+		cr.pos -= n - cr.numBytesPerRead
+	}
+	return n, nil
+}
+
+func TestRequestLineParse(t *testing.T) {
+	// Test: Good GET Request line
+	reader := &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: localhost:8080\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "GET", r.RequestLine.Method)
+	assert.Equal(t, "/", r.RequestLine.RequestTarget)
+	assert.Equal(t, "1.1", r.RequestLine.HttpVersion)
+	assert.Equal(t, 0, len(r.RequestParams))
+
+	// Test: Good GET Request line with path
+	reader = &chunkReader{
+		data:            "GET /coffee HTTP/1.1\r\nHost: localhost:8080\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "GET", r.RequestLine.Method)
+	assert.Equal(t, "/coffee", r.RequestLine.RequestTarget)
+	assert.Equal(t, "1.1", r.RequestLine.HttpVersion)
+	assert.Equal(t, 0, len(r.RequestParams))
+
+	// Test: Good GET Request line with query parameters
+	reader = &chunkReader{
+		data:            "GET /coffee?size=medium&type=black&name=shazim&test=a=b HTTP/1.1\r\nHost: localhost:8080\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "GET", r.RequestLine.Method)
+	assert.Equal(t, "/coffee", r.RequestLine.RequestTarget)
+	assert.Equal(t, "1.1", r.RequestLine.HttpVersion)
+	assert.Equal(t, 4, len(r.RequestParams))
+	assert.Equal(t, "medium", r.RequestParams["size"])
+	assert.Equal(t, "black", r.RequestParams["type"])
+	assert.Equal(t, "shazim", r.RequestParams["name"])
+	assert.Equal(t, "a=b", r.RequestParams["test"])
+	assert.Equal(t, "size=medium&type=black&name=shazim&test=a=b", r.RawQuery)
+
+	// Test: Invalid number of parts in the request line
+	testReq := "/coffee HTTP/1.1\r\nHost: localhost:8080\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n"
+	reader = &chunkReader{
+		data:            testReq,
+		numBytesPerRead: len(testReq),
+	}
+	r, err = RequestFromReader(reader)
+	require.Error(t, err)
+	require.Nil(t, r)
+	assert.Equal(t, ErrMalformedRequestLine, err)
+
+	// Test: Invalid HTTP version
+	reader = &chunkReader{
+		data:            "GET / HTTP/2.0\r\nHost: localhost:8080\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader)
+	require.Error(t, err)
+	require.Nil(t, r)
+	assert.Equal(t, ErrUnsupportedVersion, err)
+}
+
+func TestParseHeaders(t *testing.T) {
+	// Test: Standard headers
+	reader := &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: localhost:8080\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	hostStr, ok := r.Headers.Get("Host")
+	assert.True(t, ok)
+	assert.Equal(t, "localhost:8080", hostStr)
+	userStr, ok := r.Headers.Get("User-Agent")
+	assert.True(t, ok)
+	assert.Equal(t, "curl/7.81.0", userStr)
+	acceptStr, ok := r.Headers.Get("Accept")
+	assert.True(t, ok)
+	assert.Equal(t, "*/*", acceptStr)
+
+	// Test: Malformed header
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nH©st: localhost:8080\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.Error(t, err)
+}
+
+func TestParseBody(t *testing.T) {
+	// Test: Standard body
+	reader := &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:8080\r\n" +
+			"Content-Length: 13\r\n" +
+			"\r\n" +
+			"Hello World!\n",
+		numBytesPerRead: 1,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "Hello World!\n", string(r.Body))
+
+	// Test: Chunk encoded body
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:8080\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"3\r\n" +
+			"Hel\r\n" +
+			"2\r\n" +
+			"lo\r\n" +
+			"2\r\n" +
+			" W\r\n" +
+			"4\r\n" +
+			"orld\r\n" +
+			"2\r\n" +
+			"!\n\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "Hello World!\n", string(r.Body))
+
+	// Test: Chunk encoded body with trailer
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:8080\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"Trailer: Expires\r\n" +
+			"\r\n" +
+			"3\r\n" +
+			"Hel\r\n" +
+			"2\r\n" +
+			"lo\r\n" +
+			"2\r\n" +
+			" W\r\n" +
+			"4\r\n" +
+			"orld\r\n" +
+			"2\r\n" +
+			"!\n\r\n" +
+			"0\r\n" +
+			"Expires: tomorrow\r\n" +
+			"\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "Hello World!\n", string(r.Body))
+
+	// Test: Chunk encoded with CRLF in body
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:8080\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"3\r\n" +
+			"Hel\r\n" +
+			"2\r\n" +
+			"lo\r\n" +
+			"2\r\n" +
+			"\r\n\r\n" + // crlf in body here
+			"1\r\n" +
+			"W\r\n" +
+			"4\r\n" +
+			"orld\r\n" +
+			"3\r\n" +
+			"!\r\n\r\n" + // crlf in body here
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "Hello\r\nWorld!\r\n", string(r.Body))
+
+	// Test: Chunk encoded with missing CRLF at end of chunk
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:8080\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"3\r\n" +
+			"Hel\r\n" +
+			"2\r\n" +
+			"lo\r\n" +
+			"2\r\n" +
+			" W\r\n" +
+			"4\r\n" +
+			"orld" + // missing crlf here
+			"3\r\n" +
+			"!\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.Error(t, err)
+	assert.Equal(t, ErrMalformedChunkedBody, err)
+
+	// Test: Chunk encoded chunk size not valid
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:8080\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"3\r\n" +
+			"Hel\r\n" +
+			"2\r\n" +
+			"lo\r\n" +
+			"2\r\n" +
+			" W\r\n" +
+			"4" + // missing crlf causes invalid size -> "4orld\r\n"
+			"orld\r\n" +
+			"3\r\n" +
+			"!\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.Error(t, err)
+	assert.Equal(t, ErrMalformedChunkedBody, err)
+
+	// Test: Body shorter than reported content length
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:8080\r\n" +
+			"Content-Length: 20\r\n" +
+			"\r\n" +
+			"partial content\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.Error(t, err)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestParseTrailer(t *testing.T) {
+	// Test: Trailer header included
+	reader := &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:8080\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"Trailer: Expires,Another\r\n" +
+			"\r\n" +
+			"3\r\n" +
+			"Hel\r\n" +
+			"2\r\n" +
+			"lo\r\n" +
+			"2\r\n" +
+			" W\r\n" +
+			"4\r\n" +
+			"orld\r\n" +
+			"2\r\n" +
+			"!\n\r\n" +
+			"0\r\n" +
+			"Expires: tomorrow\r\n" +
+			"Another: value\r\n" +
+			"\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	expStr, ok := r.Trailer.Get("Expires")
+	assert.True(t, ok)
+	assert.Equal(t, "tomorrow", expStr)
+	anoStr, ok := r.Trailer.Get("Another")
+	assert.True(t, ok)
+	assert.Equal(t, "value", anoStr)
+	dneStr, ok := r.Trailer.Get("DoesNotExist")
+	assert.False(t, ok)
+	assert.Equal(t, "", dneStr)
+
+	// Test: Trailer header not included
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:8080\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"3\r\n" +
+			"Hel\r\n" +
+			"2\r\n" +
+			"lo\r\n" +
+			"2\r\n" +
+			" W\r\n" +
+			"4\r\n" +
+			"orld\r\n" +
+			"2\r\n" +
+			"!\n\r\n" +
+			"0\r\n" +
+			"Expires: tomorrow\r\n" +
+			"Another: value\r\n" +
+			"\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	expStr, ok = r.Trailer.Get("Expires")
+	assert.False(t, ok)
+	assert.Equal(t, "", expStr)
+	anoStr, ok = r.Trailer.Get("Another")
+	assert.False(t, ok)
+	assert.Equal(t, "", anoStr)
+}
+
+func TestRequestContext(t *testing.T) {
+	// Test: Default context is non-nil
+	reader := &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: localhost:8080\r\n\r\n",
+		numBytesPerRead: 8,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r.Context())
+	assert.NoError(t, r.Context().Err())
+
+	// Test: WithContext returns a copy carrying the new context
+	ctx, cancel := context.WithCancel(context.Background())
+	r2 := r.WithContext(ctx)
+	cancel()
+	assert.ErrorIs(t, r2.Context().Err(), context.Canceled)
+	assert.NoError(t, r.Context().Err())
+	assert.Equal(t, r.RequestLine, r2.RequestLine)
+}
+
+// continueReader only hands out the body once the 100 Continue hook has run,
+// like a client waiting on the interim response.
+type continueReader struct {
+	head      chunkReader
+	body      chunkReader
+	continued bool
+}
+
+func (cr *continueReader) Read(p []byte) (int, error) {
+	if cr.head.pos < len(cr.head.data) {
+		return cr.head.Read(p)
+	}
+	if !cr.continued {
+		return 0, io.ErrNoProgress
+	}
+	return cr.body.Read(p)
+}
+
+func TestExpectContinue(t *testing.T) {
+	// Test: Hook runs before the body is read
+	reader := &continueReader{
+		head: chunkReader{data: "POST /upload HTTP/1.1\r\nHost: localhost:8080\r\nExpect: 100-continue\r\nContent-Length: 5\r\n\r\n", numBytesPerRead: 7},
+		body: chunkReader{data: "hello", numBytesPerRead: 2},
+	}
+	calls := 0
+	r, err := RequestFromReaderContinue(reader, func(r *Request) error {
+		calls++
+		assert.Equal(t, "/upload", r.RequestLine.RequestTarget)
+		reader.continued = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "hello", string(r.Body))
+
+	// Test: Hook errors abort parsing
+	reader = &continueReader{
+		head: chunkReader{data: "POST / HTTP/1.1\r\nHost: x\r\nExpect: 100-Continue\r\nContent-Length: 5\r\n\r\n", numBytesPerRead: 64},
+	}
+	_, err = RequestFromReaderContinue(reader, func(*Request) error { return io.ErrClosedPipe })
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+
+	// Test: No hook call without a body
+	reader = &continueReader{
+		head: chunkReader{data: "GET / HTTP/1.1\r\nHost: x\r\nExpect: 100-continue\r\n\r\n", numBytesPerRead: 64},
+	}
+	_, err = RequestFromReaderContinue(reader, func(*Request) error {
+		t.Fatal("hook called for a request without a body")
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Test: Unknown expectations are rejected
+	reader = &continueReader{
+		head: chunkReader{data: "POST / HTTP/1.1\r\nHost: x\r\nExpect: teapot\r\nContent-Length: 5\r\n\r\n", numBytesPerRead: 64},
+	}
+	_, err = RequestFromReader(reader)
+	assert.ErrorIs(t, err, ErrExpectationFailed)
+}
+
+func TestHTTP10(t *testing.T) {
+	// Test: HTTP/1.0 without Host
+	r, err := RequestFromReader(&chunkReader{data: "GET /old HTTP/1.0\r\n\r\n", numBytesPerRead: 4})
+	require.NoError(t, err)
+	assert.Equal(t, "1.0", r.RequestLine.HttpVersion)
+	assert.True(t, r.IsHTTP10())
+	assert.False(t, r.KeepAlive())
+
+	// Test: Keep-alive is opt-in for HTTP/1.0
+	r, err = RequestFromReader(&chunkReader{data: "GET / HTTP/1.0\r\nConnection: Keep-Alive\r\n\r\n", numBytesPerRead: 64})
+	require.NoError(t, err)
+	assert.True(t, r.KeepAlive())
+
+	// Test: and opt-out for HTTP/1.1
+	r, err = RequestFromReader(&chunkReader{data: "GET / HTTP/1.1\r\nHost: x\r\n\r\n", numBytesPerRead: 64})
+	require.NoError(t, err)
+	assert.True(t, r.KeepAlive())
+	r, err = RequestFromReader(&chunkReader{data: "GET / HTTP/1.1\r\nHost: x\r\nConnection: upgrade, close\r\n\r\n", numBytesPerRead: 64})
+	require.NoError(t, err)
+	assert.False(t, r.KeepAlive())
+
+	// Test: Expect is ignored from HTTP/1.0 clients
+	reader := &continueReader{
+		head: chunkReader{data: "POST / HTTP/1.0\r\nExpect: 100-continue\r\nContent-Length: 2\r\n\r\nhi", numBytesPerRead: 64},
+	}
+	r, err = RequestFromReaderContinue(reader, func(*Request) error {
+		t.Fatal("hook called for an HTTP/1.0 request")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hi", string(r.Body))
+}
+
+func TestRequestURL(t *testing.T) {
+	parse := func(line string) (*Request, error) {
+		return RequestFromReader(&chunkReader{data: line + "\r\nHost: x\r\n\r\n", numBytesPerRead: 8})
+	}
+
+	// Test: Origin-form has its path decoded and the fragment dropped
+	r, err := parse("GET /files/a%20b/c?x=1&y#top HTTP/1.1")
+	require.NoError(t, err)
+	require.NotNil(t, r.URL)
+	assert.Equal(t, "/files/a b/c", r.URL.Path)
+	assert.Equal(t, "x=1&y", r.URL.RawQuery)
+	assert.Equal(t, "", r.URL.Fragment)
+	assert.Equal(t, "/files/a%20b/c", r.RequestLine.RequestTarget)
+	assert.Equal(t, "1", r.RequestParams["x"])
+
+	// Test: Encoded slashes keep the raw path
+	r, err = parse("GET /a%2Fb HTTP/1.1")
+	require.NoError(t, err)
+	assert.Equal(t, "/a/b", r.URL.Path)
+	assert.Equal(t, "/a%2Fb", r.URL.RawPath)
+
+	// Test: Absolute-form
+	r, err = parse("GET http://example.com:8080/p?q=1 HTTP/1.1")
+	require.NoError(t, err)
+	assert.Equal(t, "http", r.URL.Scheme)
+	assert.Equal(t, "example.com:8080", r.URL.Host)
+	assert.Equal(t, "/p", r.URL.Path)
+	assert.Equal(t, "q=1", r.URL.RawQuery)
+
+	// Test: Authority-form and asterisk-form
+	r, err = parse("CONNECT example.com:443 HTTP/1.1")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com:443", r.URL.Host)
+	assert.Equal(t, "", r.URL.Path)
+	r, err = parse("OPTIONS * HTTP/1.1")
+	require.NoError(t, err)
+	assert.Equal(t, "*", r.URL.Path)
+
+	// Test: Bad escapes are rejected
+	_, err = parse("GET /a%zz HTTP/1.1")
+	assert.ErrorIs(t, err, ErrMalformedRequestLine)
+}
+
+func TestHostHeader(t *testing.T) {
+	parse := func(head string) (*Request, error) {
+		return RequestFromReader(&chunkReader{data: "GET / HTTP/1.1\r\n" + head + "\r\n", numBytesPerRead: 8})
+	}
+
+	// Test: Port is split out
+	r, err := parse("Host: Example.com:8080\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "Example.com", r.Host)
+	assert.Equal(t, "8080", r.Port)
+
+	// Test: IPv6 literals are unbracketed
+	r, err = parse("Host: [::1]:443\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "::1", r.Host)
+	assert.Equal(t, "443", r.Port)
+	r, err = parse("Host: [::1]\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "::1", r.Host)
+	assert.Equal(t, "", r.Port)
+
+	// Test: Repeating the same value is allowed
+	r, err = parse("Host: a.com\r\nHost: A.com\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "a.com", r.Host)
+
+	// Test: Missing, conflicting or malformed hosts are rejected
+	_, err = parse("")
+	assert.ErrorIs(t, err, ErrMissingHost)
+	for _, head := range []string{
+		"Host: a.com\r\nHost: b.com\r\n",
+		"Host: a.com/evil\r\n",
+		"Host: user@a.com\r\n",
+		"Host: a.com:http\r\n",
+		"Host: a.com:99999\r\n",
+	} {
+		_, err = parse(head)
+		assert.ErrorIs(t, err, ErrInvalidHost, head)
+	}
+
+	// Test: HTTP/1.0 may omit it
+	r, err = RequestFromReader(&chunkReader{data: "GET / HTTP/1.0\r\n\r\n", numBytesPerRead: 8})
+	require.NoError(t, err)
+	assert.Equal(t, "", r.Host)
+
+	// Test: An absolute-form target overrides the header
+	r, err = RequestFromReader(&chunkReader{data: "GET http://origin.test:81/ HTTP/1.1\r\nHost: other\r\n\r\n", numBytesPerRead: 8})
+	require.NoError(t, err)
+	assert.Equal(t, "origin.test", r.Host)
+	assert.Equal(t, "81", r.Port)
+}
+
+func TestFraming(t *testing.T) {
+	parse := func(version, head, body string) (*Request, error) {
+		data := "POST /submit HTTP/" + version + "\r\nHost: x\r\n" + head + "\r\n" + body
+		return RequestFromReader(&chunkReader{data: data, numBytesPerRead: 8})
+	}
+
+	// Test: Repeated identical Content-Length values
+	r, err := parse("1.1", "Content-Length: 5\r\nContent-Length: 5\r\n", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(r.Body))
+
+	// Test: Transfer-Encoding is case-insensitive
+	r, err = parse("1.1", "Transfer-Encoding: Chunked\r\n", "2\r\nhi\r\n0\r\n\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "hi", string(r.Body))
+
+	cases := []struct {
+		version string
+		head    string
+		err     error
+	}{
+		{"1.1", "Content-Length: 5\r\nTransfer-Encoding: chunked\r\n", ErrConflictingFraming},
+		{"1.1", "Content-Length: 5\r\nContent-Length: 6\r\n", ErrConflictingFraming},
+		{"1.1", "Transfer-Encoding: chunked, chunked\r\n", ErrConflictingFraming},
+		{"1.0", "Transfer-Encoding: chunked\r\n", ErrConflictingFraming},
+		{"1.1", "Content-Length: abc\r\n", ErrInvalidContentLength},
+		{"1.1", "Content-Length: -1\r\n", ErrInvalidContentLength},
+		{"1.1", "Content-Length: +5\r\n", ErrInvalidContentLength},
+		{"1.1", "Content-Length: \r\n", ErrInvalidContentLength},
+		{"1.1", "Transfer-Encoding: gzip, chunked\r\n", ErrUnsupportedTransferEncoding},
+		{"1.1", "Transfer-Encoding: identity\r\n", ErrUnsupportedTransferEncoding},
+		{"1.1", "Transfer-Encoding: xchunked\r\n", ErrUnsupportedTransferEncoding},
+	}
+	for _, tc := range cases {
+		_, err := parse(tc.version, tc.head, "hello")
+		assert.ErrorIs(t, err, tc.err, tc.head)
+	}
+}
+
+func TestChunkFraming(t *testing.T) {
+	parse := func(body string, perRead int) (*Request, error) {
+		data := "POST /upload HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n" + body
+		return RequestFromReader(&chunkReader{data: data, numBytesPerRead: perRead})
+	}
+
+	// Test: CRLF bytes inside a chunk are data
+	for _, perRead := range []int{1, 3, 64} {
+		r, err := parse("6\r\na\r\n\r\nb\r\n2\r\n\r\n\r\n0\r\n\r\n", perRead)
+		require.NoError(t, err)
+		assert.Equal(t, "a\r\n\r\nb\r\n", string(r.Body))
+	}
+
+	// Test: Chunks larger than the read buffer
+	big := strings.Repeat("x", 5000)
+	r, err := parse(fmt.Sprintf("%x\r\n%s\r\n0\r\n\r\n", len(big), big), 512)
+	require.NoError(t, err)
+	assert.Equal(t, big, string(r.Body))
+
+	// Test: Data longer or shorter than the declared size
+	for _, body := range []string{
+		"3\r\nhello\r\n0\r\n\r\n",
+		"5\r\nhi\r\n0\r\n\r\n",
+		"2\r\nhi\n0\r\n\r\n",
+	} {
+		_, err := parse(body, 64)
+		assert.ErrorIs(t, err, ErrMalformedChunkedBody, body)
+	}
+}
+
+func TestChunkExtensions(t *testing.T) {
+	parse := func(body string) (*Request, error) {
+		data := "POST /upload HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n" + body
+		return RequestFromReader(&chunkReader{data: data, numBytesPerRead: 16})
+	}
+
+	// Test: Extensions are ignored
+	r, err := parse("5;name=value\r\nhello\r\n1 ; a ; b=\"c d\"\r\n!\r\n0;last\r\n\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "hello!", string(r.Body))
+
+	// Test: Overlong extensions
+	_, err = parse("5;" + strings.Repeat("x", MaxChunkExtensionSize+1) + "\r\nhello\r\n0\r\n\r\n")
+	assert.ErrorIs(t, err, ErrMalformedChunkedBody)
+
+	// Test: Chunk count limit
+	_, err = parse(strings.Repeat("1\r\nx\r\n", MaxChunks) + "0\r\n\r\n")
+	assert.ErrorIs(t, err, ErrTooManyChunks)
+	r, err = parse(strings.Repeat("1\r\nx\r\n", MaxChunks-1) + "0\r\n\r\n")
+	require.NoError(t, err)
+	assert.Len(t, r.Body, MaxChunks-1)
+}
+
+func TestLimits(t *testing.T) {
+	parse := func(data string, limits Limits) (*Request, error) {
+		return RequestFromReaderLimits(&chunkReader{data: data, numBytesPerRead: 100}, nil, limits)
+	}
+
+	// Test: Headers larger than the read buffer within the defaults
+	big := strings.Repeat("x", 4000)
+	r, err := parse("GET / HTTP/1.1\r\nHost: x\r\nX-Big: "+big+"\r\n\r\n", Limits{})
+	require.NoError(t, err)
+	v, _ := r.Headers.Get("X-Big")
+	assert.Equal(t, big, v)
+
+	// Test: Defaults
+	_, err = parse("GET / HTTP/1.1\r\nHost: x\r\nX-Big: "+strings.Repeat("x", DefaultMaxFieldSize)+"\r\n\r\n", Limits{})
+	assert.ErrorIs(t, err, headers.ErrHeaderTooLarge)
+	_, err = parse("GET / HTTP/1.1\r\nHost: x\r\n"+strings.Repeat("X: y\r\n", DefaultMaxHeaderFields)+"\r\n", Limits{})
+	assert.ErrorIs(t, err, headers.ErrHeaderTooLarge)
+	_, err = parse("GET /"+strings.Repeat("a", DefaultMaxRequestLine)+" HTTP/1.1\r\nHost: x\r\n\r\n", Limits{})
+	assert.ErrorIs(t, err, ErrRequestLineTooLong)
+
+	// Test: Custom limits, with trailers held to them too
+	limits := Limits{MaxRequestLine: 32, MaxHeaderFields: 3, MaxFieldSize: 64, MaxHeaderBytes: 128}
+	_, err = parse("GET / HTTP/1.1\r\nHost: x\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n", limits)
+	assert.ErrorIs(t, err, headers.ErrHeaderTooLarge)
+	_, err = parse("GET /"+strings.Repeat("a", 32)+" HTTP/1.1\r\nHost: x\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrRequestLineTooLong)
+	_, err = parse("POST / HTTP/1.1\r\nHost: x\r\nTrailer: X\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n"+
+		"X: "+strings.Repeat("x", 64)+"\r\n\r\n", limits)
+	assert.ErrorIs(t, err, headers.ErrHeaderTooLarge)
+
+	// Test: Chunk size lines don't grow without end
+	_, err = parse("POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n"+strings.Repeat("0", 2000), Limits{})
+	assert.ErrorIs(t, err, ErrMalformedChunkedBody)
+}
+
+func TestTrailerValidation(t *testing.T) {
+	parse := func(declared string, trailer string, limits Limits) (*Request, error) {
+		data := "POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n"
+		if declared != "" {
+			data += "Trailer: " + declared + "\r\n"
+		}
+		data += "\r\n2\r\nhi\r\n0\r\n" + trailer + "\r\n"
+		return RequestFromReaderLimits(&chunkReader{data: data, numBytesPerRead: 7}, nil, limits)
+	}
+
+	// Test: Undeclared and forbidden fields are dropped by default
+	r, err := parse("X-Checksum, Content-Length", "x-checksum: abc\r\nX-Other: 1\r\nContent-Length: 0\r\n", Limits{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Trailer.Len())
+	v, _ := r.Trailer.Get("X-Checksum")
+	assert.Equal(t, "abc", v)
+	assert.Equal(t, "hi", string(r.Body))
+	assert.Empty(t, r.Buffered())
+
+	// Test: An empty trailer section is consumed
+	r, err = parse("", "", Limits{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, r.Trailer.Len())
+	assert.Empty(t, r.Buffered())
+
+	// Test: Strict mode rejects them
+	strict := Limits{StrictTrailers: true}
+	_, err = parse("X-Checksum", "X-Checksum: abc\r\n", strict)
+	require.NoError(t, err)
+	_, err = parse("X-Checksum", "X-Other: 1\r\n", strict)
+	assert.ErrorIs(t, err, ErrInvalidTrailer)
+	_, err = parse("Host", "Host: evil\r\n", strict)
+	assert.ErrorIs(t, err, ErrInvalidTrailer)
+}
+
+func TestChunkSizeLimits(t *testing.T) {
+	parse := func(body string, limits Limits) (*Request, error) {
+		data := "POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n" + body
+		return RequestFromReaderLimits(&chunkReader{data: data, numBytesPerRead: 32}, nil, limits)
+	}
+
+	// Test: Sizes that aren't plain hex
+	for _, size := range []string{"", "0x5", "+5", "-5", "5 ", " 5", "5_0", "g"} {
+		_, err := parse(size+"\r\nhello\r\n0\r\n\r\n", Limits{})
+		assert.ErrorIs(t, err, ErrMalformedChunkedBody, "%q", size)
+	}
+
+	// Test: Sizes over the limit, or too big to parse at all
+	limits := Limits{MaxChunkSize: 4}
+	_, err := parse("5\r\nhello\r\n0\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrChunkTooLarge)
+	r, err := parse("4\r\nhell\r\n0\r\n\r\n", limits)
+	require.NoError(t, err)
+	assert.Equal(t, "hell", string(r.Body))
+	_, err = parse("ffffffffffffffff\r\n", Limits{})
+	assert.ErrorIs(t, err, ErrChunkTooLarge)
+	_, err = parse(fmt.Sprintf("%x\r\n", DefaultMaxChunkSize+1), Limits{})
+	assert.ErrorIs(t, err, ErrChunkTooLarge)
+}
+
+func TestRequestLineMethod(t *testing.T) {
+	// Test: Any token is a method, case-sensitively
+	for _, method := range []string{"GET", "PURGE", "M-SEARCH", "get"} {
+		r, err := RequestFromReader(&chunkReader{data: method + " / HTTP/1.1\r\nHost: x\r\n\r\n", numBytesPerRead: 8})
+		require.NoError(t, err, method)
+		assert.Equal(t, method, r.RequestLine.Method)
+	}
+
+	for _, method := range []string{"G(ET", "GE\"T", "G/T", "G\x00T", "GÉT"} {
+		_, err := RequestFromReader(&chunkReader{data: method + " / HTTP/1.1\r\nHost: x\r\n\r\n", numBytesPerRead: 8})
+		assert.ErrorIs(t, err, ErrMalformedRequestLine, "%q", method)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	parse := func(data string) (*Request, error) {
+		return RequestFromReaderLimits(&chunkReader{data: data, numBytesPerRead: 16}, nil, Limits{MaxBodySize: 8})
+	}
+
+	r, err := parse("POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 8\r\n\r\n12345678")
+	require.NoError(t, err)
+	assert.Equal(t, "12345678", string(r.Body))
+
+	// Test: A declared length over the limit fails before the body is read
+	p := NewParserLimits(Limits{MaxBodySize: 8})
+	_, _, err = p.Feed([]byte("POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 9\r\n\r\n"))
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	// Test: Chunked bodies fail at the chunk that goes over
+	_, err = parse("POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n5\r\n12345\r\n4\r\n1234\r\n0\r\n\r\n")
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+	r, err = parse("POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n5\r\n12345\r\n3\r\n123\r\n0\r\n\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "12345123", string(r.Body))
+}
+
+func TestRequestFromReader_PooledBuffers(t *testing.T) {
+	long := strings.Repeat("v", 5000)
+	data := "POST /path?q=1 HTTP/1.1\r\nHost: x\r\nX-Long: " + long + "\r\nContent-Length: 5\r\n\r\nhelloNEXT"
+
+	// Test: Lines longer than the first buffer grow it without losing data
+	r, err := RequestFromReader(&chunkReader{data: data, numBytesPerRead: 700})
+	require.NoError(t, err)
+	v, _ := r.Headers.Get("X-Long")
+	assert.Equal(t, long, v)
+	assert.Equal(t, "hello", string(r.Body))
+	assert.Equal(t, 5, cap(r.Body), "body sized from Content-Length")
+
+	// Test: Nothing parsed refers to a buffer once it is reused
+	other := strings.Repeat("w", 5000)
+	for range 3 {
+		_, err := RequestFromReader(strings.NewReader("GET /other HTTP/1.1\r\nHost: y\r\nX-Long: " + other + "\r\n\r\n"))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "/path", r.RequestLine.RequestTarget)
+	v, _ = r.Headers.Get("X-Long")
+	assert.Equal(t, long, v)
+	assert.Equal(t, "NEXT", string(r.Buffered()))
+}
+
+func BenchmarkRequestFromReader(b *testing.B) {
+	data := "POST /api/items?limit=10 HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"User-Agent: bench/1.0\r\n" +
+		"Accept: application/json\r\n" +
+		"Content-Type: application/json\r\n" +
+		"Content-Length: 27\r\n" +
+		"\r\n" +
+		`{"name":"widget","qty":100}`
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := RequestFromReader(strings.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package response
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+)
+
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+const DefaultCompressMinSize = 1024 // bytes
+
+// maxCompressBufferSize is the largest fixed-length body held in memory to be
+// compressed in one go; longer bodies are compressed as they stream, using
+// chunked framing since the compressed length isn't known up front.
+const maxCompressBufferSize = 1024 * 1024
+
+// compression holds the per-response state used to transparently encode bodies
+// written through a Writer. Fixed-length bodies up to maxCompressBufferSize are
+// buffered until the declared Content-Length has been written so the
+// compressed length can be sent instead; chunked bodies, and longer
+// fixed-length ones, are encoded chunk by chunk.
+type compression struct {
+	encoding string
+	minSize  int
+	status   StatusCode
+	// toChunked is set when a fixed-length body was switched to chunked
+	// framing; the Writer then sends body writes as chunks.
+	toChunked bool
+
+	// fixed-length mode
+	pending *headers.Headers
+	length  int
+	body    bytes.Buffer
+
+	// chunked mode
+	enc io.WriteCloser
+	out bytes.Buffer
+}
+
+func newEncoder(encoding string, w io.Writer) io.WriteCloser {
+	if encoding == EncodingDeflate {
+		return zlib.NewWriter(w)
+	}
+	return gzip.NewWriter(w)
+}
+
+// prepare inspects the response headers and decides whether the body should be
+// compressed. It returns true when the headers must be held back until the
+// body is known.
+func (c *compression) prepare(h *headers.Headers) bool {
+	if c.status == StatusPartialContent || c.status < StatusOK {
+		return false
+	}
+	if _, ok := h.Get("Content-Encoding"); ok {
+		return false
+	}
+	if _, ok := h.Get("Content-Range"); ok {
+		return false
+	}
+	if ct, ok := h.Get("Content-Type"); ok && !compressibleType(ct) {
+		return false
+	}
+
+	if te, ok := h.Get("Transfer-Encoding"); ok && te == "chunked" {
+		c.streamHeaders(h)
+		return false
+	}
+
+	lengthStr, ok := h.Get("Content-Length")
+	if !ok {
+		return false
+	}
+	length, err := strconv.Atoi(lengthStr)
+	if err != nil || length < c.minSize {
+		return false
+	}
+
+	if length > maxCompressBufferSize {
+		h.Set("Transfer-Encoding", "chunked")
+		c.streamHeaders(h)
+		c.toChunked = true
+		return false
+	}
+
+	c.pending = h
+	c.length = length
+	return true
+}
+
+// streamHeaders sets up chunk-by-chunk encoding for a chunked response.
+func (c *compression) streamHeaders(h *headers.Headers) {
+	h.Set("Content-Encoding", c.encoding)
+	addVary(h, "Accept-Encoding")
+	h.Del("Content-Length")
+	c.enc = newEncoder(c.encoding, &c.out)
+}
+
+// compressibleType reports whether a body of media type ct is worth
+// compressing; images (other than SVG), audio, video and archives already are.
+func compressibleType(ct string) bool {
+	mediaType, _, _ := strings.Cut(ct, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	switch {
+	case mediaType == "image/svg+xml":
+		return true
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "audio/"):
+		return false
+	}
+
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip",
+		"application/x-bzip2", "application/x-7z-compressed", "application/x-xz",
+		"font/woff", "font/woff2":
+		return false
+	}
+	return true
+}
+
+func addVary(h *headers.Headers, name string) {
+	for _, v := range h.Values("Vary") {
+		if strings.EqualFold(v, name) {
+			return
+		}
+	}
+	h.Set("Vary", name)
+}
+
+func (c *compression) buffer(w *Writer, p []byte) error {
+	c.body.Write(p)
+	if c.body.Len() < c.length {
+		return nil
+	}
+
+	h := c.pending
+	c.pending = nil
+
+	var out bytes.Buffer
+	enc := newEncoder(c.encoding, &out)
+	if _, err := enc.Write(c.body.Bytes()); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	h.Replace("Content-Length", strconv.Itoa(out.Len()))
+	h.Set("Content-Encoding", c.encoding)
+	addVary(h, "Accept-Encoding")
+	if err := w.writeResponseHeaders(h); err != nil {
+		return err
+	}
+
+	return w.writeBodyBytes(out.Bytes())
+}
+
+// flush writes out any held headers and buffered body uncompressed. This
+// covers handlers that declared a Content-Length but wrote less (e.g. HEAD).
+func (c *compression) flush(w *Writer) error {
+	if c.pending == nil {
+		return nil
+	}
+
+	h := c.pending
+	c.pending = nil
+	if err := w.writeResponseHeaders(h); err != nil {
+		return err
+	}
+
+	return w.writeBodyBytes(c.body.Bytes())
+}
+
+func (c *compression) compressChunk(p []byte) ([]byte, error) {
+	if _, err := c.enc.Write(p); err != nil {
+		return nil, err
+	}
+	if f, ok := c.enc.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	out := bytes.Clone(c.out.Bytes())
+	c.out.Reset()
+	return out, nil
+}
+
+func (c *compression) closeChunks() ([]byte, error) {
+	if err := c.enc.Close(); err != nil {
+		return nil, err
+	}
+	c.enc = nil
+
+	out := bytes.Clone(c.out.Bytes())
+	c.out.Reset()
+	return out, nil
+}
+
+// NegotiateEncoding picks the preferred supported content-coding from an
+// Accept-Encoding value, returning "" when the body should be sent as-is.
+// Handlers with a request at hand can use req.NegotiateEncoding directly.
+func NegotiateEncoding(acceptEncoding string) string {
+	req := &request.Request{Headers: headers.NewHeaders()}
+	req.Headers.Set("Accept-Encoding", acceptEncoding)
+	return req.NegotiateEncoding(EncodingGzip, EncodingDeflate)
+}
+
+// EnableCompression makes the Writer encode eligible bodies of at least
+// minSize bytes using encoding ("gzip" or "deflate").
+func (w *Writer) EnableCompression(encoding string, minSize int) {
+	w.compression = &compression{
+		encoding: encoding,
+		minSize:  minSize,
+	}
+}
+
+// Compress returns a middleware that compresses responses for clients that
+// advertise gzip or deflate support in Accept-Encoding. It can be passed
+// directly to router.Use.
+func Compress(minSize int) func(next Handler) Handler {
+	return func(next Handler) Handler {
+		return func(w *Writer, req *request.Request) error {
+			if _, ok := req.Headers.Get("Accept-Encoding"); !ok {
+				return next(w, req)
+			}
+
+			encoding := req.NegotiateEncoding(EncodingGzip, EncodingDeflate)
+			if encoding == "" {
+				return next(w, req)
+			}
+
+			w.EnableCompression(encoding, minSize)
+			err := next(w, req)
+			if err == nil {
+				// a held-back bare body has to go out while we can still encode it
+				err = w.finishBody()
+			}
+			if fErr := w.compression.flush(w); err == nil {
+				err = fErr
+			}
+			w.compression = nil
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package response
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeChunked(t *testing.T, body string) []byte {
+	t.Helper()
+	out := []byte{}
+	for {
+		line, rest, ok := strings.Cut(body, "\r\n")
+		require.True(t, ok)
+		n, err := strconv.ParseUint(line, 16, 64)
+		require.NoError(t, err)
+		if n == 0 {
+			return out
+		}
+		out = append(out, rest[:n]...)
+		body = rest[n+2:]
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	assert.Equal(t, "gzip", NegotiateEncoding("gzip, deflate, br"))
+	assert.Equal(t, "deflate", NegotiateEncoding("deflate"))
+	assert.Equal(t, "deflate", NegotiateEncoding("gzip;q=0.5, deflate;q=0.8"))
+	assert.Equal(t, "gzip", NegotiateEncoding("*"))
+	assert.Equal(t, "", NegotiateEncoding("gzip;q=0"))
+	assert.Equal(t, "", NegotiateEncoding("br, identity"))
+	assert.Equal(t, "deflate", NegotiateEncoding("gzip;q=0, *"))
+}
+
+func TestCompressFixedLength(t *testing.T) {
+	payload := []byte(strings.Repeat("hello compression ", 200))
+	handler := func(w *Writer, req *request.Request) error {
+		return w.WriteResponse(StatusOK, GetDefaultHeaders(len(payload)), payload)
+	}
+
+	// Test: gzip applied above threshold
+	req := mkReq("GET", "/")
+	req.Headers.Set("Accept-Encoding", "gzip")
+	var buf bytes.Buffer
+	require.NoError(t, Compress(DefaultCompressMinSize)(handler)(NewWriter(&buf), req))
+
+	out := buf.String()
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Encoding: gzip\r\n")
+	assert.Contains(t, hb, "Vary: Accept-Encoding\r\n")
+	body := bodyOf(out)
+	assert.Contains(t, hb, "Content-Length: "+strconv.Itoa(len(body))+"\r\n")
+
+	zr, err := gzip.NewReader(strings.NewReader(body))
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+
+	// Test: observed headers and size reflect the compressed response
+	w := NewWriter(io.Discard)
+	require.NoError(t, Compress(DefaultCompressMinSize)(handler)(w, req))
+	ce, _ := w.WrittenHeader().Get("Content-Encoding")
+	assert.Equal(t, "gzip", ce)
+	assert.Equal(t, int64(len(body)), w.BytesWritten())
+
+	// Test: below threshold is passed through
+	req = mkReq("GET", "/")
+	req.Headers.Set("Accept-Encoding", "gzip")
+	buf.Reset()
+	require.NoError(t, Compress(len(payload)+1)(handler)(NewWriter(&buf), req))
+	assert.NotContains(t, buf.String(), "Content-Encoding")
+	assert.Equal(t, string(payload), bodyOf(buf.String()))
+
+	// Test: no Accept-Encoding is passed through
+	req = mkReq("GET", "/")
+	buf.Reset()
+	require.NoError(t, Compress(0)(handler)(NewWriter(&buf), req))
+	assert.NotContains(t, buf.String(), "Content-Encoding")
+}
+
+func TestCompressChunked(t *testing.T) {
+	payload := []byte(strings.Repeat("chunk data ", 100))
+	handler := func(w *Writer, req *request.Request) error {
+		h := GetDefaultHeaders(0)
+		h.Delete("Content-Length")
+		h.Set("Transfer-Encoding", "chunked")
+		require.NoError(t, w.WriteStatusLine(StatusOK))
+		require.NoError(t, w.WriteHeaders(h))
+		require.NoError(t, w.WriteChunk(payload[:500]))
+		require.NoError(t, w.WriteChunk(payload[500:]))
+		return w.WriteChunkEnd(false)
+	}
+
+	req := mkReq("GET", "/")
+	req.Headers.Set("Accept-Encoding", "deflate")
+	var buf bytes.Buffer
+	require.NoError(t, Compress(0)(handler)(NewWriter(&buf), req))
+
+	out := buf.String()
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Encoding: deflate\r\n")
+	assert.NotContains(t, hb, "Content-Length")
+
+	zr, err := zlib.NewReader(bytes.NewReader(decodeChunked(t, bodyOf(out))))
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestCompressBareBody(t *testing.T) {
+	payload := []byte(strings.Repeat("bare body ", 200))
+	handler := func(w *Writer, req *request.Request) error {
+		return w.WriteBody(payload)
+	}
+
+	// Test: A held-back body is compressed before the middleware returns
+	req := mkReq("GET", "/")
+	req.Headers.Set("Accept-Encoding", "gzip")
+	var buf bytes.Buffer
+	require.NoError(t, Compress(0)(handler)(NewWriter(&buf), req))
+
+	out := buf.String()
+	assert.Contains(t, headerBlock(out), "Content-Encoding: gzip\r\n")
+	zr, err := gzip.NewReader(strings.NewReader(bodyOf(out)))
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestCompressLargeFixedLength(t *testing.T) {
+	payload := []byte(strings.Repeat("large streamed body ", maxCompressBufferSize/10))
+	half := len(payload) / 2
+	var buf bytes.Buffer
+	headersBeforeBody := false
+	handler := func(w *Writer, req *request.Request) error {
+		h := GetDefaultHeaders(len(payload))
+		h.Replace("Content-Type", "text/plain")
+		require.NoError(t, w.WriteStatusLine(StatusOK))
+		require.NoError(t, w.WriteHeaders(h))
+		require.NoError(t, w.WriteBody(payload[:half]))
+		headersBeforeBody = strings.Contains(buf.String(), "\r\n\r\n")
+		return w.WriteBody(payload[half:])
+	}
+
+	req := mkReq("GET", "/")
+	req.Headers.Set("Accept-Encoding", "gzip")
+	require.NoError(t, Compress(DefaultCompressMinSize)(handler)(NewWriter(&buf), req))
+
+	// Test: Bodies too large to buffer are compressed as they stream
+	assert.True(t, headersBeforeBody)
+	out := buf.String()
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Encoding: gzip\r\n")
+	assert.Contains(t, hb, "Transfer-Encoding: chunked\r\n")
+	assert.NotContains(t, hb, "Content-Length")
+
+	zr, err := gzip.NewReader(bytes.NewReader(decodeChunked(t, bodyOf(out))))
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestCompressSkipsCompressedTypes(t *testing.T) {
+	payload := bytes.Repeat([]byte{0}, 4096)
+	for _, ct := range []string{"video/mp4", "image/png", "application/zip", "application/x-gzip"} {
+		handler := func(w *Writer, req *request.Request) error {
+			h := GetDefaultHeaders(len(payload))
+			h.Replace("Content-Type", ct)
+			return w.WriteResponse(StatusOK, h, payload)
+		}
+
+		req := mkReq("GET", "/")
+		req.Headers.Set("Accept-Encoding", "gzip")
+		var buf bytes.Buffer
+		require.NoError(t, Compress(0)(handler)(NewWriter(&buf), req))
+		assert.NotContains(t, buf.String(), "Content-Encoding", ct)
+		assert.Equal(t, string(payload), bodyOf(buf.String()), ct)
+	}
+
+	// Test: SVG is still compressed
+	assert.True(t, compressibleType("image/svg+xml; charset=utf-8"))
+	assert.True(t, compressibleType("text/html"))
+}
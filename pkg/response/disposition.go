@@ -0,0 +1,85 @@
+package response
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attachment makes the response a download saved as filename, by setting
+// Content-Disposition through Header. Follow it with ServeFile or
+// ServeContent to stream the file with range support, e.g. for resumable
+// downloads:
+//
+//	w.Attachment("report 2024.pdf")
+//	return w.ServeFile(req, path)
+//
+// Any error response written afterwards carries the field as well.
+func (w *Writer) Attachment(filename string) {
+	w.Header().Replace("Content-Disposition", contentDisposition("attachment", filename))
+}
+
+// Inline is Attachment for content meant to be shown in the browser, with
+// filename used if the user saves it.
+func (w *Writer) Inline(filename string) {
+	w.Header().Replace("Content-Disposition", contentDisposition("inline", filename))
+}
+
+// contentDisposition formats a Content-Disposition value. Directory parts and
+// control characters are dropped from filename. Names that aren't plain
+// ASCII get an RFC 5987 filename* parameter holding the UTF-8 name, after a
+// filename fallback with the other characters replaced by '_' for clients
+// that don't understand it.
+func contentDisposition(kind string, filename string) string {
+	if i := strings.LastIndexAny(filename, `/\`); i >= 0 {
+		filename = filename[i+1:]
+	}
+	filename = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, filename)
+	if filename == "" {
+		return kind
+	}
+
+	var fallback strings.Builder
+	ascii := true
+	for _, r := range filename {
+		switch {
+		case r > 0x7e:
+			ascii = false
+			fallback.WriteByte('_')
+		case r == '"' || r == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(r)
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+
+	v := fmt.Sprintf("%s; filename=\"%s\"", kind, fallback.String())
+	if !ascii {
+		v += "; filename*=UTF-8''" + encodeRFC5987(filename)
+	}
+	return v
+}
+
+// encodeRFC5987 percent-encodes the UTF-8 bytes of s that aren't attr-chars
+// in RFC 5987's ext-value syntax.
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
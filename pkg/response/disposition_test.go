@@ -0,0 +1,54 @@
+package response
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		kind, filename, want string
+	}{
+		{"attachment", "report.pdf", `attachment; filename="report.pdf"`},
+		{"inline", "my \"quoted\" file.txt", `inline; filename="my \"quoted\" file.txt"`},
+		{"attachment", "../../etc/passwd", `attachment; filename="passwd"`},
+		{"attachment", "C:\\docs\\a.txt", `attachment; filename="a.txt"`},
+		{"attachment", "evil\r\nSet-Cookie: x.txt", `attachment; filename="evilSet-Cookie: x.txt"`},
+		{"attachment", "résumé.pdf", `attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`},
+		{"attachment", "报告 1.txt", `attachment; filename="__ 1.txt"; filename*=UTF-8''%E6%8A%A5%E5%91%8A%201.txt`},
+		{"attachment", "", "attachment"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, contentDisposition(tt.kind, tt.filename), tt.filename)
+	}
+}
+
+func TestWriter_Attachment(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data.bin")
+	require.NoError(t, os.WriteFile(name, []byte("0123456789"), 0o644))
+
+	// Test: The download is streamed with the disposition and ranges still work
+	req := mkReq("GET", "/download")
+	req.Headers.Set("Range", "bytes=4-")
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Attachment("données.bin")
+	require.NoError(t, w.ServeFile(req, name))
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 206 Partial Content\r\n", statusLineOf(out))
+	assert.Contains(t, headerBlock(out), "Content-Disposition: attachment; filename=\"donn_es.bin\"; filename*=UTF-8''donn%C3%A9es.bin\r\n")
+	assert.Equal(t, "456789", bodyOf(out))
+
+	// Test: Inline
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.Inline("data.bin")
+	require.NoError(t, w.ServeFile(mkReq("GET", "/view"), name))
+	assert.Contains(t, headerBlock(buf.String()), "Content-Disposition: inline; filename=\"data.bin\"\r\n")
+}
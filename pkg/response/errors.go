@@ -0,0 +1,98 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+)
+
+// HTTPError is an error that carries the status code (and client-facing
+// message) it should be answered with. Handlers return it instead of writing
+// an error response themselves.
+type HTTPError struct {
+	Status  StatusCode
+	Message string
+	Err     error
+}
+
+func NewHTTPError(status StatusCode, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+// WrapHTTPError attaches a status and message to an underlying error.
+func WrapHTTPError(status StatusCode, message string, err error) *HTTPError {
+	return &HTTPError{Status: status, Message: message, Err: err}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorStatus returns the status an error is answered with: the status of an
+// *HTTPError in its chain, 415, 413 or 400 for a *request.BindError, or 500
+// for anything else.
+func ErrorStatus(err error) StatusCode {
+	status, _ := errorResponse(err)
+	return status
+}
+
+// errorResponse returns the status and client-facing message for err.
+func errorResponse(err error) (StatusCode, string) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Status, httpErr.Message
+	}
+
+	var bindErr *request.BindError
+	if errors.As(err, &bindErr) {
+		switch {
+		case errors.Is(bindErr, request.ErrNotJSON):
+			return StatusUnsupportedMediaType, bindErr.Message
+		case errors.Is(bindErr, request.ErrBodyTooLarge):
+			return StatusContentTooLarge, bindErr.Message
+		}
+		return StatusBadRequest, bindErr.Message
+	}
+
+	var paramErr *request.ParamError
+	if errors.As(err, &paramErr) {
+		return StatusBadRequest, paramErr.Message
+	}
+
+	return StatusInternalServerError, ""
+}
+
+type ErrorHandler func(w *Writer, req *request.Request, err error) error
+
+// DefaultErrorHandler answers an *HTTPError, *request.BindError or
+// *request.ParamError with its status and message and any other error with
+// an empty 500. Nothing is written if the handler already started its
+// response.
+func DefaultErrorHandler(w *Writer, req *request.Request, err error) error {
+	if w.Written() {
+		return err
+	}
+
+	status, message := errorResponse(err)
+	body := []byte(message)
+
+	h := GetDefaultHeaders(len(body))
+	h.Replace("Content-Type", "text/plain")
+	if wErr := w.WriteResponse(status, h, body); wErr != nil {
+		return wErr
+	}
+
+	// internal errors are still reported so they get logged
+	if status >= StatusInternalServerError {
+		return err
+	}
+	return nil
+}
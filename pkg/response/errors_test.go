@@ -0,0 +1,91 @@
+package response
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPError(t *testing.T) {
+	inner := errors.New("db down")
+	err := WrapHTTPError(StatusInternalServerError, "try again later", inner)
+	assert.True(t, errors.Is(err, inner))
+	assert.Equal(t, "500 try again later: db down", err.Error())
+	assert.Equal(t, "404 no such user", NewHTTPError(StatusNotFound, "no such user").Error())
+}
+
+func TestDefaultErrorHandler(t *testing.T) {
+	// Test: HTTPError is written with its status and message
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	err := DefaultErrorHandler(w, mkReq("GET", "/"), NewHTTPError(StatusBadRequest, "bad id"))
+	require.NoError(t, err)
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 400 Bad Request\r\n", statusLineOf(out))
+	assert.Equal(t, "bad id", bodyOf(out))
+
+	// Test: Plain errors become a 500 and are still returned for logging
+	buf.Reset()
+	w = NewWriter(&buf)
+	plain := errors.New("boom")
+	err = DefaultErrorHandler(w, mkReq("GET", "/"), plain)
+	assert.Equal(t, plain, err)
+	assert.Equal(t, "HTTP/1.1 500 Internal Server Error\r\n", statusLineOf(buf.String()))
+
+	// Test: Nothing is written once the response has started
+	buf.Reset()
+	w = NewWriter(&buf)
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	err = DefaultErrorHandler(w, mkReq("GET", "/"), NewHTTPError(StatusBadRequest, "late"))
+	require.Error(t, err)
+	assert.False(t, strings.Contains(buf.String(), "400"))
+}
+
+func TestDefaultErrorHandler_BindError(t *testing.T) {
+	cases := []struct {
+		contentType string
+		body        string
+		status      string
+		message     string
+	}{
+		{"text/plain", "{}", "HTTP/1.1 415 Unsupported Media Type\r\n", "content-type must be application/json"},
+		{"application/json", `{"a":`, "HTTP/1.1 400 Bad Request\r\n", "malformed JSON: unexpected end of body"},
+	}
+
+	for _, tc := range cases {
+		req := mkReq("POST", "/")
+		req.Headers.Set("Content-Type", tc.contentType)
+		req.Body = []byte(tc.body)
+		var v map[string]any
+		bindErr := req.BindJSON(&v)
+
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		require.NoError(t, DefaultErrorHandler(w, req, bindErr))
+		assert.Equal(t, tc.status, statusLineOf(buf.String()))
+		assert.Equal(t, tc.message, bodyOf(buf.String()))
+	}
+
+	req := mkReq("POST", "/")
+	req.Headers.Set("Content-Type", "application/json")
+	req.Body = []byte("[]")
+	err := req.BindJSONWith(&[]int{}, request.BindOptions{MaxBodySize: 1})
+	assert.Equal(t, StatusContentTooLarge, ErrorStatus(err))
+}
+
+func TestDefaultErrorHandler_ParamError(t *testing.T) {
+	req := mkReq("GET", "/users/abc")
+	req.PathParams = map[string]string{"id": "abc"}
+	_, err := req.ParamInt("id")
+
+	var buf bytes.Buffer
+	require.NoError(t, DefaultErrorHandler(NewWriter(&buf), req, fmt.Errorf("loading user: %w", err)))
+	assert.Equal(t, "HTTP/1.1 400 Bad Request\r\n", statusLineOf(buf.String()))
+	assert.Equal(t, `path parameter "id" must be an integer`, bodyOf(buf.String()))
+}
@@ -0,0 +1,152 @@
+package response
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+)
+
+const DefaultETagMaxSize = 1024 * 1024 // bytes
+
+type ETagOptions struct {
+	// MinSize is the smallest body that gets an ETag; smaller ones aren't
+	// worth the round trip.
+	MinSize int
+	// MaxSize caps the response, header block included, held in memory to
+	// be hashed; larger responses are streamed without an ETag. Defaults to
+	// DefaultETagMaxSize.
+	MaxSize int
+	// Exclude, when set, is asked about every response that would get an
+	// ETag, with the header fields about to be sent. Returning true sends the
+	// response as it is.
+	Exclude func(req *request.Request, h *headers.Headers) bool
+}
+
+// spillWriter holds output back in memory until it grows past limit or is
+// flushed, after which everything goes straight to w.
+type spillWriter struct {
+	w       io.Writer
+	buf     bytes.Buffer
+	limit   int
+	spilled bool
+}
+
+func (s *spillWriter) Write(p []byte) (int, error) {
+	if !s.spilled && s.buf.Len()+len(p) <= s.limit {
+		return s.buf.Write(p)
+	}
+	if err := s.spill(); err != nil {
+		return 0, err
+	}
+	return s.w.Write(p)
+}
+
+// spill sends what was held back and stops holding back.
+func (s *spillWriter) spill() error {
+	if s.spilled {
+		return nil
+	}
+	s.spilled = true
+	_, err := s.w.Write(s.buf.Bytes())
+	s.buf.Reset()
+	return err
+}
+
+// Flush spills, since a handler flushing (e.g. for server-sent events) wants
+// its output on the wire now.
+func (s *spillWriter) Flush() error {
+	if err := s.spill(); err != nil {
+		return err
+	}
+	if f, ok := s.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func makeBodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf("\"%x\"", sum[:16])
+}
+
+// ETagHandler runs h with its output held back, so that a complete 200
+// response to a GET with a Content-Length body can be sent with an ETag
+// hashed from the body. If the request's If-None-Match lists that tag the
+// client gets a 304 Not Modified instead, saving the body. Responses that
+// already carry an ETag, are chunked, streamed (flushed or hijacked) or
+// outside opts' size bounds pass through unchanged.
+func ETagHandler(h Handler, opts ETagOptions) Handler {
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultETagMaxSize
+	}
+
+	return func(w *Writer, req *request.Request) error {
+		if req.RequestLine.Method != "GET" {
+			return h(w, req)
+		}
+
+		rec := &spillWriter{w: w.writer, limit: maxSize}
+		d := w.split(rec)
+		err := h(d, req)
+		if err == nil {
+			// a held-back bare body gets its framing now, so it can be hashed
+			err = d.finishBody()
+		}
+		w.attach(d)
+
+		if err != nil || rec.spilled || !w.taggable(opts.MinSize) ||
+			opts.Exclude != nil && opts.Exclude(req, w.sentHeader) {
+			if sErr := rec.spill(); err == nil {
+				err = sErr
+			}
+			return err
+		}
+
+		out := rec.buf.Bytes()
+		statusLine := out[:bytes.Index(out, []byte("\r\n"))+2]
+		body := out[len(out)-int(w.bodyBytes):]
+		h := w.sentHeader
+		etag := makeBodyETag(body)
+		h.Replace("ETag", etag)
+
+		if inm, ok := req.Headers.Get("If-None-Match"); ok && etagMatches(inm, etag) {
+			statusLine, _ = statusLineFor(StatusNotModified, StatusText(StatusNotModified))
+			w.status = StatusNotModified
+			h.Del("Content-Length")
+			h.Del("Content-Type")
+			h.Del("Content-Encoding")
+			body = nil
+		}
+
+		w.bodyBytes = 0
+		if err := w.write(statusLine); err != nil {
+			return err
+		}
+		if err := w.writeResponseHeaders(h); err != nil {
+			return err
+		}
+		return w.writeBodyBytes(body)
+	}
+}
+
+// taggable reports whether the response w holds is a complete 200 with a
+// Content-Length body of at least minSize bytes and no ETag yet.
+func (w *Writer) taggable(minSize int) bool {
+	if w.hijacked || w.status != StatusOK || w.sentHeader == nil || w.bodyBytes < int64(minSize) {
+		return false
+	}
+	if _, ok := w.sentHeader.Get("ETag"); ok {
+		return false
+	}
+	if _, ok := w.sentHeader.Get("Transfer-Encoding"); ok {
+		return false
+	}
+	cl, ok := w.sentHeader.Get("Content-Length")
+	return ok && cl == strconv.FormatInt(w.bodyBytes, 10)
+}
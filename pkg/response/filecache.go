@@ -0,0 +1,194 @@
+package response
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"io/fs"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+)
+
+const (
+	DefaultFileCacheMaxFileSize = 64 * 1024       // bytes
+	DefaultFileCacheMaxSize     = 8 * 1024 * 1024 // bytes
+	DefaultFileCacheRevalidate  = time.Second
+)
+
+type FileCacheOptions struct {
+	// MaxFileSize is the largest file kept in memory; larger ones are read
+	// from the file system on every request. Defaults to
+	// DefaultFileCacheMaxFileSize.
+	MaxFileSize int64
+	// MaxSize caps the memory held by the cache, gzip variants included.
+	// The least recently served files are dropped to stay under it. Defaults
+	// to DefaultFileCacheMaxSize.
+	MaxSize int64
+	// Revalidate is how long a cached file is served before its modification
+	// time and size are checked against the file system again. Defaults to
+	// DefaultFileCacheRevalidate.
+	Revalidate time.Duration
+}
+
+// cachedFile is a file held in memory by a fileCache. All fields but checked
+// and elem are fixed once it is loaded.
+type cachedFile struct {
+	name    string
+	data    []byte
+	gz      []byte // nil when not worth compressing
+	modtime time.Time
+	size    int64
+	etag    string
+	gzETag  string
+	checked time.Time
+	elem    *list.Element
+}
+
+// fileCache keeps small files of one fs.FS in memory, with their ETags and
+// gzip variants worked out once, so serving them takes no system calls until
+// they are due to be revalidated.
+type fileCache struct {
+	maxFileSize int64
+	maxSize     int64
+	revalidate  time.Duration
+
+	mu      sync.Mutex
+	files   map[string]*cachedFile
+	lru     *list.List // of *cachedFile, most recently served first
+	curSize int64
+}
+
+func newFileCache(opts FileCacheOptions) *fileCache {
+	c := &fileCache{
+		maxFileSize: opts.MaxFileSize,
+		maxSize:     opts.MaxSize,
+		revalidate:  opts.Revalidate,
+		files:       make(map[string]*cachedFile),
+		lru:         list.New(),
+	}
+	if c.maxFileSize <= 0 {
+		c.maxFileSize = DefaultFileCacheMaxFileSize
+	}
+	if c.maxSize <= 0 {
+		c.maxSize = DefaultFileCacheMaxSize
+	}
+	if c.revalidate <= 0 {
+		c.revalidate = DefaultFileCacheRevalidate
+	}
+	return c
+}
+
+// lookup returns the cached copy of the file name in fsys, loading or
+// reloading it as needed, or nil when it has to be served from fsys: it is
+// missing, not a regular file, too large, or has precompressed siblings.
+func (c *fileCache) lookup(fsys fs.FS, name string) *cachedFile {
+	now := time.Now()
+	c.mu.Lock()
+	f := c.files[name]
+	if f != nil && now.Sub(f.checked) < c.revalidate {
+		c.lru.MoveToFront(f.elem)
+		c.mu.Unlock()
+		return f
+	}
+	c.mu.Unlock()
+
+	info, err := fs.Stat(fsys, name)
+	if err == nil && f != nil && info.ModTime().Equal(f.modtime) && info.Size() == f.size {
+		c.mu.Lock()
+		f.checked = now
+		c.mu.Unlock()
+		return f
+	}
+
+	if err == nil && info.Mode().IsRegular() && info.Size() <= c.maxFileSize {
+		f = loadCachedFile(fsys, name, info)
+	} else {
+		f = nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old := c.files[name]; old != nil {
+		c.lru.Remove(old.elem)
+		c.curSize -= old.memSize()
+		delete(c.files, name)
+	}
+	if f == nil || f.memSize() > c.maxSize {
+		return nil
+	}
+
+	f.checked = now
+	f.elem = c.lru.PushFront(f)
+	c.files[name] = f
+	c.curSize += f.memSize()
+	for c.curSize > c.maxSize {
+		last := c.lru.Remove(c.lru.Back()).(*cachedFile)
+		c.curSize -= last.memSize()
+		delete(c.files, last.name)
+	}
+	return f
+}
+
+// loadCachedFile reads the file name, which info describes, or returns nil
+// if it can't or shouldn't be cached.
+func loadCachedFile(fsys fs.FS, name string, info fs.FileInfo) *cachedFile {
+	for _, p := range precompressed {
+		if _, err := fs.Stat(fsys, name+p.suffix); err == nil {
+			return nil // ServeFileFS picks between the variants
+		}
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil || int64(len(data)) != info.Size() {
+		return nil
+	}
+
+	f := &cachedFile{
+		name:    name,
+		data:    data,
+		modtime: info.ModTime(),
+		size:    info.Size(),
+		etag:    makeETag(info.ModTime(), info.Size()),
+	}
+	if f.modtime.IsZero() {
+		f.etag = makeBodyETag(data)
+	}
+
+	// a compressed body can't be sniffed, so only files typed by their
+	// extension get a gzip variant
+	ct := mime.TypeByExtension(path.Ext(name))
+	if ct != "" && compressibleType(ct) && len(data) >= DefaultCompressMinSize {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err == nil && zw.Close() == nil && buf.Len() < len(data) {
+			f.gz = buf.Bytes()
+			f.gzETag = strings.TrimSuffix(f.etag, `"`) + `-gzip"`
+		}
+	}
+	return f
+}
+
+func (f *cachedFile) memSize() int64 {
+	return int64(len(f.data) + len(f.gz))
+}
+
+// serveCachedFile is ServeFileFS for a file held by a fileCache, sending its
+// gzip variant to clients that accept it.
+func (w *Writer) serveCachedFile(req *request.Request, f *cachedFile) error {
+	h := GetDefaultHeaders(0)
+	data, etag := f.data, f.etag
+	if f.gz != nil {
+		addVary(h, "Accept-Encoding")
+		if _, ok := req.Headers.Get("Accept-Encoding"); ok && req.NegotiateEncoding(EncodingGzip) != "" {
+			data, etag = f.gz, f.gzETag
+			h.Set("Content-Encoding", EncodingGzip)
+		}
+	}
+	h.Set("ETag", etag)
+	return w.serveContent(req, f.name, f.modtime, bytes.NewReader(data), h)
+}
@@ -0,0 +1,103 @@
+package response
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileServer_Cache(t *testing.T) {
+	root := t.TempDir()
+	css := strings.Repeat("p { color: red; }\n", 100)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.css"), []byte(css), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("aaaa"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "big.txt"), []byte("0123456789"), 0o644))
+
+	serve := func(h Handler, target, acceptEncoding string) string {
+		req := mkReq("GET", target)
+		if acceptEncoding != "" {
+			req.Headers.Set("Accept-Encoding", acceptEncoding)
+		}
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		require.NoError(t, h(w, req))
+		require.NoError(t, w.Finish())
+		return buf.String()
+	}
+	h := FileServer(root, FileServerOptions{Cache: &FileCacheOptions{MaxFileSize: 8, Revalidate: time.Hour}})
+
+	// Test: Cached files are served from memory until revalidated
+	assert.Equal(t, "aaaa", bodyOf(serve(h, "/a.txt", "")))
+	require.NoError(t, os.Remove(filepath.Join(root, "a.txt")))
+	assert.Equal(t, "aaaa", bodyOf(serve(h, "/a.txt", "")))
+
+	// Test: Files over MaxFileSize are read every time
+	assert.Equal(t, "0123456789", bodyOf(serve(h, "/big.txt", "")))
+	require.NoError(t, os.Remove(filepath.Join(root, "big.txt")))
+	assert.Equal(t, "HTTP/1.1 404 Not Found\r\n", statusLineOf(serve(h, "/big.txt", "")))
+
+	// Test: The gzip variant goes to clients that accept it, with its own ETag
+	h = FileServer(root, FileServerOptions{Cache: &FileCacheOptions{}})
+	plain := serve(h, "/app.css", "")
+	assert.Equal(t, css, bodyOf(plain))
+	assert.Contains(t, headerBlock(plain), "Vary: Accept-Encoding\r\n")
+	out := serve(h, "/app.css", "gzip")
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Encoding: gzip\r\n")
+	assert.Contains(t, hb, "Content-Type: text/css; charset=utf-8\r\n")
+	assert.NotEqual(t, headerOf(plain, "ETag"), headerOf(out, "ETag"))
+	zr, err := gzip.NewReader(strings.NewReader(bodyOf(out)))
+	require.NoError(t, err)
+	unzipped, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, css, string(unzipped))
+
+	// Test: A changed modification time reloads the file
+	h = FileServer(root, FileServerOptions{Cache: &FileCacheOptions{Revalidate: time.Nanosecond}})
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.txt"), []byte("old"), 0o644))
+	assert.Equal(t, "old", bodyOf(serve(h, "/b.txt", "")))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.txt"), []byte("new"), 0o644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "b.txt"), later, later))
+	assert.Equal(t, "new", bodyOf(serve(h, "/b.txt", "")))
+}
+
+func TestFileCache_Evicts(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte("1234"), 0o644))
+	}
+	fsys := os.DirFS(root)
+	c := newFileCache(FileCacheOptions{MaxSize: 8, Revalidate: time.Hour})
+
+	// Test: The least recently served file makes room
+	require.NotNil(t, c.lookup(fsys, "a"))
+	require.NotNil(t, c.lookup(fsys, "b"))
+	require.NotNil(t, c.lookup(fsys, "a"))
+	require.NotNil(t, c.lookup(fsys, "c"))
+	assert.Contains(t, c.files, "a")
+	assert.NotContains(t, c.files, "b")
+	assert.Contains(t, c.files, "c")
+	assert.Equal(t, int64(8), c.curSize)
+
+	// Test: Missing files and directories aren't cached
+	assert.Nil(t, c.lookup(fsys, "missing"))
+	assert.Nil(t, c.lookup(fsys, "."))
+}
+
+func headerOf(out string, name string) string {
+	for _, line := range strings.Split(headerBlock(out), "\r\n") {
+		if k, v, ok := strings.Cut(line, ": "); ok && strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,197 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+)
+
+type FileServerOptions struct {
+	// StripPrefix is removed from the request path before it is looked up
+	// under the root, e.g. "/static" for files served at /static/...
+	StripPrefix string
+	// Index is the file served for a directory; defaults to index.html.
+	Index string
+	// Browse lists directories without an index file, as HTML or, for
+	// clients that prefer it, JSON. Listings show every file name under the
+	// root, so leave it off in production unless that's intended.
+	Browse bool
+	// Cache, when set, keeps small files in memory, saving the system calls
+	// of opening, checking and reading them on every request.
+	Cache *FileCacheOptions
+}
+
+// FileServer returns a handler serving GET and HEAD requests with the files
+// under root. Paths are cleaned before use, so requests can't climb out of
+// root with "..". A directory is served the same with or without a trailing
+// slash, which routers normalize away (see router.PathNormalize), so links in
+// an index file should be absolute; listings link that way.
+func FileServer(root string, opts FileServerOptions) Handler {
+	return FileServerFS(os.DirFS(root), opts)
+}
+
+// FileServerFS is FileServer for the files of fsys, via ServeFileFS. With an
+// embed.FS, a single binary can ship its assets:
+//
+//	//go:embed static
+//	var static embed.FS
+//
+//	assets, _ := fs.Sub(static, "static")
+//	handler := response.FileServerFS(assets, response.FileServerOptions{StripPrefix: "/static"})
+func FileServerFS(fsys fs.FS, opts FileServerOptions) Handler {
+	index := opts.Index
+	if index == "" {
+		index = "index.html"
+	}
+	var cache *fileCache
+	if opts.Cache != nil {
+		cache = newFileCache(*opts.Cache)
+	}
+
+	return func(w *Writer, req *request.Request) error {
+		if m := req.RequestLine.Method; m != "GET" && m != "HEAD" {
+			h := GetDefaultHeaders(0)
+			h.Set("Allow", "GET, HEAD")
+			return w.writeTextError(StatusMethodNotAllowed, h, "method not allowed")
+		}
+
+		urlPath := req.RequestLine.RequestTarget
+		if req.URL != nil {
+			urlPath = req.URL.Path
+		}
+		rel, ok := strings.CutPrefix(urlPath, opts.StripPrefix)
+		if !ok {
+			return w.writeTextError(StatusNotFound, GetDefaultHeaders(0), "not found")
+		}
+		name := strings.TrimPrefix(path.Clean("/"+rel), "/")
+		if name == "" {
+			name = "."
+		}
+		if cache != nil {
+			if f := cache.lookup(fsys, name); f != nil {
+				return w.serveCachedFile(req, f)
+			}
+		}
+
+		info, err := fs.Stat(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			return w.writeTextError(StatusNotFound, GetDefaultHeaders(0), "not found")
+		}
+		if err != nil {
+			return w.writeTextError(StatusInternalServerError, GetDefaultHeaders(0), "error loading content")
+		}
+		if !info.IsDir() {
+			return w.ServeFileFS(req, fsys, name)
+		}
+
+		indexName := path.Join(name, index)
+		if cache != nil {
+			if f := cache.lookup(fsys, indexName); f != nil {
+				return w.serveCachedFile(req, f)
+			}
+		}
+		if _, err := fs.Stat(fsys, indexName); err == nil {
+			return w.ServeFileFS(req, fsys, indexName)
+		}
+		if !opts.Browse {
+			return w.writeTextError(StatusNotFound, GetDefaultHeaders(0), "not found")
+		}
+		return w.serveDirListing(req, path.Clean("/"+urlPath), fsys, name, name != ".")
+	}
+}
+
+// dirEntry is one line of a directory listing.
+type dirEntry struct {
+	Name    string    `json:"name"`
+	Dir     bool      `json:"dir"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+func readDirEntries(fsys fs.FS, dir string) ([]dirEntry, error) {
+	des, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]dirEntry, 0, len(des))
+	for _, de := range des {
+		info, err := de.Info()
+		if err != nil {
+			continue // removed since ReadDir
+		}
+		e := dirEntry{Name: de.Name(), Dir: de.IsDir(), ModTime: info.ModTime().UTC()}
+		if !e.Dir {
+			e.Size = info.Size()
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Dir != entries[j].Dir {
+			return entries[i].Dir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// serveDirListing answers with the entries of dir in fsys, requested as the
+// clean urlPath, as JSON or HTML depending on the Accept header.
+func (w *Writer) serveDirListing(req *request.Request, urlPath string, fsys fs.FS, dir string, hasParent bool) error {
+	entries, err := readDirEntries(fsys, dir)
+	if err != nil {
+		return w.writeTextError(StatusInternalServerError, GetDefaultHeaders(0), "error loading content")
+	}
+
+	var body []byte
+	h := GetDefaultHeaders(0)
+	h.Set("Vary", "Accept")
+	if req.Negotiate("text/html", "application/json") == "application/json" {
+		if body, err = json.Marshal(entries); err != nil {
+			return err
+		}
+		h.Replace("Content-Type", "application/json")
+	} else {
+		body = dirListingHTML(urlPath, entries, hasParent)
+		h.Replace("Content-Type", "text/html; charset=utf-8")
+	}
+
+	h.Replace("Content-Length", strconv.Itoa(len(body)))
+	return w.WriteResponse(StatusOK, h, body)
+}
+
+func dirListingHTML(urlPath string, entries []dirEntry, hasParent bool) []byte {
+	title := html.EscapeString("Index of " + urlPath)
+	b := fmt.Appendf(nil, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n<body>\n<h1>%s</h1>\n<table>\n", title, title)
+	b = append(b, "<tr><th>Name</th><th>Size</th><th>Modified</th></tr>\n"...)
+	// links are absolute, as the listing is served with or without the
+	// trailing slash relative links would need
+	if hasParent {
+		href := (&url.URL{Path: path.Dir(urlPath)}).EscapedPath()
+		b = fmt.Appendf(b, "<tr><td><a href=\"%s\">../</a></td><td></td><td></td></tr>\n", html.EscapeString(href))
+	}
+
+	for _, e := range entries {
+		name, size := e.Name, strconv.FormatInt(e.Size, 10)
+		href := (&url.URL{Path: path.Join(urlPath, e.Name)}).EscapedPath()
+		if e.Dir {
+			name, size = name+"/", "-"
+		}
+		b = fmt.Appendf(b, "<tr><td><a href=\"%s\">%s</a></td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(href), html.EscapeString(name), size, e.ModTime.Format(TimeFormat))
+	}
+
+	return append(b, "</table>\n</body>\n</html>\n"...)
+}
@@ -0,0 +1,127 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileServer(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.js"), []byte("console.log(1)"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "site"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "site", "index.html"), []byte("<p>site</p>"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "files", "sub dir"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "files", "<b>&.txt"), []byte("12345"), 0o644))
+
+	serve := func(h Handler, method, target, accept string) string {
+		req := mkReq(method, target)
+		if accept != "" {
+			req.Headers.Set("Accept", accept)
+		}
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		if method == "HEAD" {
+			w.SetHEAD() // as the server does
+		}
+		require.NoError(t, h(w, req))
+		require.NoError(t, w.Finish())
+		return buf.String()
+	}
+	h := FileServer(root, FileServerOptions{StripPrefix: "/static"})
+
+	// Test: Files, and index files with or without the trailing slash
+	out := serve(h, "GET", "/static/app.js", "")
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.Equal(t, "console.log(1)", bodyOf(out))
+	assert.Equal(t, "<p>site</p>", bodyOf(serve(h, "GET", "/static/site/", "")))
+	out = serve(h, "GET", "/static/site", "")
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.Equal(t, "<p>site</p>", bodyOf(out))
+
+	// Test: Missing files, escapes from the root and other prefixes are 404s
+	for _, target := range []string{"/static/nope.js", "/static/../fileserver_test.go", "/other/app.js"} {
+		assert.Equal(t, "HTTP/1.1 404 Not Found\r\n", statusLineOf(serve(h, "GET", target, "")), target)
+	}
+	out = serve(h, "POST", "/static/app.js", "")
+	assert.Equal(t, "HTTP/1.1 405 Method Not Allowed\r\n", statusLineOf(out))
+	assert.Contains(t, out, "Allow: GET, HEAD\r\n")
+
+	// Test: Directories without an index are only listed when browsing
+	assert.Equal(t, "HTTP/1.1 404 Not Found\r\n", statusLineOf(serve(h, "GET", "/static/files/", "")))
+
+	h = FileServer(root, FileServerOptions{StripPrefix: "/static", Browse: true})
+	out = serve(h, "GET", "/static/files/", "")
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.Contains(t, out, "Content-Type: text/html; charset=utf-8\r\n")
+	assert.Contains(t, out, "Vary: Accept\r\n")
+	body := bodyOf(out)
+	assert.Contains(t, body, "<title>Index of /static/files</title>")
+	assert.Contains(t, body, `<a href="/static">../</a>`)
+	assert.Contains(t, body, `<a href="/static/files/sub%20dir">sub dir/</a>`)
+	assert.Contains(t, body, `<a href="/static/files/%3Cb%3E&amp;.txt">&lt;b&gt;&amp;.txt</a></td><td>5</td>`)
+	assert.NotContains(t, body, "<b>")
+	assert.Less(t, bytes.Index([]byte(body), []byte("sub dir")), bytes.Index([]byte(body), []byte("&lt;b&gt;")))
+	assert.NotContains(t, bodyOf(serve(h, "GET", "/static/", "")), `>../</a>`)
+	assert.Equal(t, body, bodyOf(serve(h, "GET", "/static/files", "")))
+
+	out = serve(h, "GET", "/static/files/", "application/json")
+	assert.Contains(t, out, "Content-Type: application/json\r\n")
+	var entries []dirEntry
+	require.NoError(t, json.Unmarshal([]byte(bodyOf(out)), &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "sub dir", entries[0].Name)
+	assert.True(t, entries[0].Dir)
+	assert.Equal(t, "<b>&.txt", entries[1].Name)
+	assert.Equal(t, int64(5), entries[1].Size)
+
+	out = serve(h, "HEAD", "/static/files/", "")
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.Contains(t, out, "Content-Length: "+strconv.Itoa(len(body))+"\r\n")
+	assert.Empty(t, bodyOf(out))
+}
+
+func TestFileServerFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":          {Data: []byte("console.log(1)")},
+		"site/index.html": {Data: []byte("<p>site</p>")},
+		"files/a.txt":     {Data: []byte("12345")},
+	}
+	serve := func(h Handler, method, target string, set map[string]string) string {
+		req := mkReq(method, target)
+		for k, v := range set {
+			req.Headers.Set(k, v)
+		}
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		require.NoError(t, h(w, req))
+		require.NoError(t, w.Finish())
+		return buf.String()
+	}
+	h := FileServerFS(fsys, FileServerOptions{StripPrefix: "/static", Browse: true})
+
+	// Test: Files and index files are served from the fs.FS
+	out := serve(h, "GET", "/static/app.js", nil)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.Equal(t, "console.log(1)", bodyOf(out))
+	assert.NotContains(t, out, "Last-Modified:")
+	assert.Equal(t, "<p>site</p>", bodyOf(serve(h, "GET", "/static/site/", nil)))
+	assert.Equal(t, "HTTP/1.1 404 Not Found\r\n", statusLineOf(serve(h, "GET", "/static/../app.js/x", nil)))
+	assert.Contains(t, bodyOf(serve(h, "GET", "/static/files/", nil)), `<a href="/static/files/a.txt">a.txt</a>`)
+
+	// Test: Without a modtime the ETag comes from the content
+	etag := makeBodyETag([]byte("console.log(1)"))
+	assert.Contains(t, headerBlock(out), "ETag: "+etag+"\r\n")
+	out = serve(h, "GET", "/static/app.js", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, "HTTP/1.1 304 Not Modified\r\n", statusLineOf(out))
+	out = serve(h, "GET", "/static/app.js", map[string]string{"Range": "bytes=8-"})
+	assert.Equal(t, "HTTP/1.1 206 Partial Content\r\n", statusLineOf(out))
+	assert.Equal(t, "log(1)", bodyOf(out))
+}
@@ -0,0 +1,157 @@
+package response
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const DefaultJSONStreamFlushInterval = time.Second
+
+// jsonStreamChunkSize is how much encoded output a JSONStream collects
+// before sending it as a chunk, flush or not.
+const jsonStreamChunkSize = 32 * 1024
+
+type JSONStreamOptions struct {
+	// Array sends the values as the elements of one JSON array, for clients
+	// that expect a single document, instead of newline-delimited JSON.
+	Array bool
+	// FlushInterval is how long encoded values may wait in memory before they
+	// are flushed to the client. Defaults to DefaultJSONStreamFlushInterval.
+	FlushInterval time.Duration
+}
+
+// JSONStream writes a chunked response of JSON values as they are produced,
+// for large or unbounded result sets: newline-delimited JSON
+// (application/x-ndjson) by default, or a well-formed JSON array. Values are
+// collected into chunks and flushed at least every FlushInterval, checked
+// as values are encoded. Close must be called to end the response.
+type JSONStream struct {
+	w             *Writer
+	array         bool
+	flushInterval time.Duration
+	pending       []byte
+	count         int
+	lastFlush     time.Time
+}
+
+// NewJSONStream writes the 200 header block of a chunked JSON response and
+// returns a JSONStream for sending its values.
+func NewJSONStream(w *Writer, opts JSONStreamOptions) (*JSONStream, error) {
+	h := GetDefaultHeaders(0)
+	h.Del("Content-Length")
+	h.Set("Transfer-Encoding", "chunked")
+	if opts.Array {
+		h.Replace("Content-Type", "application/json")
+	} else {
+		h.Replace("Content-Type", "application/x-ndjson")
+	}
+
+	if err := w.WriteStatusLine(StatusOK); err != nil {
+		return nil, err
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return nil, err
+	}
+
+	s := &JSONStream{
+		w:             w,
+		array:         opts.Array,
+		flushInterval: opts.FlushInterval,
+		lastFlush:     time.Now(),
+	}
+	if s.flushInterval <= 0 {
+		s.flushInterval = DefaultJSONStreamFlushInterval
+	}
+	return s, nil
+}
+
+// Encode adds v to the stream. A value that can't be marshaled is reported
+// without anything being written, so the stream can go on.
+func (s *JSONStream) Encode(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !s.array:
+		b = append(b, '\n')
+	case s.count == 0:
+		s.pending = append(s.pending, '[')
+	default:
+		s.pending = append(s.pending, ',')
+	}
+	s.pending = append(s.pending, b...)
+	s.count++
+
+	if time.Since(s.lastFlush) >= s.flushInterval {
+		return s.Flush()
+	}
+	if len(s.pending) >= jsonStreamChunkSize {
+		return s.sendPending()
+	}
+	return nil
+}
+
+// Flush sends the values encoded so far to the client.
+func (s *JSONStream) Flush() error {
+	if err := s.sendPending(); err != nil {
+		return err
+	}
+	s.lastFlush = time.Now()
+	return s.w.Flush()
+}
+
+func (s *JSONStream) sendPending() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	err := s.w.WriteChunk(s.pending)
+	s.pending = s.pending[:0]
+	return err
+}
+
+// Close sends what is left, closing the array if there is one, and ends the
+// response.
+func (s *JSONStream) Close() error {
+	if s.array {
+		if s.count == 0 {
+			s.pending = append(s.pending, '[')
+		}
+		s.pending = append(s.pending, ']')
+	}
+	if err := s.sendPending(); err != nil {
+		return err
+	}
+	return s.w.WriteChunkEnd(false)
+}
+
+// StreamJSON answers with the values received from ch as newline-delimited
+// JSON, flushed every DefaultJSONStreamFlushInterval while values keep
+// coming, and ends the response once ch is closed. It gives up at the first
+// value that can't be marshaled or written without draining ch, so producers
+// should also watch the request's context.
+func (w *Writer) StreamJSON(ch <-chan any) error {
+	s, err := NewJSONStream(w, JSONStreamOptions{})
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return s.Close()
+			}
+			if err := s.Encode(v); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
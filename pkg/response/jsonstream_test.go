@@ -0,0 +1,92 @@
+package response
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dechunk strips the chunk framing from a chunked body.
+func dechunk(t *testing.T, body string) string {
+	var out strings.Builder
+	for {
+		sizeLine, rest, ok := strings.Cut(body, "\r\n")
+		require.True(t, ok, "unterminated chunk size")
+		size, err := strconv.ParseInt(sizeLine, 16, 64)
+		require.NoError(t, err)
+		if size == 0 {
+			return out.String()
+		}
+		out.WriteString(rest[:size])
+		body = rest[size+2:]
+	}
+}
+
+func TestJSONStream(t *testing.T) {
+	// Test: NDJSON, one value per line, ended with the last chunk
+	fw := &flushWriter{}
+	s, err := NewJSONStream(NewWriter(fw), JSONStreamOptions{FlushInterval: time.Hour})
+	require.NoError(t, err)
+	hb := headerBlock(fw.String())
+	assert.Contains(t, hb, "Content-Type: application/x-ndjson\r\n")
+	assert.Contains(t, hb, "Transfer-Encoding: chunked\r\n")
+	assert.NotContains(t, hb, "Content-Length")
+
+	require.NoError(t, s.Encode(map[string]int{"n": 1}))
+	require.NoError(t, s.Encode("two"))
+	assert.Empty(t, bodyOf(fw.String()), "values wait for a flush")
+	require.NoError(t, s.Flush())
+	assert.Equal(t, 1, fw.flushes)
+	assert.Error(t, s.Encode(func() {}))
+	require.NoError(t, s.Encode(3))
+	require.NoError(t, s.Close())
+	body := bodyOf(fw.String())
+	assert.True(t, strings.HasSuffix(body, "0\r\n\r\n"))
+	assert.Equal(t, "{\"n\":1}\n\"two\"\n3\n", dechunk(t, body))
+
+	// Test: Arrays are well-formed, including empty ones
+	fw = &flushWriter{}
+	s, err = NewJSONStream(NewWriter(fw), JSONStreamOptions{Array: true})
+	require.NoError(t, err)
+	assert.Contains(t, headerBlock(fw.String()), "Content-Type: application/json\r\n")
+	for i := range 3 {
+		require.NoError(t, s.Encode(i))
+	}
+	require.NoError(t, s.Close())
+	var got []int
+	require.NoError(t, json.Unmarshal([]byte(dechunk(t, bodyOf(fw.String()))), &got))
+	assert.Equal(t, []int{0, 1, 2}, got)
+
+	fw = &flushWriter{}
+	s, err = NewJSONStream(NewWriter(fw), JSONStreamOptions{Array: true})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	assert.Equal(t, "[]", dechunk(t, bodyOf(fw.String())))
+
+	// Test: Values are flushed once the interval has passed
+	fw = &flushWriter{}
+	s, err = NewJSONStream(NewWriter(fw), JSONStreamOptions{FlushInterval: time.Nanosecond})
+	require.NoError(t, err)
+	require.NoError(t, s.Encode(1))
+	assert.Equal(t, 1, fw.flushes)
+	assert.Equal(t, "1\n", dechunk(t, bodyOf(fw.String())+"0\r\n\r\n"))
+}
+
+func TestWriter_StreamJSON(t *testing.T) {
+	fw := &flushWriter{}
+	ch := make(chan any)
+	go func() {
+		defer close(ch)
+		for i := range 3 {
+			ch <- map[string]int{"i": i}
+		}
+	}()
+
+	require.NoError(t, NewWriter(fw).StreamJSON(ch))
+	assert.Equal(t, "{\"i\":0}\n{\"i\":1}\n{\"i\":2}\n", dechunk(t, bodyOf(fw.String())))
+}
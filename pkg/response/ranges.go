@@ -0,0 +1,236 @@
+package response
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+)
+
+// maxRanges caps how many ranges one request may ask for; larger sets are
+// ignored and the full content is sent instead.
+const maxRanges = 16
+
+type rangeSpec struct {
+	start       int
+	end         int
+	endProvided bool
+	suffix      bool // "-N": the last N bytes
+}
+
+// byteRange is a resolved, inclusive byte range.
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+func (br byteRange) length() int64 {
+	return br.end - br.start + 1
+}
+
+func (br byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", br.start, br.end, size)
+}
+
+// parseRanges parses a Range header value such as "bytes=0-99,200-,-50".
+func parseRanges(s string) ([]rangeSpec, bool) {
+	prefix := "bytes="
+	if !strings.HasPrefix(s, prefix) {
+		return nil, false
+	}
+
+	specs := []rangeSpec{}
+	for _, part := range strings.Split(strings.TrimPrefix(s, prefix), ",") {
+		part = strings.TrimSpace(part)
+		first, last, found := strings.Cut(part, "-")
+		if !found {
+			return nil, false
+		}
+
+		if first == "" {
+			n, err := strconv.Atoi(last)
+			if err != nil || n <= 0 {
+				return nil, false
+			}
+			specs = append(specs, rangeSpec{end: n, suffix: true})
+			continue
+		}
+
+		st, err := strconv.Atoi(first)
+		if err != nil || st < 0 {
+			return nil, false
+		}
+		if last == "" {
+			specs = append(specs, rangeSpec{start: st})
+			continue
+		}
+
+		en, err := strconv.Atoi(last)
+		if err != nil {
+			return nil, false
+		}
+		specs = append(specs, rangeSpec{start: st, end: en, endProvided: true})
+	}
+
+	return specs, true
+}
+
+// resolveRanges turns specs into concrete ranges for content of the given
+// size. Unsatisfiable ranges are dropped; ErrRangeOutOfBounds is returned when
+// none remain.
+func resolveRanges(specs []rangeSpec, size int64) ([]byteRange, error) {
+	ranges := []byteRange{}
+	for _, spec := range specs {
+		if spec.suffix {
+			if size <= 0 {
+				continue
+			}
+			ranges = append(ranges, byteRange{start: max(size-int64(spec.end), 0), end: size - 1})
+			continue
+		}
+
+		start, end, err := resolveRange(size, spec.start, spec.end, spec.endProvided)
+		if errors.Is(err, ErrRangeOutOfBounds) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, byteRange{start: start, end: end})
+	}
+
+	if len(ranges) == 0 {
+		return nil, ErrRangeOutOfBounds
+	}
+	return ranges, nil
+}
+
+// ifRangeMatches reports whether the Range header should be honored given the
+// request's If-Range precondition. An entity tag must match etag exactly
+// (strong comparison) and a date must equal modtime to the second. Without
+// validators to compare against (empty etag, zero modtime) If-Range can never
+// match, so the full representation is sent.
+func ifRangeMatches(req *request.Request, etag string, modtime time.Time) bool {
+	ifRange, ok := req.Headers.Get("If-Range")
+	if !ok {
+		return true
+	}
+
+	ifRange = strings.TrimSpace(ifRange)
+	if strings.HasPrefix(ifRange, "\"") || strings.HasPrefix(ifRange, "W/") {
+		return etag != "" && !strings.HasPrefix(ifRange, "W/") && ifRange == etag
+	}
+
+	if modtime.IsZero() {
+		return false
+	}
+	t, err := headers.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	return modtime.Truncate(time.Second).Equal(t)
+}
+
+func newBoundary() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func partHeader(boundary string, contentType string, br byteRange, size int64) string {
+	return fmt.Sprintf("--%s\r\nContent-Type: %s\r\nContent-Range: %s\r\n\r\n",
+		boundary, contentType, br.contentRange(size))
+}
+
+// writeRanges answers a Range request over f. A single range is sent as a
+// plain 206; several ranges are sent as multipart/byteranges. bodyless skips
+// the payload (HEAD) while still reporting the right headers.
+func (w *Writer) writeRanges(f io.ReadSeeker, size int64, contentType string, h *headers.Headers, rangeStr string, bodyless bool) error {
+	specs, ok := parseRanges(rangeStr)
+	if !ok {
+		return w.writeTextError(StatusBadRequest, h, "invalid range")
+	}
+
+	if len(specs) > maxRanges {
+		h.Replace("Content-Type", contentType)
+		h.Replace("Content-Length", strconv.FormatInt(size, 10))
+		return w.writeStreamed(StatusOK, h, f, size, bodyless)
+	}
+
+	ranges, err := resolveRanges(specs, size)
+	if err != nil {
+		h.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		return w.writeTextError(StatusRangeNotSatisfiable, h, "invalid range provided")
+	}
+
+	if len(ranges) == 1 {
+		br := ranges[0]
+		if _, err := f.Seek(br.start, io.SeekStart); err != nil {
+			return w.writeTextError(StatusInternalServerError, h, "error loading range")
+		}
+
+		h.Replace("Content-Type", contentType)
+		h.Replace("Content-Length", strconv.FormatInt(br.length(), 10))
+		h.Set("Content-Range", br.contentRange(size))
+		return w.writeStreamed(StatusPartialContent, h, f, br.length(), bodyless)
+	}
+
+	boundary := newBoundary()
+	closing := fmt.Sprintf("--%s--\r\n", boundary)
+	total := int64(len(closing))
+	for _, br := range ranges {
+		total += int64(len(partHeader(boundary, contentType, br, size))) + br.length() + 2
+	}
+
+	h.Replace("Content-Type", "multipart/byteranges; boundary="+boundary)
+	h.Replace("Content-Length", strconv.FormatInt(total, 10))
+	if err := w.WriteStatusLine(StatusPartialContent); err != nil {
+		return err
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return err
+	}
+	if bodyless {
+		return nil
+	}
+
+	for _, br := range ranges {
+		if err := w.WriteBody([]byte(partHeader(boundary, contentType, br, size))); err != nil {
+			return err
+		}
+		if _, err := f.Seek(br.start, io.SeekStart); err != nil {
+			return err
+		}
+		if err := w.copyBody(f, br.length()); err != nil {
+			return err
+		}
+		if err := w.WriteBody([]byte("\r\n")); err != nil {
+			return err
+		}
+	}
+
+	return w.WriteBody([]byte(closing))
+}
+
+// writeStreamed writes the status line and headers and then streams n bytes
+// of body from r.
+func (w *Writer) writeStreamed(status StatusCode, h *headers.Headers, r io.Reader, n int64, bodyless bool) error {
+	if err := w.WriteStatusLine(status); err != nil {
+		return err
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return err
+	}
+	if bodyless {
+		return nil
+	}
+
+	return w.copyBody(r, n)
+}
@@ -0,0 +1,41 @@
+package response
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+)
+
+var (
+	ErrInvalidRedirectStatus = fmt.Errorf("invalid redirect status")
+	ErrInvalidLocation       = fmt.Errorf("invalid redirect location")
+)
+
+// IsRedirect reports whether status is a redirect status usable with
+// Redirect.
+func IsRedirect(status StatusCode) bool {
+	switch status {
+	case StatusMovedPermanently, StatusFound, StatusSeeOther, StatusTemporaryRedirect, StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// Redirect replies with a redirect to location using status, which must be
+// one of 301, 302, 303, 307 or 308. A short HTML body links to the target for
+// clients that don't follow redirects.
+func (w *Writer) Redirect(status StatusCode, location string) error {
+	if !IsRedirect(status) {
+		return fmt.Errorf("%w: %d", ErrInvalidRedirectStatus, status)
+	}
+	if !validReasonPhrase(location) {
+		return ErrInvalidLocation
+	}
+
+	body := fmt.Appendf(nil, "<a href=\"%s\">%s</a>.\n", html.EscapeString(location), StatusText(status))
+	h := GetDefaultHeaders(len(body))
+	h.Replace("Content-Type", "text/html; charset=utf-8")
+	h.Replace("Content-Length", strconv.Itoa(len(body)))
+	h.Set("Location", location)
+	return w.WriteResponse(status, h, body)
+}
@@ -0,0 +1,31 @@
+package response
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirect(t *testing.T) {
+	// Test: Location header and an escaped HTML body
+	var buf bytes.Buffer
+	require.NoError(t, NewWriter(&buf).Redirect(StatusSeeOther, "/search?q=a&b=<c>"))
+	out := buf.String()
+	hb := headerBlock(out)
+	assert.Equal(t, "HTTP/1.1 303 See Other\r\n", statusLineOf(out))
+	assert.Contains(t, hb, "Location: /search?q=a&b=<c>\r\n")
+	assert.Contains(t, hb, "Content-Type: text/html; charset=utf-8\r\n")
+	assert.Equal(t, "<a href=\"/search?q=a&amp;b=&lt;c&gt;\">See Other</a>.\n", bodyOf(out))
+
+	// Test: Only redirect statuses are allowed
+	for _, status := range []StatusCode{StatusOK, StatusNotModified, StatusMultipleChoices, StatusNotFound} {
+		buf.Reset()
+		assert.ErrorIs(t, NewWriter(&buf).Redirect(status, "/"), ErrInvalidRedirectStatus)
+		assert.Equal(t, "", buf.String())
+	}
+
+	// Test: Header injection through the location is refused
+	assert.ErrorIs(t, NewWriter(&buf).Redirect(StatusFound, "/x\r\nSet-Cookie: a=b"), ErrInvalidLocation)
+}
@@ -0,0 +1,914 @@
+package response
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/bufpool"
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+)
+
+const DefaultChunkSize = 32 * 1024 // bytes
+
+const (
+	writeBufferSize  = 4 * 1024  // bytes
+	headerBufferSize = 1024      // bytes, grown as needed
+	maxAutoBodySize  = 64 * 1024 // bytes held back to compute Content-Length
+)
+
+var (
+	ErrUnrecognizedStatusCode = fmt.Errorf("unrecognized status code")
+	ErrInvalidReasonPhrase    = fmt.Errorf("invalid reason phrase")
+	ErrFailedToWrite          = fmt.Errorf("failed to write")
+	ErrRangeOutOfBounds       = fmt.Errorf("range start out of bounds")
+	ErrRangeEndLtStart        = fmt.Errorf("range end < start")
+	ErrHijacked               = fmt.Errorf("connection has been hijacked")
+	ErrNotHijackable          = fmt.Errorf("connection cannot be hijacked")
+	ErrInformationalStatus    = fmt.Errorf("informational status must be sent with WriteInformational")
+	ErrResponseStarted        = fmt.Errorf("final response already started")
+	ErrWriteOrder             = fmt.Errorf("response written out of order")
+	ErrReleased               = fmt.Errorf("writer has been released")
+	// ErrInvalidHeaderField is returned instead of writing a header block
+	// with a field that would break the framing, e.g. a value with CRLF.
+	ErrInvalidHeaderField = fmt.Errorf("invalid header field")
+)
+
+type Handler func(w *Writer, req *request.Request) error
+
+// HijackFunc hands the underlying connection over to the caller. The server
+// installs one on every Writer it creates.
+type HijackFunc func() (net.Conn, *bufio.ReadWriter, error)
+
+// writerState tracks how far a response has progressed so out-of-order writes
+// are rejected instead of producing invalid HTTP.
+type writerState int
+
+const (
+	stateIdle     writerState = iota
+	stateStatus               // status line written
+	stateHeaders              // header block written
+	stateAutoBody             // bare body being held back, framing undecided
+	stateBody                 // body (or chunks) being written
+	stateTrailers             // chunked body ended, trailer block pending
+	stateDone
+)
+
+type Writer struct {
+	writer      io.Writer
+	header      *headers.Headers
+	defaults    *headers.Headers // shared, see SetDefaultHeaders
+	state       writerState
+	written     bool
+	compression *compression
+	hijack      HijackFunc
+	hijacked    bool
+	autoBody    []byte
+	autoBuf     *[]byte // pooled backing store of autoBody
+	autoChunked bool
+	status      StatusCode
+	sentHeader  *headers.Headers
+	bodyBytes   int64
+	http10      bool // client speaks HTTP/1.0, see SetHTTP10
+	head        bool // body is dropped for a HEAD request, see SetHEAD
+	keepAlive   bool // connection may stay open, see SetKeepAlive
+	unchunked   bool // chunked framing dropped for an HTTP/1.0 client
+}
+
+// NewWriter returns a Writer that writes straight through to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{writer: w}
+}
+
+// NewBufferedWriter returns a Writer that batches writes to w in a
+// bufio.Writer. Output is only guaranteed to reach w after Flush or Finish.
+func NewBufferedWriter(w io.Writer) *Writer {
+	bw, ok := bufioWriterPool.Get().(*bufio.Writer)
+	if ok {
+		bw.Reset(w)
+	} else {
+		bw = bufio.NewWriterSize(w, writeBufferSize)
+	}
+	return &Writer{writer: bw}
+}
+
+var bufioWriterPool sync.Pool
+
+// Release hands the buffer of a Writer from NewBufferedWriter back for reuse
+// by later ones, dropping anything not yet flushed. The Writer must not be
+// used afterwards. The server calls it after each response.
+// The buffer of a hijacked Writer is not reused, as whoever took over the
+// connection may still hold the Writer.
+func (w *Writer) Release() {
+	if w.hijacked {
+		return
+	}
+	if bw, ok := w.writer.(*bufio.Writer); ok {
+		bw.Reset(nil)
+		bufioWriterPool.Put(bw)
+	}
+	w.writer = releasedWriter{}
+	w.releaseAutoBody()
+}
+
+// releasedWriter fails the writes of a released Writer.
+type releasedWriter struct{}
+
+func (releasedWriter) Write([]byte) (int, error) {
+	return 0, ErrReleased
+}
+
+// SetHTTP10 marks the client as HTTP/1.0, which knows neither chunked
+// transfer coding nor interim responses. Chunked responses are then sent
+// unframed and delimited by closing the connection, trailers are dropped and
+// 1xx responses are skipped. The server calls it after parsing the request.
+func (w *Writer) SetHTTP10() {
+	w.http10 = true
+}
+
+// SetKeepAlive lets the connection stay open after the response, for the
+// client's next request. Responses whose length is known up front (by
+// Content-Length or chunked framing) are then sent without "Connection:
+// close", and with "Connection: keep-alive" to an HTTP/1.0 client; others
+// still close it. The server calls it when the request allows it (see
+// request.KeepAlive) and reads KeepAlive after the response.
+func (w *Writer) SetKeepAlive() {
+	w.keepAlive = true
+}
+
+// SetHEAD marks the request as HEAD. Responses are then written as they
+// would be for GET, down to the Content-Length worked out for a bare body,
+// but body bytes, chunk framing and trailers are dropped, so a GET handler
+// serves HEAD unchanged. The server calls it after parsing the request.
+func (w *Writer) SetHEAD() {
+	w.head = true
+}
+
+func statusLineFor(statusCode StatusCode, reason string) ([]byte, error) {
+	if !validStatusCode(statusCode) {
+		return nil, ErrUnrecognizedStatusCode
+	}
+	if !validReasonPhrase(reason) {
+		return nil, ErrInvalidReasonPhrase
+	}
+
+	return fmt.Appendf(nil, "HTTP/1.1 %d %s\r\n", statusCode, reason), nil
+}
+
+// WriteStatusLine writes the status line with the standard reason phrase.
+// Any code from 200 to 599 is accepted; unregistered ones get an empty reason
+// unless one is given with WriteStatusLineReason.
+func (w *Writer) WriteStatusLine(statusCode StatusCode) error {
+	return w.WriteStatusLineReason(statusCode, StatusText(statusCode))
+}
+
+// WriteStatusLineReason writes the status line with a custom reason phrase,
+// which may be empty but must not contain control characters.
+func (w *Writer) WriteStatusLineReason(statusCode StatusCode, reason string) error {
+	if statusCode >= StatusContinue && statusCode < StatusOK {
+		return ErrInformationalStatus
+	}
+	if w.state != stateIdle {
+		return fmt.Errorf("%w: status line already written", ErrWriteOrder)
+	}
+
+	statusLine, err := statusLineFor(statusCode, reason)
+	if err != nil {
+		return err
+	}
+	w.state = stateStatus
+	w.status = statusCode
+
+	if w.compression != nil {
+		w.compression.status = statusCode
+	}
+
+	return w.write(statusLine)
+}
+
+// WriteInformational sends an interim 1xx response (100 Continue, 102
+// Processing or 103 Early Hints, e.g. with Link preload fields) ahead of the
+// final one. Any number may be sent, but only before the final status line;
+// h may be nil and is written as-is, without the Writer's own header fields.
+func (w *Writer) WriteInformational(statusCode StatusCode, h *headers.Headers) error {
+	if statusCode != StatusContinue && statusCode != StatusProcessing && statusCode != StatusEarlyHints {
+		return ErrUnrecognizedStatusCode
+	}
+	if w.state != stateIdle {
+		return ErrResponseStarted
+	}
+	if w.http10 {
+		return nil
+	}
+	if h != nil {
+		if err := validateHeaders(h); err != nil {
+			return err
+		}
+	}
+
+	b, err := statusLineFor(statusCode, StatusText(statusCode))
+	if err != nil {
+		return err
+	}
+	if h != nil {
+		h.ForEach(func(name, value string) {
+			b = fmt.Appendf(b, "%s: %s\r\n", name, value)
+		})
+	}
+	b = fmt.Appendf(b, "\r\n")
+
+	// the client may be waiting on this (e.g. 100 Continue), so don't let it
+	// sit in the buffer
+	if err := w.writeRaw(b); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// Header returns the set of fields merged into the response headers when they
+// are written. Middleware uses it to add fields (e.g. CORS) without having to
+// intercept the handler's own header set. Fields already present in the
+// handler's headers take precedence.
+func (w *Writer) Header() *headers.Headers {
+	if w.header == nil {
+		w.header = headers.NewHeaders()
+	}
+	return w.header
+}
+
+// SetDefaultHeaders sets fields added to every final response that doesn't
+// have them, after those from Header; e.g. a Server or Cache-Control field
+// for every route. h is shared, not copied, so it mustn't change while in
+// use. The server sets the ones from its options on every Writer.
+func (w *Writer) SetDefaultHeaders(h *headers.Headers) {
+	w.defaults = h
+}
+
+// WriteHeaders writes the response header block, sending a 200 status line
+// first if none was written. After WriteChunkEnd(true) it writes the trailer
+// block instead, which is passed through untouched. Fields from Header and
+// SetDefaultHeaders are filled in, and unless h has a Connection field,
+// "Connection: close" is added when the connection isn't kept alive (see
+// SetKeepAlive).
+func (w *Writer) WriteHeaders(h *headers.Headers) error {
+	if err := w.validateHeaders(h); err != nil {
+		return err
+	}
+
+	switch w.state {
+	case stateIdle:
+		if err := w.WriteStatusLine(StatusOK); err != nil {
+			return err
+		}
+	case stateStatus:
+	case stateTrailers:
+		w.state = stateDone
+		if w.unchunked || w.head {
+			return nil
+		}
+		return w.writeHeaders(h)
+	default:
+		return fmt.Errorf("%w: headers already written", ErrWriteOrder)
+	}
+	w.state = stateHeaders
+
+	for _, fill := range []*headers.Headers{w.header, w.defaults} {
+		if fill == nil {
+			continue
+		}
+		fill.ForEach(func(name, value string) {
+			if _, ok := h.Get(name); !ok {
+				h.Set(name, value)
+			}
+		})
+	}
+	if w.compression != nil {
+		if hold := w.compression.prepare(h); hold {
+			return nil
+		}
+		w.autoChunked = w.autoChunked || w.compression.toChunked
+	}
+
+	return w.writeResponseHeaders(h)
+}
+
+// writeResponseHeaders writes the response header block (not trailers) and
+// keeps a snapshot of it for WrittenHeader.
+func (w *Writer) writeResponseHeaders(h *headers.Headers) error {
+	if te, ok := h.Get("Transfer-Encoding"); ok && w.http10 && strings.EqualFold(te, "chunked") {
+		h.Del("Transfer-Encoding")
+		h.Del("Trailer")
+		h.Replace("Connection", "close")
+		w.unchunked = true
+	}
+	if _, ok := h.Get("Connection"); !ok {
+		switch {
+		case !w.keepAlive || !w.delimited(h):
+			h.Set("Connection", "close")
+		case w.http10:
+			h.Set("Connection", "keep-alive")
+		}
+	}
+	w.sentHeader = h.Clone()
+	return w.writeHeaders(h)
+}
+
+// delimited reports whether the client can tell where a response with
+// header h ends without the connection being closed.
+func (w *Writer) delimited(h *headers.Headers) bool {
+	if w.head || w.status == StatusNoContent || w.status == StatusNotModified {
+		return true
+	}
+	if _, ok := h.Get("Content-Length"); ok {
+		return true
+	}
+	te, ok := h.Get("Transfer-Encoding")
+	return ok && strings.EqualFold(te, "chunked") && !w.unchunked
+}
+
+// KeepAlive reports whether the connection can carry another request after
+// the response: SetKeepAlive was called and a complete response went out
+// without "Connection: close". The server checks it after Finish.
+func (w *Writer) KeepAlive() bool {
+	if !w.keepAlive || w.hijacked || w.sentHeader == nil || w.unchunked {
+		return false
+	}
+	for _, v := range w.sentHeader.Values("Connection") {
+		if strings.EqualFold(v, "close") {
+			return false
+		}
+	}
+	if w.head || w.status == StatusNoContent || w.status == StatusNotModified {
+		return true
+	}
+
+	if cl, ok := w.sentHeader.Get("Content-Length"); ok {
+		n, err := strconv.ParseInt(cl, 10, 64)
+		return err == nil && n == w.bodyBytes
+	}
+	// chunked, ended once the last chunk and any trailers are out
+	return w.state == stateDone
+}
+
+// validateHeaders checks h and, unless trailers are due, the fields
+// middleware added through Header, before anything is written.
+func (w *Writer) validateHeaders(h *headers.Headers) error {
+	if err := validateHeaders(h); err != nil {
+		return err
+	}
+	if w.state == stateTrailers {
+		return nil
+	}
+	for _, fill := range []*headers.Headers{w.header, w.defaults} {
+		if fill == nil {
+			continue
+		}
+		if err := validateHeaders(fill); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateHeaders(h *headers.Headers) error {
+	if err := h.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidHeaderField, err)
+	}
+	return nil
+}
+
+func (w *Writer) writeHeaders(h *headers.Headers) error {
+	bp := bufpool.Get(headerBufferSize)
+	defer bufpool.Put(bp)
+	b := (*bp)[:0]
+
+	h.ForEach(func(name, value string) {
+		b = append(b, name...)
+		b = append(b, ": "...)
+		b = append(b, value...)
+		b = append(b, "\r\n"...)
+	})
+	b = append(b, "\r\n"...)
+
+	return w.write(b)
+}
+
+// WriteBody writes part of the response body. A body written before any
+// status line or headers is held back so Finish can send it as a 200 with
+// default headers, its Content-Length and a sniffed Content-Type. Bodies
+// larger than maxAutoBodySize are sent chunked instead.
+func (w *Writer) WriteBody(p []byte) error {
+	switch w.state {
+	case stateIdle, stateAutoBody:
+		return w.bufferAutoBody(p)
+	case stateHeaders, stateBody:
+	case stateStatus:
+		return fmt.Errorf("%w: body before headers", ErrWriteOrder)
+	default:
+		return fmt.Errorf("%w: body after end of response", ErrWriteOrder)
+	}
+	w.state = stateBody
+
+	if w.autoChunked {
+		if len(p) == 0 {
+			return nil
+		}
+		return w.WriteChunk(p)
+	}
+	return w.writeBody(p)
+}
+
+// Write implements io.Writer with WriteBody, so the body can be written with
+// fmt.Fprintf, json.NewEncoder, io.Copy and the like.
+func (w *Writer) Write(p []byte) (int, error) {
+	if err := w.WriteBody(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteBodyFrom streams the body from r until EOF and returns the number of
+// bytes read. Where the bytes go out as they are (after the header block, with
+// neither compression nor chunk framing added by the Writer) they are copied
+// straight to the connection, which lets a *net.TCPConn send an *os.File, or
+// an io.LimitedReader of one, with sendfile(2) instead of through user space.
+// Otherwise it writes what it reads with WriteBody. For HEAD requests (see
+// SetHEAD) r isn't read at all.
+func (w *Writer) WriteBodyFrom(r io.Reader) (int64, error) {
+	if w.head {
+		return 0, nil
+	}
+	if !w.rawBody() {
+		// hide ReadFrom from io.Copy, or it would come back here
+		return io.Copy(struct{ io.Writer }{w}, r)
+	}
+
+	w.state = stateBody
+	w.written = true
+	n, err := io.Copy(w.writer, r)
+	w.bodyBytes += n
+	if err != nil {
+		return n, fmt.Errorf("%w: %w", ErrFailedToWrite, err)
+	}
+	return n, nil
+}
+
+// ReadFrom implements io.ReaderFrom with WriteBodyFrom, so io.Copy to a
+// Writer takes the same path.
+func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
+	return w.WriteBodyFrom(r)
+}
+
+// rawBody reports whether body bytes currently go to the connection
+// unchanged.
+func (w *Writer) rawBody() bool {
+	if w.state != stateHeaders && w.state != stateBody {
+		return false
+	}
+	if w.hijacked || w.autoChunked {
+		return false
+	}
+	return w.compression == nil || w.compression.pending == nil && w.compression.enc == nil
+}
+
+func (w *Writer) writeBody(p []byte) error {
+	if w.compression != nil && w.compression.pending != nil {
+		return w.compression.buffer(w, p)
+	}
+
+	return w.writeBodyBytes(p)
+}
+
+// writeBodyBytes writes p as body bytes on the wire, counting them for
+// BytesWritten.
+func (w *Writer) writeBodyBytes(p []byte) error {
+	if w.head {
+		return nil
+	}
+	if err := w.write(p); err != nil {
+		return err
+	}
+	w.bodyBytes += int64(len(p))
+	return nil
+}
+
+func (w *Writer) bufferAutoBody(p []byte) error {
+	w.state = stateAutoBody
+	if n := len(w.autoBody) + len(p); n > cap(w.autoBody) && n <= maxAutoBodySize {
+		// move to a pooled buffer large enough for the body so far
+		bp := bufpool.Get(n)
+		w.autoBody = append((*bp)[:0], w.autoBody...)
+		bufpool.Put(w.autoBuf)
+		w.autoBuf = bp
+	}
+	w.autoBody = append(w.autoBody, p...)
+	if len(w.autoBody) <= maxAutoBodySize {
+		return nil
+	}
+
+	// too large to hold on to
+	return w.sendAutoChunked()
+}
+
+// sendAutoChunked commits a held-back bare body to chunked framing, sending
+// the header block and what was written so far as the first chunk.
+func (w *Writer) sendAutoChunked() error {
+	defer w.releaseAutoBody()
+	body := w.autoBody
+	w.autoBody = nil
+	w.state = stateIdle
+
+	h := GetDefaultHeaders(0)
+	h.Del("Content-Length")
+	h.Replace("Content-Type", DetectContentType(body))
+	h.Set("Transfer-Encoding", "chunked")
+	if err := w.WriteHeaders(h); err != nil {
+		return err
+	}
+
+	w.autoChunked = true
+	if len(body) == 0 {
+		w.state = stateBody // an empty chunk would end the body
+		return nil
+	}
+	return w.WriteChunk(body)
+}
+
+// releaseAutoBody returns the buffer of a held-back body once it was sent.
+func (w *Writer) releaseAutoBody() {
+	bufpool.Put(w.autoBuf)
+	w.autoBuf = nil
+}
+
+// Finish completes the response once the handler is done: a held-back bare
+// body is sent with its Content-Length (or its chunked body is terminated),
+// and buffered output is flushed. The server calls it after every handler.
+func (w *Writer) Finish() error {
+	if w.hijacked {
+		return nil
+	}
+
+	if err := w.finishBody(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (w *Writer) finishBody() error {
+	switch {
+	case w.state == stateAutoBody:
+		defer w.releaseAutoBody()
+		body := w.autoBody
+		w.autoBody = nil
+		w.state = stateIdle
+
+		h := GetDefaultHeaders(len(body))
+		h.Replace("Content-Type", DetectContentType(body))
+		return w.WriteResponse(StatusOK, h, body)
+	case w.autoChunked && w.state == stateBody:
+		return w.WriteChunkEnd(false)
+	}
+
+	return nil
+}
+
+// SetHijacker installs the function Hijack uses to take over the connection.
+func (w *Writer) SetHijacker(fn HijackFunc) {
+	w.hijack = fn
+}
+
+// Hijack lets the handler take over the connection, e.g. for protocol
+// upgrades. Afterwards the Writer refuses to write and the server neither
+// responds on nor closes the connection; both become the caller's job. Bytes
+// the client sent after the request, before Hijack was called, are available
+// on the returned reader ahead of anything read from the connection.
+func (w *Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.hijacked {
+		return nil, nil, ErrHijacked
+	}
+	if w.hijack == nil {
+		return nil, nil, ErrNotHijackable
+	}
+	if err := w.flushOutput(); err != nil {
+		return nil, nil, err
+	}
+
+	conn, rw, err := w.hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.hijacked = true
+	return conn, rw, nil
+}
+
+// Hijacked reports whether Hijack has taken over the connection.
+func (w *Writer) Hijacked() bool {
+	return w.hijacked
+}
+
+// Flush pushes the response so far to the client, for streaming handlers. A
+// held-back bare body is sent right away, which commits it to chunked
+// framing, and buffered output goes out when the underlying writer supports
+// it (e.g. writers from NewBufferedWriter). Bodies held for compression to a
+// fixed length are only sent once complete.
+func (w *Writer) Flush() error {
+	if w.state == stateAutoBody && !w.hijacked {
+		if err := w.sendAutoChunked(); err != nil {
+			return err
+		}
+	}
+	return w.flushOutput()
+}
+
+// flushOutput is Flush without sending a held-back body.
+func (w *Writer) flushOutput() error {
+	if f, ok := w.writer.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return fmt.Errorf("%w: %w", ErrFailedToWrite, err)
+		}
+	}
+	return nil
+}
+
+// Written reports whether the response has been started, after which it is
+// too late to switch to a different response (e.g. an error page). A bare
+// body that is still held back counts as started.
+func (w *Writer) Written() bool {
+	return w.written || w.state != stateIdle
+}
+
+// Status returns the status code of the final response, or 0 if none has been
+// written. A held-back bare body reports the 200 it will be sent with.
+func (w *Writer) Status() StatusCode {
+	if w.state == stateAutoBody {
+		return StatusOK
+	}
+	return w.status
+}
+
+// WrittenHeader returns a copy of the response header fields as sent on the
+// wire, after middleware fields were merged in and any content-coding was
+// applied, or nil if the header block has not been sent yet.
+func (w *Writer) WrittenHeader() *headers.Headers {
+	if w.sentHeader == nil {
+		return nil
+	}
+	return w.sentHeader.Clone()
+}
+
+// BytesWritten returns the number of body bytes sent so far (after any
+// content-coding and chunk framing), including a held-back bare body.
+func (w *Writer) BytesWritten() int64 {
+	return w.bodyBytes + int64(len(w.autoBody))
+}
+
+// Write100Continue sends the interim "100 Continue" response telling a client
+// that sent "Expect: 100-continue" to go ahead with the body. It does not
+// count as the start of the final response.
+func (w *Writer) Write100Continue() error {
+	return w.WriteInformational(StatusContinue, nil)
+}
+
+func (w *Writer) write(p []byte) error {
+	w.written = true
+	return w.writeRaw(p)
+}
+
+func (w *Writer) writeRaw(p []byte) error {
+	if w.hijacked {
+		return ErrHijacked
+	}
+
+	writeN := 0
+	for writeN < len(p) {
+		n, err := w.writer.Write(p[writeN:])
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrFailedToWrite, err)
+		}
+		if n == 0 {
+			return fmt.Errorf("%w", ErrFailedToWrite)
+		}
+		writeN += n
+	}
+
+	return nil
+}
+
+func (w *Writer) WriteChunk(p []byte) error {
+	if err := w.checkChunked(); err != nil {
+		return err
+	}
+	w.state = stateBody
+
+	if w.compression != nil && w.compression.enc != nil {
+		var err error
+		if p, err = w.compression.compressChunk(p); err != nil {
+			return err
+		}
+		if len(p) == 0 {
+			return nil
+		}
+	}
+
+	return w.writeChunk(p)
+}
+
+func (w *Writer) writeChunk(p []byte) error {
+	if w.unchunked {
+		return w.writeBody(p)
+	}
+	size := strconv.AppendInt(make([]byte, 0, 18), int64(len(p)), 16)
+	return w.writeBodyFrame(append(size, "\r\n"...), p, []byte("\r\n"))
+}
+
+// writeBodyFrame writes bufs as body bytes in one go: a vectored write
+// (writev) on a TCP or Unix connection, separate writes into a bufio.Writer,
+// which batches them anyway, and a single joined write otherwise.
+func (w *Writer) writeBodyFrame(bufs ...[]byte) error {
+	switch w.writer.(type) {
+	case *bufio.Writer:
+		for _, b := range bufs {
+			if err := w.writeBodyBytes(b); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *net.TCPConn, *net.UnixConn:
+		if w.head {
+			return nil
+		}
+		if w.hijacked {
+			return ErrHijacked
+		}
+		w.written = true
+		vec := net.Buffers(bufs)
+		n, err := vec.WriteTo(w.writer)
+		w.bodyBytes += n
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrFailedToWrite, err)
+		}
+		return nil
+	}
+
+	total := 0
+	for _, b := range bufs {
+		total += len(b)
+	}
+	frame := make([]byte, 0, total)
+	for _, b := range bufs {
+		frame = append(frame, b...)
+	}
+	return w.writeBodyBytes(frame)
+}
+
+func (w *Writer) checkChunked() error {
+	if w.state != stateHeaders && w.state != stateBody {
+		return fmt.Errorf("%w: chunk outside of a body", ErrWriteOrder)
+	}
+	return nil
+}
+
+func (w *Writer) WriteChunkEnd(hasTrailers bool) error {
+	if err := w.checkChunked(); err != nil {
+		return err
+	}
+
+	if w.compression != nil && w.compression.enc != nil {
+		p, err := w.compression.closeChunks()
+		if err != nil {
+			return err
+		}
+		if len(p) > 0 {
+			if err := w.writeChunk(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	b := []byte{}
+	if hasTrailers {
+		b = []byte("0\r\n")
+	} else {
+		b = []byte("0\r\n\r\n")
+	}
+
+	if !w.unchunked {
+		if err := w.writeBody(b); err != nil {
+			return err
+		}
+	}
+
+	if hasTrailers {
+		w.state = stateTrailers
+	} else {
+		w.state = stateDone
+	}
+	return nil
+}
+
+// WriteChunkedFrom writes the status line and headers (switched to
+// Transfer-Encoding: chunked) and then streams r as chunks of at most
+// chunkSize bytes until EOF. A non-positive chunkSize uses
+// DefaultChunkSize. When trailer is non-nil it is called once the body has
+// been sent and the fields it returns are written as the trailer; they
+// should be announced beforehand in h's Trailer field.
+func (w *Writer) WriteChunkedFrom(status StatusCode, h *headers.Headers, r io.Reader, chunkSize int, trailer func() *headers.Headers) error {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	h.Del("Content-Length")
+	h.Replace("Transfer-Encoding", "chunked")
+	if err := w.WriteStatusLine(status); err != nil {
+		return err
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return err
+	}
+
+	data := make([]byte, chunkSize)
+	for {
+		n, rErr := r.Read(data)
+		if n > 0 {
+			if err := w.WriteChunk(data[:n]); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(rErr, io.EOF) {
+			break
+		}
+		if rErr != nil {
+			return rErr
+		}
+	}
+
+	if trailer == nil {
+		return w.WriteChunkEnd(false)
+	}
+	if err := w.WriteChunkEnd(true); err != nil {
+		return err
+	}
+
+	t := trailer()
+	if t == nil {
+		t = headers.NewHeaders()
+	}
+	return w.WriteHeaders(t)
+}
+
+func (w *Writer) WriteResponse(statusCode StatusCode, header *headers.Headers, body []byte) error {
+	// check before the status line goes out so the error can still be
+	// answered with a clean response
+	if err := w.validateHeaders(header); err != nil {
+		return err
+	}
+	if err := w.WriteStatusLine(statusCode); err != nil {
+		return err
+	}
+	if err := w.WriteHeaders(header); err != nil {
+		return err
+	}
+	if err := w.WriteBody(body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WritePartialContentResponse answers req with content, honoring the Range
+// header (a single range or multipart/byteranges). The status line and
+// headers are written first and the body is then streamed from f, so large
+// ranges are never loaded into memory. Since no validators are known here, a
+// request carrying If-Range always gets the full 200 body.
+func (w *Writer) WritePartialContentResponse(f io.ReadSeeker, contentSize int64, contentType string, req *request.Request) error {
+	h := GetDefaultHeaders(0)
+	h.Replace("Content-Type", contentType)
+	h.Set("Accept-Ranges", "bytes")
+
+	if rangeStr, ok := req.Headers.Get("Range"); ok && ifRangeMatches(req, "", time.Time{}) {
+		return w.writeRanges(f, contentSize, contentType, h, rangeStr, false)
+	}
+
+	h.Replace("Content-Length", strconv.FormatInt(contentSize, 10))
+	return w.writeStreamed(StatusOK, h, f, contentSize, false)
+}
+
+// GetDefaultHeaders returns the fields most responses start from: the
+// Content-Length and a text/html Content-Type. Connection and any
+// configured defaults (see SetDefaultHeaders) are filled in by the Writer.
+func GetDefaultHeaders(contentLen int) *headers.Headers {
+	h := headers.NewHeaders()
+	h.Set("Content-Length", strconv.Itoa(contentLen))
+	h.Set("Content-Type", "text/html")
+
+	return h
+}
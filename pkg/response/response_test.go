@@ -0,0 +1,1446 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chunkWriter struct {
+	maxPerWrite int
+	buf         bytes.Buffer
+}
+
+// Simulates writing a variable number of bytes per chunk to a network.
+func (cw *chunkWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if cw.maxPerWrite <= 0 || len(p) <= cw.maxPerWrite {
+		_, _ = cw.buf.Write(p)
+		return len(p), nil
+	}
+	_, _ = cw.buf.Write(p[:cw.maxPerWrite])
+	return cw.maxPerWrite, nil
+}
+
+func (cw *chunkWriter) String() string { return cw.buf.String() }
+
+type errWriter struct {
+	failAfter int
+	writes    int
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	ew.writes++
+	if ew.writes > ew.failAfter {
+		return 0, errors.New("boom")
+	}
+	return len(p), nil
+}
+
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.writes++
+	return cw.Buffer.Write(p)
+}
+
+type zeroWriter struct{}
+
+func (zw *zeroWriter) Write(p []byte) (int, error) { return 0, nil }
+
+type badReadSeeker struct{}
+
+func (badReadSeeker) Read(p []byte) (int, error)                   { return 0, errors.New("readfail") }
+func (badReadSeeker) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+
+type badSeek struct {
+	data []byte
+}
+
+func (b *badSeek) Read(p []byte) (int, error) {
+	if len(b.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+	return n, nil
+}
+
+func (b *badSeek) Seek(offset int64, whence int) (int64, error) {
+	return 0, errors.New("seekfail")
+}
+
+// writerInState returns a Writer that has already progressed to state, for
+// exercising the individual write steps on their own.
+func writerInState(out io.Writer, state writerState) *Writer {
+	w := NewWriter(out)
+	w.state = state
+	return w
+}
+
+func mkReq(method, target string) *request.Request {
+	return &request.Request{
+		RequestLine: request.RequestLine{
+			Method:        method,
+			RequestTarget: target,
+		},
+		Headers:    headers.NewHeaders(),
+		PathParams: make(map[string]string),
+		// your Request always has these initialized in RequestFromReader,
+		// but for these unit tests we only need headers + requestline.
+		RequestParams: make(map[string]string),
+	}
+}
+
+func statusLineOf(out string) string {
+	i := strings.Index(out, "\r\n")
+	if i == -1 {
+		return out
+	}
+	return out[:i+2]
+}
+
+func headerBlock(out string) string {
+	i := strings.Index(out, "\r\n\r\n")
+	if i == -1 {
+		return out
+	}
+	return out[:i+4]
+}
+
+func bodyOf(out string) string {
+	i := strings.Index(out, "\r\n\r\n")
+	if i == -1 {
+		return ""
+	}
+	return out[i+4:]
+}
+
+func TestWriteStatusLine(t *testing.T) {
+	// Test: Good status line
+	cw := &chunkWriter{maxPerWrite: 3}
+	w := NewWriter(cw)
+	err := w.WriteStatusLine(StatusOK)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", cw.String())
+
+	// Test: Unrecognized status code
+	cw = &chunkWriter{maxPerWrite: 64}
+	w = NewWriter(cw)
+	err = w.WriteStatusLine(StatusCode(999))
+	require.Error(t, err)
+	assert.Equal(t, ErrUnrecognizedStatusCode, err)
+
+	// Test: Underlying writer error
+	ew := &errWriter{failAfter: 0}
+	w = NewWriter(ew)
+	err = w.WriteStatusLine(StatusOK)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+
+	// Test: Zero-byte writes
+	zw := &zeroWriter{}
+	w = NewWriter(zw)
+	err = w.WriteStatusLine(StatusOK)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+}
+
+func TestWriteHeaders(t *testing.T) {
+	// Test: Valid headers
+	cw := &chunkWriter{maxPerWrite: 2}
+	w := NewWriter(cw)
+	h := headers.NewHeaders()
+	h.Set("Host", "localhost:8080")
+	h.Set("Content-Type", "text/plain")
+	h.Set("X-Test", "abc")
+	err := w.WriteHeaders(h)
+	require.NoError(t, err)
+	out := cw.String()
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\n"))
+	assert.Contains(t, out, "Host: localhost:8080\r\n")
+	assert.Contains(t, out, "Content-Type: text/plain\r\n")
+	assert.Contains(t, out, "X-Test: abc\r\n")
+
+	// Test: Underlying writer error
+	ew := &errWriter{failAfter: 0}
+	w = NewWriter(ew)
+	err = w.WriteHeaders(h)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+
+	// Test: Zero-byte writes
+	zw := &zeroWriter{}
+	w = NewWriter(zw)
+	err = w.WriteHeaders(h)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+}
+
+func TestWriteHeaders_Injection(t *testing.T) {
+	evil := "x\r\nSet-Cookie: session=stolen"
+
+	// Test: Nothing is written for a value carrying CRLF
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	h := GetDefaultHeaders(0)
+	h.Set("Location", evil)
+	err := w.WriteResponse(StatusFound, h, nil)
+	assert.ErrorIs(t, err, ErrInvalidHeaderField)
+	assert.ErrorIs(t, err, headers.ErrMalformedHeaderValue)
+	assert.Empty(t, buf.String())
+	assert.False(t, w.Written())
+
+	// Test: so the error can still be answered cleanly
+	assert.Equal(t, err, DefaultErrorHandler(w, mkReq("GET", "/"), err))
+	assert.Equal(t, "HTTP/1.1 500 Internal Server Error\r\n", statusLineOf(buf.String()))
+	assert.NotContains(t, buf.String(), "stolen")
+
+	// Test: Fields added by middleware are checked too
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.Header().Set("X-Bad Name", "v")
+	assert.ErrorIs(t, w.WriteHeaders(GetDefaultHeaders(0)), headers.ErrMalformedHeaderName)
+	assert.Empty(t, buf.String())
+
+	// Test: and interim responses
+	h = headers.NewHeaders()
+	h.Set("Link", evil)
+	assert.ErrorIs(t, w.WriteInformational(StatusEarlyHints, h), ErrInvalidHeaderField)
+	assert.Empty(t, buf.String())
+}
+
+func TestWriteBody(t *testing.T) {
+	// Test: Writes full body under partial writes
+	cw := &chunkWriter{maxPerWrite: 1}
+	w := writerInState(cw, stateHeaders)
+	body := []byte("Hello World!\n")
+	err := w.WriteBody(body)
+	require.NoError(t, err)
+	assert.Equal(t, string(body), cw.String())
+
+	// Test: Underlying writer error
+	ew := &errWriter{failAfter: 0}
+	w = writerInState(ew, stateHeaders)
+	err = w.WriteBody([]byte("hi"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+
+	// Test: Zero-byte writes
+	zw := &zeroWriter{}
+	w = writerInState(zw, stateHeaders)
+	err = w.WriteBody([]byte("hi"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+}
+
+func TestWriteChunk(t *testing.T) {
+	// Test: Writes chunk framing and data (supports partial writes)
+	cw := &chunkWriter{maxPerWrite: 2}
+	w := writerInState(cw, stateHeaders)
+
+	payload := []byte("hello") // len=5 -> "5\r\nhello\r\n"
+	err := w.WriteChunk(payload)
+	require.NoError(t, err)
+	assert.Equal(t, "5\r\nhello\r\n", cw.String())
+
+	// Test: Underlying writer error
+	ew := &errWriter{failAfter: 0} // fail on first write
+	w = writerInState(ew, stateHeaders)
+	err = w.WriteChunk([]byte("x"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+
+	// Test: Zero-byte writes are treated as failure
+	zw := &zeroWriter{}
+	w = writerInState(zw, stateHeaders)
+	err = w.WriteChunk([]byte("x"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+}
+
+func TestWriteChunk_SingleWrite(t *testing.T) {
+	// Test: The size line, payload and CRLF go out in one write
+	cw := &countingWriter{}
+	w := writerInState(cw, stateHeaders)
+	require.NoError(t, w.WriteChunk(bytes.Repeat([]byte("a"), 300)))
+	assert.Equal(t, 1, cw.writes)
+	assert.True(t, strings.HasPrefix(cw.String(), "12c\r\naaa"))
+	assert.Equal(t, int64(len(cw.String())), w.BytesWritten())
+
+	// Test: On a TCP connection the frame is a vectored write
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		b, _ := io.ReadAll(conn)
+		received <- string(b)
+	}()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	w = writerInState(conn, stateHeaders)
+	require.NoError(t, w.WriteChunk([]byte("hello")))
+	require.NoError(t, w.WriteChunk([]byte("world!")))
+	require.NoError(t, w.WriteChunkEnd(false))
+	assert.Equal(t, int64(len("5\r\nhello\r\n6\r\nworld!\r\n0\r\n\r\n")), w.BytesWritten())
+	conn.Close()
+	assert.Equal(t, "5\r\nhello\r\n6\r\nworld!\r\n0\r\n\r\n", <-received)
+}
+
+func TestWriteChunkEnd(t *testing.T) {
+	// Test: hasTrailers=true writes only "0\r\n"
+	cw := &chunkWriter{maxPerWrite: 1}
+	w := writerInState(cw, stateHeaders)
+
+	err := w.WriteChunkEnd(true)
+	require.NoError(t, err)
+	assert.Equal(t, "0\r\n", cw.String())
+
+	// Test: hasTrailers=false writes final CRLF "0\r\n\r\n"
+	cw = &chunkWriter{maxPerWrite: 1}
+	w = writerInState(cw, stateHeaders)
+
+	err = w.WriteChunkEnd(false)
+	require.NoError(t, err)
+	assert.Equal(t, "0\r\n\r\n", cw.String())
+
+	// Test: underlying writer error
+	ew := &errWriter{failAfter: 0} // fail on first write
+	w = writerInState(ew, stateHeaders)
+
+	err = w.WriteChunkEnd(true)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+
+	// Test: zero-byte writes are treated as failure
+	zw := &zeroWriter{}
+	w = writerInState(zw, stateHeaders)
+
+	err = w.WriteChunkEnd(false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+}
+
+func TestWriterState(t *testing.T) {
+	// Test: A bare body gets a default status line and headers on Finish
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, w.WriteBody([]byte("<html>")))
+	require.NoError(t, w.WriteBody([]byte("hi</html>")))
+	assert.True(t, w.Written())
+	assert.Equal(t, "", buf.String())
+	require.NoError(t, w.Finish())
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.Contains(t, headerBlock(out), "Content-Length: 15\r\n")
+	assert.Contains(t, headerBlock(out), "Content-Type: text/html; charset=utf-8\r\n")
+	assert.Equal(t, "<html>hi</html>", bodyOf(out))
+
+	// Test: Headers without a status line default to 200
+	buf.Reset()
+	w = NewWriter(&buf)
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(0)))
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(buf.String()))
+
+	// Test: Out-of-order calls are rejected without writing
+	buf.Reset()
+	w = NewWriter(&buf)
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	assert.ErrorIs(t, w.WriteStatusLine(StatusOK), ErrWriteOrder)
+	assert.ErrorIs(t, w.WriteBody([]byte("x")), ErrWriteOrder)
+	assert.ErrorIs(t, w.WriteChunk([]byte("x")), ErrWriteOrder)
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(0)))
+	assert.ErrorIs(t, w.WriteHeaders(GetDefaultHeaders(0)), ErrWriteOrder)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\nContent-Type: text/html\r\nConnection: close\r\n\r\n", buf.String())
+
+	// Test: Chunked body, trailers, then nothing more
+	buf.Reset()
+	w = NewWriter(&buf)
+	h := GetDefaultHeaders(0)
+	h.Del("Content-Length")
+	h.Set("Transfer-Encoding", "chunked")
+	require.NoError(t, w.WriteHeaders(h))
+	require.NoError(t, w.WriteChunk([]byte("hi")))
+	require.NoError(t, w.WriteChunkEnd(true))
+	assert.ErrorIs(t, w.WriteChunk([]byte("x")), ErrWriteOrder)
+	trailer := headers.NewHeaders()
+	trailer.Set("X-Sum", "1")
+	require.NoError(t, w.WriteHeaders(trailer))
+	assert.ErrorIs(t, w.WriteHeaders(trailer), ErrWriteOrder)
+	assert.ErrorIs(t, w.WriteBody([]byte("x")), ErrWriteOrder)
+	assert.Equal(t, "2\r\nhi\r\n0\r\nX-Sum: 1\r\n\r\n", bodyOf(buf.String()))
+}
+
+func TestBufferedWriter(t *testing.T) {
+	// Test: Output is held until Finish
+	cw := &countingWriter{}
+	w := NewBufferedWriter(cw)
+	body := []byte("hello")
+	require.NoError(t, w.WriteResponse(StatusOK, GetDefaultHeaders(len(body)), body))
+	assert.Equal(t, 0, cw.writes)
+	require.NoError(t, w.Finish())
+	assert.Equal(t, 1, cw.writes)
+	assert.Equal(t, "hello", bodyOf(cw.String()))
+
+	// Test: Informational responses are flushed right away
+	cw = &countingWriter{}
+	w = NewBufferedWriter(cw)
+	require.NoError(t, w.Write100Continue())
+	assert.Equal(t, "HTTP/1.1 100 Continue\r\n\r\n", cw.String())
+
+	// Test: Large bare bodies switch to chunked framing
+	cw = &countingWriter{}
+	w = NewBufferedWriter(cw)
+	big := bytes.Repeat([]byte("a"), maxAutoBodySize)
+	require.NoError(t, w.WriteBody(big))
+	require.NoError(t, w.WriteBody([]byte("bc")))
+	require.NoError(t, w.WriteBody([]byte("d")))
+	require.NoError(t, w.Finish())
+	out := cw.String()
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Transfer-Encoding: chunked\r\n")
+	assert.NotContains(t, hb, "Content-Length")
+	expected := fmt.Sprintf("%x\r\n%sbc\r\n1\r\nd\r\n0\r\n\r\n", len(big)+2, big)
+	assert.Equal(t, expected, bodyOf(out))
+
+	// Test: Finish after an explicit response only flushes
+	cw = &countingWriter{}
+	w = NewBufferedWriter(cw)
+	require.NoError(t, w.WriteResponse(StatusNoContent, GetDefaultHeaders(0), nil))
+	require.NoError(t, w.Finish())
+	require.NoError(t, w.Finish())
+	assert.Equal(t, "HTTP/1.1 204 No Content\r\n", statusLineOf(cw.String()))
+}
+
+func TestWriter_IOWriter(t *testing.T) {
+	// Test: fmt and io.Copy write a bare body that gets its Content-Length
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	var _ io.Writer = w
+	fmt.Fprintf(w, "hello %s", "world")
+	_, err := io.Copy(w, strings.NewReader("!"))
+	require.NoError(t, err)
+	require.NoError(t, w.Finish())
+	assert.Contains(t, headerBlock(buf.String()), "Content-Length: 12\r\n")
+	assert.Equal(t, "hello world!", bodyOf(buf.String()))
+
+	// Test: Write after explicit headers writes the body as is
+	buf.Reset()
+	w = NewWriter(&buf)
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(2)))
+	n, err := w.Write([]byte("ok"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "ok", bodyOf(buf.String()))
+
+	// Test: Write errors report nothing written
+	w = NewWriter(&buf)
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	n, err = w.Write([]byte("early"))
+	assert.ErrorIs(t, err, ErrWriteOrder)
+	assert.Equal(t, 0, n)
+}
+
+func TestWriter_FlushStreams(t *testing.T) {
+	// Test: Flush sends a held-back bare body at once as a chunk
+	cw := &countingWriter{}
+	w := NewBufferedWriter(cw)
+	_, err := w.Write([]byte("first"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, cw.writes)
+	require.NoError(t, w.Flush())
+	assert.Contains(t, headerBlock(cw.String()), "Transfer-Encoding: chunked\r\n")
+	assert.Equal(t, "5\r\nfirst\r\n", bodyOf(cw.String()))
+
+	// Test: Later writes and empty ones go out as chunks as they are flushed
+	_, err = w.Write(nil)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("second"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "5\r\nfirst\r\n6\r\nsecond\r\n", bodyOf(cw.String()))
+	require.NoError(t, w.Finish())
+	assert.Equal(t, "5\r\nfirst\r\n6\r\nsecond\r\n0\r\n\r\n", bodyOf(cw.String()))
+
+	// Test: Flushing an empty held-back body sends only the header block
+	cw = &countingWriter{}
+	w = NewBufferedWriter(cw)
+	_, err = w.Write(nil)
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	require.NoError(t, w.Finish())
+	assert.Equal(t, "0\r\n\r\n", bodyOf(cw.String()))
+}
+
+func TestWriter_SetHEAD(t *testing.T) {
+	// Test: A bare body gets its Content-Length but isn't sent
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetHEAD()
+	_, err := w.Write([]byte("<p>hello</p>"))
+	require.NoError(t, err)
+	require.NoError(t, w.Finish())
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(buf.String()))
+	assert.Contains(t, headerBlock(buf.String()), "Content-Length: 12\r\n")
+	assert.Empty(t, bodyOf(buf.String()))
+	assert.Equal(t, int64(0), w.BytesWritten())
+
+	// Test: Chunks, the last chunk and trailers are dropped
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetHEAD()
+	h := GetDefaultHeaders(0)
+	h.Del("Content-Length")
+	h.Set("Transfer-Encoding", "chunked")
+	h.Set("Trailer", "X-Sum")
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+	require.NoError(t, w.WriteChunk([]byte("abc")))
+	require.NoError(t, w.WriteChunkEnd(true))
+	trailer := headers.NewHeaders()
+	trailer.Set("X-Sum", "1")
+	require.NoError(t, w.WriteHeaders(trailer))
+	assert.Contains(t, headerBlock(buf.String()), "Transfer-Encoding: chunked\r\n")
+	assert.Empty(t, bodyOf(buf.String()))
+
+	// Test: Compressed responses report the compressed length
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetHEAD()
+	w.EnableCompression(EncodingGzip, 0)
+	body := bytes.Repeat([]byte("a"), 1000)
+	require.NoError(t, w.WriteResponse(StatusOK, GetDefaultHeaders(len(body)), body))
+	hb := headerBlock(buf.String())
+	assert.Contains(t, hb, "Content-Encoding: gzip\r\n")
+	assert.NotContains(t, hb, "Content-Length: 1000\r\n")
+	assert.Empty(t, bodyOf(buf.String()))
+}
+
+// readerFromWriter records the readers handed to its ReadFrom.
+type readerFromWriter struct {
+	bytes.Buffer
+	readers []io.Reader
+}
+
+func (rw *readerFromWriter) ReadFrom(r io.Reader) (int64, error) {
+	rw.readers = append(rw.readers, r)
+	return rw.Buffer.ReadFrom(r)
+}
+
+func TestWriteBodyFrom(t *testing.T) {
+	// Test: After the header block the source goes to the underlying ReaderFrom
+	rw := &readerFromWriter{}
+	w := NewWriter(rw)
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(5)))
+	src := io.LimitReader(strings.NewReader("hello"), 5) // no WriteTo for io.Copy to prefer
+	n, err := w.WriteBodyFrom(src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, int64(5), w.BytesWritten())
+	assert.Equal(t, "hello", bodyOf(rw.String()))
+	require.Len(t, rw.readers, 1)
+	assert.Same(t, src, rw.readers[0])
+
+	// Test: io.Copy takes the same path, and ServeFile hands over a limited file
+	name := filepath.Join(t.TempDir(), "clip.mp4")
+	require.NoError(t, os.WriteFile(name, []byte("0123456789"), 0o644))
+	req := mkReq("GET", "/clip.mp4")
+	req.Headers.Set("Range", "bytes=2-5")
+	rw = &readerFromWriter{}
+	require.NoError(t, NewWriter(rw).ServeFile(req, name))
+	assert.Equal(t, "2345", bodyOf(rw.String()))
+	require.Len(t, rw.readers, 1)
+	lr, ok := rw.readers[0].(*io.LimitedReader)
+	require.True(t, ok)
+	assert.IsType(t, &os.File{}, lr.R)
+
+	// Test: A bare body and compressed bodies still go through WriteBody
+	rw = &readerFromWriter{}
+	w = NewWriter(rw)
+	_, err = io.Copy(w, strings.NewReader("bare"))
+	require.NoError(t, err)
+	require.NoError(t, w.Finish())
+	assert.Empty(t, rw.readers)
+	assert.Contains(t, headerBlock(rw.String()), "Content-Length: 4\r\n")
+	assert.Equal(t, "bare", bodyOf(rw.String()))
+
+	rw = &readerFromWriter{}
+	w = NewWriter(rw)
+	w.EnableCompression(EncodingGzip, 0)
+	body := strings.Repeat("a", 100)
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(len(body))))
+	_, err = w.WriteBodyFrom(strings.NewReader(body))
+	require.NoError(t, err)
+	assert.Empty(t, rw.readers)
+	assert.Contains(t, headerBlock(rw.String()), "Content-Encoding: gzip\r\n")
+
+	// Test: HEAD doesn't read the source
+	rw = &readerFromWriter{}
+	w = NewWriter(rw)
+	w.SetHEAD()
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(5)))
+	head := strings.NewReader("hello")
+	n, err = w.WriteBodyFrom(head)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+	assert.Equal(t, 5, head.Len())
+}
+
+func TestWriterStatusAndBytes(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	assert.Equal(t, StatusCode(0), w.Status())
+	assert.Equal(t, int64(0), w.BytesWritten())
+
+	body := []byte("hello")
+	require.NoError(t, w.WriteResponse(StatusCreated, GetDefaultHeaders(len(body)), body))
+	assert.Equal(t, StatusCreated, w.Status())
+	assert.Equal(t, int64(5), w.BytesWritten())
+
+	// Test: A held-back bare body is already accounted for
+	w = NewWriter(&buf)
+	require.NoError(t, w.WriteBody([]byte("abc")))
+	assert.Equal(t, StatusOK, w.Status())
+	assert.Equal(t, int64(3), w.BytesWritten())
+	require.NoError(t, w.Finish())
+	assert.Equal(t, int64(3), w.BytesWritten())
+}
+
+func TestWrittenHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Header().Set("X-Request-ID", "r1")
+	assert.Nil(t, w.WrittenHeader())
+
+	// Test: Snapshot includes merged middleware fields
+	h := GetDefaultHeaders(0)
+	h.Del("Content-Length")
+	h.Set("Transfer-Encoding", "chunked")
+	h.Set("Trailer", "X-Sum")
+	require.NoError(t, w.WriteHeaders(h))
+	sent := w.WrittenHeader()
+	require.NotNil(t, sent)
+	v, ok := sent.Get("X-Request-ID")
+	assert.True(t, ok)
+	assert.Equal(t, "r1", v)
+
+	// Test: Trailers and later edits don't change it
+	require.NoError(t, w.WriteChunkEnd(true))
+	trailer := headers.NewHeaders()
+	trailer.Set("X-Sum", "0")
+	require.NoError(t, w.WriteHeaders(trailer))
+	h.Set("X-Late", "1")
+	sent.Set("X-Mutated", "1")
+	_, ok = w.WrittenHeader().Get("X-Sum")
+	assert.False(t, ok)
+	_, ok = w.WrittenHeader().Get("X-Late")
+	assert.False(t, ok)
+	_, ok = w.WrittenHeader().Get("X-Mutated")
+	assert.False(t, ok)
+}
+
+func TestWriteChunkedFrom(t *testing.T) {
+	// Test: Streams the reader in chunkSize pieces and terminates the body
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	h := GetDefaultHeaders(0)
+	err := w.WriteChunkedFrom(StatusOK, h, strings.NewReader("hello world"), 4, nil)
+	require.NoError(t, err)
+
+	out := buf.String()
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Transfer-Encoding: chunked\r\n")
+	assert.NotContains(t, hb, "Content-Length")
+	assert.Equal(t, "4\r\nhell\r\n4\r\no wo\r\n3\r\nrld\r\n0\r\n\r\n", bodyOf(out))
+
+	// Test: Trailer callback runs after the body
+	buf.Reset()
+	w = NewWriter(&buf)
+	h = GetDefaultHeaders(0)
+	h.Set("Trailer", "X-Count")
+	err = w.WriteChunkedFrom(StatusOK, h, strings.NewReader("abc"), 0, func() *headers.Headers {
+		trailer := headers.NewHeaders()
+		trailer.Set("X-Count", "3")
+		return trailer
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "3\r\nabc\r\n0\r\nX-Count: 3\r\n\r\n", bodyOf(buf.String()))
+
+	// Test: Read errors are returned after the headers went out
+	buf.Reset()
+	w = NewWriter(&buf)
+	err = w.WriteChunkedFrom(StatusOK, GetDefaultHeaders(0), badReadSeeker{}, 4, nil)
+	require.Error(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(buf.String()))
+}
+
+func TestWriteResponse(t *testing.T) {
+	// Test: Writes status line + headers + body in correct order (supports partial writes)
+	cw := &chunkWriter{maxPerWrite: 3}
+	w := NewWriter(cw)
+	body := []byte("OK")
+	h := headers.NewHeaders()
+	h.Set("Content-Length", "2")
+	h.Set("Connection", "close")
+	h.Set("Content-Type", "text/plain")
+	err := w.WriteResponse(StatusOK, h, body)
+	require.NoError(t, err)
+	out := cw.String()
+
+	// starts with status line
+	require.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+
+	// header terminator exists
+	sep := "\r\n\r\n"
+	idx := strings.Index(out, sep)
+	require.NotEqual(t, -1, idx)
+
+	// body appears after the blank line
+	assert.Equal(t, "OK", out[idx+len(sep):])
+
+	// and headers are somewhere before the terminator (order-independent)
+	headerSection := out[:idx+len(sep)]
+	assert.Contains(t, headerSection, "Content-Length: 2\r\n")
+	assert.Contains(t, headerSection, "Connection: close\r\n")
+	assert.Contains(t, headerSection, "Content-Type: text/plain\r\n")
+
+	// Test: Fails fast if status line write fails
+	ew := &errWriter{failAfter: 0} // fail on first Write
+	w = NewWriter(ew)
+	err = w.WriteResponse(StatusOK, h, body)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+
+	// Test: Fails if headers write fails (status line succeeds, headers fail)
+	ew = &errWriter{failAfter: 1} // 1st write ok (status line), 2nd write fails (headers)
+	w = NewWriter(ew)
+	err = w.WriteResponse(StatusOK, h, body)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+
+	// Test: Fails if headers write fails (status line succeeds, headers fail)
+	ew = &errWriter{failAfter: 2} // 1st 2 writes ok (status line, headers), 3rd write fails (body)
+	w = NewWriter(ew)
+	err = w.WriteResponse(StatusOK, h, body)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+
+	// Test: Zero-byte writes are treated as failure
+	zw := &zeroWriter{}
+	w = NewWriter(zw)
+	err = w.WriteResponse(StatusOK, h, body)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrFailedToWrite))
+}
+
+func TestWritePartialContentResponse_NoRange_Returns200FullBody(t *testing.T) {
+	content := []byte("hello-world")
+	req := mkReq("GET", "/video")
+	// no Range header
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	rs := bytes.NewReader(content) // ReadSeeker
+	err := w.WritePartialContentResponse(rs, int64(len(content)), "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Type: video/mp4\r\n")
+	assert.Contains(t, hb, "Accept-Ranges: bytes\r\n")
+	assert.Contains(t, hb, "Content-Length: 11\r\n")
+
+	assert.Equal(t, "hello-world", bodyOf(out))
+}
+
+func TestWritePartialContentResponse_RangeStartOnly_Returns206AndFullToEOF(t *testing.T) {
+	content := []byte("0123456789") // 10 bytes
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=0-")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	rs := bytes.NewReader(content)
+	err := w.WritePartialContentResponse(rs, int64(len(content)), "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 206 Partial Content\r\n", statusLineOf(out))
+
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Type: video/mp4\r\n")
+	assert.Contains(t, hb, "Accept-Ranges: bytes\r\n")
+	assert.Contains(t, hb, "Content-Range: bytes 0-9/10\r\n")
+	assert.Contains(t, hb, "Content-Length: 10\r\n")
+
+	assert.Equal(t, "0123456789", bodyOf(out))
+}
+
+func TestWritePartialContentResponse_RangeStartEnd_Returns206Subset(t *testing.T) {
+	content := []byte("abcdefghijklmnopqrstuvwxyz") // 26 bytes
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=2-5")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	rs := bytes.NewReader(content)
+	err := w.WritePartialContentResponse(rs, int64(len(content)), "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 206 Partial Content\r\n", statusLineOf(out))
+
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Range: bytes 2-5/26\r\n")
+	assert.Contains(t, hb, "Content-Length: 4\r\n")
+
+	assert.Equal(t, "cdef", bodyOf(out))
+}
+
+func TestWritePartialContentResponse_RangeEndClamped_Returns206Clamped(t *testing.T) {
+	content := []byte("abcdefghij") // 10 bytes
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=7-999")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	rs := bytes.NewReader(content)
+	err := w.WritePartialContentResponse(rs, int64(len(content)), "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 206 Partial Content\r\n", statusLineOf(out))
+
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Range: bytes 7-9/10\r\n")
+	assert.Contains(t, hb, "Content-Length: 3\r\n")
+
+	assert.Equal(t, "hij", bodyOf(out))
+}
+
+func TestWritePartialContentResponse_IfRange_Returns200FullBody(t *testing.T) {
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=2-4")
+	req.Headers.Set("If-Range", "\"abc\"")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	err := w.WritePartialContentResponse(bytes.NewReader([]byte("abcdefghij")), 10, "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.NotContains(t, headerBlock(out), "Content-Range")
+	assert.Equal(t, "abcdefghij", bodyOf(out))
+}
+
+func TestWritePartialContentResponse_InvalidRange_Returns400(t *testing.T) {
+	content := []byte("abcdefghij") // 10 bytes
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=abc-10")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	rs := bytes.NewReader(content)
+	err := w.WritePartialContentResponse(rs, int64(len(content)), "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 400 Bad Request\r\n", statusLineOf(out))
+
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Type: text/plain\r\n")
+	assert.Contains(t, hb, "Content-Length: 13\r\n")
+	assert.Equal(t, "invalid range", bodyOf(out))
+}
+
+func TestWritePartialContentResponse_UnsatisfiableStart_Returns416(t *testing.T) {
+	content := []byte("abcdefghij") // 10 bytes
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=10-") // start == size => out of bounds
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	rs := bytes.NewReader(content)
+	err := w.WritePartialContentResponse(rs, int64(len(content)), "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 416 Range Not Satisfiable\r\n", statusLineOf(out))
+
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Type: text/plain\r\n")
+	assert.Contains(t, hb, "Content-Range: bytes */10\r\n")
+	assert.Contains(t, hb, "Content-Length: 22\r\n") // len("invalid range provided")
+	assert.Equal(t, "invalid range provided", bodyOf(out))
+}
+
+func TestWritePartialContentResponse_UnsatisfiableEndLtStart_Returns416(t *testing.T) {
+	content := []byte("abcdefghij") // 10 bytes
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=7-3")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	rs := bytes.NewReader(content)
+	err := w.WritePartialContentResponse(rs, int64(len(content)), "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 416 Range Not Satisfiable\r\n", statusLineOf(out))
+
+	hb := headerBlock(out)
+	assert.Contains(t, hb, "Content-Range: bytes */10\r\n")
+	assert.Equal(t, "invalid range provided", bodyOf(out))
+}
+
+func TestWritePartialContentResponse_ReadError_AfterHeaders(t *testing.T) {
+	req := mkReq("GET", "/video")
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	// the body is streamed after the headers, so read failures surface as an
+	// error instead of a 500 response
+	err := w.WritePartialContentResponse(badReadSeeker{}, 10, "video/mp4", req)
+	require.Error(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.Contains(t, headerBlock(out), "Content-Length: 10\r\n")
+	assert.Equal(t, "", bodyOf(out))
+}
+
+func TestWritePartialContentResponse_ShortContent_ReturnsError(t *testing.T) {
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=5-")
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	// declared size larger than the actual content
+	err := w.WritePartialContentResponse(bytes.NewReader([]byte("abcdefg")), 10, "video/mp4", req)
+	require.Error(t, err)
+	assert.Equal(t, "HTTP/1.1 206 Partial Content\r\n", statusLineOf(buf.String()))
+	assert.Equal(t, "fg", bodyOf(buf.String()))
+}
+
+func TestWritePartialContentResponse_LoadRangeSeekError_Returns500(t *testing.T) {
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=0-")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	rs := &badSeek{data: []byte("abcdefghij")} // 10 bytes
+	err := w.WritePartialContentResponse(rs, 10, "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 500 Internal Server Error\r\n", statusLineOf(out))
+	assert.Equal(t, "error loading range", bodyOf(out))
+}
+
+func TestWrite100Continue(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, w.Write100Continue())
+	assert.False(t, w.Written())
+
+	body := []byte("ok")
+	require.NoError(t, w.WriteResponse(StatusOK, GetDefaultHeaders(len(body)), body))
+	assert.True(t, strings.HasPrefix(buf.String(), "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 200 OK\r\n"))
+}
+
+func TestWriteInformational(t *testing.T) {
+	// Test: Early hints with headers, then the final response
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Header().Set("X-Middleware", "yes")
+	hints := headers.NewHeaders()
+	hints.Set("Link", "</styles.css>; rel=preload; as=style")
+	require.NoError(t, w.WriteInformational(StatusProcessing, nil))
+	require.NoError(t, w.WriteInformational(StatusEarlyHints, hints))
+	assert.Equal(t, "HTTP/1.1 102 Processing\r\n\r\n"+
+		"HTTP/1.1 103 Early Hints\r\nLink: </styles.css>; rel=preload; as=style\r\n\r\n", buf.String())
+
+	buf.Reset()
+	body := []byte("ok")
+	require.NoError(t, w.WriteResponse(StatusOK, GetDefaultHeaders(len(body)), body))
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(buf.String()))
+	assert.Contains(t, headerBlock(buf.String()), "X-Middleware: yes\r\n")
+
+	// Test: Too late once the final response started
+	assert.ErrorIs(t, w.WriteInformational(StatusEarlyHints, hints), ErrResponseStarted)
+
+	// Test: Only 1xx codes are informational, and they can't be final
+	w = NewWriter(&buf)
+	assert.ErrorIs(t, w.WriteInformational(StatusOK, nil), ErrUnrecognizedStatusCode)
+	assert.ErrorIs(t, w.WriteInformational(StatusCode(101), nil), ErrUnrecognizedStatusCode)
+	assert.ErrorIs(t, w.WriteStatusLine(StatusEarlyHints), ErrInformationalStatus)
+	assert.False(t, w.Written())
+}
+
+func TestWriterHTTP10(t *testing.T) {
+	// Test: Chunked responses are sent unframed, trailers dropped
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetHTTP10()
+	require.NoError(t, w.WriteInformational(StatusEarlyHints, nil))
+	h := GetDefaultHeaders(0)
+	h.Set("Trailer", "X-Sum")
+	err := w.WriteChunkedFrom(StatusOK, h, strings.NewReader("hello world"), 4, func() *headers.Headers {
+		tr := headers.NewHeaders()
+		tr.Set("X-Sum", "1")
+		return tr
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Finish())
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.NotContains(t, headerBlock(out), "Transfer-Encoding")
+	assert.NotContains(t, headerBlock(out), "Trailer")
+	assert.Contains(t, headerBlock(out), "Connection: close\r\n")
+	assert.Equal(t, "hello world", bodyOf(out))
+	_, ok := w.WrittenHeader().Get("Transfer-Encoding")
+	assert.False(t, ok)
+
+	// Test: Large bare bodies that would go chunked are unframed too
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetHTTP10()
+	big := bytes.Repeat([]byte("a"), maxAutoBodySize+1)
+	require.NoError(t, w.WriteBody(big))
+	require.NoError(t, w.Finish())
+	assert.NotContains(t, headerBlock(buf.String()), "chunked")
+	assert.Equal(t, string(big), bodyOf(buf.String()))
+
+	// Test: Fixed-length responses are untouched
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetHTTP10()
+	require.NoError(t, w.WriteResponse(StatusOK, GetDefaultHeaders(2), []byte("ok")))
+	assert.Contains(t, headerBlock(buf.String()), "Content-Length: 2\r\n")
+	assert.Equal(t, "ok", bodyOf(buf.String()))
+}
+
+func TestWriter_KeepAlive(t *testing.T) {
+	// Test: Without SetKeepAlive every response closes the connection
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, w.WriteResponse(StatusOK, GetDefaultHeaders(2), []byte("ok")))
+	assert.Contains(t, headerBlock(buf.String()), "Connection: close\r\n")
+	assert.False(t, w.KeepAlive())
+
+	// Test: Delimited responses stay open, whole bodies only
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetKeepAlive()
+	require.NoError(t, w.WriteResponse(StatusOK, GetDefaultHeaders(2), []byte("ok")))
+	assert.NotContains(t, headerBlock(buf.String()), "Connection")
+	assert.True(t, w.KeepAlive())
+
+	w = NewWriter(io.Discard)
+	w.SetKeepAlive()
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(5)))
+	require.NoError(t, w.WriteBody([]byte("ab")))
+	assert.False(t, w.KeepAlive(), "short body")
+
+	w = NewWriter(io.Discard)
+	w.SetKeepAlive()
+	require.NoError(t, w.WriteChunkedFrom(StatusOK, GetDefaultHeaders(0), strings.NewReader("hello"), 2, nil))
+	assert.True(t, w.KeepAlive(), "chunked")
+
+	// Test: Bodies of unknown length are delimited by closing
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetKeepAlive()
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(headers.NewHeaders()))
+	require.NoError(t, w.WriteBody([]byte("stream")))
+	assert.Contains(t, headerBlock(buf.String()), "Connection: close\r\n")
+	assert.False(t, w.KeepAlive())
+
+	// Test: Handlers can still ask to close
+	w = NewWriter(io.Discard)
+	w.SetKeepAlive()
+	h := GetDefaultHeaders(0)
+	h.Set("Connection", "close")
+	require.NoError(t, w.WriteResponse(StatusOK, h, nil))
+	assert.False(t, w.KeepAlive())
+
+	// Test: HTTP/1.0 clients are told the connection stays open, unless the
+	// body had to be unframed
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetHTTP10()
+	w.SetKeepAlive()
+	require.NoError(t, w.WriteResponse(StatusOK, GetDefaultHeaders(2), []byte("ok")))
+	assert.Contains(t, headerBlock(buf.String()), "Connection: keep-alive\r\n")
+	assert.True(t, w.KeepAlive())
+
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetHTTP10()
+	w.SetKeepAlive()
+	require.NoError(t, w.WriteChunkedFrom(StatusOK, GetDefaultHeaders(0), strings.NewReader("hello"), 2, nil))
+	assert.Contains(t, headerBlock(buf.String()), "Connection: close\r\n")
+	assert.False(t, w.KeepAlive())
+}
+
+func TestHijack(t *testing.T) {
+	// Test: Writers without a hijacker refuse
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	_, _, err := w.Hijack()
+	assert.ErrorIs(t, err, ErrNotHijackable)
+	assert.False(t, w.Hijacked())
+
+	// Test: Hijack hands over the connection and blocks further writes
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	w = NewWriter(server)
+	w.SetHijacker(func() (net.Conn, *bufio.ReadWriter, error) {
+		return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+	})
+	conn, rw, err := w.Hijack()
+	require.NoError(t, err)
+	assert.Equal(t, server, conn)
+	require.NotNil(t, rw)
+	assert.True(t, w.Hijacked())
+
+	assert.ErrorIs(t, w.WriteStatusLine(StatusOK), ErrHijacked)
+	_, _, err = w.Hijack()
+	assert.ErrorIs(t, err, ErrHijacked)
+
+	// Test: Failed hijacks leave the Writer usable
+	w = NewWriter(&buf)
+	w.SetHijacker(func() (net.Conn, *bufio.ReadWriter, error) {
+		return nil, nil, errors.New("nope")
+	})
+	_, _, err = w.Hijack()
+	require.Error(t, err)
+	assert.False(t, w.Hijacked())
+	assert.NoError(t, w.WriteStatusLine(StatusOK))
+}
+
+func TestGetDefaultHeaders(t *testing.T) {
+	h := GetDefaultHeaders(123)
+	v, ok := h.Get("Content-Length")
+	require.True(t, ok)
+	assert.Equal(t, "123", v)
+	_, ok = h.Get("Connection")
+	assert.False(t, ok, "filled in by the Writer")
+	v, ok = h.Get("Content-Type")
+	require.True(t, ok)
+	assert.Equal(t, "text/html", v)
+}
+
+func TestWriter_SetDefaultHeaders(t *testing.T) {
+	defaults := headers.NewHeaders()
+	defaults.Set("Server", "tcp-http-server")
+	defaults.Set("Cache-Control", "no-store")
+	defaults.Set("X-Frame-Options", "DENY")
+
+	// Test: Defaults fill in missing fields, after Header and the handler's own
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetDefaultHeaders(defaults)
+	w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+	h := GetDefaultHeaders(2)
+	h.Set("Cache-Control", "max-age=60")
+	require.NoError(t, w.WriteResponse(StatusOK, h, []byte("ok")))
+	hb := headerBlock(buf.String())
+	assert.Contains(t, hb, "Server: tcp-http-server\r\n")
+	assert.Contains(t, hb, "Cache-Control: max-age=60\r\n")
+	assert.Contains(t, hb, "X-Frame-Options: SAMEORIGIN\r\n")
+	assert.Contains(t, hb, "Connection: close\r\n")
+	assert.Equal(t, 1, strings.Count(hb, "Cache-Control"))
+	assert.Equal(t, 3, defaults.Len(), "defaults are left alone")
+
+	// Test: A Connection field of the response is kept
+	buf.Reset()
+	w = NewWriter(&buf)
+	h = GetDefaultHeaders(0)
+	h.Set("Connection", "Upgrade")
+	require.NoError(t, w.WriteResponse(StatusOK, h, nil))
+	assert.Contains(t, headerBlock(buf.String()), "Connection: Upgrade\r\n")
+	assert.NotContains(t, buf.String(), "close")
+
+	// Test: Invalid defaults are refused before anything is written
+	bad := headers.NewHeaders()
+	bad.Set("Bad Name", "x")
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetDefaultHeaders(bad)
+	assert.ErrorIs(t, w.WriteResponse(StatusOK, GetDefaultHeaders(0), nil), ErrInvalidHeaderField)
+}
+
+func TestParseRanges(t *testing.T) {
+	specs, ok := parseRanges("bytes=0-")
+	require.True(t, ok)
+	assert.Equal(t, []rangeSpec{{start: 0}}, specs)
+
+	specs, ok = parseRanges("bytes=5-10")
+	require.True(t, ok)
+	assert.Equal(t, []rangeSpec{{start: 5, end: 10, endProvided: true}}, specs)
+
+	specs, ok = parseRanges("bytes=-10")
+	require.True(t, ok)
+	assert.Equal(t, []rangeSpec{{end: 10, suffix: true}}, specs)
+
+	specs, ok = parseRanges("bytes=0-1, 4-, -2")
+	require.True(t, ok)
+	assert.Equal(t, []rangeSpec{
+		{start: 0, end: 1, endProvided: true},
+		{start: 4},
+		{end: 2, suffix: true},
+	}, specs)
+
+	_, ok = parseRanges("bytes=abc-10")
+	assert.False(t, ok)
+
+	_, ok = parseRanges("bytes=-0")
+	assert.False(t, ok)
+
+	_, ok = parseRanges("bytes=0-1,")
+	assert.False(t, ok)
+
+	_, ok = parseRanges("nope=0-10")
+	assert.False(t, ok)
+
+	_, ok = parseRanges("bytes=1") // missing '-'
+	assert.False(t, ok)
+}
+
+func TestResolveRanges(t *testing.T) {
+	specs, _ := parseRanges("bytes=0-1,-3,8-,20-30")
+	ranges, err := resolveRanges(specs, 10)
+	require.NoError(t, err)
+	// 20-30 is unsatisfiable and dropped
+	assert.Equal(t, []byteRange{{0, 1}, {7, 9}, {8, 9}}, ranges)
+
+	// suffix longer than the content covers all of it
+	specs, _ = parseRanges("bytes=-50")
+	ranges, err = resolveRanges(specs, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []byteRange{{0, 9}}, ranges)
+
+	specs, _ = parseRanges("bytes=10-,20-")
+	_, err = resolveRanges(specs, 10)
+	assert.ErrorIs(t, err, ErrRangeOutOfBounds)
+
+	specs, _ = parseRanges("bytes=0-1,5-2")
+	_, err = resolveRanges(specs, 10)
+	assert.ErrorIs(t, err, ErrRangeEndLtStart)
+}
+
+func TestWritePartialContentResponse_SuffixRange_Returns206Tail(t *testing.T) {
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=-3")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	err := w.WritePartialContentResponse(bytes.NewReader([]byte("abcdefghij")), 10, "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 206 Partial Content\r\n", statusLineOf(out))
+	assert.Contains(t, headerBlock(out), "Content-Range: bytes 7-9/10\r\n")
+	assert.Equal(t, "hij", bodyOf(out))
+}
+
+func TestWritePartialContentResponse_MultiRange_ReturnsMultipart(t *testing.T) {
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes=0-1,-2")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	err := w.WritePartialContentResponse(bytes.NewReader([]byte("abcdefghij")), 10, "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 206 Partial Content\r\n", statusLineOf(out))
+
+	hb := headerBlock(out)
+	assert.NotContains(t, hb, "Content-Range:")
+	_, after, found := strings.Cut(hb, "Content-Type: multipart/byteranges; boundary=")
+	require.True(t, found)
+	boundary, _, _ := strings.Cut(after, "\r\n")
+	require.NotEmpty(t, boundary)
+
+	body := bodyOf(out)
+	expected := "--" + boundary + "\r\n" +
+		"Content-Type: video/mp4\r\n" +
+		"Content-Range: bytes 0-1/10\r\n\r\n" +
+		"ab\r\n" +
+		"--" + boundary + "\r\n" +
+		"Content-Type: video/mp4\r\n" +
+		"Content-Range: bytes 8-9/10\r\n\r\n" +
+		"ij\r\n" +
+		"--" + boundary + "--\r\n"
+	assert.Equal(t, expected, body)
+	assert.Contains(t, hb, fmt.Sprintf("Content-Length: %d\r\n", len(body)))
+}
+
+func TestWritePartialContentResponse_TooManyRanges_Returns200(t *testing.T) {
+	req := mkReq("GET", "/video")
+	req.Headers.Set("Range", "bytes="+strings.Repeat("0-0,", maxRanges)+"0-0")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	err := w.WritePartialContentResponse(bytes.NewReader([]byte("abcdefghij")), 10, "video/mp4", req)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.Equal(t, "abcdefghij", bodyOf(out))
+}
+
+func TestResolveRange(t *testing.T) {
+	// bytes=0- (no end provided) => 0..25
+	start, end, err := resolveRange(26, 0, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), start)
+	assert.Equal(t, int64(25), end)
+
+	// bytes=2-5
+	start, end, err = resolveRange(26, 2, 5, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), start)
+	assert.Equal(t, int64(5), end)
+
+	// clamp end: bytes=20-999 => 20..25
+	start, end, err = resolveRange(26, 20, 999, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), start)
+	assert.Equal(t, int64(25), end)
+
+	// start out of bounds
+	_, _, err = resolveRange(26, 26, 0, false)
+	assert.ErrorIs(t, err, ErrRangeOutOfBounds)
+
+	// end < start
+	_, _, err = resolveRange(26, 10, 5, true)
+	assert.ErrorIs(t, err, ErrRangeEndLtStart)
+
+	// contentSize <= 0
+	_, _, err = resolveRange(0, 0, 0, false)
+	assert.ErrorIs(t, err, ErrRangeOutOfBounds)
+
+	// sizes beyond 32 bits
+	start, end, err = resolveRange(6<<30, 5<<30, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5<<30), start)
+	assert.Equal(t, int64(6<<30-1), end)
+}
+
+func TestWriteHeadersOrdered(t *testing.T) {
+	// Test: Serialization is deterministic and follows insertion order
+	cw := &chunkWriter{maxPerWrite: 64}
+	w := writerInState(cw, stateStatus)
+	h := GetDefaultHeaders(5)
+	h.Set("X-Test", "abc")
+	require.NoError(t, w.WriteHeaders(h))
+	assert.Equal(t,
+		"Content-Length: 5\r\nContent-Type: text/html\r\nX-Test: abc\r\nConnection: close\r\n\r\n",
+		cw.String(),
+	)
+}
+
+func TestWriter_Release(t *testing.T) {
+	// Test: Released buffers are reused without carrying output over
+	var first bytes.Buffer
+	w := NewBufferedWriter(&first)
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(0)))
+	w.Release()
+	assert.Empty(t, first.String())
+	assert.ErrorIs(t, w.WriteBody([]byte("late")), ErrReleased)
+
+	var second bytes.Buffer
+	w = NewBufferedWriter(&second)
+	require.NoError(t, w.WriteBody([]byte("hello")))
+	require.NoError(t, w.Finish())
+	w.Release()
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(second.String()))
+	assert.Equal(t, "hello", bodyOf(second.String()))
+	assert.Empty(t, first.String())
+
+	// Test: Hijacked writers are left alone
+	w = NewBufferedWriter(&bytes.Buffer{})
+	w.SetHijacker(func() (net.Conn, *bufio.ReadWriter, error) { return nil, nil, nil })
+	_, _, err := w.Hijack()
+	require.NoError(t, err)
+	w.Release()
+	assert.ErrorIs(t, w.WriteStatusLine(StatusOK), ErrHijacked)
+}
+
+func BenchmarkWriteResponse(b *testing.B) {
+	body := []byte(`{"name":"widget","qty":100}`)
+	b.ReportAllocs()
+	for range b.N {
+		w := NewBufferedWriter(io.Discard)
+		h := GetDefaultHeaders(len(body))
+		h.Replace("Content-Type", "application/json")
+		if err := w.WriteResponse(StatusOK, h, body); err != nil {
+			b.Fatal(err)
+		}
+		if err := w.Finish(); err != nil {
+			b.Fatal(err)
+		}
+		w.Release()
+	}
+}
+
+func BenchmarkWriteBody(b *testing.B) {
+	body := bytes.Repeat([]byte("x"), 512)
+	b.ReportAllocs()
+	for range b.N {
+		w := NewBufferedWriter(io.Discard)
+		for range 16 {
+			if err := w.WriteBody(body); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := w.Finish(); err != nil {
+			b.Fatal(err)
+		}
+		w.Release()
+	}
+}
@@ -0,0 +1,350 @@
+package response
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+)
+
+// TimeFormat is the IMF-fixdate layout used by Last-Modified and friends.
+const TimeFormat = headers.TimeFormat
+
+const sniffLen = 512
+
+var sniffSignatures = []struct {
+	prefix      []byte
+	contentType string
+}{
+	{[]byte("<!DOCTYPE HTML"), "text/html; charset=utf-8"},
+	{[]byte("<HTML"), "text/html; charset=utf-8"},
+	{[]byte("<?XML"), "text/xml; charset=utf-8"},
+	{[]byte("%PDF-"), "application/pdf"},
+	{[]byte("\x89PNG\r\n\x1a\n"), "image/png"},
+	{[]byte("\xff\xd8\xff"), "image/jpeg"},
+	{[]byte("GIF87a"), "image/gif"},
+	{[]byte("GIF89a"), "image/gif"},
+	{[]byte("\x00\x00\x01\x00"), "image/x-icon"},
+	{[]byte("\x1a\x45\xdf\xa3"), "video/webm"},
+	{[]byte("\x1f\x8b\x08"), "application/x-gzip"},
+	{[]byte("PK\x03\x04"), "application/zip"},
+}
+
+// DetectContentType makes a best guess at the media type of data from its
+// first bytes, falling back to text/plain for UTF-8 text and
+// application/octet-stream otherwise.
+func DetectContentType(data []byte) string {
+	if len(data) > sniffLen {
+		data = data[:sniffLen]
+	}
+
+	trimmed := bytes.TrimLeft(data, "\t\n\x0c\r ")
+	for _, sig := range sniffSignatures {
+		if len(trimmed) >= len(sig.prefix) && bytes.EqualFold(trimmed[:len(sig.prefix)], sig.prefix) {
+			return sig.contentType
+		}
+	}
+
+	// ISO base media files (mp4) have "ftyp" at offset 4
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+		return "video/mp4"
+	}
+
+	if utf8.Valid(data) && bytes.IndexFunc(data, isBinaryRune) == -1 {
+		return "text/plain; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+func isBinaryRune(r rune) bool {
+	return r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != 0x0c && r != 0x1b
+}
+
+// contentTypeFor resolves the type from the file extension, sniffing the
+// content when the extension is unknown.
+func contentTypeFor(name string, content io.ReadSeeker) (string, error) {
+	if ct := mime.TypeByExtension(filepath.Ext(name)); ct != "" {
+		return ct, nil
+	}
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(content, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return DetectContentType(buf[:n]), nil
+}
+
+func makeETag(modtime time.Time, size int64) string {
+	return fmt.Sprintf("\"%x-%x\"", modtime.UnixNano(), size)
+}
+
+// hashETag makes an ETag from the bytes of content, for content without a
+// modification time to tell versions apart by.
+func hashETag(content io.ReadSeeker) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("\"%x\"", hash.Sum(nil)[:16]), nil
+}
+
+// etagMatches reports whether etag is listed in an If-None-Match value, using
+// weak comparison.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified evaluates If-None-Match (preferred) and If-Modified-Since.
+func notModified(req *request.Request, etag string, modtime time.Time) bool {
+	if m := req.RequestLine.Method; m != "GET" && m != "HEAD" {
+		return false
+	}
+
+	if inm, ok := req.Headers.Get("If-None-Match"); ok {
+		return etagMatches(inm, etag)
+	}
+
+	if t, ok := req.Headers.GetTime("If-Modified-Since"); ok && !modtime.IsZero() {
+		return !modtime.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// resolveRange validates a single "start-end" range against the content size
+// and returns the inclusive byte offsets to send.
+func resolveRange(contentSize int64, start int, end int, endProvided bool) (int64, int64, error) {
+	if contentSize <= 0 || int64(start) >= contentSize {
+		return 0, 0, ErrRangeOutOfBounds
+	}
+
+	usedEnd := contentSize - 1
+	if endProvided {
+		if end < start {
+			return 0, 0, ErrRangeEndLtStart
+		}
+		usedEnd = min(int64(end), contentSize-1) // clamp
+	}
+
+	return int64(start), usedEnd, nil
+}
+
+// copyBody streams n bytes from r to the response body without buffering the
+// whole payload.
+func (w *Writer) copyBody(r io.Reader, n int64) error {
+	written, err := w.WriteBodyFrom(io.LimitReader(r, n))
+	if err == nil && written < n && !w.head {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (w *Writer) writeTextError(status StatusCode, h *headers.Headers, msg string) error {
+	body := []byte(msg)
+	h.Replace("Content-Type", "text/plain")
+	h.Replace("Content-Length", strconv.Itoa(len(body)))
+	return w.WriteResponse(status, h, body)
+}
+
+// ServeContent replies to req with the contents of content, handling
+// Content-Type detection, conditional requests (If-None-Match,
+// If-Modified-Since, If-Range), byte ranges, and HEAD. name is only used to pick
+// the Content-Type from its extension. A zero modtime, as files of an
+// embed.FS have, disables Last-Modified and has the ETag hashed from the
+// content instead.
+func (w *Writer) ServeContent(req *request.Request, name string, modtime time.Time, content io.ReadSeeker) error {
+	return w.serveContent(req, name, modtime, content, GetDefaultHeaders(0))
+}
+
+// serveContent is ServeContent starting from the header fields in h. An ETag
+// already in h is used as it is.
+func (w *Writer) serveContent(req *request.Request, name string, modtime time.Time, content io.ReadSeeker, h *headers.Headers) error {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = content.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return w.writeTextError(StatusInternalServerError, GetDefaultHeaders(0), "error loading content")
+	}
+
+	contentType, err := contentTypeFor(name, content)
+	if err != nil {
+		return w.writeTextError(StatusInternalServerError, GetDefaultHeaders(0), "error loading content")
+	}
+
+	etag, ok := h.Get("ETag")
+	if !ok {
+		etag = makeETag(modtime, size)
+		if modtime.IsZero() {
+			if etag, err = hashETag(content); err != nil {
+				return w.writeTextError(StatusInternalServerError, GetDefaultHeaders(0), "error loading content")
+			}
+		}
+		h.Set("ETag", etag)
+	}
+	h.Set("Accept-Ranges", "bytes")
+	if !modtime.IsZero() {
+		h.Set("Last-Modified", headers.FormatTime(modtime))
+	}
+
+	if notModified(req, etag, modtime) {
+		h.Del("Content-Length")
+		h.Del("Content-Type")
+		return w.WriteResponse(StatusNotModified, h, []byte{})
+	}
+
+	bodyless := req.RequestLine.Method == "HEAD"
+	if rangeStr, ok := req.Headers.Get("Range"); ok && ifRangeMatches(req, etag, modtime) {
+		return w.writeRanges(content, size, contentType, h, rangeStr, bodyless)
+	}
+
+	h.Replace("Content-Type", contentType)
+	h.Replace("Content-Length", strconv.FormatInt(size, 10))
+	return w.writeStreamed(StatusOK, h, content, size, bodyless)
+}
+
+// ServeFile replies to req with the named file (or the index.html inside a
+// directory) using ServeContent. Missing files get a 404. When the client
+// accepts it and the file's type is known from its extension, a precompressed
+// sibling such as app.js.br or app.js.gz is sent in its place with the
+// matching Content-Encoding.
+func (w *Writer) ServeFile(req *request.Request, name string) error {
+	return w.serveFile(req, osFS{}, name, filepath.Join)
+}
+
+// ServeFileFS is ServeFile for a file of fsys, e.g. an embed.FS, so assets
+// can ship inside the binary. name is a slash-separated fs.FS path without a
+// leading slash. Files that can't seek are read into memory to be served.
+func (w *Writer) ServeFileFS(req *request.Request, fsys fs.FS, name string) error {
+	return w.serveFile(req, fsys, name, path.Join)
+}
+
+// osFS opens names as they are, so ServeFile can share serveFile; unlike
+// os.DirFS it takes any OS path.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (w *Writer) serveFile(req *request.Request, fsys fs.FS, name string, join func(elem ...string) string) error {
+	f, err := fsys.Open(name)
+	var info fs.FileInfo
+	if err == nil {
+		if info, err = f.Stat(); err == nil && info.IsDir() {
+			f.Close()
+			name = join(name, "index.html")
+			if f, err = fsys.Open(name); err == nil {
+				info, err = f.Stat()
+			}
+		}
+	}
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		return w.writeTextError(StatusNotFound, GetDefaultHeaders(0), "not found")
+	}
+	if err != nil {
+		return w.writeTextError(StatusInternalServerError, GetDefaultHeaders(0), "error loading content")
+	}
+	defer f.Close()
+
+	h := GetDefaultHeaders(0)
+	if cf, cinfo, encoding, vary := openPrecompressed(req, fsys, name); vary {
+		addVary(h, "Accept-Encoding")
+		if cf != nil {
+			defer cf.Close()
+			f, info = cf, cinfo
+			h.Set("Content-Encoding", encoding)
+		}
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return w.writeTextError(StatusInternalServerError, GetDefaultHeaders(0), "error loading content")
+		}
+		content = bytes.NewReader(b)
+	}
+
+	return w.serveContent(req, name, info.ModTime(), content, h)
+}
+
+// precompressed lists the sibling files tried for a served file, by the
+// suffix added to its name, in order of preference.
+var precompressed = []struct{ encoding, suffix string }{
+	{"br", ".br"},
+	{EncodingGzip, ".gz"},
+}
+
+// openPrecompressed opens the precompressed sibling of name in the coding
+// req prefers among those present, or returns a nil file when name itself
+// should be sent. vary reports whether any sibling exists, in which case the
+// response depends on Accept-Encoding either way. Only files whose type is
+// known from their extension qualify, since a compressed body can't be
+// sniffed.
+func openPrecompressed(req *request.Request, fsys fs.FS, name string) (f fs.File, info fs.FileInfo, encoding string, vary bool) {
+	if mime.TypeByExtension(path.Ext(name)) == "" {
+		return nil, nil, "", false
+	}
+
+	var offers []string
+	for _, p := range precompressed {
+		if info, err := fs.Stat(fsys, name+p.suffix); err == nil && info.Mode().IsRegular() {
+			offers = append(offers, p.encoding)
+		}
+	}
+	if len(offers) == 0 {
+		return nil, nil, "", false
+	}
+	if _, ok := req.Headers.Get("Accept-Encoding"); !ok {
+		return nil, nil, "", true
+	}
+
+	encoding = req.NegotiateEncoding(offers...)
+	for _, p := range precompressed {
+		if p.encoding != encoding {
+			continue
+		}
+		f, err := fsys.Open(name + p.suffix)
+		if err != nil {
+			break
+		}
+		if info, err = f.Stat(); err != nil {
+			f.Close()
+			break
+		}
+		return f, info, encoding, true
+	}
+	return nil, nil, "", true
+}
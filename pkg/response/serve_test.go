@@ -0,0 +1,176 @@
+package response
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectContentType(t *testing.T) {
+	assert.Equal(t, "text/html; charset=utf-8", DetectContentType([]byte("  <!doctype html><html></html>")))
+	assert.Equal(t, "image/png", DetectContentType([]byte("\x89PNG\r\n\x1a\n....")))
+	assert.Equal(t, "video/mp4", DetectContentType([]byte("\x00\x00\x00\x18ftypmp42....")))
+	assert.Equal(t, "text/plain; charset=utf-8", DetectContentType([]byte("just some text\n")))
+	assert.Equal(t, "application/octet-stream", DetectContentType([]byte{0x00, 0x01, 0x02, 0xff}))
+}
+
+func TestServeContent(t *testing.T) {
+	content := "abcdefghijklmnopqrstuvwxyz"
+	modtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Test: Full response with validators and type from extension
+	req := mkReq("GET", "/letters.txt")
+	var buf bytes.Buffer
+	require.NoError(t, NewWriter(&buf).ServeContent(req, "letters.txt", modtime, strings.NewReader(content)))
+	out := buf.String()
+	hb := headerBlock(out)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(out))
+	assert.Contains(t, hb, "Content-Type: text/plain; charset=utf-8\r\n")
+	assert.Contains(t, hb, "Content-Length: 26\r\n")
+	assert.Contains(t, hb, "Last-Modified: Wed, 01 May 2024 12:00:00 GMT\r\n")
+	assert.Contains(t, hb, "Accept-Ranges: bytes\r\n")
+	assert.Equal(t, content, bodyOf(out))
+	etag := makeETag(modtime, int64(len(content)))
+	assert.Contains(t, hb, "ETag: "+etag+"\r\n")
+
+	// Test: Range is streamed
+	req = mkReq("GET", "/letters.txt")
+	req.Headers.Set("Range", "bytes=2-5")
+	buf.Reset()
+	require.NoError(t, NewWriter(&buf).ServeContent(req, "letters.txt", modtime, strings.NewReader(content)))
+	out = buf.String()
+	assert.Equal(t, "HTTP/1.1 206 Partial Content\r\n", statusLineOf(out))
+	assert.Contains(t, headerBlock(out), "Content-Range: bytes 2-5/26\r\n")
+	assert.Equal(t, "cdef", bodyOf(out))
+
+	// Test: Unsatisfiable range
+	req = mkReq("GET", "/letters.txt")
+	req.Headers.Set("Range", "bytes=50-")
+	buf.Reset()
+	require.NoError(t, NewWriter(&buf).ServeContent(req, "letters.txt", modtime, strings.NewReader(content)))
+	assert.Equal(t, "HTTP/1.1 416 Range Not Satisfiable\r\n", statusLineOf(buf.String()))
+	assert.Contains(t, buf.String(), "Content-Range: bytes */26\r\n")
+
+	// Test: If-None-Match gives 304
+	req = mkReq("GET", "/letters.txt")
+	req.Headers.Set("If-None-Match", "\"other\", "+etag)
+	buf.Reset()
+	require.NoError(t, NewWriter(&buf).ServeContent(req, "letters.txt", modtime, strings.NewReader(content)))
+	assert.Equal(t, "HTTP/1.1 304 Not Modified\r\n", statusLineOf(buf.String()))
+	assert.Equal(t, "", bodyOf(buf.String()))
+
+	// Test: If-Modified-Since gives 304 when not modified, 200 when modified
+	req = mkReq("GET", "/letters.txt")
+	req.Headers.Set("If-Modified-Since", "Wed, 01 May 2024 12:00:00 GMT")
+	buf.Reset()
+	require.NoError(t, NewWriter(&buf).ServeContent(req, "letters.txt", modtime, strings.NewReader(content)))
+	assert.Equal(t, "HTTP/1.1 304 Not Modified\r\n", statusLineOf(buf.String()))
+
+	req = mkReq("GET", "/letters.txt")
+	req.Headers.Set("If-Modified-Since", "Tue, 30 Apr 2024 12:00:00 GMT")
+	buf.Reset()
+	require.NoError(t, NewWriter(&buf).ServeContent(req, "letters.txt", modtime, strings.NewReader(content)))
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(buf.String()))
+
+	// Test: If-Range with the current ETag or date honors the Range
+	for _, ifRange := range []string{etag, "Wed, 01 May 2024 12:00:00 GMT"} {
+		req = mkReq("GET", "/letters.txt")
+		req.Headers.Set("Range", "bytes=2-5")
+		req.Headers.Set("If-Range", ifRange)
+		buf.Reset()
+		require.NoError(t, NewWriter(&buf).ServeContent(req, "letters.txt", modtime, strings.NewReader(content)))
+		assert.Equal(t, "HTTP/1.1 206 Partial Content\r\n", statusLineOf(buf.String()))
+		assert.Equal(t, "cdef", bodyOf(buf.String()))
+	}
+
+	// Test: Stale or weak If-Range falls back to the full body
+	for _, ifRange := range []string{"\"stale\"", "W/" + etag, "Tue, 30 Apr 2024 12:00:00 GMT", "garbage"} {
+		req = mkReq("GET", "/letters.txt")
+		req.Headers.Set("Range", "bytes=2-5")
+		req.Headers.Set("If-Range", ifRange)
+		buf.Reset()
+		require.NoError(t, NewWriter(&buf).ServeContent(req, "letters.txt", modtime, strings.NewReader(content)))
+		assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(buf.String()))
+		assert.Equal(t, content, bodyOf(buf.String()))
+	}
+
+	// Test: HEAD sends headers only
+	req = mkReq("HEAD", "/letters.txt")
+	buf.Reset()
+	require.NoError(t, NewWriter(&buf).ServeContent(req, "letters.txt", modtime, strings.NewReader(content)))
+	assert.Contains(t, headerBlock(buf.String()), "Content-Length: 26\r\n")
+	assert.Equal(t, "", bodyOf(buf.String()))
+
+	// Test: Unknown extension is sniffed
+	req = mkReq("GET", "/blob")
+	buf.Reset()
+	require.NoError(t, NewWriter(&buf).ServeContent(req, "blob", time.Time{}, strings.NewReader("<html><body>hi</body></html>")))
+	assert.Contains(t, headerBlock(buf.String()), "Content-Type: text/html; charset=utf-8\r\n")
+	assert.NotContains(t, headerBlock(buf.String()), "Last-Modified")
+}
+
+func TestServeFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>home</h1>"), 0o644))
+
+	// Test: Directory serves its index.html
+	var buf bytes.Buffer
+	require.NoError(t, NewWriter(&buf).ServeFile(mkReq("GET", "/"), dir))
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(buf.String()))
+	assert.Contains(t, headerBlock(buf.String()), "Content-Type: text/html; charset=utf-8\r\n")
+	assert.Equal(t, "<h1>home</h1>", bodyOf(buf.String()))
+
+	// Test: Missing file is a 404
+	buf.Reset()
+	require.NoError(t, NewWriter(&buf).ServeFile(mkReq("GET", "/"), filepath.Join(dir, "missing.css")))
+	assert.Equal(t, "HTTP/1.1 404 Not Found\r\n", statusLineOf(buf.String()))
+}
+
+func TestServeFile_Precompressed(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js.gz"), []byte("gzipped"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js.br"), []byte("brotli"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "style.css"), []byte("p{}"), 0o644))
+
+	serve := func(name, acceptEncoding string) string {
+		req := mkReq("GET", "/")
+		if acceptEncoding != "" {
+			req.Headers.Set("Accept-Encoding", acceptEncoding)
+		}
+		var buf bytes.Buffer
+		require.NoError(t, NewWriter(&buf).ServeFile(req, filepath.Join(dir, name)))
+		return buf.String()
+	}
+
+	// Test: The preferred sibling the client accepts is sent, typed as the original
+	out := serve("app.js", "gzip, br")
+	hb := headerBlock(out)
+	assert.Equal(t, "brotli", bodyOf(out))
+	assert.Contains(t, hb, "Content-Encoding: br\r\n")
+	assert.Contains(t, hb, "Vary: Accept-Encoding\r\n")
+	assert.Contains(t, hb, "Content-Type: text/javascript; charset=utf-8\r\n")
+
+	out = serve("app.js", "gzip")
+	assert.Equal(t, "gzipped", bodyOf(out))
+	assert.Contains(t, headerBlock(out), "Content-Encoding: gzip\r\n")
+
+	// Test: Otherwise the original is sent, still varying by Accept-Encoding
+	for _, ae := range []string{"", "deflate", "br;q=0, gzip;q=0"} {
+		out = serve("app.js", ae)
+		assert.Equal(t, "console.log(1)", bodyOf(out), ae)
+		assert.NotContains(t, out, "Content-Encoding:", ae)
+		assert.Contains(t, headerBlock(out), "Vary: Accept-Encoding\r\n", ae)
+	}
+
+	// Test: Files without siblings don't vary
+	out = serve("style.css", "gzip")
+	assert.Equal(t, "p{}", bodyOf(out))
+	assert.NotContains(t, out, "Vary:")
+}
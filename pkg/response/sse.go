@@ -0,0 +1,82 @@
+package response
+
+import (
+	"strings"
+)
+
+// SSEWriter streams Server-Sent Events over a response. The connection stays
+// open until the handler returns, and every event is flushed as soon as it
+// has been written.
+type SSEWriter struct {
+	w *Writer
+}
+
+// NewSSEWriter writes a 200 response with Content-Type text/event-stream and
+// returns an SSEWriter for sending events on it.
+func NewSSEWriter(w *Writer) (*SSEWriter, error) {
+	h := GetDefaultHeaders(0)
+	h.Del("Content-Length")
+	h.Replace("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+
+	if err := w.WriteStatusLine(StatusOK); err != nil {
+		return nil, err
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return nil, err
+	}
+
+	return &SSEWriter{w: w}, w.Flush()
+}
+
+// SendEvent sends one event. id and event are omitted when empty; multi-line
+// data is split across several data fields.
+func (s *SSEWriter) SendEvent(id string, event string, data string) error {
+	b := []byte{}
+	if id != "" {
+		b = append(b, "id: "+sseField(id)+"\n"...)
+	}
+	if event != "" {
+		b = append(b, "event: "+sseField(event)+"\n"...)
+	}
+	for _, line := range strings.Split(normalizeNewlines(data), "\n") {
+		b = append(b, "data: "+line+"\n"...)
+	}
+	b = append(b, '\n')
+
+	return s.send(b)
+}
+
+// Comment sends a comment line, which clients ignore.
+func (s *SSEWriter) Comment(text string) error {
+	b := []byte{}
+	for _, line := range strings.Split(normalizeNewlines(text), "\n") {
+		b = append(b, ": "+line+"\n"...)
+	}
+	b = append(b, '\n')
+
+	return s.send(b)
+}
+
+// Heartbeat sends an empty comment to keep idle connections (and any proxies
+// in between) from timing out.
+func (s *SSEWriter) Heartbeat() error {
+	return s.send([]byte(":\n\n"))
+}
+
+func (s *SSEWriter) send(b []byte) error {
+	if err := s.w.WriteBody(b); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
+
+// sseField keeps single-line fields from breaking the event framing.
+func sseField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
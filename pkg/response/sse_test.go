@@ -0,0 +1,54 @@
+package response
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type flushWriter struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (fw *flushWriter) Flush() error {
+	fw.flushes++
+	return nil
+}
+
+func TestSSEWriter(t *testing.T) {
+	fw := &flushWriter{}
+	sse, err := NewSSEWriter(NewWriter(fw))
+	require.NoError(t, err)
+
+	hb := headerBlock(fw.String())
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLineOf(hb))
+	assert.Contains(t, hb, "Content-Type: text/event-stream\r\n")
+	assert.Contains(t, hb, "Cache-Control: no-cache\r\n")
+	assert.NotContains(t, hb, "Content-Length")
+	assert.Equal(t, 1, fw.flushes)
+
+	// Test: Each event is framed and flushed
+	fw.Reset()
+	require.NoError(t, sse.SendEvent("1", "tick", "hello"))
+	assert.Equal(t, "id: 1\nevent: tick\ndata: hello\n\n", fw.String())
+	assert.Equal(t, 2, fw.flushes)
+
+	// Test: Multi-line data and empty fields
+	fw.Reset()
+	require.NoError(t, sse.SendEvent("", "", "a\r\nb\nc"))
+	assert.Equal(t, "data: a\ndata: b\ndata: c\n\n", fw.String())
+
+	// Test: Newlines cannot be injected through id or event
+	fw.Reset()
+	require.NoError(t, sse.SendEvent("7\ndata: x", "", "y"))
+	assert.Equal(t, "id: 7data: x\ndata: y\n\n", fw.String())
+
+	// Test: Comments and heartbeats
+	fw.Reset()
+	require.NoError(t, sse.Comment("hi"))
+	require.NoError(t, sse.Heartbeat())
+	assert.Equal(t, ": hi\n\n:\n\n", fw.String())
+}
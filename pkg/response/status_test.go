@@ -0,0 +1,43 @@
+package response
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusText(t *testing.T) {
+	assert.Equal(t, "OK", StatusText(StatusOK))
+	assert.Equal(t, "Too Many Requests", StatusText(StatusTooManyRequests))
+	assert.Equal(t, "HTTP Version Not Supported", StatusText(StatusHttpVersionNotSupported))
+	assert.Equal(t, "", StatusText(StatusCode(299)))
+}
+
+func TestWriteStatusLineCodes(t *testing.T) {
+	cases := map[StatusCode]string{
+		StatusFound:           "HTTP/1.1 302 Found\r\n",
+		StatusForbidden:       "HTTP/1.1 403 Forbidden\r\n",
+		StatusConflict:        "HTTP/1.1 409 Conflict\r\n",
+		StatusTooManyRequests: "HTTP/1.1 429 Too Many Requests\r\n",
+		StatusCode(299):       "HTTP/1.1 299 \r\n",
+	}
+	for code, expected := range cases {
+		var buf bytes.Buffer
+		require.NoError(t, NewWriter(&buf).WriteStatusLine(code))
+		assert.Equal(t, expected, buf.String())
+	}
+
+	// Test: Custom reason phrases
+	var buf bytes.Buffer
+	require.NoError(t, NewWriter(&buf).WriteStatusLineReason(StatusCode(599), "Network Read Timeout"))
+	assert.Equal(t, "HTTP/1.1 599 Network Read Timeout\r\n", buf.String())
+
+	// Test: Invalid codes and phrases write nothing
+	buf.Reset()
+	assert.ErrorIs(t, NewWriter(&buf).WriteStatusLine(StatusCode(600)), ErrUnrecognizedStatusCode)
+	assert.ErrorIs(t, NewWriter(&buf).WriteStatusLine(StatusCode(42)), ErrUnrecognizedStatusCode)
+	assert.ErrorIs(t, NewWriter(&buf).WriteStatusLineReason(StatusOK, "OK\r\nX-Evil: 1"), ErrInvalidReasonPhrase)
+	assert.Equal(t, "", buf.String())
+}
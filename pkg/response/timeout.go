@@ -0,0 +1,200 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+)
+
+// ErrHandlerTimeout is returned by TimeoutHandler, and by writes a handler
+// makes after TimeoutHandler stopped waiting for it.
+var ErrHandlerTimeout = fmt.Errorf("handler timed out")
+
+// writeGate decides, under a lock, whether output of a handler running on
+// another goroutine still reaches the connection.
+type writeGate struct {
+	mu       sync.Mutex
+	started  bool // output reached the connection
+	hijacked bool // the handler took over the connection
+	closed   bool // timed out, no more output
+}
+
+// enter locks the gate for one write, marking the response as started. It
+// fails once the gate is closed; otherwise the caller unlocks.
+func (g *writeGate) enter() error {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return ErrHandlerTimeout
+	}
+	g.started = true
+	return nil
+}
+
+// close shuts the gate and reports whether any output got through.
+func (g *writeGate) close() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+	return g.started
+}
+
+type gatedWriter struct {
+	gate *writeGate
+	w    io.Writer
+}
+
+func (gw gatedWriter) Write(p []byte) (int, error) {
+	if err := gw.gate.enter(); err != nil {
+		return 0, err
+	}
+	defer gw.gate.mu.Unlock()
+	return gw.w.Write(p)
+}
+
+func (gw gatedWriter) Flush() error {
+	gw.gate.mu.Lock()
+	defer gw.gate.mu.Unlock()
+	if gw.gate.closed {
+		return ErrHandlerTimeout
+	}
+	if f, ok := gw.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// split returns a Writer for w's response that writes to out instead. It
+// takes over w's compression and a copy of its header, so w itself stays
+// untouched until attach.
+func (w *Writer) split(out io.Writer) *Writer {
+	d := &Writer{
+		writer:      out,
+		compression: w.compression,
+		hijack:      w.hijack,
+		http10:      w.http10,
+		head:        w.head,
+		keepAlive:   w.keepAlive,
+		defaults:    w.defaults,
+	}
+	if w.header != nil {
+		d.header = w.header.Clone()
+	}
+	w.compression = nil
+	return d
+}
+
+// detach returns a Writer for a handler running on another goroutine, which
+// writes to w's connection through gate.
+func (w *Writer) detach(gate *writeGate) *Writer {
+	d := w.split(gatedWriter{gate: gate, w: w.writer})
+	if w.hijack != nil {
+		d.hijack = func() (net.Conn, *bufio.ReadWriter, error) {
+			if err := gate.enter(); err != nil {
+				return nil, nil, err
+			}
+			defer gate.mu.Unlock()
+			gate.hijacked = true
+			return w.hijack()
+		}
+	}
+	return d
+}
+
+// attach takes back the state of a split Writer once its handler has
+// returned, so the response can be finished through w.
+func (w *Writer) attach(d *Writer) {
+	writer, hijack := w.writer, w.hijack
+	*w = *d
+	w.writer, w.hijack = writer, hijack
+}
+
+// TimeoutHandler runs h with a deadline of d on its request context. If h
+// hasn't sent anything by then, the context is canceled and the client gets
+// a 503 Service Unavailable with msg as the body instead; whatever h writes
+// afterwards fails with ErrHandlerTimeout. A response h already started is
+// cut short the same way, since a second status line can't be sent, and
+// TimeoutHandler returns ErrHandlerTimeout.
+//
+// h runs on its own goroutine, so it should stop once its context is done. A
+// panic in h is raised again on the calling goroutine.
+func TimeoutHandler(h Handler, d time.Duration, msg string) Handler {
+	return func(w *Writer, req *request.Request) error {
+		base, cancel := context.WithCancelCause(req.Context())
+		defer cancel(nil)
+		ctx := &timeoutContext{Context: base, deadline: time.Now().Add(d)}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		gate := &writeGate{}
+		dw := w.detach(gate)
+		done := make(chan error, 1)
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			done <- h(dw, req.WithContext(ctx))
+		}()
+
+		select {
+		case err := <-done:
+			w.attach(dw)
+			return err
+
+		case p := <-panicked:
+			w.attach(dw)
+			panic(p)
+
+		case <-timer.C:
+		case <-req.Context().Done():
+		}
+
+		// close the gate before h sees its context done, so that h reacting
+		// to it can't slip a write in ahead of the 503
+		started := gate.close()
+		cancel(context.DeadlineExceeded)
+		if started {
+			// the connection is h's if it hijacked it
+			w.hijacked = gate.hijacked
+			w.written, w.state = true, stateDone
+			w.keepAlive = false // the response may be cut short
+			return ErrHandlerTimeout
+		}
+
+		body := []byte(msg)
+		h := GetDefaultHeaders(len(body))
+		h.Replace("Content-Type", "text/plain; charset=utf-8")
+		return w.WriteResponse(StatusServiceUnavailable, h, body)
+	}
+}
+
+// timeoutContext is canceled by TimeoutHandler rather than by a timer of its
+// own, but still reports the deadline and DeadlineExceeded.
+type timeoutContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	if d, ok := c.Context.Deadline(); ok && d.Before(c.deadline) {
+		return d, true
+	}
+	return c.deadline, true
+}
+
+func (c *timeoutContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && context.Cause(c.Context) == context.DeadlineExceeded {
+		return context.DeadlineExceeded
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+)
+
+// Validator is implemented by typed handler inputs that check themselves
+// once bound. An error that isn't an *HTTPError is answered with 422 and its
+// text as the message.
+type Validator interface {
+	Validate() error
+}
+
+// StatusCoder is implemented by typed handler outputs that are sent with a
+// status other than 200, e.g. 201 Created.
+type StatusCoder interface {
+	StatusCode() StatusCode
+}
+
+// TypedHandler adapts fn to a Handler. The input is bound from the request:
+// a JSON body with req.BindJSON when there is one, then, for struct inputs,
+// path params, query parameters and headers with req.Bind, and finally
+// checked with Validate if In implements Validator. fn runs with the
+// request's context and its output is sent as JSON, with 200 unless Out
+// implements StatusCoder; an empty struct output is sent as 204 No Content.
+//
+// Errors from binding, validation or fn are answered with a JSON body of the
+// form {"error": "message"}, using the status and message
+// DefaultErrorHandler would. Server errors are still returned so they get
+// logged.
+func TypedHandler[In, Out any](fn func(ctx context.Context, in In) (Out, error)) Handler {
+	return func(w *Writer, req *request.Request) error {
+		var in In
+		if err := bindTyped(req, &in); err != nil {
+			return writeJSONError(w, err)
+		}
+
+		out, err := fn(req.Context(), in)
+		if err != nil {
+			return writeJSONError(w, err)
+		}
+
+		status := StatusOK
+		if sc, ok := any(out).(StatusCoder); ok {
+			status = sc.StatusCode()
+		}
+		t := reflect.TypeOf(out)
+		if t != nil && t.Kind() == reflect.Struct && t.Size() == 0 && status == StatusOK {
+			return w.WriteResponse(StatusNoContent, GetDefaultHeaders(0), nil)
+		}
+		return writeJSON(w, status, out)
+	}
+}
+
+func bindTyped(req *request.Request, in any) error {
+	if len(req.Body) > 0 {
+		if err := req.BindJSON(in); err != nil {
+			return err
+		}
+	}
+	if reflect.TypeOf(in).Elem().Kind() == reflect.Struct {
+		if err := req.Bind(in); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := in.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			var httpErr *HTTPError
+			if errors.As(err, &httpErr) {
+				return err
+			}
+			return WrapHTTPError(StatusUnprocessableContent, err.Error(), err)
+		}
+	}
+	return nil
+}
+
+func writeJSON(w *Writer, status StatusCode, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	h := GetDefaultHeaders(len(body))
+	h.Replace("Content-Type", "application/json")
+	return w.WriteResponse(status, h, body)
+}
+
+// writeJSONError is DefaultErrorHandler with a JSON body.
+func writeJSONError(w *Writer, err error) error {
+	if w.Written() {
+		return err
+	}
+
+	status, message := errorResponse(err)
+	if message == "" {
+		message = StatusText(status)
+	}
+	if wErr := writeJSON(w, status, map[string]string{"error": message}); wErr != nil {
+		return wErr
+	}
+
+	if status >= StatusInternalServerError {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type createUserReq struct {
+	OrgID int64  `json:"-" path:"org"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Token string `json:"-" header:"X-Token"`
+}
+
+func (r createUserReq) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type createUserResp struct {
+	ID    int64  `json:"id"`
+	OrgID int64  `json:"org_id"`
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+func (createUserResp) StatusCode() StatusCode { return StatusCreated }
+
+var errNoSuchOrg = errors.New("no such org")
+
+func createUser(ctx context.Context, in createUserReq) (createUserResp, error) {
+	switch in.OrgID {
+	case 404:
+		return createUserResp{}, WrapHTTPError(StatusNotFound, "org not found", errNoSuchOrg)
+	case 500:
+		return createUserResp{}, errNoSuchOrg
+	}
+	return createUserResp{ID: 1, OrgID: in.OrgID, Name: in.Name, Token: in.Token}, nil
+}
+
+func TestTypedHandler(t *testing.T) {
+	h := TypedHandler(createUser)
+	post := func(org string, contentType string, body string) (string, error) {
+		req := mkReq("POST", "/orgs/"+org+"/users")
+		req.PathParams["org"] = org
+		req.Headers.Set("X-Token", "t0k")
+		if contentType != "" {
+			req.Headers.Set("Content-Type", contentType)
+		}
+		req.Body = []byte(body)
+
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		err := h(w, req)
+		require.NoError(t, w.Finish())
+		return buf.String(), err
+	}
+
+	// Test: Body, path and header are bound and the output sent as JSON
+	out, err := post("7", "application/json", `{"name":"ada","email":"ada@example.com"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 201 Created\r\n", statusLineOf(out))
+	assert.Contains(t, out, "Content-Type: application/json\r\n")
+	assert.JSONEq(t, `{"id":1,"org_id":7,"name":"ada","token":"t0k"}`, bodyOf(out))
+
+	// Test: Binding, validation and handler errors get JSON error bodies
+	cases := []struct {
+		org, contentType, body string
+		status, message        string
+	}{
+		{"7", "text/plain", `{}`, "HTTP/1.1 415 Unsupported Media Type\r\n", `{"error":"content-type must be application/json"}`},
+		{"7", "application/json", `{"name":`, "HTTP/1.1 400 Bad Request\r\n", `{"error":"malformed JSON: unexpected end of body"}`},
+		{"x", "application/json", `{"name":"ada"}`, "HTTP/1.1 400 Bad Request\r\n", `{"error":"path parameter \"org\" must be an integer"}`},
+		{"7", "application/json", `{"email":"a@b.c"}`, "HTTP/1.1 422 Unprocessable Content\r\n", `{"error":"name is required"}`},
+		{"404", "application/json", `{"name":"ada"}`, "HTTP/1.1 404 Not Found\r\n", `{"error":"org not found"}`},
+	}
+	for _, tc := range cases {
+		out, err := post(tc.org, tc.contentType, tc.body)
+		require.NoError(t, err, tc.body)
+		assert.Equal(t, tc.status, statusLineOf(out), tc.body)
+		assert.JSONEq(t, tc.message, bodyOf(out), tc.body)
+	}
+
+	// Test: Server errors are answered and still returned for logging
+	out, err = post("500", "application/json", `{"name":"ada"}`)
+	assert.ErrorIs(t, err, errNoSuchOrg)
+	assert.Equal(t, "HTTP/1.1 500 Internal Server Error\r\n", statusLineOf(out))
+	assert.JSONEq(t, `{"error":"Internal Server Error"}`, bodyOf(out))
+}
+
+func TestTypedHandler_NoBody(t *testing.T) {
+	type query struct {
+		Page int `query:"page"`
+	}
+	var seen query
+	h := TypedHandler(func(ctx context.Context, in query) (struct{}, error) {
+		seen = in
+		return struct{}{}, nil
+	})
+
+	req := mkReq("DELETE", "/items")
+	req.RequestParams["page"] = "3"
+	var buf bytes.Buffer
+	require.NoError(t, h(NewWriter(&buf), req))
+	assert.Equal(t, "HTTP/1.1 204 No Content\r\n", statusLineOf(buf.String()))
+	assert.Equal(t, query{Page: 3}, seen)
+
+	// Test: Non-struct inputs only come from the body
+	sum := TypedHandler(func(ctx context.Context, in []int) (int, error) {
+		total := 0
+		for _, n := range in {
+			total += n
+		}
+		return total, nil
+	})
+	req = mkReq("POST", "/sum")
+	req.Headers.Set("Content-Type", "application/json")
+	req.Body = []byte("[1,2,3]")
+	buf.Reset()
+	require.NoError(t, sum(NewWriter(&buf), req))
+	assert.Equal(t, "6", bodyOf(buf.String()))
+}
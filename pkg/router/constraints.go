@@ -0,0 +1,61 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidParamConstraint = fmt.Errorf("invalid path param constraint")
+
+// typedConstraints are the shorthand forms accepted after a second colon,
+// e.g. /users/:id:int.
+var typedConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[A-Za-z]+`,
+	"alnum": `[A-Za-z0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// parseParamToken splits a parameter token (without the leading ':') into its
+// name and optional constraint. Constraints are either a regular expression in
+// braces (:id{[0-9]+}) or a typed shorthand (:id:int). The expression must
+// match the whole segment and cannot contain '/'.
+func parseParamToken(token string) (name string, spec string, re *regexp.Regexp, err error) {
+	i := strings.IndexAny(token, "{:")
+	if i == -1 {
+		return token, "", nil, nil
+	}
+
+	name, spec = token[:i], token[i:]
+	if name == "" {
+		return "", "", nil, ErrInvalidParamConstraint
+	}
+
+	var pattern string
+	if spec[0] == ':' {
+		p, ok := typedConstraints[spec[1:]]
+		if !ok {
+			return "", "", nil, fmt.Errorf("%w: unknown type %q", ErrInvalidParamConstraint, spec[1:])
+		}
+		pattern = p
+
+	} else {
+		if len(spec) < 3 || spec[len(spec)-1] != '}' {
+			return "", "", nil, ErrInvalidParamConstraint
+		}
+		pattern = spec[1 : len(spec)-1]
+	}
+
+	re, err = regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return "", "", nil, fmt.Errorf("%w: %w", ErrInvalidParamConstraint, err)
+	}
+
+	return name, spec, re, nil
+}
+
+func (node *routerNode) accepts(segment string) bool {
+	return node.constraint == nil || node.constraint.MatchString(segment)
+}
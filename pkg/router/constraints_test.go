@@ -0,0 +1,72 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_ParamConstraints(t *testing.T) {
+	r := NewRouter()
+
+	called := ""
+	mk := func(name string) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			called = name
+			return nil
+		}
+	}
+
+	require.NoError(t, r.GET("/users/:id{[0-9]+}", mk("user")))
+	require.NoError(t, r.GET("/orders/:id:int/items/:sku:alnum", mk("item")))
+	require.NoError(t, r.GET("/users/me", mk("me")))
+
+	cases := []struct {
+		target   string
+		expected string
+		params   map[string]string
+	}{
+		{"/users/123", "user", map[string]string{"id": "123"}},
+		{"/users/me", "me", map[string]string{}},
+		{"/users/abc", "", map[string]string{}},
+		{"/orders/-5/items/AB12", "item", map[string]string{"id": "-5", "sku": "AB12"}},
+		{"/orders/x/items/AB12", "", map[string]string{}},
+		{"/orders/5/items/AB-12", "", map[string]string{"id": "5"}},
+	}
+
+	for _, tc := range cases {
+		called = ""
+		req := mkReq("GET", tc.target)
+		out := runHandler(t, r.GetHandler(req), req)
+		assert.Equal(t, tc.expected, called, tc.target)
+		if tc.expected == "" {
+			assert.Contains(t, out, "404", tc.target)
+		}
+		assert.Equal(t, tc.params, req.PathParams, tc.target)
+	}
+
+	// Test: Constraints show up in route introspection
+	patterns := []string{}
+	for _, rt := range r.Routes() {
+		patterns = append(patterns, rt.Pattern)
+	}
+	assert.Contains(t, patterns, "/users/:id{[0-9]+}")
+	assert.Contains(t, patterns, "/orders/:id:int/items/:sku:alnum")
+}
+
+func TestRouter_ParamConstraintErrors(t *testing.T) {
+	r := NewRouter()
+	okHandler := func(w *response.Writer, req *request.Request) error { return nil }
+
+	assert.ErrorIs(t, r.GET("/a/:id:float", okHandler), ErrInvalidParamConstraint)
+	assert.ErrorIs(t, r.GET("/a/:id{[0-9+}", okHandler), ErrInvalidParamConstraint)
+	assert.ErrorIs(t, r.GET("/a/:{[0-9]+}", okHandler), ErrInvalidParamConstraint)
+
+	// Test: Same name with a different constraint is ambiguous
+	require.NoError(t, r.GET("/b/:id:int", okHandler))
+	require.NoError(t, r.POST("/b/:id:int", okHandler))
+	assert.ErrorIs(t, r.PUT("/b/:id", okHandler), ErrAmbiguousPathParams)
+}
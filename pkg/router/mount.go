@@ -0,0 +1,135 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrRouteConflict  = fmt.Errorf("route already registered")
+	ErrAlreadyMounted = fmt.Errorf("router is already mounted or is a group")
+	ErrMountSelf      = fmt.Errorf("cannot mount a router onto itself")
+)
+
+// Mount grafts the routes of an independently built router under prefix. The
+// sub-router's middleware keeps applying to its routes and runs after this
+// router's chain; its error and NotFound handlers cover paths under prefix. Routes are copied at mount time, so register everything on
+// sub before mounting it. Nothing is changed if any route would conflict.
+func (r *Router) Mount(prefix string, sub *Router) error {
+	defer r.lock()()
+	if sub.parent != nil {
+		return ErrAlreadyMounted
+	}
+	if sub.routes == r.routes {
+		return ErrMountSelf
+	}
+
+	fullPath, err := r.withPrefix(prefix)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := getTokens(fullPath)
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkGraft(tokens, sub.routes); err != nil {
+		return err
+	}
+
+	node, err := r.getOrCreateNode(tokens)
+	if err != nil {
+		return err
+	}
+
+	graft(node, sub.routes)
+	sub.parent = r
+	sub.scopePrefix = strings.TrimSuffix(strings.TrimPrefix(fullPath, r.prefix), "/")
+	r.groups = append(r.groups, sub)
+	return nil
+}
+
+// checkGraft reports whether src can be merged at the node addressed by tokens
+// without overwriting handlers or creating ambiguous parameters.
+func (r *Router) checkGraft(tokens []string, src *routerNode) error {
+	dst := r.routes
+	for _, token := range tokens {
+		var next *routerNode
+		if len(token) > 0 && token[0] == ':' {
+			name, spec, constraint, err := parseParamToken(token[1:])
+			if err != nil {
+				return err
+			}
+
+			next = dst.getParamChild()
+			if next != nil && !next.sameParam(newParamNode(name, spec, constraint)) {
+				return ErrAmbiguousPathParams
+			}
+		} else {
+			next = dst.getStaticChild(token)
+		}
+
+		if next == nil {
+			return nil // nothing exists below here yet
+		}
+		dst = next
+	}
+
+	return checkMerge(dst, src)
+}
+
+func checkMerge(dst *routerNode, src *routerNode) error {
+	for m, rt := range src.handlers {
+		if rt != nil && dst.handlers[m] != nil {
+			return ErrRouteConflict
+		}
+	}
+
+	for _, child := range src.children {
+		var existing *routerNode
+		if child.isParam {
+			existing = dst.getParamChild()
+			if existing != nil && !existing.sameParam(child) {
+				return ErrAmbiguousPathParams
+			}
+		} else {
+			existing = dst.getStaticChild(child.token)
+		}
+
+		if existing != nil {
+			if err := checkMerge(existing, child); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func graft(dst *routerNode, src *routerNode) {
+	for m, rt := range src.handlers {
+		if rt != nil {
+			dst.handlers[m] = rt
+		}
+	}
+
+	for _, child := range src.children {
+		var existing *routerNode
+		if child.isParam {
+			existing = dst.getParamChild()
+		} else {
+			existing = dst.getStaticChild(child.token)
+		}
+
+		if existing == nil {
+			if child.isParam {
+				existing = newParamNode(child.token, child.spec, child.constraint)
+			} else {
+				existing = newRouterNode(child.token, false)
+			}
+			dst.addChild(existing)
+		}
+		graft(existing, child)
+	}
+}
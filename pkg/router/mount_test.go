@@ -0,0 +1,72 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_Mount(t *testing.T) {
+	var order []string
+
+	users := NewRouter()
+	users.Use(mwTag("users-mw", &order))
+	handler := func(w *response.Writer, req *request.Request) error {
+		order = append(order, "handler:"+req.PathParams["id"])
+		return nil
+	}
+	require.NoError(t, users.GET("/", handler))
+	require.NoError(t, users.GET("/:id", handler))
+
+	r := NewRouter()
+	r.Use(mwTag("root-mw", &order))
+	api := r.Group("/api")
+	require.NoError(t, api.Mount("/users", users))
+
+	// Test: Mounted routes resolve with both middleware chains
+	req := mkReq("GET", "/api/users/42")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, []string{"root-mw", "users-mw", "handler:42"}, order)
+
+	order = nil
+	req = mkReq("GET", "/api/users")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, []string{"root-mw", "users-mw", "handler:"}, order)
+
+	// Test: Mounting twice is rejected
+	assert.ErrorIs(t, r.Mount("/other", users), ErrAlreadyMounted)
+}
+
+func TestRouter_MountConflicts(t *testing.T) {
+	okHandler := func(w *response.Writer, req *request.Request) error { return nil }
+
+	r := NewRouter()
+	require.NoError(t, r.GET("/api/users", okHandler))
+	require.NoError(t, r.GET("/api/items/:itemId", okHandler))
+
+	// Test: Same method and path conflicts, and nothing is grafted
+	sub := NewRouter()
+	require.NoError(t, sub.GET("/users", okHandler))
+	require.NoError(t, sub.GET("/orders", okHandler))
+	assert.ErrorIs(t, r.Mount("/api", sub), ErrRouteConflict)
+	req := mkReq("GET", "/api/orders")
+	assert.Contains(t, runHandler(t, r.GetHandler(req), req), "404")
+
+	// Test: Different param names at the same depth are ambiguous
+	sub = NewRouter()
+	require.NoError(t, sub.GET("/items/:id", okHandler))
+	assert.ErrorIs(t, r.Mount("/api", sub), ErrAmbiguousPathParams)
+
+	// Test: Different method on an existing path is fine
+	sub = NewRouter()
+	require.NoError(t, sub.POST("/users", okHandler))
+	require.NoError(t, r.Mount("/api", sub))
+	req = mkReq("POST", "/api/users")
+	assert.NotContains(t, runHandler(t, r.GetHandler(req), req), "405")
+
+	// Test: Mounting onto itself
+	assert.ErrorIs(t, r.Mount("/x", r), ErrMountSelf)
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"net/url"
+	"path"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+)
+
+// PathPolicy controls what happens to request paths with trailing slashes,
+// repeated slashes, or dot-segments before they are looked up.
+type PathPolicy uint
+
+const (
+	// PathNormalize looks up the cleaned path (/a//b/./c/ -> /a/b/c).
+	PathNormalize PathPolicy = iota
+	// PathRedirect answers non-canonical paths with a redirect to the cleaned
+	// path (301 for GET/HEAD, 308 otherwise so the method and body are kept).
+	PathRedirect
+	// PathStrict disables normalization; only a single trailing slash is
+	// ignored.
+	PathStrict
+)
+
+// cleanPath returns the canonical form of an absolute request path. Relative
+// or empty paths are returned unchanged so they still fail lookup.
+func cleanPath(p string) string {
+	if p == "" || p[0] != '/' {
+		return p
+	}
+
+	return path.Clean(p)
+}
+
+// requestPath returns the decoded path of req used for matching, falling back
+// to the raw target for requests built without a parsed URL.
+func requestPath(req *request.Request) string {
+	if req.URL != nil {
+		return req.URL.Path
+	}
+	return req.RequestLine.RequestTarget
+}
+
+// escapePath re-encodes a decoded path for use in a Location header.
+func escapePath(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+// SetPathPolicy sets how request paths are normalized before lookup for
+// requests dispatched through this router and its groups.
+func (r *Router) SetPathPolicy(policy PathPolicy) {
+	defer r.lock()()
+	r.pathPolicy = &policy
+}
+
+func (r *Router) getPathPolicy() PathPolicy {
+	for g := r; g != nil; g = g.parent {
+		if g.pathPolicy != nil {
+			return *g.pathPolicy
+		}
+	}
+
+	return PathNormalize
+}
+
+func redirectHandler(location string) response.Handler {
+	return func(w *response.Writer, req *request.Request) error {
+		status := response.StatusPermanentRedirect
+		if m := req.RequestLine.Method; m == "GET" || m == "HEAD" {
+			status = response.StatusMovedPermanently
+		}
+
+		return w.Redirect(status, location)
+	}
+}
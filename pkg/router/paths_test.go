@@ -0,0 +1,161 @@
+package router
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanPath(t *testing.T) {
+	cases := map[string]string{
+		"/":             "/",
+		"/users/":       "/users",
+		"//users":       "/users",
+		"/a/./b":        "/a/b",
+		"/a/../b":       "/b",
+		"/../../etc":    "/etc",
+		"/a//b///c/./d": "/a/b/c/d",
+		"relative":      "relative",
+		"":              "",
+	}
+	for in, expected := range cases {
+		assert.Equal(t, expected, cleanPath(in), in)
+	}
+}
+
+func TestRouter_PathPolicy(t *testing.T) {
+	r := NewRouter()
+	called := false
+	okHandler := func(w *response.Writer, req *request.Request) error {
+		called = true
+		return nil
+	}
+	require.NoError(t, r.GET("/a/b", okHandler))
+	require.NoError(t, r.POST("/a/b", okHandler))
+
+	// Test: Normalize is the default
+	for _, target := range []string{"/a/b/", "//a//b", "/a/./b", "/a/x/../b"} {
+		called = false
+		req := mkReq("GET", target)
+		_ = runHandler(t, r.GetHandler(req), req)
+		assert.True(t, called, target)
+	}
+
+	// Test: Redirect sends 301 for GET with the query preserved
+	r.SetPathPolicy(PathRedirect)
+	called = false
+	req := mkReq("GET", "//a/./b/")
+	req.RawQuery = "x=1"
+	out := runHandler(t, r.GetHandler(req), req)
+	assert.False(t, called)
+	assert.Contains(t, out, "HTTP/1.1 301 Moved Permanently\r\n")
+	assert.Contains(t, out, "Location: /a/b?x=1\r\n")
+
+	// Test: Redirect uses 308 for other methods
+	req = mkReq("POST", "/a/b/")
+	out = runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "HTTP/1.1 308 Permanent Redirect\r\n")
+
+	// Test: Canonical paths are served directly under redirect
+	called = false
+	req = mkReq("GET", "/a/b")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.True(t, called)
+
+	// Test: Strict only ignores a single trailing slash
+	r.SetPathPolicy(PathStrict)
+	called = false
+	req = mkReq("GET", "/a/b/")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.True(t, called)
+	req = mkReq("GET", "/a//b")
+	out = runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "404")
+}
+
+func TestRouter_PathPolicyFileServer(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "site"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "site", "index.html"), []byte("<p>site</p>"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "files"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "files", "a.txt"), []byte("a"), 0o644))
+
+	r := NewRouter()
+	r.NotFound(response.FileServer(root, response.FileServerOptions{StripPrefix: "/static", Browse: true}))
+
+	// Test: Directories are served whether or not the trailing slash was
+	// normalized away, by the default policy and by redirect
+	for _, policy := range []PathPolicy{PathNormalize, PathRedirect} {
+		r.SetPathPolicy(policy)
+		for _, target := range []string{"/static/site", "/static/site/"} {
+			req := mkReq("GET", target)
+			req.Headers = headers.NewHeaders()
+			out := runHandler(t, r.GetHandler(req), req)
+			if policy == PathRedirect && target == "/static/site/" {
+				assert.Contains(t, out, "Location: /static/site\r\n")
+				continue
+			}
+			assert.Contains(t, out, "HTTP/1.1 200 OK\r\n", target)
+			assert.Contains(t, out, "<p>site</p>", target)
+		}
+
+		req := mkReq("GET", "/static/files")
+		req.Headers = headers.NewHeaders()
+		out := runHandler(t, r.GetHandler(req), req)
+		assert.Contains(t, out, "HTTP/1.1 200 OK\r\n")
+		assert.Contains(t, out, `<a href="/static/files/a.txt">a.txt</a>`)
+	}
+}
+
+func TestRouter_Redirect(t *testing.T) {
+	r := NewRouter()
+	require.NoError(t, r.Redirect("/old", "/new", response.StatusFound))
+
+	// Test: Every method is redirected with the given status
+	for _, m := range []string{"GET", "POST", "DELETE"} {
+		req := mkReq(m, "/old")
+		out := runHandler(t, r.GetHandler(req), req)
+		assert.Contains(t, out, "HTTP/1.1 302 Found\r\n", m)
+		assert.Contains(t, out, "Location: /new\r\n", m)
+	}
+
+	// Test: Non-redirect codes are rejected at registration
+	err := r.Redirect("/other", "/new", response.StatusOK)
+	assert.ErrorIs(t, err, response.ErrInvalidRedirectStatus)
+}
+
+func TestRouter_DecodedPath(t *testing.T) {
+	r := NewRouter()
+	var name string
+	require.NoError(t, r.GET("/files/:name", func(w *response.Writer, req *request.Request) error {
+		name = req.PathParams["name"]
+		return nil
+	}))
+
+	// Test: Params are matched against the decoded path
+	req := mkReq("GET", "/files/a%20b")
+	req.URL = &url.URL{Path: "/files/a b"}
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, "a b", name)
+
+	// Test: Encoded dot-segments are normalized after decoding
+	name = ""
+	req = mkReq("GET", "/x/%2E%2E/files/f")
+	req.URL = &url.URL{Path: "/x/../files/f"}
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, "f", name)
+
+	// Test: Redirect locations are re-encoded
+	r.SetPathPolicy(PathRedirect)
+	req = mkReq("GET", "/files//a%20b")
+	req.URL = &url.URL{Path: "/files//a b"}
+	out := runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "Location: /files/a%20b\r\n")
+}
@@ -0,0 +1,748 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+)
+
+type method uint
+
+const (
+	methodGET method = iota
+	methodPOST
+	methodPUT
+	methodDELETE
+	methodPATCH
+	methodOPTIONS
+	methodCount
+)
+
+var methodNames = [methodCount]string{
+	methodGET:     "GET",
+	methodPOST:    "POST",
+	methodPUT:     "PUT",
+	methodDELETE:  "DELETE",
+	methodPATCH:   "PATCH",
+	methodOPTIONS: "OPTIONS",
+}
+
+// standardMethods are the methods of RFC 7231 and RFC 5789. Requests with
+// any other method get a 501, since no route can be registered for them.
+var standardMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "DELETE": true,
+	"CONNECT": true, "OPTIONS": true, "TRACE": true, "PATCH": true,
+}
+
+var (
+	ErrInvalidHttpMethod      = fmt.Errorf("invalid http method")
+	ErrRequestTargetEmpty     = fmt.Errorf("request target is empty")
+	ErrMalformedRequestTarget = fmt.Errorf("malformed request target")
+	ErrAmbiguousPathParams    = fmt.Errorf("added ambiguous path params")
+	ErrRouteNotFound          = fmt.Errorf("route not registered")
+)
+
+// route is a registered handler together with the group that registered it.
+// The group's middleware chain is applied at dispatch time so that Use() calls
+// made after registration still take effect.
+type route struct {
+	handler    response.Handler
+	middleware []Middleware
+	group      *Router
+}
+
+// compose wraps the route's handler in its own middleware and then in its
+// group's chain.
+func (rt *route) compose() response.Handler {
+	h := rt.handler
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		h = rt.middleware[i](h)
+	}
+
+	return rt.group.applyMiddleware(h)
+}
+
+type routerNode struct {
+	token      string
+	isParam    bool
+	spec       string // constraint as written, e.g. "{[0-9]+}" or ":int"
+	constraint *regexp.Regexp
+	children   []*routerNode
+	handlers   [methodCount]*route
+	// pattern is the path leading here as registered, e.g.
+	// "/users/:id:int", kept so lookups needn't build it
+	pattern string
+}
+
+func newRouterNode(token string, isParam bool) *routerNode {
+	return &routerNode{
+		token:    token,
+		isParam:  isParam,
+		children: []*routerNode{},
+		handlers: [methodCount]*route{},
+		pattern:  "/",
+	}
+}
+
+func newParamNode(name string, spec string, constraint *regexp.Regexp) *routerNode {
+	node := newRouterNode(name, true)
+	node.spec = spec
+	node.constraint = constraint
+	return node
+}
+
+func (node *routerNode) sameParam(other *routerNode) bool {
+	return node.token == other.token && node.spec == other.spec
+}
+
+func (node *routerNode) addChild(child *routerNode) {
+	segment := child.token
+	if child.isParam {
+		segment = ":" + child.token + child.spec
+	}
+	child.pattern = strings.TrimSuffix(node.pattern, "/") + "/" + segment
+	node.children = append(node.children, child)
+}
+
+func (node *routerNode) removeChild(child *routerNode) {
+	for i, c := range node.children {
+		if c == child {
+			node.children = append(node.children[:i:i], node.children[i+1:]...)
+			return
+		}
+	}
+}
+
+func (node *routerNode) setMethodHandler(m method, rt *route) error {
+	if m >= methodCount {
+		return ErrInvalidHttpMethod
+	}
+
+	node.handlers[m] = rt
+	return nil
+}
+
+func (node *routerNode) getStaticChild(token string) *routerNode {
+	for _, child := range node.children {
+		if !child.isParam && child.token == token {
+			return child
+		}
+	}
+
+	return nil
+}
+
+func (node *routerNode) getParamChild() *routerNode {
+	for _, child := range node.children {
+		if child.isParam {
+			return child
+		}
+	}
+
+	return nil
+}
+
+func (node *routerNode) matchChild(token string) (child *routerNode, usedParam bool) {
+	if c := node.getStaticChild(token); c != nil {
+		return c, false
+	}
+
+	if c := node.getParamChild(); c != nil && c.accepts(token) {
+		return c, true
+	}
+
+	return nil, false
+}
+
+func (node *routerNode) allowedMethods() []string {
+	allow := []string{}
+	for m, h := range node.handlers {
+		if h == nil {
+			continue
+		}
+		allow = append(allow, methodNames[m])
+		if method(m) == methodGET {
+			allow = append(allow, "HEAD") // served by the GET route
+		}
+	}
+
+	return allow
+}
+
+// anyRoute returns one of the node's registered routes, used to pick the group
+// whose middleware wraps the 405/OPTIONS responses for that path.
+func (node *routerNode) anyRoute() *route {
+	for _, rt := range node.handlers {
+		if rt != nil {
+			return rt
+		}
+	}
+
+	return nil
+}
+
+func (node *routerNode) getRoute(m method) (*route, error) {
+	if m >= methodCount {
+		return nil, ErrInvalidHttpMethod
+	}
+
+	return node.handlers[m], nil
+}
+
+type Middleware func(next response.Handler) response.Handler
+
+// Router registers routes into a trie shared by all of its groups. Each group
+// keeps only its own middleware and settings; anything it does not set is
+// inherited from its parent when a request is dispatched.
+//
+// A Router is safe for concurrent use: routes, groups, middleware and
+// settings can be added, changed or removed while it is serving, e.g. by
+// plugins or an admin endpoint. Requests already dispatched finish with the
+// handler chain they were given.
+type Router struct {
+	// mu guards the trie and the settings of every group; only the one of
+	// the top-level router is used, see lock
+	mu sync.RWMutex
+
+	routes       *routerNode
+	parent       *Router
+	prefix       string
+	middleware   []Middleware
+	errorHandler response.ErrorHandler
+	autoOptions  *bool
+	pathPolicy   *PathPolicy
+	spa          *spaFallback
+	// groups and mounted routers, for settings resolved by path prefix;
+	// scopePrefix is this router's prefix relative to its parent's
+	groups          []*Router
+	scopePrefix     string
+	notFoundHandler response.Handler
+}
+
+func NewRouter() *Router {
+	head := newRouterNode("/", false)
+	return &Router{
+		routes:     head,
+		prefix:     "",
+		middleware: []Middleware{},
+	}
+}
+
+// lock takes the write lock of the top-level router and returns the
+// matching unlock, for use as defer r.lock()().
+func (r *Router) lock() func() {
+	mu := &r.top().mu
+	mu.Lock()
+	return mu.Unlock
+}
+
+// rlock is lock for readers.
+func (r *Router) rlock() func() {
+	mu := &r.top().mu
+	mu.RLock()
+	return mu.RUnlock
+}
+
+// top returns the router at the top of r's group and mount chain, which owns
+// the trie.
+func (r *Router) top() *Router {
+	for r.parent != nil {
+		r = r.parent
+	}
+	return r
+}
+
+func (r *Router) withPrefix(path string) (string, error) {
+	if r.prefix == "" {
+		return path, nil
+	}
+	if path == "/" || path == "" {
+		return r.prefix, nil
+	}
+	if path[0] != '/' {
+		return path, ErrMalformedRequestTarget
+	}
+
+	return (r.prefix + path), nil
+}
+
+// applyMiddleware wraps h in this group's middleware and then in each parent's,
+// so the root middleware ends up outermost.
+func (r *Router) applyMiddleware(h response.Handler) response.Handler {
+	wrapped := h
+
+	for g := r; g != nil; g = g.parent {
+		for i := len(g.middleware) - 1; i >= 0; i-- {
+			wrapped = g.middleware[i](wrapped)
+		}
+	}
+
+	return wrapped
+}
+
+func (r *Router) addRoute(tokens []string, m method, rt *route) error {
+	node, err := r.getOrCreateNode(tokens)
+	if err != nil {
+		return err
+	}
+
+	return node.setMethodHandler(m, rt)
+}
+
+func (r *Router) getOrCreateNode(tokens []string) (*routerNode, error) {
+	runner := r.routes
+	for _, token := range tokens {
+		isParam := len(token) > 0 && token[0] == ':'
+
+		var node *routerNode
+		if isParam {
+			name, spec, constraint, err := parseParamToken(token[1:])
+			if err != nil {
+				return nil, err
+			}
+			param := newParamNode(name, spec, constraint)
+
+			node = runner.getParamChild()
+			if node == nil {
+				node = param
+				runner.addChild(node)
+
+			} else if !node.sameParam(param) {
+				return nil, ErrAmbiguousPathParams
+			}
+
+		} else {
+			node = runner.getStaticChild(token)
+			if node == nil {
+				node = newRouterNode(token, false)
+				runner.addChild(node)
+			}
+		}
+
+		runner = node
+	}
+
+	return runner, nil
+}
+
+// handle registers handler for m at path. Route middleware runs after the
+// router/group middleware, in the order given.
+func (r *Router) handle(m method, path string, handler response.Handler, mw []Middleware) error {
+	fullPath, err := r.withPrefix(path)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := getTokens(cleanPath(fullPath))
+	if err != nil {
+		return err
+	}
+
+	rt := &route{
+		handler:    handler,
+		middleware: append([]Middleware{}, mw...),
+		group:      r,
+	}
+	defer r.lock()()
+	return r.addRoute(tokens, m, rt)
+}
+
+// Remove unregisters the route for method at path, which has to be written
+// as it was registered, param names and constraints included. Nodes left
+// without routes are pruned from the trie.
+func (r *Router) Remove(methodName string, path string) error {
+	m := getMethod(methodName)
+	if m >= methodCount {
+		return ErrInvalidHttpMethod
+	}
+
+	fullPath, err := r.withPrefix(path)
+	if err != nil {
+		return err
+	}
+	tokens, err := getTokens(cleanPath(fullPath))
+	if err != nil {
+		return err
+	}
+
+	defer r.lock()()
+	nodes := []*routerNode{r.routes}
+	for _, token := range tokens {
+		runner := nodes[len(nodes)-1]
+		var node *routerNode
+		if len(token) > 0 && token[0] == ':' {
+			name, spec, constraint, err := parseParamToken(token[1:])
+			if err != nil {
+				return err
+			}
+			node = runner.getParamChild()
+			if node != nil && !node.sameParam(newParamNode(name, spec, constraint)) {
+				node = nil
+			}
+		} else {
+			node = runner.getStaticChild(token)
+		}
+
+		if node == nil {
+			return fmt.Errorf("%w: %s %s", ErrRouteNotFound, methodName, fullPath)
+		}
+		nodes = append(nodes, node)
+	}
+
+	node := nodes[len(nodes)-1]
+	if node.handlers[m] == nil {
+		return fmt.Errorf("%w: %s %s", ErrRouteNotFound, methodName, fullPath)
+	}
+	node.handlers[m] = nil
+
+	for i := len(nodes) - 1; i > 0; i-- {
+		if nodes[i].anyRoute() != nil || len(nodes[i].children) > 0 {
+			break
+		}
+		nodes[i-1].removeChild(nodes[i])
+	}
+	return nil
+}
+
+func (r *Router) GET(path string, handler response.Handler, mw ...Middleware) error {
+	return r.handle(methodGET, path, handler, mw)
+}
+
+func (r *Router) POST(path string, handler response.Handler, mw ...Middleware) error {
+	return r.handle(methodPOST, path, handler, mw)
+}
+
+func (r *Router) PUT(path string, handler response.Handler, mw ...Middleware) error {
+	return r.handle(methodPUT, path, handler, mw)
+}
+
+func (r *Router) DELETE(path string, handler response.Handler, mw ...Middleware) error {
+	return r.handle(methodDELETE, path, handler, mw)
+}
+
+func (r *Router) PATCH(path string, handler response.Handler, mw ...Middleware) error {
+	return r.handle(methodPATCH, path, handler, mw)
+}
+
+func (r *Router) OPTIONS(path string, handler response.Handler, mw ...Middleware) error {
+	return r.handle(methodOPTIONS, path, handler, mw)
+}
+
+// Redirect registers a route that answers every method except OPTIONS at path
+// with a redirect to target using code (301, 302, 303, 307 or 308).
+func (r *Router) Redirect(path string, target string, code response.StatusCode, mw ...Middleware) error {
+	if !response.IsRedirect(code) {
+		return fmt.Errorf("%w: %d", response.ErrInvalidRedirectStatus, code)
+	}
+
+	handler := func(w *response.Writer, req *request.Request) error {
+		return w.Redirect(code, target)
+	}
+
+	for m := methodGET; m < methodOPTIONS; m++ {
+		if err := r.handle(m, path, handler, mw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Router) Group(prefix string) *Router {
+	var newPrefix string
+	if prefix == "/" || prefix == "" {
+		newPrefix = ""
+
+	} else if !strings.HasPrefix(prefix, "/") {
+		newPrefix = "/" + strings.TrimSuffix(prefix, "/")
+
+	} else {
+		newPrefix = strings.TrimSuffix(prefix, "/")
+	}
+
+	defer r.lock()()
+	g := &Router{
+		routes:      r.routes,
+		parent:      r,
+		prefix:      r.prefix + newPrefix,
+		middleware:  []Middleware{},
+		scopePrefix: newPrefix,
+	}
+	r.groups = append(r.groups, g)
+	return g
+}
+
+// AutoOptions enables automatic 204 responses to OPTIONS requests on matched
+// paths without an explicit OPTIONS handler, listing the registered methods in
+// the Allow header.
+func (r *Router) AutoOptions(enabled bool) {
+	defer r.lock()()
+	r.autoOptions = &enabled
+}
+
+func (r *Router) getAutoOptions() bool {
+	for g := r; g != nil; g = g.parent {
+		if g.autoOptions != nil {
+			return *g.autoOptions
+		}
+	}
+
+	return false
+}
+
+// SetErrorHandler registers the function that converts errors returned by
+// handlers (including *response.HTTPError) into responses for requests
+// dispatched through this router. On a group it only covers requests under
+// the group's prefix; the group with the longest such prefix that set one
+// wins.
+func (r *Router) SetErrorHandler(h response.ErrorHandler) {
+	defer r.lock()()
+	r.errorHandler = h
+}
+
+func (r *Router) getErrorHandler() response.ErrorHandler {
+	for g := r; g != nil; g = g.parent {
+		if g.errorHandler != nil {
+			return g.errorHandler
+		}
+	}
+
+	return nil
+}
+
+func (r *Router) withErrorHandler(req *request.Request, h response.Handler) response.Handler {
+	eh := r.errorHandlerFor(req)
+	if eh == nil {
+		return h
+	}
+
+	return func(w *response.Writer, req *request.Request) error {
+		if err := h(w, req); err != nil {
+			return eh(w, req, err)
+		}
+		return nil
+	}
+}
+
+func (r *Router) Use(mw ...Middleware) {
+	defer r.lock()()
+	r.middleware = append(r.middleware, mw...)
+}
+
+func (r *Router) GetHandler(req *request.Request) response.Handler {
+	defer r.rlock()()
+	return r.withErrorHandler(req, r.getHandler(req))
+}
+
+// getHandler resolves the route for req and composes the middleware chain of
+// the group that registered it. Not-found responses use the dispatching
+// router's chain; 405 and automatic OPTIONS responses use the chain of a group
+// registered at the matched path.
+func (r *Router) getHandler(req *request.Request) response.Handler {
+	m := getMethod(req.RequestLine.Method)
+	if m >= methodCount && !standardMethods[req.RequestLine.Method] {
+		return r.applyMiddleware(notImplementedHandler)
+	}
+
+	target := requestPath(req)
+	switch r.getPathPolicy() {
+	case PathNormalize:
+		target = cleanPath(target)
+
+	case PathRedirect:
+		if clean := cleanPath(target); clean != target {
+			location := escapePath(clean)
+			if req.RawQuery != "" {
+				location += "?" + req.RawQuery
+			}
+			return r.applyMiddleware(redirectHandler(location))
+		}
+	}
+
+	runner := r.findNode(req, target)
+	if runner == nil {
+		return r.applyMiddleware(r.notFound(req))
+	}
+
+	// a standard method the router can't register, e.g. TRACE, gets a 405
+	// where the path has routes, like any other unrouted method
+	rt, _ := runner.getRoute(m)
+	if rt == nil && req.RequestLine.Method == "HEAD" {
+		// the Writer drops the body of the GET response
+		rt, _ = runner.getRoute(methodGET)
+	}
+	if rt == nil {
+		other := runner.anyRoute()
+		if other == nil {
+			return r.applyMiddleware(r.notFound(req))
+		}
+		req.RoutePattern = runner.pattern
+
+		allow := runner.allowedMethods()
+		if r.getAutoOptions() && runner.handlers[methodOPTIONS] == nil {
+			allow = append(allow, methodNames[methodOPTIONS])
+		}
+		if m == methodOPTIONS && r.getAutoOptions() {
+			return other.group.applyMiddleware(optionsHandler(allow))
+		}
+		return other.group.applyMiddleware(methodNotAllowedHandler(allow))
+	}
+
+	req.RoutePattern = runner.pattern
+	return rt.compose()
+}
+
+// findNode walks the trie for target, recording path params on req, and
+// returns the matched node, or nil. Segments are scanned in place rather
+// than split out, and PathParams is only allocated once a param matches.
+func (r *Router) findNode(req *request.Request, target string) *routerNode {
+	if target == "" || target[0] != '/' {
+		return nil
+	}
+
+	runner := r.routes
+	if target == "/" {
+		return runner
+	}
+
+	rest := strings.TrimSuffix(target[1:], "/")
+	for {
+		token, tail, more := strings.Cut(rest, "/")
+		node, usedParam := runner.matchChild(token)
+		if node == nil {
+			return nil
+		}
+
+		if usedParam {
+			if req.PathParams == nil {
+				req.PathParams = make(map[string]string)
+			}
+			req.PathParams[node.token] = token
+		}
+
+		runner = node
+		if !more {
+			return runner
+		}
+		rest = tail
+	}
+}
+
+// Match reports whether a route is registered for req's method and path, i.e.
+// whether dispatching it would reach a handler rather than a 404, 405 or path
+// redirect. Nothing is run; path params are recorded on req.
+func (r *Router) Match(req *request.Request) bool {
+	m := getMethod(req.RequestLine.Method)
+	if m >= methodCount {
+		return false
+	}
+
+	defer r.rlock()()
+
+	target := requestPath(req)
+	switch r.getPathPolicy() {
+	case PathNormalize:
+		target = cleanPath(target)
+	case PathRedirect:
+		if cleanPath(target) != target {
+			return false
+		}
+	}
+
+	node := r.findNode(req, target)
+	return node != nil && node.handlers[m] != nil
+}
+
+// Handler returns a response.Handler that dispatches each request through the
+// router, so the router can be wrapped by middleware or passed to
+// server.Serve as a plain handler.
+func (r *Router) Handler() response.Handler {
+	return func(w *response.Writer, req *request.Request) error {
+		return r.GetHandler(req)(w, req)
+	}
+}
+
+func getTokens(path string) ([]string, error) {
+	if len(path) == 0 {
+		return nil, ErrRequestTargetEmpty
+	}
+	if path[0] != '/' {
+		return nil, ErrMalformedRequestTarget
+	}
+	if path == "/" {
+		return []string{}, nil
+	}
+
+	path = strings.TrimPrefix(path, "/")
+	path = strings.TrimSuffix(path, "/")
+	return strings.Split(path, "/"), nil
+}
+
+func getMethod(s string) method {
+	var m method
+
+	switch s {
+	case "GET":
+		m = methodGET
+	case "POST":
+		m = methodPOST
+	case "PUT":
+		m = methodPUT
+	case "DELETE":
+		m = methodDELETE
+	case "PATCH":
+		m = methodPATCH
+	case "OPTIONS":
+		m = methodOPTIONS
+	default:
+		m = methodCount
+	}
+
+	return m
+}
+
+func optionsHandler(allow []string) response.Handler {
+	allowStr := strings.Join(allow, ", ")
+	return func(w *response.Writer, req *request.Request) error {
+		status := response.StatusNoContent
+		h := response.GetDefaultHeaders(0)
+		h.Del("Content-Length")
+		h.Set("Allow", allowStr)
+		return w.WriteResponse(status, h, []byte{})
+	}
+}
+
+func methodNotAllowedHandler(allow []string) response.Handler {
+	allowStr := strings.Join(allow, ", ")
+	return func(w *response.Writer, req *request.Request) error {
+		status := response.StatusMethodNotAllowed
+		h := response.GetDefaultHeaders(0)
+		h.Set("Allow", allowStr)
+		return w.WriteResponse(status, h, []byte{})
+	}
+}
+
+func notImplementedHandler(w *response.Writer, req *request.Request) error {
+	status := response.StatusNotImplemented
+	h := response.GetDefaultHeaders(0)
+	body := []byte("")
+	if err := w.WriteResponse(status, h, body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func notFoundHandler(w *response.Writer, req *request.Request) error {
+	status := response.StatusNotFound
+	h := response.GetDefaultHeaders(0)
+	body := []byte("")
+	if err := w.WriteResponse(status, h, body); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -3,8 +3,8 @@ package router
 import (
 	"testing"
 
-	"github.com/ShazimR/tcp-http-server/internal/request"
-	"github.com/ShazimR/tcp-http-server/internal/response"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -134,3 +134,82 @@ func TestMiddleware_RouterAndGroupCombinedOrder(t *testing.T) {
 		order,
 	)
 }
+
+func TestMiddleware_PerRoute(t *testing.T) {
+	var order []string
+	r := NewRouter()
+	r.Use(mwTag("root-mw", &order))
+	api := r.Group("/api")
+	api.Use(mwTag("api-mw", &order))
+
+	handler := func(w *response.Writer, req *request.Request) error {
+		order = append(order, "handler")
+		return nil
+	}
+
+	require.NoError(t, api.GET("/admin", handler, mwTag("auth", &order), mwTag("audit", &order)))
+	require.NoError(t, api.GET("/public", handler))
+
+	// Test: Route middleware runs after group middleware, in order
+	req := mkReq("GET", "/api/admin")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, []string{"root-mw", "api-mw", "auth", "audit", "handler"}, order)
+
+	// Test: Other routes are unaffected
+	order = nil
+	req = mkReq("GET", "/api/public")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, []string{"root-mw", "api-mw", "handler"}, order)
+
+	// Test: Route middleware can short-circuit
+	require.NoError(t, r.POST("/blocked", handler, mwShortCircuit("denied")))
+	order = nil
+	req = mkReq("POST", "/blocked")
+	out := runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "denied")
+	assert.Equal(t, []string{"root-mw"}, order)
+}
+
+func TestMiddleware_WrapsNotFoundAndMethodNotAllowed(t *testing.T) {
+	var order []string
+	r := NewRouter()
+	r.Use(mwTag("root-mw", &order))
+	api := r.Group("/api")
+	api.Use(mwTag("api-mw", &order))
+
+	okHandler := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, api.GET("/items", okHandler))
+
+	// Test: 404 runs the root middleware
+	req := mkReq("GET", "/missing")
+	out := runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "404")
+	assert.Equal(t, []string{"root-mw"}, order)
+
+	// Test: 405 runs the middleware of the group that owns the path
+	order = nil
+	req = mkReq("POST", "/api/items")
+	out = runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "405")
+	assert.Equal(t, []string{"root-mw", "api-mw"}, order)
+}
+
+func TestMiddleware_UseAfterRegistration(t *testing.T) {
+	var order []string
+	r := NewRouter()
+	api := r.Group("/api")
+
+	handler := func(w *response.Writer, req *request.Request) error {
+		order = append(order, "handler")
+		return nil
+	}
+	require.NoError(t, api.GET("/ping", handler))
+
+	// Test: Middleware added after registration and after grouping still applies
+	r.Use(mwTag("root-mw", &order))
+	api.Use(mwTag("api-mw", &order))
+
+	req := mkReq("GET", "/api/ping")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, []string{"root-mw", "api-mw", "handler"}, order)
+}
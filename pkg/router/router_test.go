@@ -0,0 +1,588 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mkReq(method, target string) *request.Request {
+	return &request.Request{
+		RequestLine: request.RequestLine{
+			Method:        method,
+			RequestTarget: target,
+		},
+		PathParams: make(map[string]string),
+	}
+}
+
+func runHandler(t *testing.T, h response.Handler, req *request.Request) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+
+	err := h(w, req)
+	require.NoError(t, err)
+	require.NoError(t, w.Finish())
+
+	return buf.String()
+}
+
+func TestRouter_StaticExactMatch(t *testing.T) {
+	r := NewRouter()
+
+	called := false
+	okHandler := func(w *response.Writer, req *request.Request) error {
+		called = true
+		return nil
+	}
+
+	require.NoError(t, r.GET("/hello", okHandler))
+
+	req := mkReq("GET", "/hello")
+	h := r.GetHandler(req)
+	_ = runHandler(t, h, req)
+
+	assert.True(t, called)
+}
+
+func TestRouter_NotFound(t *testing.T) {
+	r := NewRouter()
+
+	req := mkReq("GET", "/nope")
+	h := r.GetHandler(req)
+	out := runHandler(t, h, req)
+
+	assert.Contains(t, out, "404")
+}
+
+func TestRouter_MethodNotAllowed(t *testing.T) {
+	r := NewRouter()
+
+	okHandler := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, r.GET("/only-get", okHandler))
+
+	req := mkReq("POST", "/only-get")
+	h := r.GetHandler(req)
+	out := runHandler(t, h, req)
+
+	assert.Contains(t, out, "405")
+}
+
+func TestRouter_HEADUsesGET(t *testing.T) {
+	r := NewRouter()
+	getHandler := func(w *response.Writer, req *request.Request) error {
+		body := []byte("hello")
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+	}
+	require.NoError(t, r.GET("/hello", getHandler))
+	require.NoError(t, r.POST("/submit", getHandler))
+
+	// Test: HEAD runs the GET route
+	req := mkReq("HEAD", "/hello")
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+	w.SetHEAD()
+	require.NoError(t, r.GetHandler(req)(w, req))
+	require.NoError(t, w.Finish())
+	assert.Contains(t, buf.String(), "200 OK")
+	assert.Contains(t, buf.String(), "Content-Length: 5\r\n")
+	assert.NotContains(t, buf.String(), "hello")
+
+	// Test: Without a GET route HEAD isn't allowed
+	req = mkReq("HEAD", "/submit")
+	out := runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "405")
+	assert.Contains(t, out, "Allow: POST\r\n")
+}
+
+func TestRouter_MethodNotAllowed_Allow(t *testing.T) {
+	r := NewRouter()
+
+	okHandler := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, r.GET("/items", okHandler))
+	require.NoError(t, r.POST("/items", okHandler))
+
+	// Test: 405 lists the allowed methods
+	req := mkReq("DELETE", "/items")
+	out := runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "405")
+	assert.Contains(t, out, "Allow: GET, HEAD, POST\r\n")
+
+	// Test: Automatic OPTIONS is allowed too
+	r.AutoOptions(true)
+	req = mkReq("DELETE", "/items")
+	out = runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "Allow: GET, HEAD, POST, OPTIONS\r\n")
+
+	// Test: Standard methods the router can't route get a 405 too
+	req = mkReq("TRACE", "/items")
+	out = runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "405")
+	req = mkReq("TRACE", "/nope")
+	out = runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "404")
+}
+
+func TestRouter_UnknownMethod(t *testing.T) {
+	r := NewRouter()
+
+	okHandler := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, r.GET("/items", okHandler))
+
+	for _, target := range []string{"/items", "/nope"} {
+		req := mkReq("PURGE", target)
+		out := runHandler(t, r.GetHandler(req), req)
+		assert.Contains(t, out, "501 Not Implemented", target)
+		assert.False(t, r.Match(req))
+	}
+}
+
+func TestRouter_PathParamCaptured(t *testing.T) {
+	r := NewRouter()
+
+	okHandler := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, r.GET("/users/:id", okHandler))
+
+	req := mkReq("GET", "/users/42")
+	h := r.GetHandler(req)
+	_ = runHandler(t, h, req)
+
+	assert.Equal(t, "42", req.PathParams["id"])
+}
+
+func TestRouter_StaticBeatsParam(t *testing.T) {
+	r := NewRouter()
+
+	var matched string
+
+	staticHandler := func(w *response.Writer, req *request.Request) error {
+		matched = "static"
+		return nil
+	}
+	paramHandler := func(w *response.Writer, req *request.Request) error {
+		matched = "param"
+		return nil
+	}
+
+	require.NoError(t, r.GET("/users/me", staticHandler))
+	require.NoError(t, r.GET("/users/:id", paramHandler))
+
+	req := mkReq("GET", "/users/me")
+	h := r.GetHandler(req)
+	_ = runHandler(t, h, req)
+
+	assert.Equal(t, "static", matched)
+}
+
+func TestRouter_MultiParamRoute(t *testing.T) {
+	r := NewRouter()
+
+	okHandler := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, r.GET("/users/:id/posts/:postId", okHandler))
+
+	req := mkReq("GET", "/users/7/posts/99")
+	h := r.GetHandler(req)
+	_ = runHandler(t, h, req)
+
+	assert.Equal(t, "7", req.PathParams["id"])
+	assert.Equal(t, "99", req.PathParams["postId"])
+}
+
+func TestRouter_AmbiguousParamNamesRejected(t *testing.T) {
+	r := NewRouter()
+
+	okHandler := func(w *response.Writer, req *request.Request) error { return nil }
+
+	require.NoError(t, r.GET("/users/:id", okHandler))
+	err := r.GET("/users/:userId", okHandler)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrAmbiguousPathParams)
+}
+
+func TestRouter_RootRoute(t *testing.T) {
+	r := NewRouter()
+
+	called := false
+	rootHandler := func(w *response.Writer, req *request.Request) error {
+		called = true
+		return nil
+	}
+
+	require.NoError(t, r.GET("/", rootHandler))
+
+	req := mkReq("GET", "/")
+	h := r.GetHandler(req)
+	_ = runHandler(t, h, req)
+
+	assert.True(t, called)
+}
+
+func TestRouter_ManyRoutes_StaticAndParamBranches(t *testing.T) {
+	r := NewRouter()
+
+	noop := func(w *response.Writer, req *request.Request) error { return nil }
+
+	// Register a bunch of static routes
+	staticRoutes := []string{
+		"/",
+		"/health",
+		"/users",
+		"/users/me",
+		"/users/settings",
+		"/users/settings/privacy",
+		"/posts",
+		"/posts/recent",
+		"/assets/app.js",
+		"/assets/styles.css",
+		"/api/v1/ping",
+		"/api/v1/users",
+		"/api/v1/users/me",
+		"/api/v1/users/settings",
+		"/api/v1/posts",
+		"/api/v1/posts/recent",
+	}
+
+	for _, p := range staticRoutes {
+		require.NoError(t, r.GET(p, noop), "failed to add GET %s", p)
+	}
+
+	// Register param routes that overlap with statics
+	require.NoError(t, r.GET("/users/:id", noop))
+	require.NoError(t, r.GET("/users/:id/posts", noop))
+	require.NoError(t, r.GET("/users/:id/posts/:postId", noop))
+	require.NoError(t, r.GET("/api/v1/users/:id", noop))
+	require.NoError(t, r.GET("/api/v1/posts/:id", noop))
+
+	// Ensure a bunch of lookups work and don't confuse branches
+	cases := []struct {
+		method    string
+		target    string
+		want404   bool
+		wantParam map[string]string
+	}{
+		{"GET", "/", false, nil},
+		{"GET", "/health", false, nil},
+		{"GET", "/users/me", false, nil}, // must hit static, not param
+		{"GET", "/users/123", false, map[string]string{"id": "123"}},
+		{"GET", "/users/123/posts", false, map[string]string{"id": "123"}},
+		{"GET", "/users/123/posts/999", false, map[string]string{"id": "123", "postId": "999"}},
+		{"GET", "/api/v1/users/me", false, nil}, // must hit static, not param
+		{"GET", "/api/v1/users/77", false, map[string]string{"id": "77"}},
+		{"GET", "/api/v1/posts/888", false, map[string]string{"id": "888"}},
+		{"GET", "/does-not-exist", true, nil},
+		{"GET", "/users/123/does-not-exist", true, nil},
+	}
+
+	for _, tc := range cases {
+		req := mkReq(tc.method, tc.target)
+		h := r.GetHandler(req)
+		out := runHandler(t, h, req)
+
+		if tc.want404 {
+			assert.Contains(t, out, "404", "expected 404 for %s %s", tc.method, tc.target)
+			continue
+		}
+		assert.NotContains(t, out, "404", "unexpected 404 for %s %s", tc.method, tc.target)
+
+		for k, v := range tc.wantParam {
+			assert.Equal(t, v, req.PathParams[k], "wrong param for %s %s", tc.method, tc.target)
+		}
+	}
+}
+
+func TestRouter_StaticBeatsParam_MultipleDepths(t *testing.T) {
+	r := NewRouter()
+
+	var hit string
+	static := func(name string) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			hit = name
+			return nil
+		}
+	}
+
+	noop := func(w *response.Writer, req *request.Request) error { return nil }
+
+	// Level 1 overlap
+	require.NoError(t, r.GET("/users/me", static("users/me")))
+	require.NoError(t, r.GET("/users/:id", noop))
+
+	// Level 2 overlap
+	require.NoError(t, r.GET("/users/me/posts", static("users/me/posts")))
+	require.NoError(t, r.GET("/users/:id/posts", noop))
+
+	// Level 3 overlap
+	require.NoError(t, r.GET("/users/me/posts/recent", static("users/me/posts/recent")))
+	require.NoError(t, r.GET("/users/:id/posts/:postId", noop))
+
+	req := mkReq("GET", "/users/me")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, "users/me", hit)
+
+	req = mkReq("GET", "/users/me/posts")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, "users/me/posts", hit)
+
+	req = mkReq("GET", "/users/me/posts/recent")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, "users/me/posts/recent", hit)
+}
+
+func TestRouter_MethodNotAllowed_OnParamMatch(t *testing.T) {
+	r := NewRouter()
+
+	getOnly := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, r.GET("/users/:id", getOnly))
+
+	req := mkReq("POST", "/users/123")
+	h := r.GetHandler(req)
+	out := runHandler(t, h, req)
+
+	assert.Contains(t, out, "405")
+}
+
+func TestRouter_AmbiguousParamsRejected_Deeper(t *testing.T) {
+	r := NewRouter()
+	noop := func(w *response.Writer, req *request.Request) error { return nil }
+
+	require.NoError(t, r.GET("/users/:id/posts/:postId", noop))
+	err := r.GET("/users/:id/posts/:pid", noop) // same position, different param name
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrAmbiguousPathParams)
+}
+
+func TestRouter_AddRouteValidation(t *testing.T) {
+	r := NewRouter()
+	noop := func(w *response.Writer, req *request.Request) error { return nil }
+
+	// empty path
+	err := r.GET("", noop)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrRequestTargetEmpty)
+
+	// malformed (no leading slash)
+	err = r.GET("users", noop)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrMalformedRequestTarget)
+}
+
+func TestRouter_ErrorHandler(t *testing.T) {
+	r := NewRouter()
+
+	failing := func(w *response.Writer, req *request.Request) error {
+		return response.NewHTTPError(response.StatusBadRequest, "invalid id")
+	}
+	require.NoError(t, r.GET("/users/:id", failing))
+
+	// Test: Without an error handler the error is returned unchanged
+	req := mkReq("GET", "/users/abc")
+	var buf bytes.Buffer
+	err := r.GetHandler(req)(response.NewWriter(&buf), req)
+	require.Error(t, err)
+	assert.Empty(t, buf.String())
+
+	// Test: Error handler converts the error into a response
+	var seen error
+	r.SetErrorHandler(func(w *response.Writer, req *request.Request, err error) error {
+		seen = err
+		return response.DefaultErrorHandler(w, req, err)
+	})
+	req = mkReq("GET", "/users/abc")
+	out := runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "400 Bad Request")
+	assert.Contains(t, out, "invalid id")
+	require.Error(t, seen)
+
+	// Test: Groups created afterwards inherit the error handler
+	api := r.Group("/api")
+	require.NoError(t, api.GET("/fail", failing))
+	req = mkReq("GET", "/api/fail")
+	out = runHandler(t, api.GetHandler(req), req)
+	assert.Contains(t, out, "400 Bad Request")
+}
+
+func TestRouter_AutoOptions(t *testing.T) {
+	r := NewRouter()
+	okHandler := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, r.GET("/items", okHandler))
+	require.NoError(t, r.POST("/items", okHandler))
+
+	// Test: Disabled by default
+	req := mkReq("OPTIONS", "/items")
+	out := runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "405")
+
+	// Test: Enabled answers with the registered methods
+	r.AutoOptions(true)
+	req = mkReq("OPTIONS", "/items")
+	out = runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "HTTP/1.1 204 No Content\r\n")
+	assert.Contains(t, out, "Allow: GET, HEAD, POST, OPTIONS\r\n")
+
+	// Test: Unknown path is still a 404
+	req = mkReq("OPTIONS", "/nope")
+	out = runHandler(t, r.GetHandler(req), req)
+	assert.Contains(t, out, "404")
+
+	// Test: Explicit OPTIONS handler wins
+	called := false
+	require.NoError(t, r.OPTIONS("/items", func(w *response.Writer, req *request.Request) error {
+		called = true
+		return nil
+	}))
+	req = mkReq("OPTIONS", "/items")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.True(t, called)
+}
+
+func TestRouter_RoutePattern(t *testing.T) {
+	r := NewRouter()
+	ok := func(w *response.Writer, req *request.Request) error {
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(0), []byte{})
+	}
+	require.NoError(t, r.GET("/users/:id{[0-9]+}/posts", ok))
+	sub := NewRouter()
+	require.NoError(t, sub.GET("/:name", ok))
+	require.NoError(t, r.Mount("/files", sub))
+
+	// Test: Params are reported as registered, not as requested
+	req := mkReq("GET", "/users/42/posts")
+	runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, "/users/:id{[0-9]+}/posts", req.RoutePattern)
+
+	// Test: Mounted routes include the mount prefix
+	req = mkReq("GET", "/files/readme")
+	runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, "/files/:name", req.RoutePattern)
+
+	// Test: 405 still reports the matched path
+	req = mkReq("POST", "/users/42/posts")
+	runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, "/users/:id{[0-9]+}/posts", req.RoutePattern)
+
+	// Test: No match leaves it empty
+	req = mkReq("GET", "/nope")
+	runHandler(t, r.GetHandler(req), req)
+	assert.Equal(t, "", req.RoutePattern)
+}
+
+func TestRouter_Match(t *testing.T) {
+	r := NewRouter()
+	ok := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, r.POST("/users/:id/avatar", ok))
+
+	// Test: Registered method and path
+	req := mkReq("POST", "/users/7/avatar")
+	assert.True(t, r.Match(req))
+	assert.Equal(t, "7", req.PathParams["id"])
+
+	// Test: Wrong method, unknown path and unknown method
+	assert.False(t, r.Match(mkReq("PUT", "/users/7/avatar")))
+	assert.False(t, r.Match(mkReq("POST", "/users/7")))
+	assert.False(t, r.Match(mkReq("BREW", "/users/7/avatar")))
+
+	// Test: Paths that would be redirected don't match
+	r.SetPathPolicy(PathRedirect)
+	assert.False(t, r.Match(mkReq("POST", "/users/7//avatar")))
+}
+
+func TestRouter_Remove(t *testing.T) {
+	r := NewRouter()
+	noop := func(w *response.Writer, req *request.Request) error { return nil }
+	api := r.Group("/api")
+	require.NoError(t, api.GET("/users/:id:int", noop))
+	require.NoError(t, api.DELETE("/users/:id:int", noop))
+	require.NoError(t, api.GET("/users/:id:int/posts", noop))
+
+	// Test: Removing one method leaves the others
+	require.NoError(t, api.Remove("DELETE", "/users/:id:int"))
+	req := mkReq("DELETE", "/api/users/1")
+	assert.Contains(t, runHandler(t, r.GetHandler(req), req), "405")
+	assert.True(t, r.Match(mkReq("GET", "/api/users/1")))
+
+	// Test: The path has to be given as registered
+	assert.ErrorIs(t, api.Remove("GET", "/users/:uid:int"), ErrRouteNotFound)
+	assert.ErrorIs(t, api.Remove("GET", "/users/:id"), ErrRouteNotFound)
+	assert.ErrorIs(t, r.Remove("GET", "/users/:id:int"), ErrRouteNotFound)
+	assert.ErrorIs(t, api.Remove("DELETE", "/users/:id:int"), ErrRouteNotFound)
+	assert.ErrorIs(t, api.Remove("TRACE", "/users/:id:int"), ErrInvalidHttpMethod)
+
+	// Test: Emptied branches are pruned, so the param name is free again
+	require.NoError(t, r.Remove("GET", "/api/users/:id:int/posts"))
+	require.NoError(t, r.Remove("GET", "/api/users/:id:int"))
+	assert.Empty(t, r.Routes())
+	req = mkReq("GET", "/api/users/1")
+	assert.Contains(t, runHandler(t, r.GetHandler(req), req), "404")
+	require.NoError(t, api.GET("/users/:name", noop))
+	assert.True(t, r.Match(mkReq("GET", "/api/users/bob")))
+}
+
+func TestRouter_ConcurrentMutation(t *testing.T) {
+	r := NewRouter()
+	noop := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, r.GET("/stable", noop))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			g := r.Group(fmt.Sprintf("/plugin%d", i))
+			for j := 0; j < 50; j++ {
+				path := fmt.Sprintf("/route%d", j)
+				assert.NoError(t, g.GET(path, noop))
+				g.Use(func(next response.Handler) response.Handler { return next })
+				assert.NoError(t, g.Remove("GET", path))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				req := mkReq("GET", "/stable")
+				assert.NoError(t, r.Handler()(response.NewWriter(&bytes.Buffer{}), req))
+				r.Match(mkReq("GET", fmt.Sprintf("/plugin%d/route%d", i, j%50)))
+				_ = r.Routes()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, r.Routes(), 1)
+}
+
+func TestRouter_LookupAllocs(t *testing.T) {
+	r := NewRouter()
+	noop := func(w *response.Writer, req *request.Request) error { return nil }
+	require.NoError(t, r.GET("/api/v1/users/:id:int/posts", noop))
+	require.NoError(t, r.GET("/api/v1/status", noop))
+
+	// Test: Static lookups don't allocate, not even a PathParams map
+	req := &request.Request{RequestLine: request.RequestLine{Method: "GET", RequestTarget: "/api/v1/status"}}
+	allocs := testing.AllocsPerRun(100, func() {
+		require.NotNil(t, r.findNode(req, "/api/v1/status"))
+	})
+	assert.Zero(t, allocs)
+	assert.Nil(t, req.PathParams)
+
+	// Test: Param lookups allocate the map once and nothing else
+	req = &request.Request{RequestLine: request.RequestLine{Method: "GET", RequestTarget: "/api/v1/users/42/posts"}}
+	require.NotNil(t, r.findNode(req, "/api/v1/users/42/posts"))
+	assert.Equal(t, map[string]string{"id": "42"}, req.PathParams)
+	allocs = testing.AllocsPerRun(100, func() {
+		require.NotNil(t, r.findNode(req, "/api/v1/users/42/posts"))
+	})
+	assert.Zero(t, allocs)
+
+	assert.True(t, r.Match(req))
+	_ = r.GetHandler(req)
+	assert.Equal(t, "/api/v1/users/:id:int/posts", req.RoutePattern)
+}
@@ -0,0 +1,70 @@
+package router
+
+import (
+	"reflect"
+	"runtime"
+
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+)
+
+// RouteInfo describes a registered route for logging, admin pages, and
+// generated documentation.
+type RouteInfo struct {
+	Method      string
+	Pattern     string
+	Prefix      string // prefix of the group that registered the route
+	HandlerName string
+	Middleware  int // route middleware plus the group chain, at call time
+}
+
+func handlerName(h response.Handler) string {
+	if h == nil {
+		return ""
+	}
+
+	fn := runtime.FuncForPC(reflect.ValueOf(h).Pointer())
+	if fn == nil {
+		return ""
+	}
+	return fn.Name()
+}
+
+func (r *Router) middlewareCount() int {
+	n := 0
+	for g := r; g != nil; g = g.parent {
+		n += len(g.middleware)
+	}
+
+	return n
+}
+
+// Routes walks the trie depth-first (children in registration order) and
+// returns every registered route.
+func (r *Router) Routes() []RouteInfo {
+	defer r.rlock()()
+	routes := []RouteInfo{}
+
+	var walk func(node *routerNode)
+	walk = func(node *routerNode) {
+		for m, rt := range node.handlers {
+			if rt == nil {
+				continue
+			}
+
+			routes = append(routes, RouteInfo{
+				Method:      methodNames[m],
+				Pattern:     node.pattern,
+				Prefix:      rt.group.prefix,
+				HandlerName: handlerName(rt.handler),
+				Middleware:  len(rt.middleware) + rt.group.middlewareCount(),
+			})
+		}
+
+		for _, child := range node.children {
+			walk(child)
+		}
+	}
+	walk(r.routes)
+
+	return routes
+}
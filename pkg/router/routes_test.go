@@ -0,0 +1,49 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listUsers(w *response.Writer, req *request.Request) error { return nil }
+
+func TestRouter_Routes(t *testing.T) {
+	var order []string
+	r := NewRouter()
+	r.Use(mwTag("root", &order))
+	api := r.Group("/api")
+	api.Use(mwTag("api", &order))
+
+	require.NoError(t, r.GET("/", listUsers))
+	require.NoError(t, api.GET("/users", listUsers))
+	require.NoError(t, api.POST("/users", listUsers, mwTag("audit", &order)))
+	require.NoError(t, api.DELETE("/users/:id", listUsers))
+
+	routes := r.Routes()
+	require.Len(t, routes, 4)
+
+	assert.Equal(t, "GET", routes[0].Method)
+	assert.Equal(t, "/", routes[0].Pattern)
+	assert.Equal(t, "", routes[0].Prefix)
+	assert.Equal(t, 1, routes[0].Middleware)
+	assert.Contains(t, routes[0].HandlerName, "listUsers")
+
+	assert.Equal(t, RouteInfo{
+		Method:      "POST",
+		Pattern:     "/api/users",
+		Prefix:      "/api",
+		HandlerName: routes[2].HandlerName,
+		Middleware:  3,
+	}, routes[2])
+	assert.Contains(t, routes[2].HandlerName, "listUsers")
+
+	assert.Equal(t, "DELETE", routes[3].Method)
+	assert.Equal(t, "/api/users/:id", routes[3].Pattern)
+
+	// Test: Groups see the whole shared trie
+	assert.Len(t, api.Routes(), 4)
+}
@@ -0,0 +1,105 @@
+package router
+
+import (
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+)
+
+// NotFound registers the handler answering requests that match no route
+// under this group's prefix, e.g. with a JSON body for an API group while the
+// rest of the site keeps HTML pages. Like SetErrorHandler, the group with the
+// longest prefix covering the request path that set one wins, whether or not
+// the request was dispatched through it.
+func (r *Router) NotFound(h response.Handler) {
+	defer r.lock()()
+	r.notFoundHandler = h
+}
+
+// scope returns the path prefix this router's settings cover in the trie it
+// dispatches from: a group's prefix, or where a router was mounted.
+func (r *Router) scope() string {
+	if r.parent == nil {
+		return ""
+	}
+	return r.parent.scope() + r.scopePrefix
+}
+
+// coversPath reports whether target lies at or below prefix, with a param
+// segment in prefix standing for any one segment.
+func coversPath(prefix string, target string) bool {
+	want := strings.Split(strings.Trim(prefix, "/"), "/")
+	have := strings.Split(strings.Trim(target, "/"), "/")
+	if prefix == "" || prefix == "/" {
+		return true
+	}
+	if len(have) < len(want) {
+		return false
+	}
+
+	for i, token := range want {
+		if strings.HasPrefix(token, ":") {
+			if have[i] == "" {
+				return false
+			}
+			continue
+		}
+		if token != have[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scoped returns the group or mounted router below r (or r itself) that
+// covers target with the longest prefix and satisfies has, or nil.
+func (r *Router) scoped(target string, has func(g *Router) bool) *Router {
+	var best *Router
+	bestLen := -1
+
+	var walk func(g *Router, prefix string)
+	walk = func(g *Router, prefix string) {
+		if !coversPath(prefix, target) {
+			return
+		}
+		if has(g) && len(prefix) > bestLen {
+			best, bestLen = g, len(prefix)
+		}
+		for _, child := range g.groups {
+			walk(child, prefix+child.scopePrefix)
+		}
+	}
+	walk(r, r.scope())
+
+	return best
+}
+
+// errorHandlerFor returns the error handler for req: the one of the group
+// covering its path with the longest prefix, else the one r inherits.
+func (r *Router) errorHandlerFor(req *request.Request) response.ErrorHandler {
+	g := r.scoped(cleanPath(requestPath(req)), func(g *Router) bool {
+		return g.errorHandler != nil
+	})
+	if g != nil {
+		return g.errorHandler
+	}
+	return r.getErrorHandler()
+}
+
+// notFoundHandlerFor returns the NotFound handler for req, found like
+// errorHandlerFor, or nil.
+func (r *Router) notFoundHandlerFor(req *request.Request) response.Handler {
+	g := r.scoped(cleanPath(requestPath(req)), func(g *Router) bool {
+		return g.notFoundHandler != nil
+	})
+	if g != nil {
+		return g.notFoundHandler
+	}
+	for g := r.parent; g != nil; g = g.parent {
+		if g.notFoundHandler != nil {
+			return g.notFoundHandler
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoversPath(t *testing.T) {
+	assert.True(t, coversPath("", "/anything"))
+	assert.True(t, coversPath("/api", "/api"))
+	assert.True(t, coversPath("/api", "/api/users"))
+	assert.False(t, coversPath("/api", "/apix"))
+	assert.False(t, coversPath("/api/v1", "/api"))
+	assert.True(t, coversPath("/tenants/:id", "/tenants/7/users"))
+	assert.False(t, coversPath("/tenants/:id", "/tenants/"))
+}
+
+func TestRouter_GroupHandlers(t *testing.T) {
+	textError := func(body string) response.ErrorHandler {
+		return func(w *response.Writer, req *request.Request, err error) error {
+			h := response.GetDefaultHeaders(len(body))
+			h.Replace("Content-Type", "text/plain")
+			return w.WriteResponse(response.StatusInternalServerError, h, []byte(body))
+		}
+	}
+	reply := func(status response.StatusCode, body string) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			h := response.GetDefaultHeaders(len(body))
+			return w.WriteResponse(status, h, []byte(body))
+		}
+	}
+	failing := func(w *response.Writer, req *request.Request) error {
+		return response.NewHTTPError(response.StatusBadRequest, "bad")
+	}
+
+	r := NewRouter()
+	r.SetErrorHandler(textError("html error"))
+	r.NotFound(reply(response.StatusNotFound, "html 404"))
+	api := r.Group("/api")
+	api.SetErrorHandler(textError(`{"error":true}`))
+	api.NotFound(reply(response.StatusNotFound, `{"error":"not found"}`))
+	v2 := api.Group("/v2")
+	v2.NotFound(reply(response.StatusNotFound, "v2 404"))
+
+	require.NoError(t, r.GET("/page", failing))
+	require.NoError(t, api.GET("/items", failing))
+	require.NoError(t, v2.GET("/items", failing))
+	require.NoError(t, r.GET("/api/legacy", failing))
+
+	dispatch := func(target string) string {
+		req := mkReq("GET", target)
+		return runHandler(t, r.Handler(), req)
+	}
+
+	// Test: Errors use the handler of the longest covering group
+	assert.Contains(t, dispatch("/page"), "html error")
+	assert.Contains(t, dispatch("/api/items"), `{"error":true}`)
+	assert.Contains(t, dispatch("/api/v2/items"), `{"error":true}`)
+	assert.Contains(t, dispatch("/api/legacy"), `{"error":true}`)
+
+	// Test: So do unmatched paths, by prefix rather than by route
+	assert.Contains(t, dispatch("/missing"), "html 404")
+	assert.Contains(t, dispatch("/apiary"), "html 404")
+	assert.Contains(t, dispatch("/api"), `{"error":"not found"}`)
+	assert.Contains(t, dispatch("/api/missing"), `{"error":"not found"}`)
+	assert.Contains(t, dispatch("/api/v2/missing"), "v2 404")
+	assert.Contains(t, dispatch("/api/v3/missing"), `{"error":"not found"}`)
+
+	// Test: Dispatching through a group resolves the same way
+	req := mkReq("GET", "/api/missing")
+	assert.Contains(t, runHandler(t, api.Handler(), req), `{"error":"not found"}`)
+
+	// Test: A mounted router's handlers cover its mount point
+	sub := NewRouter()
+	require.NoError(t, sub.GET("/users", failing))
+	sub.SetErrorHandler(textError("admin error"))
+	sub.NotFound(reply(response.StatusNotFound, "admin 404"))
+	require.NoError(t, r.Mount("/admin", sub))
+	assert.Contains(t, dispatch("/admin/users"), "admin error")
+	assert.Contains(t, dispatch("/admin/nope"), "admin 404")
+	assert.Contains(t, dispatch("/administrator"), "html 404")
+}
@@ -0,0 +1,77 @@
+package router
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+)
+
+type spaFallback struct {
+	indexFile string
+	exclude   []string
+}
+
+// SPAFallback serves indexFile for GET/HEAD requests that match no route so a
+// client-side routed app can handle the path itself. Paths under any of the
+// exclude prefixes (default "/api") and paths that look like files (the last
+// segment has an extension) still get a 404.
+func (r *Router) SPAFallback(indexFile string, exclude ...string) {
+	if len(exclude) == 0 {
+		exclude = []string{"/api"}
+	}
+
+	defer r.lock()()
+	r.spa = &spaFallback{
+		indexFile: indexFile,
+		exclude:   exclude,
+	}
+}
+
+func (r *Router) getSPAFallback() *spaFallback {
+	for g := r; g != nil; g = g.parent {
+		if g.spa != nil {
+			return g.spa
+		}
+	}
+
+	return nil
+}
+
+func (spa *spaFallback) applies(req *request.Request) bool {
+	if m := req.RequestLine.Method; m != "GET" && m != "HEAD" {
+		return false
+	}
+
+	target := cleanPath(requestPath(req))
+	for _, prefix := range spa.exclude {
+		if target == prefix || strings.HasPrefix(target, strings.TrimSuffix(prefix, "/")+"/") {
+			return false
+		}
+	}
+
+	return path.Ext(target) == ""
+}
+
+func (spa *spaFallback) handler(w *response.Writer, req *request.Request) error {
+	body, err := os.ReadFile(spa.indexFile)
+	if err != nil {
+		return response.WrapHTTPError(response.StatusNotFound, "", err)
+	}
+
+	return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+}
+
+// notFound returns the handler used when no route matches req.
+func (r *Router) notFound(req *request.Request) response.Handler {
+	if spa := r.getSPAFallback(); spa != nil && spa.applies(req) {
+		return spa.handler
+	}
+	if h := r.notFoundHandlerFor(req); h != nil {
+		return h
+	}
+
+	return notFoundHandler
+}
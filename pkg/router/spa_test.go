@@ -0,0 +1,70 @@
+package router
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_SPAFallback(t *testing.T) {
+	index := filepath.Join(t.TempDir(), "index.html")
+	require.NoError(t, os.WriteFile(index, []byte("<div id=app></div>"), 0o644))
+
+	r := NewRouter()
+	called := false
+	require.NoError(t, r.GET("/api/items", func(w *response.Writer, req *request.Request) error {
+		called = true
+		return nil
+	}))
+	r.SPAFallback(index)
+
+	// Test: Client-side routes get index.html
+	for _, target := range []string{"/", "/dashboard", "/users/42/settings"} {
+		req := mkReq("GET", target)
+		out := runHandler(t, r.GetHandler(req), req)
+		assert.Contains(t, out, "HTTP/1.1 200 OK\r\n", target)
+		assert.Contains(t, out, "<div id=app></div>", target)
+	}
+
+	// Test: Registered routes still win
+	req := mkReq("GET", "/api/items")
+	_ = runHandler(t, r.GetHandler(req), req)
+	assert.True(t, called)
+
+	// Test: API paths, asset-like paths and other methods still 404
+	for _, tc := range []struct{ method, target string }{
+		{"GET", "/api/missing"},
+		{"GET", "/api"},
+		{"GET", "/missing.js"},
+		{"POST", "/dashboard"},
+	} {
+		req := mkReq(tc.method, tc.target)
+		out := runHandler(t, r.GetHandler(req), req)
+		assert.Contains(t, out, "404", tc.target)
+	}
+
+	// Test: Custom exclusions replace the default
+	r.SPAFallback(index, "/rpc")
+	req = mkReq("GET", "/api/missing")
+	assert.Contains(t, runHandler(t, r.GetHandler(req), req), "200 OK")
+	req = mkReq("GET", "/rpc/call")
+	assert.Contains(t, runHandler(t, r.GetHandler(req), req), "404")
+
+	// Test: HEAD gets the length of index.html, the Writer drops the body
+	req = mkReq("HEAD", "/dashboard")
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+	w.SetHEAD()
+	require.NoError(t, r.GetHandler(req)(w, req))
+	require.NoError(t, w.Finish())
+	assert.Contains(t, buf.String(), "HTTP/1.1 200 OK\r\n")
+	assert.Contains(t, buf.String(), "Content-Length: 18\r\n")
+	assert.True(t, strings.HasSuffix(buf.String(), "\r\n\r\n"))
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+const (
+	minAcceptBackoff = 5 * time.Millisecond
+	maxAcceptBackoff = time.Second
+)
+
+// Stats are counters a Server keeps from its creation, across listeners.
+type Stats struct {
+	Accepted     uint64 // connections accepted, including rejected ones
+	AcceptErrors uint64 // failed Accept calls, not counting Close
+}
+
+// Stats returns the server's accept counters, e.g. for metrics.TrackServer.
+func (s *Server) Stats() Stats {
+	return Stats{
+		Accepted:     s.accepted.Load(),
+		AcceptErrors: s.acceptErrors.Load(),
+	}
+}
+
+// temporaryAcceptError reports whether a failed Accept is worth retrying:
+// the process or system ran out of descriptors or buffers, a connection went
+// away before it was accepted, or a deadline passed. Anything else means the
+// listener itself is broken.
+func temporaryAcceptError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EMFILE, syscall.ENFILE, syscall.ENOBUFS, syscall.ENOMEM, syscall.ECONNABORTED, syscall.ECONNRESET} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// listen accepts connections from l until it is closed. Temporary errors,
+// such as running out of file descriptors, are retried after a backoff that
+// doubles from minAcceptBackoff up to maxAcceptBackoff rather than straight
+// away, which would spin. Other errors close l.
+func (s *Server) listen(l net.Listener) {
+	var backoff time.Duration
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.closed.Load() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.acceptErrors.Add(1)
+			if !temporaryAcceptError(err) {
+				s.logger.Error("error accepting connection, closing listener", "addr", l.Addr(), "error", err)
+				l.Close()
+				return
+			}
+
+			backoff = min(max(2*backoff, minAcceptBackoff), maxAcceptBackoff)
+			s.logger.Warn("error accepting connection, retrying", "error", err, "backoff", backoff)
+			t := time.NewTimer(backoff)
+			select {
+			case <-t.C:
+			case <-s.ctx.Done():
+				t.Stop()
+				return
+			}
+			continue
+		}
+		backoff = 0
+		s.accepted.Add(1)
+
+		ip := connIP(conn)
+		if !s.limiter.acquire(ip) {
+			s.logger.Warn("connection limit reached, rejecting", "remote_addr", remoteAddr(conn))
+			s.refuse(conn, "too many connections", 0)
+			continue
+		}
+
+		if s.pool != nil {
+			s.enqueue(conn, ip)
+			continue
+		}
+		go s.serveConn(conn, ip)
+	}
+}
+
+// serveReusePort listens on port with one SO_REUSEPORT socket per acceptor,
+// so the kernel spreads incoming connections across them instead of the
+// acceptors contending for a single socket.
+func serveReusePort(port uint16, handler response.Handler, router *router.Router, opts Options) (*Server, error) {
+	lc := net.ListenConfig{Control: setReusePort}
+	first, err := lc.Listen(context.Background(), "tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, err
+	}
+	// bind the rest to the port the first got, in case it was 0
+	addr := fmt.Sprintf(":%d", first.Addr().(*net.TCPAddr).Port)
+
+	listeners := []net.Listener{first}
+	for range opts.Acceptors - 1 {
+		l, err := lc.Listen(context.Background(), "tcp", addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+
+	s := New(handler, router, opts)
+	for _, l := range listeners {
+		s.addListener(l, nil, 1)
+	}
+	return s, nil
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingListener fails Accept with errs in turn, then blocks until closed.
+type failingListener struct {
+	mu     sync.Mutex
+	errs   []error
+	times  []time.Time
+	done   chan struct{}
+	closed sync.Once
+}
+
+func newFailingListener(errs ...error) *failingListener {
+	return &failingListener{errs: errs, done: make(chan struct{})}
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	l.times = append(l.times, time.Now())
+	if len(l.errs) > 0 {
+		err := l.errs[0]
+		l.errs = l.errs[1:]
+		l.mu.Unlock()
+		return nil, err
+	}
+	l.mu.Unlock()
+	<-l.done
+	return nil, net.ErrClosed
+}
+
+func (l *failingListener) Close() error {
+	l.closed.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *failingListener) Addr() net.Addr { return pipeAddr{} }
+
+func (l *failingListener) attempts() []time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]time.Time(nil), l.times...)
+}
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestTemporaryAcceptError(t *testing.T) {
+	assert.True(t, temporaryAcceptError(&net.OpError{Op: "accept", Err: os.NewSyscallError("accept", syscall.EMFILE)}))
+	assert.True(t, temporaryAcceptError(fmt.Errorf("wrapped: %w", syscall.ECONNABORTED)))
+	assert.True(t, temporaryAcceptError(os.ErrDeadlineExceeded))
+	assert.False(t, temporaryAcceptError(errors.New("listener broken")))
+	assert.False(t, temporaryAcceptError(syscall.EBADF))
+}
+
+func TestServer_AcceptBackoff(t *testing.T) {
+	// Test: Temporary errors are retried after a doubling backoff
+	emfile := &net.OpError{Op: "accept", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	l := newFailingListener(emfile, emfile, emfile)
+	s := New(helloHandler, nil, Options{Logger: quietLogger()})
+	s.AddListener(l, nil)
+	defer s.Close()
+
+	require.Eventually(t, func() bool { return len(l.attempts()) == 4 }, 2*time.Second, time.Millisecond)
+	times := l.attempts()
+	for i, want := range []time.Duration{minAcceptBackoff, 2 * minAcceptBackoff, 4 * minAcceptBackoff} {
+		assert.GreaterOrEqual(t, times[i+1].Sub(times[i]), want, i)
+	}
+	assert.Equal(t, Stats{AcceptErrors: 3}, s.Stats())
+
+	// Test: Close cuts a backoff short
+	l = newFailingListener(emfile, emfile, emfile, emfile, emfile, emfile, emfile, emfile)
+	s2 := New(helloHandler, nil, Options{Logger: quietLogger()})
+	s2.AddListener(l, nil)
+	require.Eventually(t, func() bool { return len(l.attempts()) >= 6 }, 2*time.Second, time.Millisecond)
+	require.NoError(t, s2.Close())
+	n := len(l.attempts())
+	time.Sleep(4 * maxAcceptBackoff / 10)
+	assert.Equal(t, n, len(l.attempts()))
+
+	// Test: Other errors close the listener
+	l = newFailingListener(errors.New("listener broken"))
+	s3 := New(helloHandler, nil, Options{Logger: quietLogger()})
+	s3.AddListener(l, nil)
+	defer s3.Close()
+	select {
+	case <-l.done:
+	case <-time.After(time.Second):
+		t.Fatal("listener not closed")
+	}
+	assert.Len(t, l.attempts(), 1)
+	assert.Equal(t, uint64(1), s3.Stats().AcceptErrors)
+}
+
+func TestServer_Acceptors(t *testing.T) {
+	// Test: Several acceptors share a listener
+	l := NewPipeListener()
+	s := New(helloHandler, nil, Options{Acceptors: 3})
+	s.AddListener(l, nil)
+	defer s.Close()
+	for range 5 {
+		conn, err := l.Dial()
+		require.NoError(t, err)
+		assert.Contains(t, roundTrip(t, conn), "hello")
+	}
+	assert.Equal(t, Stats{Accepted: 5}, s.Stats())
+
+	// Test: ServeWithOptions opens a socket per acceptor on one port
+	s, err := ServeWithOptions(0, helloHandler, nil, Options{Acceptors: 3})
+	require.NoError(t, err)
+	defer s.Close()
+	addrs := s.Addrs()
+	if reusePortSupported {
+		require.Len(t, addrs, 3)
+		for _, a := range addrs[1:] {
+			assert.Equal(t, addrs[0].(*net.TCPAddr).Port, a.(*net.TCPAddr).Port)
+		}
+	} else {
+		require.Len(t, addrs, 1)
+	}
+	port := addrs[0].(*net.TCPAddr).Port
+	for range 10 {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		require.NoError(t, err)
+		assert.Contains(t, roundTrip(t, conn), "hello")
+	}
+	assert.Equal(t, uint64(10), s.Stats().Accepted)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_ExpectContinue(t *testing.T) {
+	r := router.NewRouter()
+	require.NoError(t, r.POST("/upload", func(w *response.Writer, req *request.Request) error {
+		body := []byte(strconv.Itoa(len(req.Body)))
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+	}))
+
+	s, err := ServeWithOptions(0, nil, r, Options{
+		ExpectContinue: func(req *request.Request) error {
+			if cl, _ := req.Headers.Get("Content-Length"); cl != "5" {
+				return response.NewHTTPError(response.StatusContentTooLarge, "too large")
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+	addr := s.Addr().String()
+
+	send := func(target string, length int) (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: x\r\nConnection: close\r\nExpect: 100-continue\r\nContent-Length: %d\r\n\r\n", target, length)
+		return conn, bufio.NewReader(conn)
+	}
+
+	// Test: Routed and accepted requests get 100 Continue, then the body is read
+	conn, br := send("/upload", 5)
+	defer conn.Close()
+	line, err := br.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 100 Continue\r\n", line)
+	_, err = br.ReadString('\n')
+	require.NoError(t, err)
+	fmt.Fprint(conn, "hello")
+	out, err := io.ReadAll(br)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(out), "HTTP/1.1 200 OK\r\n"))
+	assert.True(t, strings.HasSuffix(string(out), "\r\n\r\n5"))
+
+	// Test: Unrouted requests get their 404 without 100 Continue
+	conn, br = send("/nope", 5)
+	defer conn.Close()
+	out, err = io.ReadAll(br)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(out), "HTTP/1.1 404 Not Found\r\n"))
+
+	// Test: The hook can refuse with its own status
+	conn, br = send("/upload", 1<<20)
+	defer conn.Close()
+	out, err = io.ReadAll(br)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(out), "HTTP/1.1 413 Content Too Large\r\n"))
+	assert.NotContains(t, string(out), "100 Continue")
+}
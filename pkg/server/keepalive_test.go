@@ -0,0 +1,172 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readResponse reads one response with a Content-Length body from br.
+func readResponse(t *testing.T, br *bufio.Reader) (string, string) {
+	t.Helper()
+	var head strings.Builder
+	length := 0
+	for {
+		line, err := br.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\r\n" {
+			break
+		}
+		head.WriteString(line)
+		if v, ok := strings.CutPrefix(line, "Content-Length: "); ok {
+			length, err = strconv.Atoi(strings.TrimSpace(v))
+			require.NoError(t, err)
+		}
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(br, body)
+	require.NoError(t, err)
+	return head.String(), string(body)
+}
+
+// assertClosed checks that the server closes conn without sending more.
+func assertClosed(t *testing.T, br *bufio.Reader) {
+	t.Helper()
+	rest, err := io.ReadAll(br)
+	require.NoError(t, err)
+	assert.Empty(t, string(rest))
+}
+
+func TestServer_KeepAlive(t *testing.T) {
+	var mu sync.Mutex
+	var seen []*headers.Headers
+	echo := func(w *response.Writer, req *request.Request) error {
+		mu.Lock()
+		seen = append(seen, req.Headers)
+		mu.Unlock()
+		body := []byte(req.RequestLine.RequestTarget + ":" + string(req.Body))
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := ServeListener(l, echo, nil, Options{IdleTimeout: 200 * time.Millisecond})
+	defer s.Close()
+	dial := func() (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		return conn, bufio.NewReader(conn)
+	}
+
+	// Test: Two requests on one connection, parsed into the same Request
+	conn, br := dial()
+	fmt.Fprint(conn, "POST /a HTTP/1.1\r\nHost: x\r\nContent-Length: 3\r\n\r\none")
+	head, body := readResponse(t, br)
+	assert.NotContains(t, head, "Connection:")
+	assert.Equal(t, "/a:one", body)
+	fmt.Fprint(conn, "GET /b HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n")
+	head, body = readResponse(t, br)
+	assert.Contains(t, head, "Connection: close\r\n")
+	assert.Equal(t, "/b:", body)
+	assertClosed(t, br)
+	mu.Lock()
+	require.Len(t, seen, 2)
+	assert.Same(t, seen[0], seen[1])
+	mu.Unlock()
+
+	// Test: Pipelined requests are answered in order
+	conn, br = dial()
+	fmt.Fprint(conn, "GET /1 HTTP/1.1\r\nHost: x\r\n\r\nPOST /2 HTTP/1.1\r\nHost: x\r\nContent-Length: 2\r\n\r\nhiGET /3 HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n")
+	for _, want := range []string{"/1:", "/2:hi", "/3:"} {
+		_, body = readResponse(t, br)
+		assert.Equal(t, want, body)
+	}
+	assertClosed(t, br)
+
+	// Test: HTTP/1.0 clients opt in with Connection: keep-alive
+	conn, br = dial()
+	fmt.Fprint(conn, "GET /old HTTP/1.0\r\nConnection: keep-alive\r\n\r\n")
+	head, body = readResponse(t, br)
+	assert.Contains(t, head, "Connection: keep-alive\r\n")
+	assert.Equal(t, "/old:", body)
+	fmt.Fprint(conn, "GET /old2 HTTP/1.0\r\n\r\n")
+	head, body = readResponse(t, br)
+	assert.Contains(t, head, "Connection: close\r\n")
+	assert.Equal(t, "/old2:", body)
+	assertClosed(t, br)
+
+	// Test: Idle connections are closed after IdleTimeout
+	conn, br = dial()
+	fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	readResponse(t, br)
+	start := time.Now()
+	assertClosed(t, br)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	// Test: A request that starts arriving isn't cut off by the idle timeout
+	conn, br = dial()
+	fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	readResponse(t, br)
+	fmt.Fprint(conn, "POST /slow HTTP/1.1\r\nHost: x\r\nContent-Length: 4\r\n\r\nab")
+	time.Sleep(300 * time.Millisecond)
+	fmt.Fprint(conn, "cd")
+	_, body = readResponse(t, br)
+	assert.Equal(t, "/slow:abcd", body)
+
+	// Test: Closing the server closes idle connections
+	conn, br = dial()
+	fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	readResponse(t, br)
+	require.NoError(t, s.Close())
+	assertClosed(t, br)
+}
+
+func TestServer_KeepAliveClosing(t *testing.T) {
+	unframed := func(w *response.Writer, req *request.Request) error {
+		if req.RequestLine.RequestTarget == "/stream" {
+			h := headers.NewHeaders()
+			h.Set("Content-Type", "text/plain")
+			if err := w.WriteStatusLine(response.StatusOK); err != nil {
+				return err
+			}
+			if err := w.WriteHeaders(h); err != nil {
+				return err
+			}
+			return w.WriteBody([]byte("until close"))
+		}
+		return helloHandler(w, req)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		opts   Options
+		target string
+	}{
+		{"body without length", Options{}, "/stream"},
+		{"keep-alive disabled", Options{DisableKeepAlives: true}, "/"},
+	} {
+		l := NewPipeListener()
+		s := ServeListener(l, unframed, nil, tc.opts)
+		conn, err := l.Dial()
+		require.NoError(t, err)
+		fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: x\r\n\r\n", tc.target)
+		out, err := io.ReadAll(conn)
+		require.NoError(t, err, tc.name)
+		assert.Contains(t, string(out), "Connection: close\r\n", tc.name)
+		conn.Close()
+		s.Close()
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+)
+
+// rejectTimeout bounds how long writing the 503 to a rejected connection may
+// take, so slow clients can't hold on to the slot we refused them.
+const rejectTimeout = 2 * time.Second
+
+// connLimiter tracks open connections in total and per client IP. A zero
+// limit means unlimited.
+type connLimiter struct {
+	mu       sync.Mutex
+	max      int
+	maxPerIP int
+	total    int
+	perIP    map[string]int
+}
+
+func newConnLimiter(max int, maxPerIP int) *connLimiter {
+	return &connLimiter{
+		max:      max,
+		maxPerIP: maxPerIP,
+		perIP:    map[string]int{},
+	}
+}
+
+// acquire reserves a slot for a connection from ip, reporting false when
+// either limit has been reached.
+func (l *connLimiter) acquire(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max > 0 && l.total >= l.max {
+		return false
+	}
+	if l.maxPerIP > 0 && l.perIP[ip] >= l.maxPerIP {
+		return false
+	}
+
+	l.total++
+	if l.maxPerIP > 0 {
+		l.perIP[ip]++
+	}
+	return true
+}
+
+func (l *connLimiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total--
+	if l.maxPerIP > 0 {
+		if l.perIP[ip] <= 1 {
+			delete(l.perIP, ip)
+		} else {
+			l.perIP[ip]--
+		}
+	}
+}
+
+// connIP returns the host part of the connection's remote address.
+func connIP(conn io.ReadWriteCloser) string {
+	addr := remoteAddr(conn)
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// maxRejecting caps the 503s written at once. Each takes a goroutine for up
+// to rejectTimeout, so under a flood of connections past the limits the rest
+// are closed without one.
+const maxRejecting = 64
+
+// refuse rejects conn on its own goroutine, or closes it right away when
+// maxRejecting connections are already being rejected.
+func (s *Server) refuse(conn io.ReadWriteCloser, msg string, retryAfter time.Duration) {
+	select {
+	case s.rejecting <- struct{}{}:
+		go func() {
+			defer func() { <-s.rejecting }()
+			reject(conn, msg, retryAfter)
+		}()
+	default:
+		conn.Close()
+	}
+}
+
+// rejectDrainLimit caps how much of the refused request is read and thrown
+// away before closing.
+const rejectDrainLimit = 64 * 1024
+
+// reject answers a connection the server has no room for with 503 and closes
+// it. A non-zero retryAfter is sent as Retry-After, rounded up to whole
+// seconds. The request itself is never parsed, but whatever the client
+// already sent is drained first: closing with unread data makes the kernel
+// send a reset, which would discard the 503 before the client reads it.
+func reject(conn io.ReadWriteCloser, msg string, retryAfter time.Duration) {
+	defer conn.Close()
+	netConn, isNetConn := conn.(net.Conn)
+	if isNetConn {
+		_ = netConn.SetDeadline(time.Now().Add(rejectTimeout))
+	}
+
+	body := []byte(msg)
+	h := response.GetDefaultHeaders(len(body))
+	h.Replace("Content-Type", "text/plain")
+	h.Replace("Connection", "close")
+	if retryAfter > 0 {
+		secs := (retryAfter + time.Second - 1) / time.Second
+		h.Set("Retry-After", strconv.Itoa(int(secs)))
+	}
+	w := response.NewWriter(conn)
+	if err := w.WriteResponse(response.StatusServiceUnavailable, h, body); err != nil {
+		return
+	}
+
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+	}
+	if isNetConn {
+		_, _ = io.Copy(io.Discard, io.LimitReader(conn, rejectDrainLimit))
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnLimiter(t *testing.T) {
+	l := newConnLimiter(3, 2)
+
+	// Test: Per-IP cap
+	assert.True(t, l.acquire("10.0.0.1"))
+	assert.True(t, l.acquire("10.0.0.1"))
+	assert.False(t, l.acquire("10.0.0.1"))
+
+	// Test: Total cap
+	assert.True(t, l.acquire("10.0.0.2"))
+	assert.False(t, l.acquire("10.0.0.3"))
+
+	// Test: Releasing frees both slots
+	l.release("10.0.0.1")
+	assert.True(t, l.acquire("10.0.0.1"))
+	l.release("10.0.0.2")
+	l.release("10.0.0.1")
+	l.release("10.0.0.1")
+	assert.Empty(t, l.perIP)
+	assert.Equal(t, 0, l.total)
+
+	// Test: Zero means unlimited
+	l = newConnLimiter(0, 0)
+	for range 100 {
+		assert.True(t, l.acquire("10.0.0.1"))
+	}
+}
+
+func TestServer_MaxConnections(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := func(w *response.Writer, req *request.Request) error {
+		close(started)
+		<-release
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(0), []byte{})
+	}
+
+	s, err := ServeWithOptions(0, handler, nil, Options{MaxConnections: 1})
+	require.NoError(t, err)
+	defer s.Close()
+	addr := s.Addr().String()
+
+	first, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer first.Close()
+	fmt.Fprint(first, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	<-started
+
+	// Test: The second connection is refused with a 503 even though its
+	// request was never read
+	second, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer second.Close()
+	fmt.Fprint(second, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	out, err := io.ReadAll(second)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(out), "HTTP/1.1 503 Service Unavailable\r\n"))
+	assert.True(t, strings.HasSuffix(string(out), "too many connections"))
+
+	// Test: The first one is still served
+	close(release)
+	line, err := bufio.NewReader(first).ReadString('\n')
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "HTTP/1.1 200"))
+}
+
+func TestServer_RefuseCap(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	s := &Server{rejecting: make(chan struct{}, 1)}
+	refuse := func() string {
+		client, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		defer client.Close()
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		s.refuse(conn, "busy", 0)
+		require.NoError(t, client.(*net.TCPConn).CloseWrite())
+		client.SetDeadline(time.Now().Add(5 * time.Second))
+		out, err := io.ReadAll(client)
+		require.NoError(t, err)
+		return string(out)
+	}
+
+	// Test: Under the cap the 503 is written
+	assert.True(t, strings.HasPrefix(refuse(), "HTTP/1.1 503 Service Unavailable\r\n"))
+
+	// Test: At the cap the connection is closed without one
+	assert.Eventually(t, func() bool { return len(s.rejecting) == 0 }, time.Second, time.Millisecond)
+	s.rejecting <- struct{}{}
+	assert.Empty(t, refuse())
+	<-s.rejecting
+	assert.True(t, strings.HasPrefix(refuse(), "HTTP/1.1 503 Service Unavailable\r\n"))
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func helloHandler(w *response.Writer, req *request.Request) error {
+	body := []byte("hello")
+	return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+}
+
+func roundTrip(t *testing.T, conn net.Conn) string {
+	t.Helper()
+	defer conn.Close()
+	fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n")
+	out, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestServeUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "http.sock")
+
+	// Test: A stale socket is replaced
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	s, err := ServeUnix(path, 0o660, helloHandler, nil, Options{})
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0o660), info.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	out := roundTrip(t, conn)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.True(t, strings.HasSuffix(out, "hello"))
+
+	// Test: The socket file is removed on Close
+	require.NoError(t, s.Close())
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestListenUnix_NotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte("keep me"), 0o600))
+
+	_, err := ListenUnix(path, 0o600)
+	assert.ErrorIs(t, err, ErrNotSocket)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "keep me", string(data))
+}
+
+func TestServeListener_Pipe(t *testing.T) {
+	l := NewPipeListener()
+	s := ServeListener(l, helloHandler, nil, Options{})
+
+	conn, err := l.Dial()
+	require.NoError(t, err)
+	out := roundTrip(t, conn)
+	assert.True(t, strings.HasSuffix(out, "hello"))
+
+	require.NoError(t, s.Close())
+	_, err = l.Dial()
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
+
+func selfSignedConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+}
+
+func TestServer_MultipleListeners(t *testing.T) {
+	s := New(helloHandler, nil, Options{})
+
+	plain, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	secure, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	pipe := NewPipeListener()
+	s.AddListener(plain, nil)
+	s.AddListener(secure, selfSignedConfig(t))
+	s.AddListener(pipe, nil)
+	assert.Len(t, s.Addrs(), 3)
+	assert.Equal(t, plain.Addr(), s.Addr())
+
+	// Test: Each listener serves the same handler
+	conn, err := net.Dial("tcp", plain.Addr().String())
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(roundTrip(t, conn), "hello"))
+
+	tlsConn, err := tls.Dial("tcp", secure.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(roundTrip(t, tlsConn), "hello"))
+
+	conn, err = pipe.Dial()
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(roundTrip(t, conn), "hello"))
+
+	// Test: Close shuts every listener down
+	require.NoError(t, s.Close())
+	_, err = net.Dial("tcp", plain.Addr().String())
+	assert.Error(t, err)
+	_, err = pipe.Dial()
+	assert.ErrorIs(t, err, net.ErrClosed)
+
+	// Test: Listeners added after Close are closed
+	late := NewPipeListener()
+	s.AddListener(late, nil)
+	_, err = late.Dial()
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
+
+func TestServer_ConnInfo(t *testing.T) {
+	seen := make(chan *request.Request, 1)
+	handler := func(w *response.Writer, req *request.Request) error {
+		seen <- req
+		return helloHandler(w, req)
+	}
+	s := New(handler, nil, Options{})
+	defer s.Close()
+
+	plain, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	secure, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s.AddListener(plain, nil)
+	s.AddListener(secure, selfSignedConfig(t))
+
+	// Test: Plain connections carry both addresses and no TLS state
+	conn, err := net.Dial("tcp", plain.Addr().String())
+	require.NoError(t, err)
+	local := conn.LocalAddr().String()
+	roundTrip(t, conn)
+	req := <-seen
+	assert.Equal(t, local, req.RemoteAddr)
+	assert.Equal(t, plain.Addr().String(), req.LocalAddr)
+	assert.Nil(t, req.TLS)
+
+	// Test: TLS connections expose the handshake state
+	tlsConn, err := tls.Dial("tcp", secure.Addr().String(), &tls.Config{InsecureSkipVerify: true, ServerName: "localhost"})
+	require.NoError(t, err)
+	roundTrip(t, tlsConn)
+	req = <-seen
+	require.NotNil(t, req.TLS)
+	assert.True(t, req.TLS.HandshakeComplete)
+	assert.Equal(t, "localhost", req.TLS.ServerName)
+}
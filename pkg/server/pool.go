@@ -0,0 +1,105 @@
+package server
+
+import (
+	"io"
+	"time"
+)
+
+// QueuePolicy decides what the accept loop does when every worker is busy and
+// the queue is full.
+type QueuePolicy uint
+
+const (
+	// QueueBlock stops accepting until a queue slot frees up, leaving new
+	// connections in the kernel's listen backlog.
+	QueueBlock QueuePolicy = iota
+	// QueueReject answers the connection with 503 and a Retry-After header.
+	QueueReject
+)
+
+// DefaultRetryAfter is sent with QueueReject when Options.RetryAfter is zero.
+const DefaultRetryAfter = time.Second
+
+type job struct {
+	conn io.ReadWriteCloser
+	ip   string
+}
+
+// workerPool runs connections on a fixed set of goroutines fed by a bounded
+// queue.
+type workerPool struct {
+	jobs       chan job
+	policy     QueuePolicy
+	retryAfter time.Duration
+}
+
+func newWorkerPool(queueSize int, policy QueuePolicy, retryAfter time.Duration) *workerPool {
+	if retryAfter == 0 {
+		retryAfter = DefaultRetryAfter
+	}
+
+	return &workerPool{
+		jobs:       make(chan job, max(queueSize, 0)),
+		policy:     policy,
+		retryAfter: retryAfter,
+	}
+}
+
+// startWorkers launches n workers that handle queued connections until the server
+// is closed, then close whatever is still waiting.
+func (s *Server) startWorkers(n int) {
+	for range n {
+		go func() {
+			for {
+				select {
+				case j := <-s.pool.jobs:
+					s.serveConn(j.conn, j.ip)
+				case <-s.ctx.Done():
+					s.drainQueue()
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (s *Server) drainQueue() {
+	for {
+		select {
+		case j := <-s.pool.jobs:
+			j.conn.Close()
+			s.limiter.release(j.ip)
+		default:
+			return
+		}
+	}
+}
+
+// enqueue hands conn to the pool according to its queue policy.
+func (s *Server) enqueue(conn io.ReadWriteCloser, ip string) {
+	j := job{conn: conn, ip: ip}
+	if s.pool.policy == QueueReject {
+		select {
+		case s.pool.jobs <- j:
+		default:
+			s.logger.Warn("worker queue full, rejecting", "remote_addr", remoteAddr(conn))
+			s.limiter.release(ip)
+			s.refuse(conn, "server busy", s.pool.retryAfter)
+			return
+		}
+	} else {
+		select {
+		case s.pool.jobs <- j:
+		case <-s.ctx.Done():
+			conn.Close()
+			s.limiter.release(ip)
+			return
+		}
+	}
+
+	// the workers may have drained the queue and exited just before the
+	// send, leaving nobody to pick the job up
+	if s.ctx.Err() != nil {
+		s.drainQueue()
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) response.Handler {
+	return func(w *response.Writer, req *request.Request) error {
+		started <- struct{}{}
+		<-release
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(0), []byte{})
+	}
+}
+
+func sendRequest(t *testing.T, addr string) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n")
+	return conn
+}
+
+func TestServer_WorkerPoolReject(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	s, err := ServeWithOptions(0, blockingHandler(started, release), nil, Options{
+		Workers:     1,
+		QueuePolicy: QueueReject,
+		RetryAfter:  1500 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer s.Close()
+	addr := s.Addr().String()
+
+	first := sendRequest(t, addr)
+	defer first.Close()
+	<-started
+
+	// Test: With the only worker busy and no queue the next one is refused
+	second := sendRequest(t, addr)
+	defer second.Close()
+	out, err := io.ReadAll(second)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(out), "HTTP/1.1 503 Service Unavailable\r\n"))
+	assert.Contains(t, string(out), "Retry-After: 2\r\n")
+
+	close(release)
+	line, err := bufio.NewReader(first).ReadString('\n')
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "HTTP/1.1 200"))
+}
+
+func TestServer_WorkerPoolQueue(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	s, err := ServeWithOptions(0, blockingHandler(started, release), nil, Options{
+		Workers:   1,
+		QueueSize: 1,
+	})
+	require.NoError(t, err)
+	defer s.Close()
+	addr := s.Addr().String()
+
+	first := sendRequest(t, addr)
+	defer first.Close()
+	<-started
+
+	// Test: The queued connection waits for the worker instead of failing
+	second := sendRequest(t, addr)
+	defer second.Close()
+	select {
+	case <-started:
+		t.Fatal("queued connection ran before a worker was free")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-started
+	for _, conn := range []net.Conn{first, second} {
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(line, "HTTP/1.1 200"))
+	}
+}
+
+func TestServer_EnqueueAfterShutdown(t *testing.T) {
+	s, err := ServeWithOptions(0, nil, nil, Options{Workers: 1, QueueSize: 1})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	time.Sleep(10 * time.Millisecond) // let the worker drain and exit
+
+	client, srv := net.Pipe()
+	defer client.Close()
+	require.True(t, s.limiter.acquire("pipe"))
+	s.enqueue(srv, "pipe")
+
+	// Test: The late connection is closed and its slot released
+	_, err = client.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 0, s.limiter.total)
+}
@@ -0,0 +1,24 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+
+package server
+
+import "syscall"
+
+const reusePortSupported = true
+
+// soReusePort is SO_REUSEPORT, which the syscall package doesn't define for
+// Linux; it is 15 on every architecture but MIPS.
+const soReusePort = 0xf
+
+// setReusePort is a net.ListenConfig Control function setting SO_REUSEPORT,
+// which lets several sockets listen on one port with the kernel balancing
+// connections across them.
+func setReusePort(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,13 @@
+//go:build !linux || mips || mipsle || mips64 || mips64le
+
+package server
+
+import "syscall"
+
+// Elsewhere SO_REUSEPORT is missing or doesn't balance connections, so
+// acceptors share one socket instead.
+const reusePortSupported = false
+
+func setReusePort(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,624 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+// Options configures a Server. The zero value is valid.
+type Options struct {
+	// Logger receives accept errors, request parse failures, handler errors
+	// and recovered panics. Defaults to slog.Default().
+	Logger *slog.Logger
+	// MaxConnections caps the number of connections handled at once and
+	// MaxConnectionsPerIP the number from a single client IP. Connections
+	// over either limit get a 503 and are closed, or are closed without one
+	// while dozens of others are still being answered. Zero means unlimited.
+	// Hijacked connections stop counting once their handler returns.
+	MaxConnections      int
+	MaxConnectionsPerIP int
+	// Workers, when positive, handles connections on that many goroutines
+	// instead of one per connection. Up to QueueSize accepted connections
+	// wait for a free worker; beyond that QueuePolicy applies, with
+	// RetryAfter (default DefaultRetryAfter) sent for QueueReject.
+	Workers     int
+	QueueSize   int
+	QueuePolicy QueuePolicy
+	RetryAfter  time.Duration
+	// ExpectContinue decides whether a routed request carrying
+	// "Expect: 100-continue" may send its body; it only sees the headers.
+	// Returning an error (typically a *response.HTTPError with 413 or 417)
+	// answers the request with it instead of 100 Continue, without reading
+	// the body. Requests the router has no handler for are answered (404,
+	// 405, redirect) without 100 Continue before this is consulted.
+	ExpectContinue func(req *request.Request) error
+	// TrustedProxies are the proxies whose forwarding headers
+	// request.ClientIP honors. Nil trusts none.
+	TrustedProxies *request.TrustedProxies
+	// Limits bounds the request line, headers and body of each request; zero
+	// fields use the request package defaults. Requests over a header limit
+	// get a 431, an overlong request line a 414 and an oversized chunk or
+	// body a 413. middleware.BodyLimit sets tighter body limits per route.
+	Limits request.Limits
+	// DefaultHeaders are added to every response that doesn't set them,
+	// e.g. Server or security headers; see response.Writer.SetDefaultHeaders.
+	// They are copied when the server is created.
+	DefaultHeaders *headers.Headers
+	// DisableKeepAlives closes every connection after one response, as if
+	// each request had sent "Connection: close".
+	DisableKeepAlives bool
+	// IdleTimeout is how long a kept-alive connection waits for the next
+	// request to start arriving, defaulting to DefaultIdleTimeout. Idle
+	// connections hold their worker and their MaxConnections slot.
+	IdleTimeout time.Duration
+	// Acceptors is the number of goroutines accepting connections from each
+	// listener, for servers accepting faster than one can keep up with.
+	// ServeWithOptions instead opens one SO_REUSEPORT socket per acceptor
+	// where the kernel balances connections across them (Linux). Defaults
+	// to 1.
+	Acceptors int
+}
+
+// DefaultIdleTimeout is used when Options.IdleTimeout is zero.
+const DefaultIdleTimeout = 60 * time.Second
+
+var (
+	// errSkipBody stops parsing before the body of a request that won't be
+	// sent 100 Continue.
+	errSkipBody = errors.New("request body not wanted")
+	// errNoRoute refuses 100 Continue to requests that will be dispatched
+	// without reaching a route handler.
+	errNoRoute = errors.New("no route for request")
+)
+
+type Server struct {
+	closed       atomic.Bool
+	accepted     atomic.Uint64
+	acceptErrors atomic.Uint64
+	mu           sync.Mutex
+	listeners    []net.Listener
+	handler      response.Handler
+	router       *router.Router
+	logger       *slog.Logger
+	limiter      *connLimiter
+	rejecting    chan struct{} // one token per 503 being written
+	pool         *workerPool
+	expect       func(req *request.Request) error
+	proxies      *request.TrustedProxies
+	limits       request.Limits
+	defaults     *headers.Headers
+	acceptors    int
+	keepAlive    bool
+	idleTimeout  time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+// Close stops accepting on every listener and cancels in-flight requests.
+func (s *Server) Close() error {
+	s.closed.Store(true)
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, l := range s.listeners {
+		if err := l.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// maxEarlyData bounds the bytes kept from the client while its request is
+// being handled, in case the handler hijacks the connection or for the next
+// request on a kept-alive connection.
+const maxEarlyData = 64 * 1024
+
+// watchDisconnect keeps reading from the connection after the request has been
+// parsed and cancels the request context once the peer goes away. Bytes read
+// meanwhile are kept in early for a handler that hijacks the connection, or
+// for the next request; once maxEarlyData are kept it stops reading, and so
+// watching. When stop is set the read error is expected and the context is
+// left alone; done is closed on return, after which early is safe to read.
+func watchDisconnect(conn io.Reader, cancel context.CancelFunc, stop *atomic.Bool, early *bytes.Buffer, done chan<- struct{}) {
+	defer close(done)
+
+	buf := make([]byte, 512)
+	for {
+		room := maxEarlyData - early.Len()
+		if room <= 0 {
+			return
+		}
+		n, err := conn.Read(buf[:min(len(buf), room)])
+		early.Write(buf[:n])
+		if err != nil {
+			if !stop.Load() {
+				cancel()
+			}
+			return
+		}
+	}
+}
+
+// stopWatching stops watchDisconnect by expiring its pending read.
+func stopWatching(netConn net.Conn, stop *atomic.Bool, watchDone <-chan struct{}) error {
+	stop.Store(true)
+	if err := netConn.SetReadDeadline(time.Now()); err != nil {
+		return err
+	}
+	<-watchDone
+	return netConn.SetReadDeadline(time.Time{})
+}
+
+// hijacker returns the HijackFunc for conn. It stops the disconnect watcher
+// before handing the connection over, with the bytes read past the request
+// (buffered by the parser, then early by the watcher) replayed ahead of the
+// connection on the returned reader.
+func hijacker(conn io.ReadWriteCloser, buffered []byte, early *bytes.Buffer, stop *atomic.Bool, watchDone <-chan struct{}) response.HijackFunc {
+	return func() (net.Conn, *bufio.ReadWriter, error) {
+		netConn, ok := conn.(net.Conn)
+		if !ok {
+			return nil, nil, response.ErrNotHijackable
+		}
+		if err := stopWatching(netConn, stop, watchDone); err != nil {
+			return nil, nil, err
+		}
+
+		r := io.MultiReader(bytes.NewReader(buffered), early, netConn)
+		rw := bufio.NewReadWriter(bufio.NewReader(r), bufio.NewWriter(netConn))
+		return netConn, rw, nil
+	}
+}
+
+// connReader reads a connection for the request parser, starting with the
+// bytes the disconnect watcher read ahead while the last request was being
+// handled. arrived, when set, runs once the next request starts arriving.
+type connReader struct {
+	conn    io.Reader
+	early   bytes.Buffer
+	arrived func()
+}
+
+func (cr *connReader) Read(p []byte) (int, error) {
+	if cr.early.Len() > 0 {
+		n, _ := cr.early.Read(p)
+		cr.started()
+		return n, nil
+	}
+	n, err := cr.conn.Read(p)
+	if n > 0 {
+		cr.started()
+	}
+	return n, err
+}
+
+func (cr *connReader) started() {
+	if f := cr.arrived; f != nil {
+		cr.arrived = nil
+		f()
+	}
+}
+
+// remoteAddr returns the peer address of conn, or "" when it isn't a net.Conn.
+func remoteAddr(conn io.ReadWriteCloser) string {
+	if netConn, ok := conn.(net.Conn); ok && netConn.RemoteAddr() != nil {
+		return netConn.RemoteAddr().String()
+	}
+	return ""
+}
+
+// attachConnInfo records the connection's addresses and TLS state on r.
+func attachConnInfo(r *request.Request, conn io.ReadWriteCloser) {
+	r.RemoteAddr = remoteAddr(conn)
+	if netConn, ok := conn.(net.Conn); ok && netConn.LocalAddr() != nil {
+		r.LocalAddr = netConn.LocalAddr().String()
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		r.TLS = &state
+	}
+}
+
+// recoverHandler takes the logger by reference so a panic is reported with
+// whatever request fields were attached by the time it happened.
+func recoverHandler(w *response.Writer, logger **slog.Logger) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+
+	(*logger).Error("panic in handler", "panic", rec, "stack", string(debug.Stack()))
+	if w.Written() || w.Hijacked() {
+		return // too late for an error response, the connection is closed
+	}
+
+	body := []byte("")
+	h := response.GetDefaultHeaders(len(body))
+	_ = w.WriteResponse(response.StatusInternalServerError, h, body)
+}
+
+// parseErrorStatus maps a request parse error to the status it is answered
+// with.
+func parseErrorStatus(err error) response.StatusCode {
+	switch {
+	case errors.Is(err, request.ErrExpectationFailed):
+		return response.StatusExpectationFailed
+	case errors.Is(err, headers.ErrHeaderTooLarge):
+		return response.StatusRequestHeaderFieldsTooLarge
+	case errors.Is(err, request.ErrRequestLineTooLong):
+		return response.StatusURITooLong
+	case errors.Is(err, request.ErrTooManyChunks),
+		errors.Is(err, request.ErrChunkTooLarge),
+		errors.Is(err, request.ErrBodyTooLarge):
+		return response.StatusContentTooLarge
+	case errors.Is(err, request.ErrUnsupportedTransferEncoding):
+		return response.StatusNotImplemented
+	case errors.Is(err, request.ErrUnsupportedVersion):
+		return response.StatusHttpVersionNotSupported
+	case errors.Is(err, request.ErrMalformedRequestLine),
+		errors.Is(err, headers.ErrMalformedFieldLine),
+		errors.Is(err, headers.ErrMalformedHeader),
+		errors.Is(err, headers.ErrMalformedHeaderName),
+		errors.Is(err, headers.ErrMalformedHeaderValue),
+		errors.Is(err, request.ErrMalformedChunkedBody),
+		errors.Is(err, request.ErrMissingHost),
+		errors.Is(err, request.ErrInvalidHost),
+		errors.Is(err, request.ErrInvalidContentLength),
+		errors.Is(err, request.ErrConflictingFraming),
+		errors.Is(err, request.ErrInvalidTrailer),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return response.StatusBadRequest
+	}
+	return response.StatusInternalServerError
+}
+
+// writeParseError answers a request that failed to parse. The connection is
+// closed afterwards, since where the next request would start is unknown.
+func writeParseError(w *response.Writer, err error) error {
+	body := []byte(err.Error())
+	h := response.GetDefaultHeaders(len(body))
+	h.Replace("Content-Type", "text/plain; charset=utf-8")
+	h.SetTime("Date", time.Now())
+	return w.WriteResponse(parseErrorStatus(err), h, body)
+}
+
+// clientGone reports whether a parse error means the client closed or reset
+// the connection rather than sent something malformed.
+func clientGone(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, net.ErrClosed)
+}
+
+const (
+	lingerTimeout  = 500 * time.Millisecond
+	lingerMaxBytes = 256 << 10
+)
+
+// lingerClose half-closes conn after an error response and discards what the
+// client is still sending for a moment. Closing with unread request bytes
+// would make the kernel reset the connection, and the client could lose the
+// response before reading it.
+func lingerClose(conn io.ReadWriteCloser) {
+	cw, ok := conn.(interface{ CloseWrite() error })
+	if !ok {
+		return
+	}
+	d, ok := conn.(interface{ SetReadDeadline(time.Time) error })
+	if !ok {
+		return
+	}
+
+	if cw.CloseWrite() != nil {
+		return
+	}
+	_ = d.SetReadDeadline(time.Now().Add(lingerTimeout))
+	_, _ = io.CopyN(io.Discard, conn, lingerMaxBytes)
+}
+
+// connState is what becomes of a connection after a request on it.
+type connState int
+
+const (
+	connClose    connState = iota
+	connLinger             // close after an error response, see lingerClose
+	connKeep               // wait for the next request
+	connHijacked           // the handler took the connection over
+)
+
+// handle serves the requests on conn one after another while the client
+// keeps the connection alive, parsing each into the same Request, then
+// closes it unless a handler hijacked it.
+func (s *Server) handle(conn io.ReadWriteCloser) {
+	cr := &connReader{conn: conn}
+	rd := request.NewReader(cr, s.limits)
+
+	state := s.serveRequest(conn, rd, cr, true)
+	for state == connKeep && !s.closed.Load() {
+		state = s.serveRequest(conn, rd, cr, false)
+	}
+	if state == connHijacked {
+		return // the request's buffered bytes went along
+	}
+
+	rd.Release()
+	if state == connLinger {
+		lingerClose(conn)
+	}
+	conn.Close()
+}
+
+// serveRequest reads one request from rd and answers it. Connections other
+// than the first wait at most idleTimeout for the request to start arriving.
+func (s *Server) serveRequest(conn io.ReadWriteCloser, rd *request.Reader, cr *connReader, first bool) (state connState) {
+	netConn, _ := conn.(net.Conn)
+	responseWriter := response.NewBufferedWriter(conn)
+	responseWriter.SetDefaultHeaders(s.defaults)
+	defer func() {
+		if responseWriter.Hijacked() {
+			state = connHijacked
+			return
+		}
+		_ = responseWriter.Flush()
+		responseWriter.Release()
+	}()
+	logger := s.logger.With("remote_addr", remoteAddr(conn))
+	defer recoverHandler(responseWriter, &logger)
+
+	if !first {
+		_ = netConn.SetReadDeadline(time.Now().Add(s.idleTimeout))
+		stop := context.AfterFunc(s.ctx, func() { _ = netConn.SetReadDeadline(time.Now()) })
+		cr.arrived = func() {
+			stop()
+			_ = netConn.SetReadDeadline(time.Time{})
+		}
+	}
+
+	var pending *request.Request
+	var refused error
+	r, err := rd.Next(func(pr *request.Request) error {
+		if refused = s.checkContinue(pr); refused != nil {
+			pending = pr
+			return errSkipBody
+		}
+		return responseWriter.Write100Continue()
+	})
+	cr.started() // a pipelined request needn't have been read
+	if errors.Is(err, errSkipBody) {
+		r, err = pending, nil
+	}
+	if err != nil {
+		if !first && (clientGone(err) || errors.Is(err, os.ErrDeadlineExceeded)) {
+			logger.Debug("closing idle connection", "error", err)
+			return connClose
+		}
+		if clientGone(err) {
+			logger.Debug("client went away before sending a request", "error", err)
+			return connClose
+		}
+		logger.Warn("failed to parse request", "error", err)
+		_ = writeParseError(responseWriter, err)
+		return connLinger
+	}
+
+	attachConnInfo(r, conn)
+	if r.IsHTTP10() {
+		responseWriter.SetHTTP10()
+	}
+	if r.RequestLine.Method == "HEAD" {
+		responseWriter.SetHEAD()
+	}
+	// the body of a refused request was never read, so the next request
+	// can't be found
+	if netConn != nil && s.keepAlive && refused == nil && r.KeepAlive() && !s.closed.Load() {
+		responseWriter.SetKeepAlive()
+	}
+	r.TrustedProxies = s.proxies
+	logger = logger.With("method", r.RequestLine.Method, "target", r.RequestLine.RequestTarget)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	r = r.WithContext(ctx)
+	defer func() {
+		if err := r.RemoveTempFiles(); err != nil {
+			logger.Warn("failed to remove multipart temp files", "error", err)
+		}
+	}()
+	var stopWatch atomic.Bool
+	watchDone := make(chan struct{})
+	go watchDisconnect(conn, cancel, &stopWatch, &cr.early, watchDone)
+	responseWriter.SetHijacker(hijacker(conn, r.Buffered(), &cr.early, &stopWatch, watchDone))
+
+	var handler response.Handler
+	if s.handler != nil {
+		handler = s.handler
+	} else if s.router != nil {
+		handler = s.router.GetHandler(r)
+	} else {
+		body := []byte("")
+		h := response.GetDefaultHeaders(len(body))
+		_ = responseWriter.WriteResponse(response.StatusInternalServerError, h, body)
+		logger.Error("handler function does not exist")
+		return connClose
+	}
+
+	if refused != nil && !errors.Is(refused, errNoRoute) {
+		err = refused
+	} else {
+		err = handler(responseWriter, r)
+	}
+	if err != nil && responseWriter.Hijacked() {
+		logger.Error("error from hijacked handler", "error", err)
+		return connHijacked
+	}
+	if err != nil {
+		err = response.DefaultErrorHandler(responseWriter, r, err)
+	}
+	if fErr := responseWriter.Finish(); err == nil {
+		err = fErr
+	}
+	if errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, net.ErrClosed) {
+		logger.Debug("client went away", "error", err)
+		return connClose
+	}
+	if err != nil {
+		logger.Error("error from handler", "error", err)
+		return connClose
+	}
+
+	if !responseWriter.KeepAlive() || stopWatching(netConn, &stopWatch, watchDone) != nil {
+		return connClose
+	}
+	return connKeep
+}
+
+// checkContinue returns nil when a request expecting 100 Continue may send
+// its body, errNoRoute when the router would answer it without a handler, or
+// the error from Options.ExpectContinue.
+func (s *Server) checkContinue(r *request.Request) error {
+	if s.handler == nil && s.router != nil && !s.router.Match(r) {
+		return errNoRoute
+	}
+	if s.expect != nil {
+		return s.expect(r)
+	}
+	return nil
+}
+
+// serveConn handles conn and then frees its connection limit slot.
+func (s *Server) serveConn(conn io.ReadWriteCloser, ip string) {
+	defer s.limiter.release(ip)
+	s.handle(conn)
+}
+
+func Serve(port uint16, handler response.Handler, router *router.Router) (*Server, error) {
+	return ServeWithOptions(port, handler, router, Options{})
+}
+
+// ServeWithOptions is like Serve but configures the server with opts.
+func ServeWithOptions(port uint16, handler response.Handler, router *router.Router, opts Options) (*Server, error) {
+	if opts.Acceptors > 1 && reusePortSupported {
+		return serveReusePort(port, handler, router, opts)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, err
+	}
+
+	return ServeListener(listener, handler, router, opts), nil
+}
+
+// ServeListener serves connections accepted from listener, which the server
+// takes ownership of and closes on Close.
+func ServeListener(listener net.Listener, handler response.Handler, router *router.Router, opts Options) *Server {
+	s := New(handler, router, opts)
+	s.AddListener(listener, nil)
+	return s
+}
+
+// New creates a server that isn't listening anywhere yet; add listeners with
+// AddListener. Every listener shares the handler, limits, worker pool and
+// shutdown.
+func New(handler response.Handler, router *router.Router, opts Options) *Server {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server := &Server{
+		closed:      atomic.Bool{},
+		handler:     handler,
+		router:      router,
+		logger:      opts.Logger,
+		expect:      opts.ExpectContinue,
+		proxies:     opts.TrustedProxies,
+		limits:      opts.Limits,
+		limiter:     newConnLimiter(opts.MaxConnections, opts.MaxConnectionsPerIP),
+		rejecting:   make(chan struct{}, maxRejecting),
+		acceptors:   max(opts.Acceptors, 1),
+		keepAlive:   !opts.DisableKeepAlives,
+		idleTimeout: opts.IdleTimeout,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	if server.idleTimeout <= 0 {
+		server.idleTimeout = DefaultIdleTimeout
+	}
+	if opts.DefaultHeaders != nil {
+		server.defaults = opts.DefaultHeaders.Clone()
+	}
+
+	if opts.Workers > 0 {
+		server.pool = newWorkerPool(opts.QueueSize, opts.QueuePolicy, opts.RetryAfter)
+		server.startWorkers(opts.Workers)
+	}
+
+	return server
+}
+
+// AddListener starts accepting connections from l, wrapped in TLS when
+// tlsConfig is non-nil. The server takes ownership of l and closes it on
+// Close; adding a listener to a closed server closes it right away.
+func (s *Server) AddListener(l net.Listener, tlsConfig *tls.Config) {
+	s.addListener(l, tlsConfig, s.acceptors)
+}
+
+func (s *Server) addListener(l net.Listener, tlsConfig *tls.Config, acceptors int) {
+	if tlsConfig != nil {
+		l = tls.NewListener(l, tlsConfig)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed.Load() {
+		l.Close()
+		return
+	}
+	s.listeners = append(s.listeners, l)
+	for range acceptors {
+		go s.listen(l)
+	}
+}
+
+// Addr returns the address of the first listener, or nil if there is none.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.listeners) == 0 {
+		return nil
+	}
+	return s.listeners[0].Addr()
+}
+
+// Addrs returns the addresses of all listeners in the order they were added.
+func (s *Server) Addrs() []net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addrs := make([]net.Addr, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		addrs = append(addrs, l.Addr())
+	}
+	return addrs
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_ParseErrors(t *testing.T) {
+	l := NewPipeListener()
+	s := ServeListener(l, helloHandler, nil, Options{})
+	t.Cleanup(func() { s.Close() })
+
+	cases := []struct {
+		name   string
+		req    string
+		status string
+	}{
+		{"missing host", "GET / HTTP/1.1\r\n\r\n", "400 Bad Request"},
+		{"conflicting hosts", "GET / HTTP/1.1\r\nHost: a\r\nHost: b\r\n\r\n", "400 Bad Request"},
+		{"te and cl", "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 4\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\nGET /admin HTTP/1.1\r\nHost: x\r\n\r\n", "400 Bad Request"},
+		{"conflicting cl", "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 1\r\nContent-Length: 2\r\n\r\nab", "400 Bad Request"},
+		{"unknown coding", "POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: gzip\r\n\r\n", "501 Not Implemented"},
+		{"bad chunk size", "POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\n", "400 Bad Request"},
+		{"huge chunk", "POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\nffffffffffffffff\r\n", "413 Content Too Large"},
+	}
+
+	for _, tc := range cases {
+		conn, err := l.Dial()
+		require.NoError(t, err)
+		fmt.Fprint(conn, tc.req)
+		out, err := io.ReadAll(conn)
+		conn.Close()
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(string(out), "HTTP/1.1 "+tc.status+"\r\n"), tc.name)
+		// only one response, so nothing smuggled after the body was served
+		assert.Equal(t, 1, strings.Count(string(out), "HTTP/1.1 "), tc.name)
+	}
+}
+
+func TestServer_Limits(t *testing.T) {
+	l := NewPipeListener()
+	limits := request.Limits{MaxRequestLine: 32, MaxHeaderFields: 2, MaxBodySize: 4}
+	s := ServeListener(l, helloHandler, nil, Options{Limits: limits})
+	t.Cleanup(func() { s.Close() })
+
+	cases := []struct {
+		req    string
+		status string
+	}{
+		{"GET / HTTP/1.0\r\nHost: x\r\nA: 1\r\n\r\n", "200 OK"},
+		{"GET / HTTP/1.1\r\nHost: x\r\nA: 1\r\nB: 2\r\n\r\n", "431 Request Header Fields Too Large"},
+		{"GET /" + strings.Repeat("a", 32) + " HTTP/1.1\r\nHost: x\r\n\r\n", "414 URI Too Long"},
+		{"POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\n\r\n", "413 Content Too Large"},
+	}
+
+	for _, tc := range cases {
+		conn, err := l.Dial()
+		require.NoError(t, err)
+		fmt.Fprint(conn, tc.req)
+		out, err := io.ReadAll(conn)
+		conn.Close()
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(string(out), "HTTP/1.1 "+tc.status+"\r\n"), "%q", out)
+	}
+}
+
+func TestServer_ParseErrorResponse(t *testing.T) {
+	l := NewPipeListener()
+	s := ServeListener(l, helloHandler, nil, Options{})
+	t.Cleanup(func() { s.Close() })
+
+	conn, err := l.Dial()
+	require.NoError(t, err)
+	fmt.Fprint(conn, "GET / HTTP/1.1\r\n\r\n")
+	out, err := io.ReadAll(conn)
+	conn.Close()
+	require.NoError(t, err)
+
+	head, _, _ := strings.Cut(string(out), "\r\n\r\n")
+	assert.Contains(t, head, "\r\nConnection: close")
+	assert.Contains(t, head, "\r\nContent-Type: text/plain; charset=utf-8")
+	assert.Regexp(t, `\r\nDate: \w{3}, \d{2} \w{3} \d{4} \d{2}:\d{2}:\d{2} GMT`, head)
+}
+
+func TestServer_DefaultHeaders(t *testing.T) {
+	defaults := headers.NewHeaders()
+	defaults.Set("Server", "tcp-http-server")
+	l := NewPipeListener()
+	s := ServeListener(l, helloHandler, nil, Options{DefaultHeaders: defaults})
+	t.Cleanup(func() { s.Close() })
+	defaults.Set("X-Late", "1")
+
+	// Test: Responses and parse errors both get the defaults, as they were
+	for _, req := range []string{"GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n", "GET / HTTP/1.1\r\n\r\n"} {
+		conn, err := l.Dial()
+		require.NoError(t, err)
+		fmt.Fprint(conn, req)
+		out, err := io.ReadAll(conn)
+		conn.Close()
+		require.NoError(t, err)
+
+		head, _, _ := strings.Cut(string(out), "\r\n\r\n")
+		assert.Contains(t, head, "\r\nServer: tcp-http-server")
+		assert.Contains(t, head, "\r\nConnection: close")
+		assert.NotContains(t, head, "X-Late")
+	}
+}
+
+func TestServer_ParseErrorLingers(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := ServeListener(l, helloHandler, nil, Options{})
+	t.Cleanup(func() { s.Close() })
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the server stops reading at the bad header while the body is in flight
+	body := strings.Repeat("x", 128<<10)
+	go fmt.Fprintf(conn, "POST / HTTP/1.1\r\nHost: x\r\nBad Header: 1\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+
+	out, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(out), "HTTP/1.1 400 Bad Request\r\n"), "%q", out)
+	assert.True(t, strings.HasSuffix(string(out), "malformed header name"), "%q", out)
+
+	// Test: A client that hangs up without a request gets nothing
+	conn2, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn2.Close()
+	require.NoError(t, conn2.(*net.TCPConn).CloseWrite())
+	out, err = io.ReadAll(conn2)
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
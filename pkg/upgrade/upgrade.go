@@ -0,0 +1,155 @@
+package upgrade
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+)
+
+var (
+	ErrInvalidToken      = fmt.Errorf("invalid upgrade token")
+	ErrDuplicateUpgrader = fmt.Errorf("upgrader already registered")
+)
+
+// Upgrader switches a connection to another protocol.
+type Upgrader struct {
+	// Check, when set, validates the upgrade request before anything is
+	// sent. It returns extra fields for the 101 response (e.g.
+	// Sec-WebSocket-Accept) or an error, usually an *response.HTTPError, to
+	// refuse the upgrade with a normal response instead.
+	Check func(req *request.Request) (*headers.Headers, error)
+	// Serve speaks the new protocol after 101 Switching Protocols has been
+	// sent. rw reads any bytes the client sent after the request first. The
+	// connection is closed when Serve returns.
+	Serve func(conn net.Conn, rw *bufio.ReadWriter, req *request.Request)
+}
+
+// Registry maps upgrade tokens (websocket, h2c, ...) to their Upgrader.
+type Registry struct {
+	mu        sync.RWMutex
+	upgraders map[string]Upgrader
+}
+
+func NewRegistry() *Registry {
+	return &Registry{upgraders: map[string]Upgrader{}}
+}
+
+// Register adds u for token, which is matched case-insensitively against the
+// protocols in a request's Upgrade header, e.g. "websocket" or "h2c".
+func (reg *Registry) Register(token string, u Upgrader) error {
+	key := strings.ToLower(strings.TrimSpace(token))
+	if key == "" || strings.ContainsAny(key, " ,\t") || u.Serve == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidToken, token)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, ok := reg.upgraders[key]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateUpgrader, token)
+	}
+	reg.upgraders[key] = u
+	return nil
+}
+
+// headerTokens splits the comma-separated values of name.
+func headerTokens(h *headers.Headers, name string) []string {
+	tokens := []string{}
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+// match returns the first protocol the client offers that has an Upgrader,
+// honoring the client's order of preference.
+func (reg *Registry) match(req *request.Request) (string, Upgrader, bool) {
+	if req.IsHTTP10() {
+		return "", Upgrader{}, false // Upgrade is HTTP/1.1 only
+	}
+
+	upgradeRequested := false
+	for _, t := range headerTokens(req.Headers, "Connection") {
+		if strings.EqualFold(t, "upgrade") {
+			upgradeRequested = true
+			break
+		}
+	}
+	if !upgradeRequested {
+		return "", Upgrader{}, false
+	}
+
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for _, t := range headerTokens(req.Headers, "Upgrade") {
+		if u, ok := reg.upgraders[strings.ToLower(t)]; ok {
+			return t, u, true
+		}
+	}
+	return "", Upgrader{}, false
+}
+
+// Middleware switches protocols for requests asking for a registered upgrade
+// with Connection: Upgrade and Upgrade headers. It sends 101 Switching
+// Protocols itself and hands the hijacked connection to the Upgrader. Other
+// requests, including upgrades to unregistered protocols, go to the next
+// handler as ordinary requests.
+func (reg *Registry) Middleware() router.Middleware {
+	return func(next response.Handler) response.Handler {
+		return func(w *response.Writer, req *request.Request) error {
+			token, u, ok := reg.match(req)
+			if !ok {
+				return next(w, req)
+			}
+
+			extra := headers.NewHeaders()
+			if u.Check != nil {
+				h, err := u.Check(req)
+				if err != nil {
+					return err
+				}
+				if h != nil {
+					if err := h.Validate(); err != nil {
+						return err
+					}
+					extra = h
+				}
+			}
+
+			conn, rw, err := w.Hijack()
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			if err := writeSwitchingProtocols(rw, token, extra); err != nil {
+				return err
+			}
+			u.Serve(conn, rw, req)
+			return nil
+		}
+	}
+}
+
+func writeSwitchingProtocols(rw *bufio.ReadWriter, token string, extra *headers.Headers) error {
+	h := extra.Clone()
+	h.Replace("Connection", "Upgrade")
+	h.Replace("Upgrade", token)
+
+	fmt.Fprintf(rw, "HTTP/1.1 %d %s\r\n", response.StatusSwitchingProtocols, response.StatusText(response.StatusSwitchingProtocols))
+	h.ForEach(func(name, value string) {
+		fmt.Fprintf(rw, "%s: %s\r\n", name, value)
+	})
+	rw.WriteString("\r\n")
+	return rw.Flush()
+}
@@ -0,0 +1,134 @@
+package upgrade
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShazimR/tcp-http-server/pkg/headers"
+	"github.com/ShazimR/tcp-http-server/pkg/request"
+	"github.com/ShazimR/tcp-http-server/pkg/response"
+	"github.com/ShazimR/tcp-http-server/pkg/router"
+	"github.com/ShazimR/tcp-http-server/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoUpgrader echoes every line back upper-cased until the client closes.
+var echoUpgrader = Upgrader{
+	Check: func(req *request.Request) (*headers.Headers, error) {
+		if _, ok := req.Headers.Get("X-Deny"); ok {
+			return nil, response.NewHTTPError(response.StatusForbidden, "denied")
+		}
+		h := headers.NewHeaders()
+		h.Set("X-Echo-Version", "1")
+		return h, nil
+	},
+	Serve: func(conn net.Conn, rw *bufio.ReadWriter, req *request.Request) {
+		for {
+			line, err := rw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			rw.WriteString(strings.ToUpper(line))
+			rw.Flush()
+		}
+	},
+}
+
+func startServer(t *testing.T) string {
+	t.Helper()
+	reg := NewRegistry()
+	require.NoError(t, reg.Register("Echo", echoUpgrader))
+
+	r := router.NewRouter()
+	r.Use(reg.Middleware())
+	require.NoError(t, r.GET("/ws", func(w *response.Writer, req *request.Request) error {
+		body := []byte("plain")
+		return w.WriteResponse(response.StatusOK, response.GetDefaultHeaders(len(body)), body)
+	}))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := server.ServeListener(l, nil, r, server.Options{})
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String()
+}
+
+func readHead(t *testing.T, br *bufio.Reader) string {
+	t.Helper()
+	var sb strings.Builder
+	for {
+		line, err := br.ReadString('\n')
+		require.NoError(t, err)
+		sb.WriteString(line)
+		if line == "\r\n" {
+			return sb.String()
+		}
+	}
+}
+
+func TestRegistry_Upgrade(t *testing.T) {
+	addr := startServer(t)
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// the first line of the new protocol arrives with the request
+	_, err = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: x\r\nConnection: keep-alive, Upgrade\r\nUpgrade: h2c, echo\r\n\r\nearly\n")
+	require.NoError(t, err)
+
+	br := bufio.NewReader(conn)
+	head := readHead(t, br)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 101 Switching Protocols\r\n"))
+	assert.Contains(t, head, "Connection: Upgrade\r\n")
+	assert.Contains(t, head, "Upgrade: echo\r\n")
+	assert.Contains(t, head, "X-Echo-Version: 1\r\n")
+
+	line, err := br.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "EARLY\n", line)
+
+	_, err = io.WriteString(conn, "later\n")
+	require.NoError(t, err)
+	line, err = br.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "LATER\n", line)
+}
+
+func TestRegistry_FallThrough(t *testing.T) {
+	addr := startServer(t)
+	cases := []struct {
+		name   string
+		head   string
+		status string
+	}{
+		{"no upgrade", "Host: x\r\n", "HTTP/1.1 200 OK\r\n"},
+		{"unregistered protocol", "Host: x\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n", "HTTP/1.1 200 OK\r\n"},
+		{"upgrade token without connection option", "Host: x\r\nUpgrade: echo\r\n", "HTTP/1.1 200 OK\r\n"},
+		{"refused by check", "Host: x\r\nConnection: Upgrade\r\nUpgrade: echo\r\nX-Deny: 1\r\n", "HTTP/1.1 403 Forbidden\r\n"},
+	}
+
+	for _, tc := range cases {
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		_, err = io.WriteString(conn, "GET /ws HTTP/1.1\r\n"+tc.head+"Connection: close\r\n\r\n")
+		require.NoError(t, err)
+		out, err := io.ReadAll(conn)
+		conn.Close()
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(out), tc.status), tc.name)
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	reg := NewRegistry()
+	require.NoError(t, reg.Register("websocket", echoUpgrader))
+	assert.ErrorIs(t, reg.Register("WebSocket", echoUpgrader), ErrDuplicateUpgrader)
+	assert.ErrorIs(t, reg.Register("a, b", echoUpgrader), ErrInvalidToken)
+	assert.ErrorIs(t, reg.Register("custom", Upgrader{}), ErrInvalidToken)
+}